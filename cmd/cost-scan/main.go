@@ -3,24 +3,36 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/api"
 	"github.com/opscart/k8s-cost-optimizer/pkg/config"
+	"github.com/opscart/k8s-cost-optimizer/pkg/controller"
 	"github.com/opscart/k8s-cost-optimizer/pkg/converter"
+	"github.com/opscart/k8s-cost-optimizer/pkg/crd"
 	"github.com/opscart/k8s-cost-optimizer/pkg/datasource"
 	"github.com/opscart/k8s-cost-optimizer/pkg/executor"
+	"github.com/opscart/k8s-cost-optimizer/pkg/exporter"
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
 	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/policy"
 	"github.com/opscart/k8s-cost-optimizer/pkg/pricing"
 	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
 	"github.com/opscart/k8s-cost-optimizer/pkg/reporter"
 	"github.com/opscart/k8s-cost-optimizer/pkg/scanner"
 	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 var (
@@ -31,9 +43,11 @@ var (
 	namespace           string
 	allNamespaces       bool
 	outputFormat        string
+	vpaUpdateMode       string
 	saveResults         bool
 	clusterID           string
 	usePrometheus       bool
+	useCollected        bool
 	provider            string
 	region              string
 	dryRun              bool
@@ -44,17 +58,45 @@ var (
 	prometheusURL       string
 	lookbackDays        int
 	kubeconfigPath      string
+	dbLocation          string
+	writeCRs            bool
+	policyFile          string
+	workloadKindsFile   string
 
 	// Global config
 	cfg   *config.Config
 	store storage.Store
 
+	// openStore opens the storage backend; tests swap in an in-memory store
+	openStore = storage.New
+
 	// History command vars
 	historyLimit int
+
+	// Apply command vars
+	applyAll           bool
+	applyMinConfidence string
+	applyLimit         int
+	applyDryRun        bool
+	applyUser          string
+
+	// DB command vars
+	dbDownSteps int
+
+	// Serve command vars
+	scanInterval         time.Duration
+	scanDebounce         time.Duration
+	leaderElect          bool
+	leaderElectNamespace string
+	httpAddr             string
+
+	// Collect command vars
+	collectInterval      time.Duration
+	collectRetentionDays int
 )
 
 func logVerbose(format string, args ...interface{}) {
-	if verbose {
+	if verbose && !machineOutput() {
 		fmt.Printf("[DEBUG] "+format+"\n", args...)
 	}
 }
@@ -73,10 +115,12 @@ func main() {
 	// Scan flags
 	rootCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace to scan")
 	rootCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Scan all namespaces")
-	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, commands")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, commands, vpa")
+	rootCmd.Flags().StringVar(&vpaUpdateMode, "vpa-update-mode", "Off", "updateMode of the VerticalPodAutoscalers printed by -o vpa: Off or Initial")
 	rootCmd.Flags().BoolVar(&saveResults, "save", false, "Save recommendations to database")
 	rootCmd.Flags().StringVar(&clusterID, "cluster-id", "default", "Cluster identifier")
 	rootCmd.Flags().BoolVar(&usePrometheus, "use-prometheus", true, "Use Prometheus for P95/P99 metrics (default: true)")
+	rootCmd.Flags().BoolVar(&useCollected, "use-collected", false, "Analyze the usage saved to the database by 'cost-scan collect' when Prometheus is unavailable")
 	rootCmd.Flags().StringVar(&provider, "provider", "", "Cloud provider: azure, aws, gcp (auto-detect if empty)")
 	rootCmd.Flags().StringVar(&region, "region", "", "Cloud region (e.g., eastus, us-east-1)")
 	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show recommendations without saving")
@@ -87,6 +131,10 @@ func main() {
 	rootCmd.Flags().StringVar(&prometheusURL, "prometheus-url", "", "Prometheus URL (default: env PROMETHEUS_URL or http://localhost:9090)")
 	rootCmd.Flags().IntVar(&lookbackDays, "lookback-days", 7, "Days of historical data to analyze")
 	rootCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to kubeconfig file (default: ~/.kube/config)")
+	rootCmd.Flags().BoolVar(&writeCRs, "write-crs", false, "Write a CostRecommendation resource per workload container (requires the CRD)")
+	rootCmd.Flags().StringVar(&policyFile, "policy-file", "", "YAML file of OptimizationPolicy documents overriding safety buffers and thresholds")
+	rootCmd.Flags().StringVar(&workloadKindsFile, "workload-kinds", "", "YAML file of additional workload kinds to scan, e.g. Argo Rollouts")
+	rootCmd.PersistentFlags().StringVar(&dbLocation, "db", "", "Database: a SQLite file path or a postgres:// URL (default: STORAGE_TYPE and DATABASE_URL/SQLITE_PATH)")
 
 	// History command
 	historyCmd := &cobra.Command{
@@ -105,8 +153,39 @@ func main() {
 		Run:   runAudit,
 	}
 
+	// Apply command
+	applyCmd := &cobra.Command{
+		Use:   "apply [recommendation-id]",
+		Short: "Apply saved recommendations to the cluster",
+		Long:  "Patch workloads with a saved recommendation (or every eligible one with --all) and record the result in the audit log",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runApply,
+	}
+	applyCmd.Flags().BoolVar(&applyAll, "all", false, "Apply the latest pending recommendation of every workload in the namespace")
+	applyCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace to apply recommendations in (required with --all)")
+	applyCmd.Flags().StringVar(&applyMinConfidence, "min-confidence", "HIGH", "Minimum confidence for --all: LOW, MEDIUM, HIGH")
+	applyCmd.Flags().IntVar(&applyLimit, "limit", 100, "Number of saved recommendations to consider with --all")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show what would be applied without changing the cluster")
+	applyCmd.Flags().StringVar(&applyUser, "applied-by", "", "Identity recorded in the audit log (default: $USER)")
+	applyCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to kubeconfig file (default: ~/.kube/config)")
+	applyCmd.Flags().StringVar(&workloadKindsFile, "workload-kinds", "", "YAML file of additional workload kinds, e.g. Argo Rollouts")
+
 	rootCmd.AddCommand(historyCmd)
 	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(applyCmd)
+
+	// Rollback command
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback <recommendation-id>",
+		Short: "Undo an applied recommendation",
+		Long:  "Restore the container resources (or replica count) snapshotted when the recommendation was applied",
+		Args:  cobra.ExactArgs(1),
+		Run:   runRollback,
+	}
+	rollbackCmd.Flags().StringVar(&applyUser, "applied-by", "", "Identity recorded in the audit log (default: $USER)")
+	rollbackCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to kubeconfig file (default: ~/.kube/config)")
+	rollbackCmd.Flags().StringVar(&workloadKindsFile, "workload-kinds", "", "YAML file of additional workload kinds, e.g. Argo Rollouts")
+	rootCmd.AddCommand(rollbackCmd)
 
 	// Analytics command (after line 100)
 	analyticsCmd := &cobra.Command{
@@ -176,6 +255,87 @@ func main() {
 	// Add analytics to root
 	rootCmd.AddCommand(analyticsCmd)
 
+	// Database schema commands
+	dbCmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the database schema",
+		Long:  "Apply, inspect and revert schema migrations of the configured database",
+	}
+
+	dbMigrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending migrations",
+		Args:  cobra.NoArgs,
+		Run:   runDBMigrate,
+	}
+
+	dbStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Args:  cobra.NoArgs,
+		Run:   runDBStatus,
+	}
+
+	dbDownCmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the most recent migrations",
+		Long:  "Revert the most recently applied migrations. Data stored in dropped tables or columns is lost.",
+		Args:  cobra.NoArgs,
+		Run:   runDBDown,
+	}
+	dbDownCmd.Flags().IntVar(&dbDownSteps, "steps", 1, "Number of migrations to revert")
+
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbDownCmd)
+	rootCmd.AddCommand(dbCmd)
+
+	// Serve command
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run continuously as an in-cluster controller",
+		Long: `Watch workloads with shared informers and rescan on a schedule and whenever
+Deployments, StatefulSets, DaemonSets, Pods or HPAs change, saving every
+recommendation to the configured database`,
+		Args: cobra.NoArgs,
+		Run:  runServe,
+	}
+	serveCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace to watch (default: all namespaces)")
+	serveCmd.Flags().StringVar(&clusterID, "cluster-id", "default", "Cluster identifier")
+	serveCmd.Flags().DurationVar(&scanInterval, "scan-interval", time.Hour, "Time between scheduled scans")
+	serveCmd.Flags().DurationVar(&scanDebounce, "debounce", 30*time.Second, "Quiet period after a workload change before rescanning")
+	serveCmd.Flags().BoolVar(&leaderElect, "leader-elect", false, "Use a Lease so only one replica scans at a time")
+	serveCmd.Flags().StringVar(&leaderElectNamespace, "leader-elect-namespace", "", "Namespace of the leader election Lease (default: env POD_NAMESPACE or default)")
+	serveCmd.Flags().StringVar(&httpAddr, "http", "", "Serve the JSON API and Prometheus /metrics on this address, e.g. :8080 (disabled if empty)")
+	serveCmd.Flags().BoolVar(&usePrometheus, "use-prometheus", true, "Use Prometheus for P95/P99 metrics")
+	serveCmd.Flags().BoolVar(&useCollected, "use-collected", false, "Analyze the usage saved to the database by 'cost-scan collect' when Prometheus is unavailable")
+	serveCmd.Flags().StringVar(&prometheusURL, "prometheus-url", "", "Prometheus URL (default: env PROMETHEUS_URL or http://localhost:9090)")
+	serveCmd.Flags().IntVar(&lookbackDays, "lookback-days", 7, "Days of historical data to analyze")
+	serveCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to kubeconfig file (default: in-cluster config or ~/.kube/config)")
+	serveCmd.Flags().BoolVar(&writeCRs, "write-crs", false, "Write a CostRecommendation resource per workload container (requires the CRD)")
+	serveCmd.Flags().StringVar(&policyFile, "policy-file", "", "YAML file of OptimizationPolicy documents overriding safety buffers and thresholds")
+	serveCmd.Flags().StringVar(&workloadKindsFile, "workload-kinds", "", "YAML file of additional workload kinds to scan, e.g. Argo Rollouts")
+	serveCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	rootCmd.AddCommand(serveCmd)
+
+	// Collect command
+	collectCmd := &cobra.Command{
+		Use:   "collect",
+		Short: "Save metrics-server usage over time for history without Prometheus",
+		Long: `Poll the metrics API on an interval and save the usage of every container to
+the configured database, deleting samples older than the retention. Scans with
+--use-collected analyze this history (P95/P99, usage patterns and growth) like
+Prometheus history.`,
+		Args: cobra.NoArgs,
+		Run:  runCollect,
+	}
+	collectCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace to collect (default: all namespaces)")
+	collectCmd.Flags().StringVar(&clusterID, "cluster-id", "default", "Cluster identifier")
+	collectCmd.Flags().DurationVar(&collectInterval, "interval", datasource.DefaultCollectInterval, "Time between polls of the metrics API (at least 15s)")
+	collectCmd.Flags().IntVar(&collectRetentionDays, "retention-days", 14, "Days of usage to keep")
+	collectCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to kubeconfig file (default: in-cluster config or ~/.kube/config)")
+	rootCmd.AddCommand(collectCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -188,7 +348,7 @@ func initStorage() error {
 	}
 
 	var err error
-	store, err = storage.NewPostgresStore(cfg.DatabaseURL)
+	store, err = openStore(storageConfig())
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
@@ -198,103 +358,234 @@ func initStorage() error {
 
 func initStorageForced() error {
 	var err error
-	store, err = storage.NewPostgresStore(cfg.DatabaseURL)
+	store, err = openStore(storageConfig())
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	return nil
 }
 
+// scanStorageConfig is storageConfig for scan --save and serve: with no
+// database configured at all, results are kept in memory for the process
+// instead of failing to reach the default local PostgreSQL
+func scanStorageConfig() storage.Config {
+	if dbLocation == "" && !cfg.DatabaseConfigured {
+		return storage.Config{Type: storage.TypeMemory}
+	}
+	return storageConfig()
+}
+
+// configuredLimitPolicy returns the validated limit policy from the config
+func configuredLimitPolicy() (recommender.LimitPolicy, error) {
+	limitPolicy := recommender.LimitPolicy{
+		CPU:      cfg.CPULimitPolicy,
+		CPURatio: cfg.CPULimitRatio,
+		Memory:   cfg.MemoryLimitPolicy,
+	}
+	return limitPolicy, limitPolicy.Validate()
+}
+
+// prometheusSettings returns the Prometheus URL and lookback window, using
+// flags first and then falling back to config
+func prometheusSettings() (string, int) {
+	finalPrometheusURL := prometheusURL
+	if finalPrometheusURL == "" {
+		finalPrometheusURL = cfg.PrometheusURL
+	}
+	if finalPrometheusURL == "" {
+		finalPrometheusURL = "http://localhost:9090" // Final fallback
+	}
+
+	finalLookbackDays := lookbackDays
+	if finalLookbackDays == 0 {
+		finalLookbackDays = cfg.MetricsLookbackDays
+	}
+	if finalLookbackDays == 0 {
+		finalLookbackDays = 7 // Default
+	}
+
+	return finalPrometheusURL, finalLookbackDays
+}
+
+// configureDataSource makes scan read usage from Prometheus when it is
+// enabled and reachable, then from the collected usage in store with
+// --use-collected, falling back to metrics-server. It returns the chain of
+// sources with the lookback window for history.
+func configureDataSource(ctx context.Context, scan *scanner.Scanner) (datasource.DataSource, int) {
+	finalPrometheusURL, finalLookbackDays := prometheusSettings()
+
+	dsConfig := datasource.Config{UseMetricsServer: true}
+	if usePrometheus {
+		dsConfig.PrometheusURL = finalPrometheusURL
+	}
+	if useCollected {
+		dsConfig.CollectedStore = store
+		dsConfig.ClusterID = clusterID
+	}
+	source, skipped := datasource.New(ctx, dsConfig, scan.DataSource())
+	scan.WithDataSource(source)
+
+	if !machineOutput() {
+		for _, err := range skipped {
+			fmt.Printf("[WARN] %v, falling back to metrics-server\n", err)
+		}
+		if usePrometheus && finalPrometheusURL == "" {
+			fmt.Println("[INFO] Prometheus URL not configured, using metrics-server")
+			fmt.Println("[INFO] Set --prometheus-url flag or PROMETHEUS_URL environment variable")
+		}
+		switch datasource.HistoryOf(source).(type) {
+		case *datasource.PrometheusSource:
+			fmt.Printf("[INFO] Using Prometheus at %s (%d days lookback)\n", finalPrometheusURL, finalLookbackDays)
+		case *datasource.CollectedSource:
+			fmt.Printf("[INFO] Using collected usage (%d days lookback)\n", finalLookbackDays)
+		}
+	}
+	return source, finalLookbackDays
+}
+
+// prometheusOnlyScanner returns a scanner of Prometheus alone when the
+// cluster cannot be reached (clusterErr) but a Prometheus URL was given
+// with --prometheus-url or PROMETHEUS_URL
+func prometheusOnlyScanner(clusterErr error) (*scanner.Scanner, error) {
+	// cfg.PrometheusURL defaults to localhost, which is no sign of a
+	// Prometheus to scan
+	url := prometheusURL
+	if url == "" {
+		url = os.Getenv("PROMETHEUS_URL")
+	}
+	if !usePrometheus || url == "" {
+		return nil, clusterErr
+	}
+
+	source, err := datasource.NewPrometheusSource(url)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "[INFO] No cluster access (%v)\n", clusterErr)
+	fmt.Fprintf(os.Stderr, "[INFO] Scanning from Prometheus at %s alone: container resources only\n", url)
+	return scanner.NewFromPrometheus(source, verbose && !machineOutput()), nil
+}
+
+// storageConfig selects the storage backend from --db, falling back to the
+// environment configuration
+func storageConfig() storage.Config {
+	if dbLocation != "" {
+		return storage.ParseDB(dbLocation)
+	}
+	return storage.Config{
+		Type: cfg.StorageType,
+		URL:  cfg.DatabaseURL,
+		Path: cfg.SQLitePath,
+	}
+}
+
+// machineOutput reports whether the scan output is meant for kubectl, so
+// nothing else may be printed
+func machineOutput() bool {
+	return outputFormat == "commands" || outputFormat == "vpa"
+}
+
 func runScan(cmd *cobra.Command, args []string) {
 	if namespace == "" && !allNamespaces {
 		fmt.Fprintln(os.Stderr, "Error: either --namespace or --all-namespaces must be specified")
 		os.Exit(1)
 	}
 
-	if outputFormat != "text" && outputFormat != "json" && outputFormat != "commands" {
-		fmt.Fprintln(os.Stderr, "Error: output must be text, json, or commands")
+	if outputFormat != "text" && outputFormat != "json" && !machineOutput() {
+		fmt.Fprintln(os.Stderr, "Error: output must be text, json, commands, or vpa")
+		os.Exit(1)
+	}
+	if mode := kube.VPAUpdateMode(vpaUpdateMode); mode != kube.VPAUpdateModeOff && mode != kube.VPAUpdateModeInitial {
+		fmt.Fprintln(os.Stderr, "Error: --vpa-update-mode must be Off or Initial")
 		os.Exit(1)
 	}
 
-	// Initialize storage if --save flag is used
-	if saveResults {
-		if err := initStorageForced(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	// Initialize storage if --save or --use-collected is used
+	storeConfig := scanStorageConfig()
+	if useCollected {
+		// Collected usage is only ever in a configured database
+		storeConfig = storageConfig()
+	}
+	if saveResults || useCollected {
+		var err error
+		store, err = openStore(storeConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to initialize storage: %v\n", err)
 			os.Exit(1)
 		}
 		defer store.Close()
 	}
 
-	if outputFormat != "commands" {
+	if !machineOutput() {
 		fmt.Println("[INFO] K8s Cost Optimizer - Starting scan")
-		if saveResults && !dryRun {
+		if saveResults && !dryRun && storeConfig.Type == storage.TypeMemory {
+			fmt.Println("[INFO] No database configured: results are kept in memory for this run only")
+			fmt.Println("[INFO] Set DATABASE_URL, STORAGE_TYPE or --db to persist them")
+		} else if saveResults && !dryRun {
 			fmt.Println("[INFO] Results will be saved to database")
 		} else if dryRun {
 			fmt.Println("[INFO] Dry-run mode: recommendations will not be saved")
 		}
 	}
 
-	// Initialize scanner with verbose flag
-	scan, err := scanner.New(kubeconfigPath, verbose)
+	// Initialize scanner with verbose flag; its debug output would be mixed
+	// into machine output
+	scan, err := scanner.New(kubeconfigPath, verbose && !machineOutput())
+	if err != nil {
+		scan, err = prometheusOnlyScanner(err)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing scanner: %v\n", err)
 		os.Exit(1)
 	}
 
-	ctx := context.Background()
-
-	// Initialize Prometheus datasource if enabled
-	var promDS *datasource.PrometheusSource
-	var metricsSource string = "metrics-server (instant)" // Default
-
-	// Use flags first, then fall back to config
-	finalPrometheusURL := prometheusURL
-	if finalPrometheusURL == "" {
-		finalPrometheusURL = cfg.PrometheusURL
-	}
-	if finalPrometheusURL == "" {
-		finalPrometheusURL = "http://localhost:9090" // Final fallback
+	// Limit policy
+	limitPolicy, err := configuredLimitPolicy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+	scan.WithLimitPolicy(limitPolicy)
 
-	finalLookbackDays := lookbackDays
-	if finalLookbackDays == 0 {
-		finalLookbackDays = cfg.MetricsLookbackDays
+	kinds, err := loadWorkloadKinds()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
-	if finalLookbackDays == 0 {
-		finalLookbackDays = 7 // Default
+	scan.WithWorkloadKinds(kinds)
+	if len(kinds) > 0 && !machineOutput() {
+		fmt.Printf("[INFO] Scanning %d additional workload kind(s)\n", len(kinds))
 	}
 
-	if usePrometheus && finalPrometheusURL != "" {
-		promDS, err = datasource.NewPrometheusSource(finalPrometheusURL)
+	ctx := context.Background()
 
-		if err != nil {
-			if outputFormat != "commands" {
-				fmt.Printf("[WARN] Prometheus initialization failed: %v\n", err)
-				fmt.Println("[INFO] Falling back to metrics-server")
-			}
-			promDS = nil
-		} else if promDS.IsAvailable(ctx) {
-			metricsSource = fmt.Sprintf("Prometheus P95/P99 (%d days lookback)", finalLookbackDays)
-			if outputFormat != "commands" {
-				fmt.Printf("[INFO] Using Prometheus at %s\n", finalPrometheusURL)
-				fmt.Printf("[INFO] Metrics window: %d days, Safety buffer: %.1fx\n",
-					finalLookbackDays, cfg.SafetyBuffer)
-			}
-		} else {
-			if outputFormat != "commands" {
-				fmt.Println("[WARN] Prometheus not reachable, falling back to metrics-server")
-			}
-			promDS = nil
-		}
-	} else if usePrometheus && finalPrometheusURL == "" {
-		if outputFormat != "commands" {
-			fmt.Println("[INFO] Prometheus URL not configured, using metrics-server")
-			fmt.Println("[INFO] Set --prometheus-url flag or PROMETHEUS_URL environment variable")
-		}
+	policies, err := loadPolicies(ctx, scan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	scan.WithPolicies(policies)
+	if policies.Len() > 0 && !machineOutput() {
+		fmt.Printf("[INFO] Applying %d optimization policy(ies)\n", policies.Len())
 	}
 
-	// Historical analyzer will be integrated in Week 6 Day 4-5
-	// For now, standard metrics flow continues below
+	// Prometheus or collected history, falling back to metrics-server.
+	// Scans without a cluster read Prometheus alone.
+	var source datasource.DataSource
+	var finalLookbackDays int
+	if scan.GetClientset() == nil {
+		source = scan.DataSource()
+		_, finalLookbackDays = prometheusSettings()
+	} else {
+		source, finalLookbackDays = configureDataSource(ctx, scan)
+	}
+	metricsSource := "metrics-server (instant)"
+	switch datasource.HistoryOf(source).(type) {
+	case *datasource.PrometheusSource:
+		metricsSource = fmt.Sprintf("Prometheus P95/P99 (%d days lookback)", finalLookbackDays)
+	case *datasource.CollectedSource:
+		metricsSource = fmt.Sprintf("collected metrics-server P95/P99 (%d days lookback)", finalLookbackDays)
+	}
 
 	// Cloud provider - use flags if provided, otherwise auto-detect
 	detectedProvider := provider
@@ -305,7 +596,7 @@ func runScan(cmd *cobra.Command, args []string) {
 		detectedRegion = "unknown"
 		if clientset := scan.GetClientset(); clientset != nil {
 			detectedProvider, detectedRegion, err = pricing.DetectProvider(ctx, clientset)
-			if err != nil && outputFormat != "commands" {
+			if err != nil && !machineOutput() {
 				fmt.Printf("[WARN] Cloud detection failed: %v, using default\n", err)
 				detectedProvider = "default"
 			}
@@ -328,74 +619,52 @@ func runScan(cmd *cobra.Command, args []string) {
 
 	_, err = pricing.NewProvider(ctx, scan.GetClientset(), pricingConfig)
 	if err != nil {
-		if outputFormat != "commands" {
+		if !machineOutput() {
 			fmt.Printf("[WARN] Pricing provider failed: %v, using defaults\n", err)
 		}
 		_ = pricing.NewDefaultProvider(23.0, 3.0)
 	}
 
 	// Get version info
-	versionInfo, err := scan.GetClientset().Discovery().ServerVersion()
-	if err == nil && outputFormat != "commands" {
-		fmt.Printf("[INFO] Connected to cluster (version: %s)\n", versionInfo.String())
+	if clientset := scan.GetClientset(); clientset != nil {
+		versionInfo, err := clientset.Discovery().ServerVersion()
+		if err == nil && !machineOutput() {
+			fmt.Printf("[INFO] Connected to cluster (version: %s)\n", versionInfo.String())
+		}
 	}
 
-	if outputFormat != "commands" {
+	if !machineOutput() {
 		fmt.Printf("[INFO] Cloud provider: %s (region: %s)\n", detectedProvider, detectedRegion)
 		fmt.Printf("[INFO] Metrics source: %s\n", metricsSource)
 		fmt.Printf("[INFO] Scanning namespace: %s\n", namespace)
 	}
 
-	var oldRecommendations []*recommender.Recommendation
-
-	if promDS != nil {
-		// Use historical analyzer with Prometheus
-		if outputFormat != "commands" {
-			fmt.Printf("[INFO] Using historical analysis (P95 over %d days)\n", finalLookbackDays)
-		}
-
-		// Get the Prometheus API client
-		promClient := promDS.GetAPIClient()
-		oldRecommendations, err = scan.ScanAndRecommendWithHistory(
-			ctx,
-			namespace,
-			allNamespaces,
-			promClient,
-			finalLookbackDays,
-		)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error scanning with historical data: %v\n", err)
-			fmt.Println("[INFO] Falling back to instant metrics")
-			// Fallback to standard scan
-			oldRecommendations, err = scan.ScanAndRecommend(namespace, allNamespaces)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error scanning cluster: %v\n", err)
-				os.Exit(1)
-			}
-		}
-	} else {
-		// Standard scan with instant metrics
-		if outputFormat != "commands" {
-			fmt.Println("[INFO] Using instant metrics (Prometheus not available)")
-		}
-		oldRecommendations, err = scan.ScanAndRecommend(namespace, allNamespaces)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error scanning cluster: %v\n", err)
-			os.Exit(1)
-		}
+	oldRecommendations, err := scan.Scan(ctx, namespace, allNamespaces, finalLookbackDays)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning cluster: %v\n", err)
+		os.Exit(1)
 	}
 
 	if len(oldRecommendations) == 0 {
-		if outputFormat != "commands" {
+		if !machineOutput() {
 			fmt.Println("[INFO] No optimization opportunities found")
 		}
 		return
 	}
 
-	if outputFormat != "commands" {
+	if !machineOutput() {
 		fmt.Printf("[INFO] Found %d recommendation(s)\n\n", len(oldRecommendations))
 	}
 
+	var crWriter *crd.Writer
+	if writeCRs && !dryRun {
+		crWriter, err = newCRWriter(scan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Convert to new models and save if requested
 	var recommendations []*models.Recommendation
 	totalSavings := 0.0
@@ -410,11 +679,17 @@ func runScan(cmd *cobra.Command, args []string) {
 		if saveResults && !dryRun && store != nil {
 			if err := store.SaveRecommendation(ctx, newRec); err != nil {
 				fmt.Fprintf(os.Stderr, "[WARN] Failed to save recommendation: %v\n", err)
-			} else if outputFormat != "commands" {
+			} else if !machineOutput() {
 				fmt.Printf("[INFO] Saved recommendation for %s/%s (ID: %s)\n",
 					newRec.Workload.Namespace, newRec.Workload.Deployment, newRec.ID)
 			}
 		}
+
+		if crWriter != nil {
+			if err := crWriter.Write(ctx, newRec); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] %v\n", err)
+			}
+		}
 	}
 
 	// Output results
@@ -423,6 +698,8 @@ func runScan(cmd *cobra.Command, args []string) {
 		outputJSON(recommendations, totalSavings)
 	case "commands":
 		outputCommands(oldRecommendations)
+	case "vpa":
+		outputVPAs(oldRecommendations, kinds)
 	default:
 		outputText(recommendations, totalSavings)
 	}
@@ -434,33 +711,218 @@ func runScan(cmd *cobra.Command, args []string) {
 	}
 }
 
-// Helper function to group pod analyses by deployment
-func groupPodsByDeployment(analyses []analyzer.PodAnalysis) map[string][]analyzer.PodAnalysis {
-	deploymentPods := make(map[string][]analyzer.PodAnalysis)
+func runServe(cmd *cobra.Command, args []string) {
+	if scanInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --scan-interval must be positive")
+		os.Exit(1)
+	}
+
+	storeConfig := scanStorageConfig()
+	var err error
+	store, err = openStore(storeConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize storage: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
 
-	for _, analysis := range analyses {
-		// Extract deployment name from pod name (before last dash and hash)
-		deploymentName := extractDeploymentName(analysis.Name)
-		deploymentPods[deploymentName] = append(deploymentPods[deploymentName], analysis)
+	fmt.Println("[INFO] K8s Cost Optimizer - Starting controller")
+	if storeConfig.Type == storage.TypeMemory {
+		fmt.Println("[WARN] No database configured: recommendations are kept in memory and lost on restart")
+		if useCollected {
+			fmt.Println("[WARN] No database configured: there is no collected usage to analyze")
+		}
 	}
 
-	return deploymentPods
-}
+	scan, err := scanner.New(kubeconfigPath, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing scanner: %v\n", err)
+		os.Exit(1)
+	}
+
+	limitPolicy, err := configuredLimitPolicy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	scan.WithLimitPolicy(limitPolicy)
+
+	kinds, err := loadWorkloadKinds()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	scan.WithWorkloadKinds(kinds)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-// Extract deployment name from pod name (e.g., "api-server-7d9f8b-xyz" -> "api-server")
-func extractDeploymentName(podName string) string {
-	// Simple heuristic: remove last two dash-separated segments (replicaset hash + pod hash)
-	parts := []rune(podName)
-	dashCount := 0
-	for i := len(parts) - 1; i >= 0; i-- {
-		if parts[i] == '-' {
-			dashCount++
-			if dashCount == 2 {
-				return string(parts[:i])
+	// Policies are loaded now to fail fast on a bad file, and reloaded before
+	// every scan to pick up OptimizationPolicy changes
+	policies, err := loadPolicies(ctx, scan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	scan.WithPolicies(policies)
+
+	_, finalLookbackDays := configureDataSource(ctx, scan)
+
+	allNamespaces := namespace == ""
+	var factoryOptions []informers.SharedInformerOption
+	if allNamespaces {
+		fmt.Println("[INFO] Watching all namespaces")
+	} else {
+		fmt.Printf("[INFO] Watching namespace: %s\n", namespace)
+		factoryOptions = append(factoryOptions, informers.WithNamespace(namespace))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(scan.GetClientset(), 0, factoryOptions...)
+
+	scanFunc := func(ctx context.Context) ([]*recommender.Recommendation, error) {
+		if policies, err := loadPolicies(ctx, scan); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] Keeping previous optimization policies: %v\n", err)
+		} else {
+			scan.WithPolicies(policies)
+		}
+
+		return scan.Scan(ctx, namespace, allNamespaces, finalLookbackDays)
+	}
+
+	metrics := exporter.New(clusterID)
+	recorders := []controller.Recorder{metrics}
+	if writeCRs {
+		crWriter, err := newCRWriter(scan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		recorders = append(recorders, crWriter)
+		fmt.Println("[INFO] Writing CostRecommendation resources")
+	}
+
+	ctrl := controller.New(factory, scanFunc, store, controller.Options{
+		Interval:  scanInterval,
+		Debounce:  scanDebounce,
+		ClusterID: clusterID,
+		Recorders: recorders,
+	})
+	// Scans read workloads from the informer caches instead of the API server
+	scan.WithLister(ctrl.Lister())
+
+	fmt.Printf("[INFO] Scan interval: %s, debounce: %s\n", scanInterval, scanDebounce)
+
+	// The API runs on every replica; only the leader scans
+	apiErr := make(chan error, 1)
+	if httpAddr != "" {
+		server := api.NewServer(store, ctrl).WithToken(os.Getenv("COST_SCAN_API_TOKEN"))
+		server.Handle("GET /metrics", metrics.Handler())
+		fmt.Printf("[INFO] Serving API and /metrics on %s\n", httpAddr)
+		if os.Getenv("COST_SCAN_API_TOKEN") == "" {
+			fmt.Fprintln(os.Stderr, "[WARN] COST_SCAN_API_TOKEN is not set: anyone reaching the API can trigger scans")
+		}
+		go func() {
+			err := server.ListenAndServe(ctx, httpAddr)
+			if err != nil {
+				// Stop the controller too rather than run without the API
+				stop()
 			}
+			apiErr <- err
+		}()
+	}
+
+	if leaderElect {
+		identity, err := os.Hostname()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to determine leader election identity: %v\n", err)
+			os.Exit(1)
 		}
+		leNamespace := leaderElectNamespace
+		if leNamespace == "" {
+			leNamespace = os.Getenv("POD_NAMESPACE")
+		}
+		if leNamespace == "" {
+			leNamespace = "default"
+		}
+
+		le := controller.DefaultLeaderElection(leNamespace, identity)
+		err = controller.RunWithLeaderElection(ctx, scan.GetClientset(), le, ctrl.Run)
+	} else {
+		err = ctrl.Run(ctx)
 	}
-	return podName
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if httpAddr != "" {
+		stop()
+		if err := <-apiErr; err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("[INFO] Controller stopped")
+}
+
+func runCollect(cmd *cobra.Command, args []string) {
+	if collectRetentionDays < 1 {
+		fmt.Fprintln(os.Stderr, "Error: --retention-days must be at least 1")
+		os.Exit(1)
+	}
+
+	storeConfig := storageConfig()
+	var err error
+	store, err = openStore(storeConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize storage: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	fmt.Println("[INFO] K8s Cost Optimizer - Starting usage collector")
+	if storeConfig.Type == storage.TypeMemory {
+		fmt.Println("[WARN] In-memory storage: collected usage is lost on exit and cannot be scanned")
+	}
+
+	scan, err := scanner.New(kubeconfigPath, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing scanner: %v\n", err)
+		os.Exit(1)
+	}
+	metricsClient, err := metricsv.NewForConfig(scan.GetRESTConfig())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create metrics client: %v\n", err)
+		os.Exit(1)
+	}
+
+	collector := datasource.NewCollector(
+		datasource.NewMetricsServerSource(scan.GetClientset(), metricsClient),
+		store,
+		datasource.CollectorOptions{
+			ClusterID: clusterID,
+			Namespace: namespace,
+			Interval:  collectInterval,
+			Retention: time.Duration(collectRetentionDays) * 24 * time.Hour,
+		},
+	)
+
+	if namespace == "" {
+		fmt.Println("[INFO] Collecting all namespaces")
+	} else {
+		fmt.Printf("[INFO] Collecting namespace: %s\n", namespace)
+	}
+	fmt.Printf("[INFO] Interval: %s, retention: %d days\n", collector.Interval(), collectRetentionDays)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := collector.Run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("[INFO] Collector stopped")
 }
 
 func runHistory(cmd *cobra.Command, args []string) {
@@ -490,7 +952,11 @@ func runHistory(cmd *cobra.Command, args []string) {
 		fmt.Printf("%d. %s (ID: %s)\n", i+1, rec.Workload.Deployment, rec.ID)
 		fmt.Printf("   Type: %s\n", rec.Type)
 		fmt.Printf("   Savings: $%.2f/mo\n", rec.SavingsMonthly)
-		fmt.Printf("   Status: %s\n", "pending")
+		status := "pending"
+		if rec.AppliedAt != nil {
+			status = fmt.Sprintf("applied %s by %s", rec.AppliedAt.Format("2006-01-02 15:04:05"), rec.AppliedBy)
+		}
+		fmt.Printf("   Status: %s\n", status)
 		fmt.Printf("   Created: %s\n", rec.CreatedAt.Format("2006-01-02 15:04:05"))
 		fmt.Println()
 	}
@@ -517,6 +983,9 @@ func runAudit(cmd *cobra.Command, args []string) {
 
 	fmt.Printf("Recommendation: %s\n", rec.ID)
 	fmt.Printf("Deployment: %s (Namespace: %s)\n", rec.Workload.Deployment, rec.Workload.Namespace)
+	if rec.Workload.Container != "" {
+		fmt.Printf("Container: %s\n", rec.Workload.Container)
+	}
 	fmt.Printf("Type: %s\n", rec.Type)
 	fmt.Printf("Savings: $%.2f/mo\n", rec.SavingsMonthly)
 	fmt.Printf("Created: %s\n\n", rec.CreatedAt.Format("2006-01-02 15:04:05"))
@@ -547,23 +1016,274 @@ func runAudit(cmd *cobra.Command, args []string) {
 	}
 }
 
+// confidenceRank orders confidence levels for --min-confidence filtering
+var confidenceRank = map[string]int{
+	"LOW":    1,
+	"MEDIUM": 2,
+	"HIGH":   3,
+}
+
+func runApply(cmd *cobra.Command, args []string) {
+	if applyAll == (len(args) == 1) {
+		fmt.Fprintln(os.Stderr, "Error: specify either a recommendation ID or --all")
+		os.Exit(1)
+	}
+	if applyAll && namespace == "" {
+		fmt.Fprintln(os.Stderr, "Error: --namespace is required with --all")
+		os.Exit(1)
+	}
+
+	minRank, ok := confidenceRank[strings.ToUpper(applyMinConfidence)]
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Error: --min-confidence must be LOW, MEDIUM, or HIGH")
+		os.Exit(1)
+	}
+
+	if err := initStorageForced(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	var targets []*models.Recommendation
+	if applyAll {
+		recommendations, err := store.ListRecommendations(ctx, namespace, applyLimit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		targets = selectApplicable(recommendations, minRank)
+	} else {
+		rec, err := store.GetRecommendation(ctx, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		targets = []*models.Recommendation{rec}
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("[INFO] No recommendations to apply")
+		return
+	}
+
+	scan, err := scanner.New(kubeconfigPath, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+	applier, err := newApplier(scan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// CostRecommendations are optional; a missing CRD is not an error
+	crWriter, err := newCRWriter(scan)
+	if err != nil {
+		fmt.Printf("[WARN] %v\n", err)
+	}
+
+	failed := 0
+	for _, rec := range targets {
+		target := fmt.Sprintf("%s/%s", rec.Workload.Namespace, rec.Workload.Deployment)
+		if rec.Workload.Container != "" {
+			target = fmt.Sprintf("%s (container: %s)", target, rec.Workload.Container)
+		}
+
+		if applyDryRun {
+			fmt.Printf("[DRY-RUN] Would apply %s to %s (ID: %s)\n", rec.Type, target, rec.ID)
+			continue
+		}
+
+		if err := applier.Apply(ctx, rec); errors.Is(err, executor.ErrSnapshotNotSaved) {
+			fmt.Fprintf(os.Stderr, "[WARN] %s cannot be rolled back: %v\n", rec.ID, err)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to apply %s to %s: %v\n", rec.ID, target, err)
+			failed++
+			continue
+		}
+		fmt.Printf("[INFO] Applied %s to %s (ID: %s, savings: $%.2f/month)\n",
+			rec.Type, target, rec.ID, rec.SavingsMonthly)
+
+		if crWriter != nil {
+			if err := crWriter.MarkApplied(ctx, rec); err != nil {
+				fmt.Printf("[WARN] %v\n", err)
+			}
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "[ERROR] %d of %d recommendation(s) failed\n", failed, len(targets))
+		os.Exit(1)
+	}
+}
+
+func runRollback(cmd *cobra.Command, args []string) {
+	if err := initStorageForced(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	rec, err := store.GetRecommendation(ctx, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	scan, err := scanner.New(kubeconfigPath, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+	applier, err := newApplier(scan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := applier.Rollback(ctx, rec); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to roll back %s: %v\n", rec.ID, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("[INFO] Rolled back %s on %s/%s (ID: %s)\n",
+		rec.Type, rec.Workload.Namespace, rec.Workload.Deployment, rec.ID)
+}
+
+// loadPolicies combines SAFETY_BUFFER, the --policy-file policies and the
+// cluster's OptimizationPolicy resources. Equally specific policies are
+// applied in that order, so the cluster's win.
+func loadPolicies(ctx context.Context, scan *scanner.Scanner) (*policy.Set, error) {
+	var policies []policy.OptimizationPolicy
+
+	if cfg.SafetyBufferConfigured {
+		buffer := cfg.SafetyBuffer
+		policies = append(policies, policy.OptimizationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "SAFETY_BUFFER"},
+			Spec:       policy.Spec{SafetyBuffer: &buffer},
+		})
+	}
+
+	if policyFile != "" {
+		filePolicies, err := policy.LoadFile(policyFile)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, filePolicies...)
+	}
+
+	// OptimizationPolicy resources are optional
+	if restConfig := scan.GetRESTConfig(); restConfig != nil {
+		client, err := dynamic.NewForConfig(restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+		}
+		clusterPolicies, err := policy.LoadCluster(ctx, client)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] Ignoring OptimizationPolicy resources: %v\n", err)
+		}
+		// Another team's invalid policy must not stop the scan
+		for _, p := range clusterPolicies {
+			if err := policy.Validate(p); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] Ignoring invalid OptimizationPolicy: %v\n", err)
+				continue
+			}
+			policies = append(policies, p)
+		}
+	}
+
+	return policy.NewSet(policies)
+}
+
+// loadWorkloadKinds reads the --workload-kinds file, if any
+func loadWorkloadKinds() ([]kube.WorkloadKind, error) {
+	if workloadKindsFile == "" {
+		return nil, nil
+	}
+	return kube.LoadWorkloadKinds(workloadKindsFile)
+}
+
+// newApplier creates an applier using the scanner's cluster connection,
+// able to update the --workload-kinds kinds
+func newApplier(scan *scanner.Scanner) (*executor.Applier, error) {
+	kinds, err := loadWorkloadKinds()
+	if err != nil {
+		return nil, err
+	}
+	return executor.NewApplier(scan.GetClientset(), store).
+		WithUser(applyUser).
+		WithWorkloadKinds(scan.GetDynamicClient(), scan.GetRESTMapper(), kinds), nil
+}
+
+// newCRWriter creates a CostRecommendation writer using the scanner's
+// cluster connection
+func newCRWriter(scan *scanner.Scanner) (*crd.Writer, error) {
+	restConfig := scan.GetRESTConfig()
+	if restConfig == nil {
+		return nil, fmt.Errorf("cannot write CostRecommendations: no cluster configuration")
+	}
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return crd.NewWriter(client), nil
+}
+
+// selectApplicable picks the newest recommendation per workload container and
+// keeps it only if it is actionable, still pending and confident enough.
+// recommendations must be ordered newest first.
+func selectApplicable(recommendations []*models.Recommendation, minRank int) []*models.Recommendation {
+	seen := make(map[string]bool)
+	var selected []*models.Recommendation
+
+	for _, rec := range recommendations {
+		key := rec.Workload.Namespace + "/" + rec.Workload.Deployment + "/" + rec.Workload.Container
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if rec.Type == models.RecommendationNoAction || rec.AppliedAt != nil {
+			continue
+		}
+		if confidenceRank[rec.Confidence] < minRank {
+			continue
+		}
+		selected = append(selected, rec)
+	}
+
+	return selected
+}
+
 func outputText(recommendations []*models.Recommendation, totalSavings float64) {
 	if len(recommendations) == 0 {
 		fmt.Println("[INFO] No optimization opportunities found")
 		return
 	}
 
-	fmt.Println("=== Optimization Recommendations ===\n")
+	fmt.Print("=== Optimization Recommendations ===\n\n")
 
 	for i, rec := range recommendations {
 		// Print workload name and environment badge on SAME line
 		fmt.Printf("%d. %s/%s", i+1, rec.Workload.Namespace, rec.Workload.Deployment)
+		if rec.Workload.Container != "" {
+			fmt.Printf(" (container: %s)", rec.Workload.Container)
+		}
 		if rec.Environment != "" && rec.Environment != "unknown" {
 			fmt.Printf(" [%s]", strings.ToUpper(rec.Environment))
 		}
 		fmt.Println() // Now move to next line
 
 		fmt.Printf("   Type: %s\n", rec.Type)
+		if rec.Workload.Owner != "" {
+			fmt.Printf("   Owner: %s\n", rec.Workload.Owner)
+		}
 
 		// Display confidence and pattern info (Week 9 Day 2)
 		if rec.Confidence != "" && rec.Confidence != "N/A" {
@@ -586,10 +1306,27 @@ func outputText(recommendations []*models.Recommendation, totalSavings float64)
 		if rec.Reason != "" {
 			fmt.Printf("   Reason: %s\n", rec.Reason)
 		}
+		if rec.Type == models.RecommendationReplicaRightSize {
+			fmt.Printf("   Replicas: %d → %d (per replica below)\n", rec.CurrentReplicas, rec.RecommendedReplicas)
+		}
+		if rec.Type == models.RecommendationHPAAdjust {
+			fmt.Printf("   HPA %s: CPU target %d%% → %d%%, minReplicas %d → %d\n",
+				rec.HPAName, rec.CurrentTargetCPU, rec.RecommendedTargetCPU, rec.CurrentMinReplicas, rec.RecommendedMinReplicas)
+			fmt.Printf("   Replicas: %d → ~%d at the current load (per replica below)\n", rec.CurrentReplicas, rec.RecommendedReplicas)
+		}
 		fmt.Printf("   Current:  CPU=%dm Memory=%dMi\n",
 			rec.CurrentCPU, rec.CurrentMemory/(1024*1024))
 		fmt.Printf("   Recommended: CPU=%dm Memory=%dMi\n",
 			rec.RecommendedCPU, rec.RecommendedMemory/(1024*1024))
+		if rec.VPAName != "" {
+			fmt.Printf("   VPA %s: CPU=%dm Memory=%dMi\n",
+				rec.VPAName, rec.VPACPU, rec.VPAMemory/(1024*1024))
+		}
+		if rec.Type == models.RecommendationRightSize {
+			fmt.Printf("   Limits:   CPU=%s Memory=%s (current: CPU=%s Memory=%s)\n",
+				formatCPULimit(rec.RecommendedCPULimit), formatMemoryLimit(rec.RecommendedMemoryLimit),
+				formatCPULimit(rec.CurrentCPULimit), formatMemoryLimit(rec.CurrentMemoryLimit))
+		}
 		fmt.Printf("   Savings: $%.2f/month\n", rec.SavingsMonthly)
 		fmt.Printf("   Risk: %s\n", rec.Risk)
 		if rec.Command != "" {
@@ -600,6 +1337,22 @@ func outputText(recommendations []*models.Recommendation, totalSavings float64)
 	fmt.Printf("Total potential savings: $%.2f/month\n", totalSavings)
 }
 
+// formatCPULimit renders a CPU limit, 0 meaning unlimited
+func formatCPULimit(millicores int64) string {
+	if millicores == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%dm", millicores)
+}
+
+// formatMemoryLimit renders a memory limit, 0 meaning unlimited
+func formatMemoryLimit(bytes int64) string {
+	if bytes == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%dMi", bytes/(1024*1024))
+}
+
 func outputJSON(recommendations []*models.Recommendation, totalSavings float64) {
 	output := map[string]interface{}{
 		"recommendations": recommendations,
@@ -625,6 +1378,15 @@ func outputCommands(recommendations []*recommender.Recommendation) {
 	}
 }
 
+// outputVPAs prints a VerticalPodAutoscaler manifest per workload
+func outputVPAs(recommendations []*recommender.Recommendation, kinds []kube.WorkloadKind) {
+	vpas := executor.GenerateVPAs(recommendations, kube.VPAUpdateMode(vpaUpdateMode), kinds)
+	if err := executor.WriteVPAs(os.Stdout, vpas); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func generateCostReport(recommendations []*models.Recommendation, totalSavings float64, namespace string) error {
 	rep := reporter.New(reporter.ReportFormat(reportFormat))
 
@@ -916,3 +1678,104 @@ func runAnalyticsWorkload(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+// Database command handlers
+
+// openMigrator opens the configured database without migrating it
+func openMigrator() *storage.Migrator {
+	migrator, err := storage.NewMigrator(storageConfig())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+	return migrator
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) {
+	migrator := openMigrator()
+	defer migrator.Close()
+
+	ran, err := migrator.Up(context.Background())
+	for _, m := range ran {
+		fmt.Printf("Applied %03d_%s\n", m.Version, m.Description)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Migration failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(ran) == 0 {
+		fmt.Printf("[INFO] Schema is up to date (version %d)\n", migrator.Latest())
+		return
+	}
+	fmt.Printf("[INFO] Schema migrated to version %d\n", migrator.Latest())
+}
+
+func runDBStatus(cmd *cobra.Command, args []string) {
+	migrator := openMigrator()
+	defer migrator.Close()
+
+	ctx := context.Background()
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to read schema status: %v\n", err)
+		os.Exit(1)
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to read schema version: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Schema version: %d (latest: %d)\n\n", version, migrator.Latest())
+	fmt.Printf("%-8s | %-28s | %s\n", "Version", "Migration", "Status")
+	fmt.Println(strings.Repeat("-", 70))
+
+	pending := 0
+	for _, s := range status {
+		state := "pending"
+		if s.Applied {
+			state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		fmt.Printf("%-8s | %-28s | %s\n", fmt.Sprintf("%03d", s.Version), s.Description, state)
+	}
+
+	if pending > 0 {
+		fmt.Printf("\n[INFO] %d pending migration(s): run 'cost-scan db migrate'\n", pending)
+	}
+}
+
+func runDBDown(cmd *cobra.Command, args []string) {
+	if dbDownSteps < 1 {
+		fmt.Fprintln(os.Stderr, "Error: --steps must be at least 1")
+		os.Exit(1)
+	}
+
+	migrator := openMigrator()
+	defer migrator.Close()
+
+	ctx := context.Background()
+	reverted, err := migrator.Down(ctx, dbDownSteps)
+	for _, m := range reverted {
+		fmt.Printf("Reverted %03d_%s\n", m.Version, m.Description)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Revert failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(reverted) == 0 {
+		fmt.Println("[INFO] No applied migrations to revert")
+		return
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Failed to read schema version: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("[INFO] Schema is now at version %d\n", version)
+}
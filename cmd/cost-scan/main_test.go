@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/config"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+)
+
+// useMemoryStore points the command handlers at a fresh in-memory store
+func useMemoryStore(t *testing.T) *storage.MemoryStore {
+	t.Helper()
+
+	mem := storage.NewMemoryStore()
+	previousOpen, previousCfg := openStore, cfg
+	openStore = func(storage.Config) (storage.Store, error) { return mem, nil }
+	cfg = config.NewConfig()
+	t.Cleanup(func() {
+		openStore, cfg, store = previousOpen, previousCfg, nil
+	})
+
+	return mem
+}
+
+// captureOutput returns what fn writes to stdout
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+
+	fn()
+	w.Close()
+
+	return <-done
+}
+
+func saveRecommendation(t *testing.T, mem *storage.MemoryStore, deployment string, savings float64, createdAt time.Time) *models.Recommendation {
+	t.Helper()
+
+	rec := &models.Recommendation{
+		Type: models.RecommendationRightSize,
+		Workload: &models.Workload{
+			ClusterID:  "test",
+			Namespace:  "shop",
+			Deployment: deployment,
+			Kind:       "Deployment",
+			Container:  "app",
+		},
+		CurrentCPU:        1000,
+		CurrentMemory:     1024 * 1024 * 1024,
+		RecommendedCPU:    300,
+		RecommendedMemory: 256 * 1024 * 1024,
+		SavingsMonthly:    savings,
+		Risk:              models.RiskLow,
+		CreatedAt:         createdAt,
+	}
+	if err := mem.SaveRecommendation(context.Background(), rec); err != nil {
+		t.Fatalf("SaveRecommendation failed: %v", err)
+	}
+
+	return rec
+}
+
+func assertContains(t *testing.T, output string, expected ...string) {
+	t.Helper()
+
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, output)
+		}
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	mem := useMemoryStore(t)
+
+	now := time.Now()
+	saveRecommendation(t, mem, "web", 12.5, now.Add(-time.Hour))
+	applied := saveRecommendation(t, mem, "api", 20, now)
+	applied.AppliedAt = &now
+	applied.AppliedBy = "alice"
+	if err := mem.UpdateRecommendation(context.Background(), applied); err != nil {
+		t.Fatalf("UpdateRecommendation failed: %v", err)
+	}
+
+	historyLimit = 10
+	output := captureOutput(t, func() { runHistory(nil, []string{"shop"}) })
+
+	assertContains(t, output,
+		"Recent recommendations for namespace 'shop'",
+		"1. api (ID: "+applied.ID+")",
+		"2. web",
+		"Savings: $12.50/mo",
+		"Status: pending",
+		"by alice",
+	)
+
+	output = captureOutput(t, func() { runHistory(nil, []string{"other"}) })
+	assertContains(t, output, "No recommendations found for namespace: other")
+}
+
+func TestRunAudit(t *testing.T) {
+	mem := useMemoryStore(t)
+	ctx := context.Background()
+
+	rec := saveRecommendation(t, mem, "web", 12.5, time.Now())
+
+	output := captureOutput(t, func() { runAudit(nil, []string{rec.ID}) })
+	assertContains(t, output,
+		"Recommendation: "+rec.ID,
+		"Deployment: web (Namespace: shop)",
+		"Container: app",
+		"No audit log entries found",
+	)
+
+	entries := []*models.AuditEntry{
+		{RecommendationID: rec.ID, Action: models.AuditActionApplied, Status: models.AuditStatusSuccess, ExecutedBy: "alice"},
+		{RecommendationID: rec.ID, Action: models.AuditActionRolledBack, Status: models.AuditStatusFailed, ErrorMessage: "deployment not found"},
+	}
+	for i, entry := range entries {
+		entry.ExecutedAt = time.Now().Add(time.Duration(i) * time.Second)
+		if err := mem.LogAction(ctx, entry); err != nil {
+			t.Fatalf("LogAction failed: %v", err)
+		}
+	}
+
+	output = captureOutput(t, func() { runAudit(nil, []string{rec.ID}) })
+	assertContains(t, output,
+		"1. ROLLED_BACK - FAILED",
+		"Error: deployment not found",
+		"2. APPLIED - SUCCESS",
+		"By: alice",
+	)
+}
+
+func TestRunAnalytics(t *testing.T) {
+	mem := useMemoryStore(t)
+
+	now := time.Now()
+	applied := saveRecommendation(t, mem, "web", 10, now)
+	applied.AppliedAt = &now
+	applied.AppliedBy = "alice"
+	if err := mem.UpdateRecommendation(context.Background(), applied); err != nil {
+		t.Fatalf("UpdateRecommendation failed: %v", err)
+	}
+	saveRecommendation(t, mem, "api", 30, now.Add(-2*24*time.Hour))
+	saveRecommendation(t, mem, "web", 20, now.Add(-10*24*time.Hour))
+
+	namespace = "shop"
+	analyticsDays = 7
+	analyticsDeployment = "web"
+	analyticsLimit = 10
+	t.Cleanup(func() { namespace, analyticsDeployment = "", "" })
+
+	t.Run("stats", func(t *testing.T) {
+		output := captureOutput(t, func() { runAnalyticsStats(nil, nil) })
+		assertContains(t, output,
+			"Total: 2",
+			"Applied: 1",
+			"Adoption Rate: 50.0%",
+			"Potential: $40.00/month",
+			"Realized: $10.00/month",
+			"Unique Workloads: 2",
+		)
+	})
+
+	t.Run("trends", func(t *testing.T) {
+		output := captureOutput(t, func() { runAnalyticsTrends(nil, nil) })
+		assertContains(t, output,
+			now.UTC().Format("2006-01-02"),
+			"Total Potential Savings: $40.00/month",
+			"Total Applied: 1",
+			"Overall Adoption Rate: 50.0%",
+		)
+	})
+
+	t.Run("compare", func(t *testing.T) {
+		output := captureOutput(t, func() { runAnalyticsCompare(nil, nil) })
+		assertContains(t, output,
+			"Current Period: Last 7 days",
+			"Recommendations      | 2               | 1               | +100.0%",
+			"Recommendations increased by 100.0%",
+		)
+	})
+
+	t.Run("workload", func(t *testing.T) {
+		output := captureOutput(t, func() { runAnalyticsWorkload(nil, nil) })
+		assertContains(t, output,
+			"Deployment: web",
+			"Applied: ",
+			"Workload improving ($10.00 decrease in potential savings)",
+		)
+	})
+}
+
+func TestScanStorageConfig(t *testing.T) {
+	previousCfg, previousDB := cfg, dbLocation
+	t.Cleanup(func() { cfg, dbLocation = previousCfg, previousDB })
+
+	cfg = config.NewConfig()
+	cfg.DatabaseConfigured = false
+	dbLocation = ""
+	if got := scanStorageConfig(); got.Type != storage.TypeMemory {
+		t.Errorf("Expected in-memory storage without a database, got %+v", got)
+	}
+
+	cfg.DatabaseConfigured = true
+	cfg.StorageType = storage.TypeSQLite
+	if got := scanStorageConfig(); got.Type != storage.TypeSQLite || got.Path != cfg.SQLitePath {
+		t.Errorf("Expected configured SQLite storage, got %+v", got)
+	}
+
+	cfg.DatabaseConfigured = false
+	dbLocation = "costs.db"
+	if got := scanStorageConfig(); got.Type != storage.TypeSQLite || got.Path != "costs.db" {
+		t.Errorf("Expected --db to take precedence, got %+v", got)
+	}
+}
+
+func TestDBCommands(t *testing.T) {
+	previousCfg, previousDB := cfg, dbLocation
+	t.Cleanup(func() { cfg, dbLocation = previousCfg, previousDB })
+
+	cfg = config.NewConfig()
+	dbLocation = filepath.Join(t.TempDir(), "costs.db")
+
+	output := captureOutput(t, func() { runDBStatus(nil, nil) })
+	assertContains(t, output, "Schema version: 0 (latest: 9)", "9 pending migration(s)")
+
+	output = captureOutput(t, func() { runDBMigrate(nil, nil) })
+	assertContains(t, output, "Applied 001_sqlite_schema", "Applied 009_usage_samples", "Schema migrated to version 9")
+
+	output = captureOutput(t, func() { runDBMigrate(nil, nil) })
+	assertContains(t, output, "Schema is up to date (version 9)")
+
+	dbDownSteps = 3
+	output = captureOutput(t, func() { runDBDown(nil, nil) })
+	assertContains(t, output, "Reverted 009_usage_samples", "Reverted 008_add_hpa", "Reverted 007_add_replicas", "Schema is now at version 6")
+
+	output = captureOutput(t, func() { runDBStatus(nil, nil) })
+	assertContains(t, output, "Schema version: 6 (latest: 9)", "3 pending migration(s)")
+}
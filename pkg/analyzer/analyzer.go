@@ -3,31 +3,50 @@ package analyzer
 import (
 	"context"
 	"fmt"
-	"strings"
+	"os"
 
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 type PodAnalysis struct {
 	Name              string
 	Namespace         string
 	ContainerName     string
-	RequestedCPU      int64   // in millicores
-	RequestedMemory   int64   // in bytes
-	ActualCPU         int64   // in millicores
-	ActualMemory      int64   // in bytes
-	CPUUtilization    float64 // percentage
-	MemoryUtilization float64 // percentage
-	HasHPA            bool    // indicates if workload has HPA
-	HPAName           string  // name of the HPA
-	WorkloadType      string  // Deployment, StatefulSet, etc.
-	WorkloadName      string  // name of the parent workload
+	RequestedCPU      int64    // in millicores
+	RequestedMemory   int64    // in bytes
+	ActualCPU         int64    // in millicores
+	ActualMemory      int64    // in bytes
+	LimitCPU          int64    // in millicores, 0 when unlimited
+	LimitMemory       int64    // in bytes, 0 when unlimited
+	PeakCPU           int64    // observed peak (P99) in millicores
+	PeakMemory        int64    // observed peak (P99) in bytes
+	CPUUtilization    float64  // percentage
+	MemoryUtilization float64  // percentage
+	HasHPA            bool     // indicates if workload has HPA
+	HPAName           string   // name of the HPA
+	HPA               *HPAInfo // targets and replica bounds of the HPA
+	VPA               *VPAInfo // recommendation of an existing VPA, if any
+	WorkloadType      string   // Deployment, StatefulSet, etc.
+	WorkloadName      string   // name of the parent workload
 	Environment       Environment
 
+	// TreatAs is the built-in workload type whose safety buffers apply to
+	// a custom workload kind such as an Argo Rollout (set by the scanner)
+	TreatAs string
+
+	// Labels of the pod and its namespace, matched by optimization policies
+	Labels          map[string]string
+	NamespaceLabels map[string]string
+
+	// cost-optimizer.io annotations of the namespace and the parent
+	// workload (set by the scanner, which has the workload objects)
+	NamespaceAnnotations map[string]string
+	WorkloadAnnotations  map[string]string
+
 	// Pattern Analysis
 	CPUPattern    UsagePattern
 	MemoryPattern UsagePattern
@@ -39,141 +58,137 @@ type PodAnalysis struct {
 	// Data Quality
 	DataQuality       float64 // 0.0-1.0 confidence score
 	HasSufficientData bool    // true if >= 3 days of data
+	DataDays          float64 // days of historical samples, 0 for instant metrics
 }
 
 type Analyzer struct {
-	clientset     *kubernetes.Clientset
-	metricsClient *metricsv.Clientset
+	clientset kubernetes.Interface
+	lister    kube.Lister
+	owners    *kube.OwnerResolver
+	dynamic   dynamic.Interface
 }
 
-func New(clientset *kubernetes.Clientset, metricsClient *metricsv.Clientset) *Analyzer {
+func New(clientset kubernetes.Interface) *Analyzer {
 	return &Analyzer{
-		clientset:     clientset,
-		metricsClient: metricsClient,
+		clientset: clientset,
+		lister:    kube.NewClientLister(clientset),
+		owners:    kube.NewOwnerResolver(clientset),
 	}
 }
 
-// getTopLevelOwner extracts the top-level workload (Deployment/StatefulSet) from pod
-func getTopLevelOwner(pod corev1.Pod) (kind string, name string) {
-	if len(pod.OwnerReferences) == 0 {
-		return "", ""
-	}
-
-	owner := pod.OwnerReferences[0]
-
-	// If owner is ReplicaSet, extract Deployment name
-	if owner.Kind == "ReplicaSet" {
-		rsName := owner.Name
-		lastDash := strings.LastIndex(rsName, "-")
-		if lastDash > 0 {
-			return "Deployment", rsName[:lastDash]
-		}
+// WithLister reads namespaces, pods, HPAs and, when lister can get them,
+// the ReplicaSets and Jobs owning pods through lister, e.g. informer
+// caches, instead of the API server
+func (a *Analyzer) WithLister(lister kube.Lister) *Analyzer {
+	a.lister = lister
+	if getter, ok := lister.(kube.OwnerGetter); ok {
+		a.owners.WithOwnerGetter(getter)
 	}
+	return a
+}
 
-	return owner.Kind, owner.Name
+// WithDynamicClient lets the analyzer read custom resources: owners of
+// pods, e.g. Argo Rollouts, and existing VerticalPodAutoscalers
+func (a *Analyzer) WithDynamicClient(client dynamic.Interface, mapper meta.RESTMapper) *Analyzer {
+	a.dynamic = client
+	a.owners.WithDynamicClient(client, mapper)
+	return a
 }
 
-// checkHPA checks if a pod's workload has an HPA configured
-func (a *Analyzer) checkHPA(ctx context.Context, pod corev1.Pod) (bool, string) {
-	ownerKind, ownerName := getTopLevelOwner(pod)
+// Owners returns the resolver that finds the workload of each pod. Scans
+// reset it so owner changes between scans are picked up.
+func (a *Analyzer) Owners() *kube.OwnerResolver {
+	return a.owners
+}
 
-	if ownerName == "" {
-		return false, ""
+// checkHPA returns the HPA of a pod's workload, nil when it has none
+func (a *Analyzer) checkHPA(ctx context.Context, namespace string, owner kube.Workload) *HPAInfo {
+	if owner.Name == "" {
+		return nil
 	}
 
-	hpaList, err := a.clientset.AutoscalingV2().HorizontalPodAutoscalers(pod.Namespace).List(ctx, metav1.ListOptions{})
+	hpas, err := a.lister.HorizontalPodAutoscalers(ctx, namespace)
 	if err != nil {
 		// Log error but don't fail - just assume no HPA
-		return false, ""
+		return nil
+	}
+
+	for _, hpa := range hpas {
+		if hpa.Spec.ScaleTargetRef.Name == owner.Name &&
+			hpa.Spec.ScaleTargetRef.Kind == owner.Kind {
+			return NewHPAInfo(hpa)
+		}
 	}
+	return nil
+}
+
+// GroupByContainer splits analyses into one group per container name,
+// preserving the order in which containers were first seen
+func GroupByContainer(analyses []PodAnalysis) [][]PodAnalysis {
+	index := make(map[string]int)
+	var groups [][]PodAnalysis
 
-	for _, hpa := range hpaList.Items {
-		if hpa.Spec.ScaleTargetRef.Name == ownerName &&
-			hpa.Spec.ScaleTargetRef.Kind == ownerKind {
-			return true, hpa.Name
+	for _, analysis := range analyses {
+		i, exists := index[analysis.ContainerName]
+		if !exists {
+			i = len(groups)
+			index[analysis.ContainerName] = i
+			groups = append(groups, nil)
 		}
+		groups[i] = append(groups[i], analysis)
 	}
-	return false, ""
+
+	return groups
 }
 
+// AnalyzePods describes the containers of the pods of namespace: their
+// requests, workload, autoscalers and environment. Usage is left for the
+// caller to fill in with SetUsage from a data source.
 func (a *Analyzer) AnalyzePods(ctx context.Context, namespace string) ([]PodAnalysis, error) {
 	// Get pods
-	pods, err := a.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	pods, err := a.lister.Pods(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pods: %w", err)
 	}
 	// Classify namespace environment ONCE for all pods
-	environment := ClassifyNamespace(ctx, a.clientset, namespace)
-	// Get pod metrics
-	podMetrics, err := a.metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pod metrics: %w", err)
-	}
-
-	// Create metrics lookup
-	metricsMap := make(map[string]map[string]struct {
-		cpu    resource.Quantity
-		memory resource.Quantity
-	})
-
-	for _, pm := range podMetrics.Items {
-		metricsMap[pm.Name] = make(map[string]struct {
-			cpu    resource.Quantity
-			memory resource.Quantity
-		})
-		for _, container := range pm.Containers {
-			metricsMap[pm.Name][container.Name] = struct {
-				cpu    resource.Quantity
-				memory resource.Quantity
-			}{
-				cpu:    container.Usage[corev1.ResourceCPU],
-				memory: container.Usage[corev1.ResourceMemory],
-			}
-		}
-	}
+	ns := a.describeNamespace(ctx, namespace)
+	vpas := a.listVPAs(ctx, namespace)
 
 	var analyses []PodAnalysis
 
 	// Analyze each pod
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
+		owner, err := a.owners.Resolve(ctx, pod.Namespace, pod.OwnerReferences)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] Failed to resolve the workload of pod %s/%s: %v\n", pod.Namespace, pod.Name, err)
+		}
+
 		// Check HPA once per pod (not per container)
-		hasHPA, hpaName := a.checkHPA(ctx, pod)
-		workloadKind, workloadName := getTopLevelOwner(pod)
+		hpa := a.checkHPA(ctx, pod.Namespace, owner)
+		hpaName := ""
+		if hpa != nil {
+			hpaName = hpa.Name
+		}
 
 		for _, container := range pod.Spec.Containers {
 			analysis := PodAnalysis{
 				Name:          pod.Name,
 				Namespace:     pod.Namespace,
 				ContainerName: container.Name,
-				HasHPA:        hasHPA,
+				HasHPA:        hpa != nil,
 				HPAName:       hpaName,
-				WorkloadType:  workloadKind,
-				WorkloadName:  workloadName,
-				Environment:   environment,
-			}
-
-			// Get requested resources
-			if cpu, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
-				analysis.RequestedCPU = cpu.MilliValue()
-			}
-			if mem, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
-				analysis.RequestedMemory = mem.Value()
-			}
-
-			// Get actual usage from metrics
-			if podMetrics, ok := metricsMap[pod.Name]; ok {
-				if containerMetrics, ok := podMetrics[container.Name]; ok {
-					analysis.ActualCPU = containerMetrics.cpu.MilliValue()
-					analysis.ActualMemory = containerMetrics.memory.Value()
-				}
-			}
+				HPA:           hpa,
+				WorkloadType:  owner.Kind,
+				WorkloadName:  owner.Name,
+				Environment:   ns.environment,
 
-			// Calculate utilization
-			if analysis.RequestedCPU > 0 {
-				analysis.CPUUtilization = float64(analysis.ActualCPU) / float64(analysis.RequestedCPU) * 100
+				Labels:               pod.Labels,
+				NamespaceLabels:      ns.labels,
+				NamespaceAnnotations: ns.annotations,
 			}
-			if analysis.RequestedMemory > 0 {
-				analysis.MemoryUtilization = float64(analysis.ActualMemory) / float64(analysis.RequestedMemory) * 100
+			analysis.setResources(container)
+			if vpa, ok := vpas[kube.Workload{Kind: owner.Kind, Name: owner.Name}]; ok {
+				analysis.VPA = NewVPAInfo(vpa, container.Name)
 			}
 
 			analyses = append(analyses, analysis)
@@ -182,3 +197,122 @@ func (a *Analyzer) AnalyzePods(ctx context.Context, namespace string) ([]PodAnal
 
 	return analyses, nil
 }
+
+// SetUsage sets the usage of the container and its utilization of the
+// requests. Peaks are in millicores and bytes like usage.
+func (p *PodAnalysis) SetUsage(cpu, memory, peakCPU, peakMemory int64) {
+	p.ActualCPU = cpu
+	p.ActualMemory = memory
+	p.PeakCPU = peakCPU
+	p.PeakMemory = peakMemory
+
+	// Calculate utilization
+	if p.RequestedCPU > 0 {
+		p.CPUUtilization = float64(p.ActualCPU) / float64(p.RequestedCPU) * 100
+	}
+	if p.RequestedMemory > 0 {
+		p.MemoryUtilization = float64(p.ActualMemory) / float64(p.RequestedMemory) * 100
+	}
+}
+
+// AnalyzeTemplate describes the containers of a workload's pod template,
+// for batch workloads whose pods are gone by the time of a scan. Usage is
+// left for the caller to fill in from history.
+func (a *Analyzer) AnalyzeTemplate(ctx context.Context, namespace, kind, name string, template corev1.PodTemplateSpec) []PodAnalysis {
+	ns := a.describeNamespace(ctx, namespace)
+
+	analyses := make([]PodAnalysis, 0, len(template.Spec.Containers))
+	for _, container := range template.Spec.Containers {
+		analysis := PodAnalysis{
+			Name:          name,
+			Namespace:     namespace,
+			ContainerName: container.Name,
+			WorkloadType:  kind,
+			WorkloadName:  name,
+			Environment:   ns.environment,
+
+			Labels:               template.Labels,
+			NamespaceLabels:      ns.labels,
+			NamespaceAnnotations: ns.annotations,
+		}
+		analysis.setResources(container)
+		analyses = append(analyses, analysis)
+	}
+	return analyses
+}
+
+// ContainerResources are the requests and limits of a container of a
+// running pod, in millicores and bytes, e.g. as recorded by
+// kube-state-metrics
+type ContainerResources struct {
+	Pod       string
+	Container string
+
+	RequestedCPU    int64
+	RequestedMemory int64
+	LimitCPU        int64
+	LimitMemory     int64
+}
+
+// AnalyzeResources describes the containers of the running pods of a
+// workload from their resources alone, for scans without the API server.
+// The environment is told from the namespace name; labels, annotations and
+// autoscalers are unknown. Usage is left for the caller to fill in.
+func AnalyzeResources(namespace, kind, name string, containers []ContainerResources) []PodAnalysis {
+	environment := classifyNamespaceLabels(namespace, nil)
+
+	analyses := make([]PodAnalysis, 0, len(containers))
+	for _, container := range containers {
+		analyses = append(analyses, PodAnalysis{
+			Name:            container.Pod,
+			Namespace:       namespace,
+			ContainerName:   container.Container,
+			RequestedCPU:    container.RequestedCPU,
+			RequestedMemory: container.RequestedMemory,
+			LimitCPU:        container.LimitCPU,
+			LimitMemory:     container.LimitMemory,
+			WorkloadType:    kind,
+			WorkloadName:    name,
+			Environment:     environment,
+		})
+	}
+	return analyses
+}
+
+// namespaceInfo holds the namespace properties shared by its pods
+type namespaceInfo struct {
+	environment Environment
+
+	// Labels and annotations are also used by optimization policies
+	labels      map[string]string
+	annotations map[string]string
+}
+
+func (a *Analyzer) describeNamespace(ctx context.Context, namespace string) namespaceInfo {
+	var details namespaceInfo
+	if ns, err := a.lister.Namespace(ctx, namespace); err == nil {
+		details.labels = ns.Labels
+		details.annotations = ns.Annotations
+	}
+	details.environment = classifyNamespaceLabels(namespace, details.labels)
+	return details
+}
+
+// setResources copies the container's requests and limits
+func (p *PodAnalysis) setResources(container corev1.Container) {
+	// Get requested resources
+	if cpu, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
+		p.RequestedCPU = cpu.MilliValue()
+	}
+	if mem, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
+		p.RequestedMemory = mem.Value()
+	}
+
+	// Get current limits
+	if cpu, ok := container.Resources.Limits[corev1.ResourceCPU]; ok {
+		p.LimitCPU = cpu.MilliValue()
+	}
+	if mem, ok := container.Resources.Limits[corev1.ResourceMemory]; ok {
+		p.LimitMemory = mem.Value()
+	}
+}
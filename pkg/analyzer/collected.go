@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+)
+
+// defaultCollectedResolution is assumed when too few collections were made
+// to tell the interval of cost-scan collect, and is also the resolution
+// data quality is scored at
+const defaultCollectedResolution = 5 * time.Minute
+
+// UsageSampleReader reads the usage collected by cost-scan collect, e.g. a
+// storage.Store
+type UsageSampleReader interface {
+	ListUsageSamples(ctx context.Context, clusterID, namespace string, since time.Time) ([]*models.UsageSample, error)
+}
+
+// CollectedAnalyzer analyzes the usage that cost-scan collect polled from
+// the metrics API, like HistoricalAnalyzer does with Prometheus history,
+// for clusters without Prometheus
+type CollectedAnalyzer struct {
+	samples   UsageSampleReader
+	clusterID string
+	verbose   bool
+}
+
+// NewCollectedAnalyzer creates an analyzer of the usage collected for
+// clusterID
+func NewCollectedAnalyzer(samples UsageSampleReader, clusterID string, verbose bool) *CollectedAnalyzer {
+	return &CollectedAnalyzer{
+		samples:   samples,
+		clusterID: clusterID,
+		verbose:   verbose,
+	}
+}
+
+// GetWorkloadMetrics returns the usage of a container in every pod of a
+// long-running workload collected in the past days. Pods are those
+// collected with workload as their owner.
+func (c *CollectedAnalyzer) GetWorkloadMetrics(
+	ctx context.Context,
+	workload *models.Workload,
+	days int,
+) (*HistoricalMetrics, error) {
+
+	metrics, err := c.collectedMetrics(ctx, workload, days)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collections are scored as if they were 5-minute Prometheus samples
+	sampled := sampleTimes(metrics.CPUSamples)
+	covered := time.Duration(sampled) * metrics.Resolution
+	metrics.DataQuality = calculateDataQuality(int(covered/defaultCollectedResolution), metrics.EndTime.Sub(metrics.StartTime))
+	metrics.HasSufficientData = covered >= 3*24*time.Hour
+
+	summarize(metrics, c.verbose)
+
+	return metrics, nil
+}
+
+// GetBatchMetrics returns the usage of every run of a batch workload
+// collected in the past days. Only runs longer than the collection
+// interval were sampled.
+func (c *CollectedAnalyzer) GetBatchMetrics(
+	ctx context.Context,
+	workload *models.Workload,
+	days int,
+) (*HistoricalMetrics, error) {
+
+	metrics, err := c.collectedMetrics(ctx, workload, days)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.Runs = metrics.Pods
+	metrics.DataQuality = math.Min(float64(metrics.Runs)/idealBatchRuns, 1.0)
+	metrics.HasSufficientData = metrics.Runs >= minBatchRuns
+
+	summarize(metrics, c.verbose)
+
+	return metrics, nil
+}
+
+// collectedMetrics returns the usage of the pods of workload in the past
+// days, one sample per pod and collection. A pod's usage is that of
+// workload.Container, or of all its containers when it is empty.
+// Workloads without a Kind are Deployments.
+func (c *CollectedAnalyzer) collectedMetrics(
+	ctx context.Context,
+	workload *models.Workload,
+	days int,
+) (*HistoricalMetrics, error) {
+
+	kind := workload.Kind
+	if kind == "" {
+		kind = "Deployment"
+	}
+
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Duration(days) * 24 * time.Hour)
+
+	samples, err := c.samples.ListUsageSamples(ctx, c.clusterID, workload.Namespace, startTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collected usage: %w", err)
+	}
+
+	// Times are compared as instants, whatever their location
+	type podTime struct {
+		pod  string
+		time int64
+	}
+	cpu := make(map[podTime]float64)
+	memory := make(map[podTime]float64)
+	for _, sample := range samples {
+		if workload.Pod != "" && sample.Pod != workload.Pod {
+			continue
+		}
+		if workload.Pod == "" && (sample.Kind != kind || sample.Workload != workload.Deployment) {
+			continue
+		}
+		if workload.Container != "" && sample.Container != workload.Container {
+			continue
+		}
+		key := podTime{pod: sample.Pod, time: sample.Timestamp.UnixNano()}
+		cpu[key] += float64(sample.CPU)
+		memory[key] += float64(sample.Memory)
+	}
+
+	keys := make([]podTime, 0, len(cpu))
+	for key := range cpu {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].time != keys[j].time {
+			return keys[i].time < keys[j].time
+		}
+		return keys[i].pod < keys[j].pod
+	})
+
+	metrics := workloadMetrics(workload, startTime, endTime, 0)
+	metrics.CPUSamples = make([]MetricSample, 0, len(keys))
+	metrics.MemorySamples = make([]MetricSample, 0, len(keys))
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		timestamp := time.Unix(0, key.time)
+		metrics.CPUSamples = append(metrics.CPUSamples, MetricSample{Timestamp: timestamp, Value: cpu[key]})
+		metrics.MemorySamples = append(metrics.MemorySamples, MetricSample{Timestamp: timestamp, Value: memory[key]})
+		seen[key.pod] = true
+	}
+	metrics.Pods = len(seen)
+	metrics.Resolution = collectionInterval(metrics.CPUSamples)
+
+	if c.verbose {
+		fmt.Printf("[DEBUG] Collected usage of %s %s/%s: %d samples of %d pod(s) every %s\n",
+			kind, workload.Namespace, metrics.PodName, len(metrics.CPUSamples), metrics.Pods, metrics.Resolution)
+	}
+
+	return metrics, nil
+}
+
+// collectionInterval returns the median time between the collections of
+// samples, which are in time order. Gaps while the collector was down
+// are outliers the median ignores.
+func collectionInterval(samples []MetricSample) time.Duration {
+	var gaps []time.Duration
+	for i := 1; i < len(samples); i++ {
+		if gap := samples[i].Timestamp.Sub(samples[i-1].Timestamp); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) == 0 {
+		return defaultCollectedResolution
+	}
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	return gaps[len(gaps)/2]
+}
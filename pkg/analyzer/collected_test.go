@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+)
+
+// collectedSamples is a UsageSampleReader over samples of cluster "test"
+type collectedSamples []*models.UsageSample
+
+func (c collectedSamples) ListUsageSamples(ctx context.Context, clusterID, namespace string, since time.Time) ([]*models.UsageSample, error) {
+	var samples []*models.UsageSample
+	for _, sample := range c {
+		if sample.ClusterID == clusterID && sample.Namespace == namespace && !sample.Timestamp.Before(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
+
+func TestCollectedAnalyzer(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-4 * 24 * time.Hour).Truncate(time.Minute)
+
+	// Four days of collections every 10 minutes, with an hour missed while
+	// the collector was down, of two replicas of web and a pod of web-api.
+	// Each collection of a pod sums to 100m + 10m of istio-proxy.
+	var samples collectedSamples
+	add := func(kind, workload, pod, container string, at time.Time, cpu, memory int64) {
+		samples = append(samples, &models.UsageSample{
+			ClusterID: "test", Namespace: "shop", Kind: kind, Workload: workload,
+			Pod: pod, Container: container, Timestamp: at, CPU: cpu, Memory: memory,
+		})
+	}
+	collections := 0
+	for at := start; at.Before(time.Now()); at = at.Add(10 * time.Minute) {
+		if at.Sub(start) > 24*time.Hour && at.Sub(start) <= 25*time.Hour {
+			continue
+		}
+		collections++
+		for _, pod := range []string{"web-7d9f8b-abcde", "web-7d9f8b-fghij"} {
+			add("Deployment", "web", pod, "app", at, 100, 200<<20)
+			add("Deployment", "web", pod, "istio-proxy", at, 10, 50<<20)
+		}
+		add("Deployment", "web-api", "web-api-5c4d3e-klmno", "app", at, 900, 900<<20)
+	}
+
+	history := NewCollectedAnalyzer(samples, "test", false)
+
+	web := &models.Workload{Namespace: "shop", Kind: "Deployment", Deployment: "web"}
+	metrics, err := history.GetWorkloadMetrics(ctx, web, 7)
+	if err != nil {
+		t.Fatalf("GetWorkloadMetrics failed: %v", err)
+	}
+	if metrics.Pods != 2 || len(metrics.CPUSamples) != 2*collections {
+		t.Fatalf("Expected a sample per replica and collection, got %d pod(s) and %d samples", metrics.Pods, len(metrics.CPUSamples))
+	}
+	if metrics.CPUSamples[0].Value != 110 || metrics.MemorySamples[0].Value != 250<<20 {
+		t.Errorf("Expected the containers of a pod to be summed, got %v and %v", metrics.CPUSamples[0], metrics.MemorySamples[0])
+	}
+	if metrics.Resolution != 10*time.Minute {
+		t.Errorf("Expected the collection interval as resolution, got %s", metrics.Resolution)
+	}
+	if !metrics.HasSufficientData || metrics.DataDays() < 3.9 {
+		t.Errorf("Expected four days of data to be sufficient, got %.1f days", metrics.DataDays())
+	}
+	if metrics.CPUPattern.Type == "" {
+		t.Error("Expected the usage pattern to be analyzed")
+	}
+
+	app, err := history.GetWorkloadMetrics(ctx, &models.Workload{Namespace: "shop", Kind: "Deployment", Deployment: "web", Container: "app"}, 7)
+	if err != nil {
+		t.Fatalf("GetWorkloadMetrics of a container failed: %v", err)
+	}
+	if app.CPUSamples[0].Value != 100 {
+		t.Errorf("Expected the usage of app alone, got %v", app.CPUSamples[0])
+	}
+
+	recent, err := history.GetWorkloadMetrics(ctx, web, 1)
+	if err != nil {
+		t.Fatalf("GetWorkloadMetrics of a day failed: %v", err)
+	}
+	if recent.HasSufficientData {
+		t.Error("Expected a day of data to be insufficient")
+	}
+
+	// Pods are matched by the workload they were collected with, whatever
+	// their names
+	add("StatefulSet", "web", "web-0", "app", start, 50, 64<<20)
+	sts, err := NewCollectedAnalyzer(samples, "test", false).GetWorkloadMetrics(ctx, &models.Workload{Namespace: "shop", Kind: "StatefulSet", Deployment: "web"}, 7)
+	if err != nil || sts.Pods != 1 || len(sts.CPUSamples) != 1 {
+		t.Errorf("Expected only the StatefulSet's pod, got %+v (%v)", sts, err)
+	}
+	single, err := history.GetWorkloadMetrics(ctx, &models.Workload{Namespace: "shop", Kind: "Pod", Deployment: "web-api-5c4d3e-klmno", Pod: "web-api-5c4d3e-klmno"}, 7)
+	if err != nil || single.Pods != 1 || single.CPUSamples[0].Value != 900 {
+		t.Errorf("Expected a single pod to be matched by name, got %+v (%v)", single, err)
+	}
+}
+
+func TestCollectedBatchMetrics(t *testing.T) {
+	start := time.Now().Add(-3 * 24 * time.Hour)
+
+	var samples collectedSamples
+	for day, run := range []string{"backup-28391040-abcde", "backup-28392480-fghij", "backup-28393920-klmno"} {
+		for minute := 0; minute < 30; minute += 5 {
+			samples = append(samples, &models.UsageSample{
+				ClusterID: "test", Namespace: "shop", Kind: "CronJob", Workload: "backup", Pod: run, Container: "backup",
+				Timestamp: start.Add(time.Duration(day)*24*time.Hour + time.Duration(minute)*time.Minute), CPU: 500, Memory: 1 << 30,
+			})
+		}
+	}
+
+	metrics, err := NewCollectedAnalyzer(samples, "test", false).GetBatchMetrics(context.Background(),
+		&models.Workload{Namespace: "shop", Kind: "CronJob", Deployment: "backup", Container: "backup"}, 7)
+	if err != nil {
+		t.Fatalf("GetBatchMetrics failed: %v", err)
+	}
+	if metrics.Runs != 3 || !metrics.HasSufficientData {
+		t.Errorf("Expected 3 runs to be sufficient, got %d", metrics.Runs)
+	}
+	if metrics.Resolution != 5*time.Minute {
+		t.Errorf("Expected the collection interval within runs, got %s", metrics.Resolution)
+	}
+}
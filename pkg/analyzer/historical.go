@@ -3,13 +3,26 @@ package analyzer
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
 	"github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 )
 
+// History is the usage history historical recommendations are based on,
+// from Prometheus or from the usage collected by cost-scan collect. The
+// pods of a workload are those it has owned, and their usage is that of
+// workload.Container, or of all their containers when it is empty.
+type History interface {
+	GetWorkloadMetrics(ctx context.Context, workload *models.Workload, days int) (*HistoricalMetrics, error)
+	GetBatchMetrics(ctx context.Context, workload *models.Workload, days int) (*HistoricalMetrics, error)
+}
+
 // HistoricalAnalyzer queries and analyzes historical metrics
 type HistoricalAnalyzer struct {
 	promAPI v1.API
@@ -60,6 +73,174 @@ func (h *HistoricalAnalyzer) GetHistoricalMetrics(
 	}
 	metrics.MemorySamples = memorySamples
 
+	// Calculate data quality and confidence
+	metrics.DataQuality = calculateDataQuality(len(cpuSamples), endTime.Sub(startTime))
+	metrics.HasSufficientData = len(cpuSamples) >= 864 // ~3 days at 5-min intervals
+
+	summarize(metrics, h.verbose)
+
+	return metrics, nil
+}
+
+// GetWorkloadMetrics fetches the usage of a container in every pod of a
+// long-running workload in the past days. Pods are those kube-state-metrics
+// recorded the workload owning, so replicas and pods replaced by rollouts
+// or restarts all count towards the percentiles instead of a single pod's
+// lifetime. Each pod contributes its own samples; Pods counts them.
+func (h *HistoricalAnalyzer) GetWorkloadMetrics(
+	ctx context.Context,
+	workload *models.Workload,
+	days int,
+) (*HistoricalMetrics, error) {
+
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Duration(days) * 24 * time.Hour)
+
+	resolution := 5 * time.Minute
+	r := v1.Range{Start: startTime, End: endTime, Step: resolution}
+
+	metrics := workloadMetrics(workload, startTime, endTime, resolution)
+
+	cpuQuery := fmt.Sprintf(`sum by (pod) (%s) * 1000`,
+		OwnedBy(workload, fmt.Sprintf(`rate(container_cpu_usage_seconds_total{%s}[5m])`, WorkloadSelector(workload))))
+	cpuSamples, pods, err := h.queryRuns(ctx, cpuQuery, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CPU usage: %w", err)
+	}
+	metrics.CPUSamples = cpuSamples
+
+	memoryQuery := fmt.Sprintf(`max by (pod) (%s)`,
+		OwnedBy(workload, fmt.Sprintf(`container_memory_working_set_bytes{%s}`, WorkloadSelector(workload))))
+	memorySamples, memoryPods, err := h.queryRuns(ctx, memoryQuery, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory usage: %w", err)
+	}
+	metrics.MemorySamples = memorySamples
+
+	// Replicas are sampled at the same times, so coverage is the number
+	// of distinct sample times rather than the number of samples
+	metrics.Pods = max(pods, memoryPods)
+	sampled := sampleTimes(cpuSamples)
+	metrics.DataQuality = calculateDataQuality(sampled, endTime.Sub(startTime))
+	metrics.HasSufficientData = sampled >= 864 // ~3 days at 5-min intervals
+
+	summarize(metrics, h.verbose)
+
+	return metrics, nil
+}
+
+// NewSampledMetrics analyzes the CPU and memory usage of workload sampled
+// over the past days by another source, e.g. the timeseries of a data
+// source, like Prometheus history is. Samples are in time order and are
+// scored like collected usage.
+func NewSampledMetrics(workload *models.Workload, days int, cpuSamples, memorySamples []MetricSample, verbose bool) *HistoricalMetrics {
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Duration(days) * 24 * time.Hour)
+
+	metrics := workloadMetrics(workload, startTime, endTime, collectionInterval(cpuSamples))
+	metrics.CPUSamples = cpuSamples
+	metrics.MemorySamples = memorySamples
+
+	covered := time.Duration(sampleTimes(cpuSamples)) * metrics.Resolution
+	metrics.DataQuality = calculateDataQuality(int(covered/defaultCollectedResolution), endTime.Sub(startTime))
+	metrics.HasSufficientData = covered >= 3*24*time.Hour
+
+	summarize(metrics, verbose)
+
+	return metrics
+}
+
+// Batch runs are short, so they are sampled more finely than long-running
+// workloads, within Prometheus' limit of 11,000 points per series
+const (
+	minBatchResolution = time.Minute
+	maxBatchPoints     = 10000
+
+	// minBatchRuns is the number of runs needed for sufficient data
+	minBatchRuns = 3
+	// idealBatchRuns gives full data quality
+	idealBatchRuns = 10
+)
+
+// GetBatchMetrics fetches the usage of every run of a batch workload in
+// the past days. Runs are the pods the workload owned, through its Jobs
+// for a CronJob, since they are usually deleted by the time of a scan.
+// Samples of all runs are combined; Runs counts them.
+func (h *HistoricalAnalyzer) GetBatchMetrics(
+	ctx context.Context,
+	workload *models.Workload,
+	days int,
+) (*HistoricalMetrics, error) {
+
+	endTime := time.Now()
+	lookback := time.Duration(days) * 24 * time.Hour
+	startTime := endTime.Add(-lookback)
+
+	resolution := lookback / maxBatchPoints
+	if resolution < minBatchResolution {
+		resolution = minBatchResolution
+	}
+	r := v1.Range{Start: startTime, End: endTime, Step: resolution}
+
+	metrics := workloadMetrics(workload, startTime, endTime, resolution)
+
+	// Runs are too short for counters to be sampled at the step, so the
+	// rate is taken by Prometheus over a window of a few scrapes
+	cpuQuery := fmt.Sprintf(`sum by (pod) (%s) * 1000`,
+		OwnedBy(workload, fmt.Sprintf(`rate(container_cpu_usage_seconds_total{%s}[2m])`, WorkloadSelector(workload))))
+	cpuSamples, runs, err := h.queryRuns(ctx, cpuQuery, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CPU usage: %w", err)
+	}
+	metrics.CPUSamples = cpuSamples
+
+	memoryQuery := fmt.Sprintf(`max by (pod) (%s)`,
+		OwnedBy(workload, fmt.Sprintf(`container_memory_working_set_bytes{%s}`, WorkloadSelector(workload))))
+	memorySamples, memoryRuns, err := h.queryRuns(ctx, memoryQuery, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory usage: %w", err)
+	}
+	metrics.MemorySamples = memorySamples
+
+	// A run too short for a CPU rate still has memory samples
+	metrics.Runs = max(runs, memoryRuns)
+	metrics.DataQuality = math.Min(float64(metrics.Runs)/idealBatchRuns, 1.0)
+	metrics.HasSufficientData = metrics.Runs >= minBatchRuns
+
+	summarize(metrics, h.verbose)
+
+	return metrics, nil
+}
+
+// queryRuns runs a range query returning one series per pod, and returns
+// the samples of all series in time order along with the series count
+func (h *HistoricalAnalyzer) queryRuns(ctx context.Context, query string, r v1.Range) ([]MetricSample, int, error) {
+	if h.verbose {
+		fmt.Printf("[DEBUG] Prometheus range query: %s (step: %s)\n", query, r.Step)
+	}
+
+	result, warnings, err := h.promAPI.QueryRange(ctx, query, r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("prometheus query failed: %w", err)
+	}
+	if len(warnings) > 0 && h.verbose {
+		fmt.Printf("[DEBUG] Prometheus warnings: %v\n", warnings)
+	}
+
+	samples, err := parsePrometheusResult(result)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Timestamp.Before(samples[j].Timestamp) })
+
+	return samples, len(result.(model.Matrix)), nil
+}
+
+// summarize fills in the pattern, growth and weekday/weekend analysis of
+// the samples in metrics
+func summarize(metrics *HistoricalMetrics, verbose bool) {
+	cpuSamples, memorySamples := metrics.CPUSamples, metrics.MemorySamples
+
 	metrics.SampleCount = len(cpuSamples)
 
 	// Calculate CPU pattern analysis
@@ -88,10 +269,6 @@ func (h *HistoricalAnalyzer) GetHistoricalMetrics(
 		}
 	}
 
-	// Calculate data quality and confidence
-	metrics.DataQuality = calculateDataQuality(len(cpuSamples), endTime.Sub(startTime))
-	metrics.HasSufficientData = len(cpuSamples) >= 864 // ~3 days at 5-min intervals
-
 	// Week 9 Day 3: Split samples by weekday/weekend and calculate separate P95
 	if len(cpuSamples) > 0 {
 		weekdayCPU, weekendCPU := SplitSamplesByWeekday(cpuSamples)
@@ -129,7 +306,7 @@ func (h *HistoricalAnalyzer) GetHistoricalMetrics(
 		}
 	}
 
-	if h.verbose {
+	if verbose {
 		fmt.Printf("[DEBUG] Pattern Analysis - CPU: %s (CV: %.2f), Memory: %s (CV: %.2f)\n",
 			metrics.CPUPattern.Type, metrics.CPUPattern.Variation,
 			metrics.MemoryPattern.Type, metrics.MemoryPattern.Variation)
@@ -146,8 +323,6 @@ func (h *HistoricalAnalyzer) GetHistoricalMetrics(
 				metrics.WeekdayMemoryP95/(1024*1024), metrics.WeekendMemoryP95/(1024*1024))
 		}
 	}
-
-	return metrics, nil
 }
 
 // queryCPUUsage queries historical CPU usage from Prometheus
@@ -159,8 +334,8 @@ func (h *HistoricalAnalyzer) queryCPUUsage(
 ) ([]MetricSample, error) {
 
 	query := fmt.Sprintf(
-		`container_cpu_usage_seconds_total{namespace="%s",pod="%s",container!="POD"}`,
-		namespace, podName,
+		`container_cpu_usage_seconds_total{namespace="%s",pod="%s",%s}`,
+		namespace, podName, containerSelector(containerName),
 	)
 
 	r := v1.Range{
@@ -195,15 +370,19 @@ func (h *HistoricalAnalyzer) queryCPUUsage(
 		}
 	}
 
-	samples, err := parsePrometheusResult(result)
+	containers, err := parseContainerSeries(result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse CPU results: %w", err)
 	}
 
-	// Convert counter to rate (millicores)
-	if len(samples) > 0 {
-		samples = calculateRateFromCounter(samples)
+	// Convert each counter to a rate (millicores) before combining them,
+	// as counters of different containers and restarts are unrelated
+	for _, series := range containers {
+		for i := range series {
+			series[i] = calculateRateFromCounter(series[i])
+		}
 	}
+	samples := combineContainers(containers)
 
 	if len(samples) == 0 {
 		return []MetricSample{}, nil
@@ -224,8 +403,8 @@ func (h *HistoricalAnalyzer) queryMemoryUsage(
 ) ([]MetricSample, error) {
 
 	query := fmt.Sprintf(
-		`container_memory_working_set_bytes{namespace="%s",pod="%s",container!="POD"}`,
-		namespace, podName,
+		`container_memory_working_set_bytes{namespace="%s",pod="%s",%s}`,
+		namespace, podName, containerSelector(containerName),
 	)
 
 	r := v1.Range{
@@ -247,10 +426,11 @@ func (h *HistoricalAnalyzer) queryMemoryUsage(
 		fmt.Printf("[DEBUG] Prometheus warnings: %v\n", warnings)
 	}
 
-	samples, err := parsePrometheusResult(result)
+	containers, err := parseContainerSeries(result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse memory results: %w", err)
 	}
+	samples := combineContainers(containers)
 
 	if len(samples) == 0 {
 		return []MetricSample{}, nil
@@ -262,7 +442,96 @@ func (h *HistoricalAnalyzer) queryMemoryUsage(
 	return samples, nil
 }
 
-// parsePrometheusResult converts Prometheus query result to MetricSample array
+// containerSelector narrows a query to a single container, or to all
+// application containers when containerName is empty
+func containerSelector(containerName string) string {
+	if containerName == "" {
+		return `container!="POD",container!=""`
+	}
+	return fmt.Sprintf(`container="%s"`, containerName)
+}
+
+// workloadMetrics returns the metrics of workload over a range, without
+// samples
+func workloadMetrics(workload *models.Workload, startTime, endTime time.Time, resolution time.Duration) *HistoricalMetrics {
+	podName := workload.Pod
+	if podName == "" {
+		podName = workload.Deployment
+	}
+	return &HistoricalMetrics{
+		PodName:       podName,
+		Namespace:     workload.Namespace,
+		ContainerName: workload.Container,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		Resolution:    resolution,
+	}
+}
+
+// WorkloadSelector returns the label matchers of the containers of
+// workload, of its pod when it is a single pod. Without a container it
+// matches every application container, excluding pause containers and
+// pod-level cgroups.
+func WorkloadSelector(workload *models.Workload) string {
+	matchers := []string{fmt.Sprintf(`namespace="%s"`, workload.Namespace)}
+	if workload.Pod != "" {
+		matchers = append(matchers, fmt.Sprintf(`pod="%s"`, workload.Pod))
+	}
+	return strings.Join(append(matchers, containerSelector(workload.Container)), ",")
+}
+
+// OwnedBy restricts expr, with namespace and pod labels, to the pods
+// workload has owned. Single pods are already selected by name.
+func OwnedBy(workload *models.Workload, expr string) string {
+	if workload.Pod != "" {
+		return expr
+	}
+	return fmt.Sprintf(`%s * on (namespace, pod) group_left () %s`, expr, PodOwnerQuery(workload))
+}
+
+// podOwner is an object through which a workload kind owns its pods, with
+// its kube-state-metrics owner metric and the label naming the object
+type podOwner struct {
+	kind   string
+	metric string
+	label  string
+}
+
+// podOwners are the intermediate owners of the pods of workload kinds.
+// Argo Rollouts manage ReplicaSets just like Deployments.
+var podOwners = map[string]podOwner{
+	"Deployment": {kind: "ReplicaSet", metric: "kube_replicaset_owner", label: "replicaset"},
+	"Rollout":    {kind: "ReplicaSet", metric: "kube_replicaset_owner", label: "replicaset"},
+	"CronJob":    {kind: "Job", metric: "kube_job_owner", label: "job_name"},
+}
+
+// PodOwnerQuery returns a query for the pods workload has owned, one
+// series per namespace and pod with a value of 1, from kube_pod_owner and
+// the owner metrics of intermediate owners. Workloads without a Kind are
+// Deployments.
+func PodOwnerQuery(workload *models.Workload) string {
+	kind := workload.Kind
+	if kind == "" {
+		kind = "Deployment"
+	}
+
+	owner, ok := podOwners[kind]
+	if !ok {
+		return fmt.Sprintf(`max by (namespace, pod) (kube_pod_owner{namespace="%s",owner_kind="%s",owner_name="%s"})`,
+			workload.Namespace, kind, workload.Deployment)
+	}
+
+	// Rename the intermediate owner's own name label to owner_name, so it
+	// matches the owner of the pods
+	owned := fmt.Sprintf(`max by (namespace, owner_name) (label_replace(%s{namespace="%s",owner_kind="%s",owner_name="%s"}, "owner_name", "$1", "%s", "(.+)"))`,
+		owner.metric, workload.Namespace, kind, workload.Deployment, owner.label)
+	return fmt.Sprintf(`max by (namespace, pod) (kube_pod_owner{namespace="%s",owner_kind="%s"} * on (namespace, owner_name) group_left () %s)`,
+		workload.Namespace, owner.kind, owned)
+}
+
+// parsePrometheusResult converts Prometheus query result to MetricSample array.
+// Samples of all series are returned together, so each series must already
+// be a usage rather than a counter (one per pod of a batch or workload query).
 func parsePrometheusResult(result model.Value) ([]MetricSample, error) {
 	matrix, ok := result.(model.Matrix)
 	if !ok {
@@ -276,7 +545,6 @@ func parsePrometheusResult(result model.Value) ([]MetricSample, error) {
 
 	var samples []MetricSample
 
-	// Aggregate all series (multiple containers in a pod)
 	for _, series := range matrix {
 		for _, value := range series.Values {
 			samples = append(samples, MetricSample{
@@ -289,10 +557,64 @@ func parsePrometheusResult(result model.Value) ([]MetricSample, error) {
 	return samples, nil
 }
 
-// calculateRateFromCounter converts CPU counter values to per-second rate in millicores
+// parseContainerSeries returns the series of a Prometheus query result by
+// container. A container has several series when it restarted during the
+// range, as cAdvisor labels each container instance separately.
+func parseContainerSeries(result model.Value) (map[string][][]MetricSample, error) {
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", result)
+	}
+
+	containers := make(map[string][][]MetricSample)
+	for _, series := range matrix {
+		samples := make([]MetricSample, 0, len(series.Values))
+		for _, value := range series.Values {
+			samples = append(samples, MetricSample{
+				Timestamp: value.Timestamp.Time(),
+				Value:     float64(value.Value),
+			})
+		}
+		container := string(series.Metric["container"])
+		containers[container] = append(containers[container], samples)
+	}
+	return containers, nil
+}
+
+// combineContainers returns the usage of the containers at each time, in
+// time order. Series of the same container only overlap while the series
+// of a restarted instance goes stale, so the highest is taken rather than
+// counting the container twice.
+func combineContainers(containers map[string][][]MetricSample) []MetricSample {
+	total := make(map[time.Time]float64)
+	for _, series := range containers {
+		usage := make(map[time.Time]float64)
+		for _, samples := range series {
+			for _, sample := range samples {
+				if value, ok := usage[sample.Timestamp]; !ok || sample.Value > value {
+					usage[sample.Timestamp] = sample.Value
+				}
+			}
+		}
+		for timestamp, value := range usage {
+			total[timestamp] += value
+		}
+	}
+
+	samples := make([]MetricSample, 0, len(total))
+	for timestamp, value := range total {
+		samples = append(samples, MetricSample{Timestamp: timestamp, Value: value})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Timestamp.Before(samples[j].Timestamp) })
+	return samples
+}
+
+// calculateRateFromCounter converts the samples of one CPU counter series
+// to per-second rates in millicores. A counter that goes down was reset, so
+// its whole value is the increase since the reset, as with PromQL rate().
 func calculateRateFromCounter(samples []MetricSample) []MetricSample {
 	if len(samples) < 2 {
-		return samples
+		return nil
 	}
 
 	rates := make([]MetricSample, 0, len(samples)-1)
@@ -301,6 +623,9 @@ func calculateRateFromCounter(samples []MetricSample) []MetricSample {
 		timeDiff := samples[i].Timestamp.Sub(samples[i-1].Timestamp).Seconds()
 		if timeDiff > 0 {
 			valueDiff := samples[i].Value - samples[i-1].Value
+			if valueDiff < 0 {
+				valueDiff = samples[i].Value
+			}
 			// Convert to millicores (rate per second * 1000)
 			rate := (valueDiff / timeDiff) * 1000
 
@@ -314,6 +639,37 @@ func calculateRateFromCounter(samples []MetricSample) []MetricSample {
 	return rates
 }
 
+// DataDays returns how many days of CPU samples were collected, excluding
+// gaps. For batch workloads it is the time between the first and last
+// run, as they are not expected to run continuously.
+func (m *HistoricalMetrics) DataDays() float64 {
+	if m.Runs > 0 {
+		samples := m.MemorySamples
+		if len(samples) == 0 {
+			return 0
+		}
+		return samples[len(samples)-1].Timestamp.Sub(samples[0].Timestamp).Hours() / 24
+	}
+	return float64(sampleTimes(m.CPUSamples)) * m.Resolution.Hours() / 24
+}
+
+// ActiveTime returns how long samples were collected. Times at which
+// several pods were sampled, e.g. parallel runs of a batch workload, count
+// once.
+func (m *HistoricalMetrics) ActiveTime() time.Duration {
+	return time.Duration(sampleTimes(m.MemorySamples)) * m.Resolution
+}
+
+// sampleTimes returns the number of distinct times in samples, which
+// differs from their count when several pods were sampled
+func sampleTimes(samples []MetricSample) int {
+	times := make(map[int64]struct{}, len(samples))
+	for _, sample := range samples {
+		times[sample.Timestamp.UnixNano()] = struct{}{}
+	}
+	return len(times)
+}
+
 // calculateDataQuality returns a quality score (0.0-1.0) based on sample count and time span
 func calculateDataQuality(sampleCount int, timeSpan time.Duration) float64 {
 	// Ideal: 7 days * 288 samples/day (5-min intervals) = ~2000 samples
@@ -1,8 +1,16 @@
 package analyzer
 
 import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/api"
 )
 
 func TestParsePrometheusResult(t *testing.T) {
@@ -10,9 +18,95 @@ func TestParsePrometheusResult(t *testing.T) {
 	// We'll add mock Prometheus responses here
 }
 
+// recordedPrometheus answers CPU and memory range queries with the
+// responses recorded in testdata/prometheus
+func recordedPrometheus(t *testing.T, cpuFile, memoryFile string) api.Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file := memoryFile
+		if strings.Contains(r.FormValue("query"), "container_cpu_usage_seconds_total") {
+			file = cpuFile
+		}
+		data, err := os.ReadFile("../../testdata/prometheus/" + file)
+		if err != nil {
+			t.Errorf("Failed to load recording: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
 func TestGetHistoricalMetrics(t *testing.T) {
-	// Test with mock Prometheus client
-	// This will be expanded with actual test fixtures
+	// A pod whose app container restarted between the 3rd and 4th sample
+	// and whose istio-proxy counter was reset at the 4th sample
+	client := recordedPrometheus(t, "cpu_multi_container.json", "memory_multi_container.json")
+	h := NewHistoricalAnalyzer(client, false)
+
+	metrics, err := h.GetHistoricalMetrics(context.Background(), "shop", "web-7d9f8b-abcde", "", 7)
+	if err != nil {
+		t.Fatalf("GetHistoricalMetrics failed: %v", err)
+	}
+
+	// app at 100m plus istio-proxy at 10m, or 5m over the reset
+	expectedCPU := []float64{110, 110, 105, 110, 110}
+	if len(metrics.CPUSamples) != len(expectedCPU) {
+		t.Fatalf("Expected %d CPU samples, got %d: %v", len(expectedCPU), len(metrics.CPUSamples), metrics.CPUSamples)
+	}
+	for i, sample := range metrics.CPUSamples {
+		if math.Abs(sample.Value-expectedCPU[i]) > 0.001 {
+			t.Errorf("CPU sample %d: expected %.0fm, got %.3fm", i, expectedCPU[i], sample.Value)
+		}
+		if i > 0 && !sample.Timestamp.After(metrics.CPUSamples[i-1].Timestamp) {
+			t.Errorf("Expected CPU samples in time order, got %v", metrics.CPUSamples)
+		}
+	}
+
+	// app's old instance overlaps the new one at the restart, so it is
+	// not counted twice
+	expectedMemory := []float64{264, 264, 264, 264, 214, 214}
+	if len(metrics.MemorySamples) != len(expectedMemory) {
+		t.Fatalf("Expected %d memory samples, got %d", len(expectedMemory), len(metrics.MemorySamples))
+	}
+	for i, sample := range metrics.MemorySamples {
+		if sample.Value != expectedMemory[i]*1024*1024 {
+			t.Errorf("Memory sample %d: expected %.0fMi, got %.1fMi", i, expectedMemory[i], sample.Value/(1024*1024))
+		}
+	}
+}
+
+func TestCalculateRateFromCounter(t *testing.T) {
+	start := time.Unix(1732200000, 0)
+	samples := []MetricSample{
+		{Timestamp: start, Value: 10},
+		{Timestamp: start.Add(time.Minute), Value: 16},
+		{Timestamp: start.Add(2 * time.Minute), Value: 3}, // reset
+		{Timestamp: start.Add(3 * time.Minute), Value: 9},
+	}
+
+	rates := calculateRateFromCounter(samples)
+	expected := []float64{100, 50, 100}
+	if len(rates) != len(expected) {
+		t.Fatalf("Expected %d rates, got %d", len(expected), len(rates))
+	}
+	for i, rate := range rates {
+		if math.Abs(rate.Value-expected[i]) > 0.001 {
+			t.Errorf("Rate %d: expected %.0fm, got %.3fm", i, expected[i], rate.Value)
+		}
+	}
+
+	if rates := calculateRateFromCounter(samples[:1]); len(rates) != 0 {
+		t.Errorf("Expected no rate from a single counter sample, got %v", rates)
+	}
 }
 
 // Helper to create mock metric samples for testing
@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// defaultTargetCPUUtilization is the target of HPAs that list no metrics
+const defaultTargetCPUUtilization = 80
+
+// HPAInfo describes the HorizontalPodAutoscaler scaling a workload
+type HPAInfo struct {
+	Name            string
+	MinReplicas     int32
+	MaxReplicas     int32
+	CurrentReplicas int32
+
+	// ScalesOnCPU and ScalesOnMemory are true when the HPA scales on the
+	// utilization of that request, so changing the request changes the
+	// replica count
+	ScalesOnCPU    bool
+	ScalesOnMemory bool
+
+	// ScalesOnOtherMetrics is true when the HPA also scales on a metric
+	// other than pod CPU or memory utilization, e.g. a pods, object or
+	// external metric
+	ScalesOnOtherMetrics bool
+
+	// TargetCPUUtilization is the pod CPU utilization target in percent,
+	// 0 when the HPA has none
+	TargetCPUUtilization int32
+
+	// CPUMetricIndex is the index of that target in spec.metrics, -1 for
+	// HPAs relying on the default target
+	CPUMetricIndex int
+}
+
+// NewHPAInfo summarizes hpa
+func NewHPAInfo(hpa autoscalingv2.HorizontalPodAutoscaler) *HPAInfo {
+	info := &HPAInfo{
+		Name:            hpa.Name,
+		MinReplicas:     1,
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		CPUMetricIndex:  -1,
+	}
+	if hpa.Spec.MinReplicas != nil {
+		info.MinReplicas = *hpa.Spec.MinReplicas
+	}
+
+	if len(hpa.Spec.Metrics) == 0 {
+		info.ScalesOnCPU = true
+		info.TargetCPUUtilization = defaultTargetCPUUtilization
+		return info
+	}
+
+	for i, metric := range hpa.Spec.Metrics {
+		var name corev1.ResourceName
+		var target autoscalingv2.MetricTarget
+		switch {
+		case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil:
+			name, target = metric.Resource.Name, metric.Resource.Target
+		case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil:
+			name, target = metric.ContainerResource.Name, metric.ContainerResource.Target
+		default:
+			// Pods, object and external metrics do not depend on requests
+			info.ScalesOnOtherMetrics = true
+			continue
+		}
+		if target.Type != autoscalingv2.UtilizationMetricType || target.AverageUtilization == nil {
+			info.ScalesOnOtherMetrics = true
+			continue
+		}
+
+		switch name {
+		case corev1.ResourceCPU:
+			info.ScalesOnCPU = true
+			// Container targets only cover part of the pod, so only a
+			// pod-wide target can be tuned
+			if metric.Type == autoscalingv2.ResourceMetricSourceType {
+				info.TargetCPUUtilization = *target.AverageUtilization
+				info.CPUMetricIndex = i
+			} else {
+				info.ScalesOnOtherMetrics = true
+			}
+		case corev1.ResourceMemory:
+			info.ScalesOnMemory = true
+		default:
+			info.ScalesOnOtherMetrics = true
+		}
+	}
+	return info
+}
@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func utilization(name corev1.ResourceName, percent int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name:   name,
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &percent},
+		},
+	}
+}
+
+func TestNewHPAInfo(t *testing.T) {
+	averageValue := resource.MustParse("100")
+	requestsPerSecond := autoscalingv2.MetricSpec{
+		Type: autoscalingv2.PodsMetricSourceType,
+		Pods: &autoscalingv2.PodsMetricSource{
+			Metric: autoscalingv2.MetricIdentifier{Name: "requests_per_second"},
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: &averageValue},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		metrics            []autoscalingv2.MetricSpec
+		cpu, memory, other bool
+		targetCPU          int32
+		cpuIndex           int
+	}{
+		{name: "default target", cpu: true, targetCPU: 80, cpuIndex: -1},
+		{name: "cpu", metrics: []autoscalingv2.MetricSpec{utilization(corev1.ResourceCPU, 60)}, cpu: true, targetCPU: 60},
+		{
+			name:    "cpu and memory",
+			metrics: []autoscalingv2.MetricSpec{utilization(corev1.ResourceMemory, 70), utilization(corev1.ResourceCPU, 60)},
+			cpu:     true, memory: true, targetCPU: 60, cpuIndex: 1,
+		},
+		{
+			name:    "cpu and pods metric",
+			metrics: []autoscalingv2.MetricSpec{utilization(corev1.ResourceCPU, 60), requestsPerSecond},
+			cpu:     true, other: true, targetCPU: 60,
+		},
+		{name: "pods metric only", metrics: []autoscalingv2.MetricSpec{requestsPerSecond}, other: true, cpuIndex: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := NewHPAInfo(autoscalingv2.HorizontalPodAutoscaler{Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 10,
+				Metrics:     tt.metrics,
+			}})
+			if info.ScalesOnCPU != tt.cpu || info.ScalesOnMemory != tt.memory || info.ScalesOnOtherMetrics != tt.other {
+				t.Errorf("Expected cpu=%v memory=%v other=%v, got %v %v %v",
+					tt.cpu, tt.memory, tt.other, info.ScalesOnCPU, info.ScalesOnMemory, info.ScalesOnOtherMetrics)
+			}
+			if info.TargetCPUUtilization != tt.targetCPU || info.CPUMetricIndex != tt.cpuIndex {
+				t.Errorf("Expected a %d%% CPU target at %d, got %d%% at %d",
+					tt.targetCPU, tt.cpuIndex, info.TargetCPUUtilization, info.CPUMetricIndex)
+			}
+		})
+	}
+}
@@ -68,17 +68,27 @@ func GetEnvironmentConfig(env Environment) EnvironmentConfig {
 }
 
 // ClassifyNamespace determines the environment type of a namespace
-func ClassifyNamespace(ctx context.Context, clientset *kubernetes.Clientset, namespace string) Environment {
+func ClassifyNamespace(ctx context.Context, clientset kubernetes.Interface, namespace string) Environment {
 	// Try to get namespace object to check labels
+	var nsLabels map[string]string
 	ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
-	if err == nil && ns.Labels != nil {
+	if err == nil {
+		nsLabels = ns.Labels
+	}
+	return classifyNamespaceLabels(namespace, nsLabels)
+}
+
+// classifyNamespaceLabels determines the environment from the labels of a
+// namespace, falling back to its name
+func classifyNamespaceLabels(namespace string, nsLabels map[string]string) Environment {
+	if nsLabels != nil {
 		// Check for environment label
-		if env, exists := ns.Labels["environment"]; exists {
+		if env, exists := nsLabels["environment"]; exists {
 			return normalizeEnvironment(env)
 		}
 
 		// Check for tier label
-		if tier, exists := ns.Labels["tier"]; exists {
+		if tier, exists := nsLabels["tier"]; exists {
 			if tier == "prod" || tier == "production" {
 				return EnvironmentProduction
 			}
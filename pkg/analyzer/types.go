@@ -30,6 +30,14 @@ type HistoricalMetrics struct {
 	WeekdayMemoryP95 uint64  // P95 for Monday-Friday
 	WeekendMemoryP95 uint64  // P95 for Saturday-Sunday
 
+	// Runs is the number of pods of a batch workload that were sampled,
+	// 0 for long-running workloads
+	Runs int
+
+	// Pods is the number of pods of a long-running workload that were
+	// sampled, including those replaced during the lookback
+	Pods int
+
 	// Metadata
 	SampleCount       int
 	Resolution        time.Duration
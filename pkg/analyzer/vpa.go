@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// VPAInfo is the recommendation of an existing VerticalPodAutoscaler for
+// one container, in millicores and bytes. Bounds are 0 when the VPA does
+// not report them.
+type VPAInfo struct {
+	Name       string
+	UpdateMode string
+
+	TargetCPU        int64
+	TargetMemory     int64
+	LowerBoundCPU    int64
+	LowerBoundMemory int64
+	UpperBoundCPU    int64
+	UpperBoundMemory int64
+}
+
+// NewVPAInfo summarizes the recommendation of vpa for container, nil when
+// it has none yet
+func NewVPAInfo(vpa *kube.VerticalPodAutoscaler, container string) *VPAInfo {
+	rec := vpa.Container(container)
+	if rec == nil {
+		return nil
+	}
+
+	// VPAs without an update policy evict pods to apply recommendations
+	mode := "Auto"
+	if vpa.Spec.UpdatePolicy != nil && vpa.Spec.UpdatePolicy.UpdateMode != "" {
+		mode = string(vpa.Spec.UpdatePolicy.UpdateMode)
+	}
+
+	info := &VPAInfo{Name: vpa.Name, UpdateMode: mode}
+	info.TargetCPU, info.TargetMemory = resourceValues(rec.Target)
+	info.LowerBoundCPU, info.LowerBoundMemory = resourceValues(rec.LowerBound)
+	info.UpperBoundCPU, info.UpperBoundMemory = resourceValues(rec.UpperBound)
+	return info
+}
+
+// resourceValues returns the CPU (millicores) and memory (bytes) of list
+func resourceValues(list corev1.ResourceList) (cpu, memory int64) {
+	if q, ok := list[corev1.ResourceCPU]; ok {
+		cpu = q.MilliValue()
+	}
+	if q, ok := list[corev1.ResourceMemory]; ok {
+		memory = q.Value()
+	}
+	return cpu, memory
+}
+
+// listVPAs returns the VPAs of namespace by the kind and name of the
+// workload they target. Without a dynamic client or the VPA CRD there are
+// none.
+func (a *Analyzer) listVPAs(ctx context.Context, namespace string) map[kube.Workload]*kube.VerticalPodAutoscaler {
+	if a.dynamic == nil {
+		return nil
+	}
+
+	vpas, err := kube.ListVerticalPodAutoscalers(ctx, a.dynamic, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] %v\n", err)
+		return nil
+	}
+
+	byWorkload := make(map[kube.Workload]*kube.VerticalPodAutoscaler, len(vpas))
+	for i := range vpas {
+		if ref := vpas[i].Spec.TargetRef; ref != nil {
+			byWorkload[kube.Workload{Kind: ref.Kind, Name: ref.Name}] = &vpas[i]
+		}
+	}
+	return byWorkload
+}
@@ -0,0 +1,407 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+	defaultDays  = 30
+)
+
+// ScanTrigger starts a scan in the background; *controller.Controller
+// implements it. Trigger returns false when no scan will run, e.g. on a
+// replica that is not the leader.
+type ScanTrigger interface {
+	Trigger() bool
+}
+
+// Server exposes saved recommendations and analytics as JSON under /api/v1.
+// Reads are unauthenticated, so the address should only be reachable from
+// inside the cluster.
+type Server struct {
+	store storage.Store
+	scans ScanTrigger
+	token string
+	mux   *http.ServeMux
+}
+
+// NewServer creates an API server reading from store. scans may be nil, in
+// which case POST /api/v1/scans is not available.
+func NewServer(store storage.Store, scans ScanTrigger) *Server {
+	s := &Server{
+		store: store,
+		scans: scans,
+		mux:   http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /api/v1/recommendations", s.listRecommendations)
+	s.mux.HandleFunc("GET /api/v1/recommendations/{id}", s.getRecommendation)
+	s.mux.HandleFunc("POST /api/v1/scans", s.triggerScan)
+	s.mux.HandleFunc("GET /api/v1/analytics/stats", s.analyticsStats)
+	s.mux.HandleFunc("GET /api/v1/analytics/trends", s.analyticsTrends)
+	s.mux.HandleFunc("GET /api/v1/analytics/compare", s.analyticsCompare)
+	s.mux.HandleFunc("GET /api/v1/analytics/workload", s.analyticsWorkload)
+	s.mux.HandleFunc("GET /healthz", s.healthz)
+
+	return s
+}
+
+// WithToken requires "Authorization: Bearer <token>" on POST /api/v1/scans.
+// An empty token leaves it open.
+func (s *Server) WithToken(token string) *Server {
+	s.token = token
+	return s
+}
+
+// Handle mounts an additional handler, such as /metrics, next to the API
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves on addr until ctx is done, then shuts down
+// gracefully
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("API server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// recommendationFilter selects recommendations by query parameters
+type recommendationFilter struct {
+	deployment string
+	kind       string
+	recType    models.RecommendationType
+	risk       models.RiskLevel
+	status     string
+	minSavings float64
+}
+
+func (f recommendationFilter) empty() bool {
+	return f == recommendationFilter{}
+}
+
+func (f recommendationFilter) match(rec *models.Recommendation) bool {
+	if f.deployment != "" && rec.Workload.Deployment != f.deployment {
+		return false
+	}
+	if f.kind != "" && !strings.EqualFold(rec.Workload.Kind, f.kind) {
+		return false
+	}
+	if f.recType != "" && rec.Type != f.recType {
+		return false
+	}
+	if f.risk != "" && rec.Risk != f.risk {
+		return false
+	}
+	if f.status != "" && recommendationStatus(rec) != f.status {
+		return false
+	}
+	return rec.SavingsMonthly >= f.minSavings
+}
+
+// recommendationStatus matches the status shown by `cost-scan history`
+func recommendationStatus(rec *models.Recommendation) string {
+	if rec.AppliedAt != nil {
+		return "applied"
+	}
+	return "pending"
+}
+
+// listRecommendations handles GET /api/v1/recommendations?namespace=...
+// with optional deployment, kind, type, risk, status, min_savings and
+// limit filters
+func (s *Server) listRecommendations(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	namespace, ok := requireParam(w, r, "namespace")
+	if !ok {
+		return
+	}
+	limit, ok := intParam(w, r, "limit", defaultLimit, maxLimit)
+	if !ok {
+		return
+	}
+
+	filter := recommendationFilter{
+		deployment: q.Get("deployment"),
+		kind:       q.Get("kind"),
+		recType:    models.RecommendationType(strings.ToUpper(q.Get("type"))),
+		risk:       models.RiskLevel(strings.ToUpper(q.Get("risk"))),
+		status:     strings.ToLower(q.Get("status")),
+	}
+	if filter.status != "" && filter.status != "pending" && filter.status != "applied" {
+		writeError(w, http.StatusBadRequest, "status must be pending or applied")
+		return
+	}
+	if v := q.Get("min_savings"); v != "" {
+		minSavings, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "min_savings must be a number")
+			return
+		}
+		filter.minSavings = minSavings
+	}
+
+	// Filters are applied to the most recent maxLimit recommendations
+	fetch := limit
+	if !filter.empty() {
+		fetch = maxLimit
+	}
+	all, err := s.store.ListRecommendations(r.Context(), namespace, fetch)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	recommendations := make([]*models.Recommendation, 0, len(all))
+	totalSavings := 0.0
+	for _, rec := range all {
+		if len(recommendations) == limit {
+			break
+		}
+		if filter.match(rec) {
+			recommendations = append(recommendations, rec)
+			totalSavings += rec.SavingsMonthly
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"recommendations": recommendations,
+		"total_savings":   totalSavings,
+		"count":           len(recommendations),
+	})
+}
+
+// getRecommendation handles GET /api/v1/recommendations/{id}, returning the
+// recommendation with its audit log
+func (s *Server) getRecommendation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	rec, err := s.store.GetRecommendation(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	entries, err := s.store.GetAuditLog(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"recommendation": rec,
+		"status":         recommendationStatus(rec),
+		"audit_log":      entries,
+	})
+}
+
+// triggerScan handles POST /api/v1/scans. The scan runs in the background;
+// its recommendations appear in the list endpoint once saved.
+func (s *Server) triggerScan(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "a valid bearer token is required")
+		return
+	}
+	if s.scans == nil {
+		writeError(w, http.StatusServiceUnavailable, "scans cannot be triggered by this server")
+		return
+	}
+
+	if !s.scans.Trigger() {
+		writeError(w, http.StatusServiceUnavailable, "scans run on the leader replica, not this one")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status": "accepted",
+	})
+}
+
+// analyticsStats handles GET /api/v1/analytics/stats?namespace=...&days=30
+func (s *Server) analyticsStats(w http.ResponseWriter, r *http.Request) {
+	namespace, days, ok := analyticsParams(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := s.store.GetDashboardStats(r.Context(), namespace, days)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// analyticsTrends handles GET /api/v1/analytics/trends?namespace=...&days=30
+func (s *Server) analyticsTrends(w http.ResponseWriter, r *http.Request) {
+	namespace, days, ok := analyticsParams(w, r)
+	if !ok {
+		return
+	}
+
+	trend, err := s.store.GetSavingsTrend(r.Context(), namespace, days)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, trend)
+}
+
+// analyticsCompare handles GET /api/v1/analytics/compare?namespace=...&days=30
+func (s *Server) analyticsCompare(w http.ResponseWriter, r *http.Request) {
+	namespace, days, ok := analyticsParams(w, r)
+	if !ok {
+		return
+	}
+
+	comparison, err := s.store.ComparePerformance(r.Context(), namespace, days)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, comparison)
+}
+
+// analyticsWorkload handles
+// GET /api/v1/analytics/workload?namespace=...&deployment=...&limit=10
+func (s *Server) analyticsWorkload(w http.ResponseWriter, r *http.Request) {
+	namespace, ok := requireParam(w, r, "namespace")
+	if !ok {
+		return
+	}
+	deployment, ok := requireParam(w, r, "deployment")
+	if !ok {
+		return
+	}
+	limit, ok := intParam(w, r, "limit", 10, maxLimit)
+	if !ok {
+		return
+	}
+
+	history, err := s.store.GetWorkloadHistory(r.Context(), namespace, deployment, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if history == nil {
+		history = []*models.Recommendation{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace":       namespace,
+		"deployment":      deployment,
+		"recommendations": history,
+		"count":           len(history),
+	})
+}
+
+// healthz handles GET /healthz by pinging the store
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.Ping(r.Context()); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+// authorized reports whether r carries the server's bearer token, if one
+// is required
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func analyticsParams(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	namespace, ok := requireParam(w, r, "namespace")
+	if !ok {
+		return "", 0, false
+	}
+	days, ok := intParam(w, r, "days", defaultDays, 3650)
+	if !ok {
+		return "", 0, false
+	}
+	return namespace, days, true
+}
+
+// requireParam returns a mandatory query parameter, writing a 400 if it is
+// missing
+func requireParam(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is required", name))
+		return "", false
+	}
+	return value, true
+}
+
+// intParam parses an optional positive integer query parameter, writing a
+// 400 if it is invalid
+func intParam(w http.ResponseWriter, r *http.Request, name string, def, max int) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, true
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > max {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s must be between 1 and %d", name, max))
+		return 0, false
+	}
+	return n, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("[WARN] Failed to write API response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
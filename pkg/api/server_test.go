@@ -0,0 +1,322 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+)
+
+type countingTrigger struct {
+	calls    int
+	follower bool
+}
+
+func (t *countingTrigger) Trigger() bool {
+	if t.follower {
+		return false
+	}
+	t.calls++
+	return true
+}
+
+func saveRecommendation(t *testing.T, store storage.Store, deployment string, risk models.RiskLevel, savings float64, createdAt time.Time) *models.Recommendation {
+	t.Helper()
+
+	rec := &models.Recommendation{
+		Type: models.RecommendationRightSize,
+		Workload: &models.Workload{
+			ClusterID:  "test",
+			Namespace:  "shop",
+			Deployment: deployment,
+			Kind:       "Deployment",
+			Container:  "app",
+		},
+		CurrentCPU:        1000,
+		RecommendedCPU:    300,
+		CurrentMemory:     1 << 30,
+		RecommendedMemory: 256 << 20,
+		SavingsMonthly:    savings,
+		Risk:              risk,
+		CreatedAt:         createdAt,
+	}
+	if err := store.SaveRecommendation(context.Background(), rec); err != nil {
+		t.Fatalf("SaveRecommendation failed: %v", err)
+	}
+	return rec
+}
+
+// do performs a request against server and decodes the JSON body
+func do(t *testing.T, server http.Handler, method, target string, wantStatus int) map[string]interface{} {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+
+	if rr.Code != wantStatus {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, target, wantStatus, rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: expected JSON, got %q", method, target, ct)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s: invalid JSON %q: %v", method, target, rr.Body.String(), err)
+	}
+	return body
+}
+
+// assertSnakeCase fails for any object key in v that is not snake_case
+func assertSnakeCase(t *testing.T, path string, v interface{}) {
+	t.Helper()
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if key != strings.ToLower(key) || strings.Contains(key, "-") {
+				t.Errorf("Expected snake_case key, got %s%s", path, key)
+			}
+			assertSnakeCase(t, path+key+".", value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			assertSnakeCase(t, path, value)
+		}
+	}
+}
+
+func newTestServer(t *testing.T) (*Server, *storage.MemoryStore, *countingTrigger) {
+	t.Helper()
+
+	store := storage.NewMemoryStore()
+	trigger := &countingTrigger{}
+	return NewServer(store, trigger), store, trigger
+}
+
+func TestListRecommendations(t *testing.T) {
+	server, store, _ := newTestServer(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	saveRecommendation(t, store, "web", models.RiskLow, 10, now.Add(-2*time.Hour))
+	api := saveRecommendation(t, store, "api", models.RiskMedium, 25, now.Add(-time.Hour))
+	saveRecommendation(t, store, "web", models.RiskHigh, 40, now)
+
+	api.AppliedAt = &now
+	api.AppliedBy = "alice"
+	if err := store.UpdateRecommendation(ctx, api); err != nil {
+		t.Fatalf("UpdateRecommendation failed: %v", err)
+	}
+
+	tests := []struct {
+		query   string
+		count   int
+		first   string
+		savings float64
+		name    string
+	}{
+		{"namespace=shop", 3, "web", 75, "newest first"},
+		{"namespace=shop&limit=2", 2, "web", 65, "limit"},
+		{"namespace=shop&deployment=web", 2, "web", 50, "deployment"},
+		{"namespace=shop&status=applied", 1, "api", 25, "status"},
+		{"namespace=shop&status=pending&risk=low", 1, "web", 10, "status and risk"},
+		{"namespace=shop&min_savings=20", 2, "web", 65, "min savings"},
+		{"namespace=shop&type=scale_down", 0, "", 0, "type"},
+		{"namespace=other", 0, "", 0, "other namespace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := do(t, server, http.MethodGet, "/api/v1/recommendations?"+tt.query, http.StatusOK)
+
+			if got := int(body["count"].(float64)); got != tt.count {
+				t.Fatalf("Expected %d recommendations, got %d", tt.count, got)
+			}
+			if got := body["total_savings"].(float64); got != tt.savings {
+				t.Errorf("Expected total savings %.2f, got %.2f", tt.savings, got)
+			}
+
+			recs := body["recommendations"].([]interface{})
+			if tt.count > 0 {
+				workload := recs[0].(map[string]interface{})["workload"].(map[string]interface{})
+				if workload["deployment"] != tt.first {
+					t.Errorf("Expected first recommendation for %s, got %v", tt.first, workload["deployment"])
+				}
+			}
+		})
+	}
+}
+
+func TestListRecommendationsValidation(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	for _, query := range []string{
+		"",
+		"namespace=shop&limit=0",
+		"namespace=shop&limit=abc",
+		"namespace=shop&status=done",
+		"namespace=shop&min_savings=lots",
+	} {
+		body := do(t, server, http.MethodGet, "/api/v1/recommendations?"+query, http.StatusBadRequest)
+		if body["error"] == "" {
+			t.Errorf("Expected error message for %q", query)
+		}
+	}
+}
+
+func TestGetRecommendation(t *testing.T) {
+	server, store, _ := newTestServer(t)
+
+	rec := saveRecommendation(t, store, "web", models.RiskLow, 10, time.Now())
+	if err := store.LogAction(context.Background(), &models.AuditEntry{
+		RecommendationID: rec.ID,
+		Action:           models.AuditActionApplied,
+		Status:           models.AuditStatusSuccess,
+		ExecutedBy:       "alice",
+	}); err != nil {
+		t.Fatalf("LogAction failed: %v", err)
+	}
+
+	body := do(t, server, http.MethodGet, "/api/v1/recommendations/"+rec.ID, http.StatusOK)
+	if got := body["recommendation"].(map[string]interface{})["id"]; got != rec.ID {
+		t.Errorf("Expected recommendation %s, got %v", rec.ID, got)
+	}
+	if body["status"] != "pending" {
+		t.Errorf("Expected pending status, got %v", body["status"])
+	}
+	audit := body["audit_log"].([]interface{})
+	if len(audit) != 1 || audit[0].(map[string]interface{})["executed_by"] != "alice" {
+		t.Errorf("Expected one audit entry by alice, got %v", audit)
+	}
+
+	do(t, server, http.MethodGet, "/api/v1/recommendations/missing", http.StatusNotFound)
+}
+
+func TestJSONFieldNames(t *testing.T) {
+	server, store, _ := newTestServer(t)
+
+	now := time.Now()
+	rec := saveRecommendation(t, store, "web", models.RiskLow, 10, now)
+	rec.AppliedAt, rec.AppliedBy = &now, "alice"
+	if err := store.UpdateRecommendation(context.Background(), rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.LogAction(context.Background(), &models.AuditEntry{
+		RecommendationID: rec.ID,
+		Action:           models.AuditActionApplied,
+		Status:           models.AuditStatusFailed,
+		ErrorMessage:     "denied",
+		ExecutedBy:       "alice",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{
+		"/api/v1/recommendations?namespace=shop",
+		"/api/v1/recommendations/" + rec.ID,
+		"/api/v1/analytics/stats?namespace=shop",
+		"/api/v1/analytics/trends?namespace=shop",
+		"/api/v1/analytics/compare?namespace=shop",
+		"/api/v1/analytics/workload?namespace=shop&deployment=web",
+	} {
+		assertSnakeCase(t, target+" ", do(t, server, http.MethodGet, target, http.StatusOK))
+	}
+
+	got := do(t, server, http.MethodGet, "/api/v1/recommendations/"+rec.ID, http.StatusOK)["recommendation"].(map[string]interface{})
+	for _, field := range []string{"id", "type", "workload", "current_cpu", "recommended_memory", "savings_monthly", "risk", "created_at", "applied_at", "applied_by"} {
+		if _, ok := got[field]; !ok {
+			t.Errorf("Expected field %s in %v", field, got)
+		}
+	}
+}
+
+func TestTriggerScan(t *testing.T) {
+	server, _, trigger := newTestServer(t)
+
+	body := do(t, server, http.MethodPost, "/api/v1/scans", http.StatusAccepted)
+	if body["status"] != "accepted" || trigger.calls != 1 {
+		t.Errorf("Expected an accepted scan, got %v after %d trigger(s)", body, trigger.calls)
+	}
+
+	do(t, NewServer(storage.NewMemoryStore(), nil), http.MethodPost, "/api/v1/scans", http.StatusServiceUnavailable)
+
+	// Replicas that are not the leader do not scan
+	trigger.follower = true
+	do(t, server, http.MethodPost, "/api/v1/scans", http.StatusServiceUnavailable)
+}
+
+func TestTriggerScanToken(t *testing.T) {
+	trigger := &countingTrigger{}
+	server := NewServer(storage.NewMemoryStore(), trigger).WithToken("s3cret")
+
+	for _, header := range []string{"", "Bearer wrong", "s3cret", "Basic s3cret"} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/scans", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		server.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %q to be rejected, got %d", header, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scans", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	server.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted || trigger.calls != 1 {
+		t.Errorf("Expected the token to trigger a scan, got %d after %d trigger(s)", rr.Code, trigger.calls)
+	}
+
+	// Reads stay open
+	do(t, server, http.MethodGet, "/healthz", http.StatusOK)
+}
+
+func TestAnalytics(t *testing.T) {
+	server, store, _ := newTestServer(t)
+
+	now := time.Now()
+	saveRecommendation(t, store, "web", models.RiskLow, 10, now)
+	saveRecommendation(t, store, "api", models.RiskLow, 30, now.Add(-2*24*time.Hour))
+	saveRecommendation(t, store, "web", models.RiskLow, 20, now.Add(-10*24*time.Hour))
+
+	body := do(t, server, http.MethodGet, "/api/v1/analytics/stats?namespace=shop&days=7", http.StatusOK)
+	if body["total_recommendations"].(float64) != 2 || body["potential_savings"].(float64) != 40 {
+		t.Errorf("Unexpected stats: %v", body)
+	}
+
+	body = do(t, server, http.MethodGet, "/api/v1/analytics/trends?namespace=shop&days=7", http.StatusOK)
+	if len(body["data_points"].([]interface{})) != 2 {
+		t.Errorf("Expected 2 trend data points, got %v", body["data_points"])
+	}
+
+	body = do(t, server, http.MethodGet, "/api/v1/analytics/compare?namespace=shop&days=7", http.StatusOK)
+	if body["current_recommendations"].(float64) != 2 || body["previous_recommendations"].(float64) != 1 {
+		t.Errorf("Unexpected comparison: %v", body)
+	}
+
+	body = do(t, server, http.MethodGet, "/api/v1/analytics/workload?namespace=shop&deployment=web", http.StatusOK)
+	if body["count"].(float64) != 2 {
+		t.Errorf("Expected 2 history entries, got %v", body["count"])
+	}
+
+	do(t, server, http.MethodGet, "/api/v1/analytics/stats", http.StatusBadRequest)
+	do(t, server, http.MethodGet, "/api/v1/analytics/workload?namespace=shop", http.StatusBadRequest)
+}
+
+func TestHealthz(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	body := do(t, server, http.MethodGet, "/healthz", http.StatusOK)
+	if body["status"] != "ok" {
+		t.Errorf("Expected ok, got %v", body)
+	}
+}
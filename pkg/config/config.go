@@ -14,12 +14,27 @@ type Config struct {
 	
 	// Storage
 	StorageEnabled bool
+	StorageType    string // postgres, sqlite, memory
 	DatabaseURL    string
+	SQLitePath     string
+
+	// DatabaseConfigured is true when STORAGE_TYPE or DATABASE_URL was set
+	// explicitly rather than left at the local development default
+	DatabaseConfigured bool
 	
 	// Metrics Analysis
 	MetricsLookbackDays int           // 3, 7, 14, 30
 	MetricsDuration     time.Duration // Computed from LookbackDays
 	SafetyBuffer        float64       // e.g., 1.5 = 50% buffer on P95
+
+	// SafetyBufferConfigured is true when SAFETY_BUFFER was set, in which
+	// case it replaces the workload and environment buffers
+	SafetyBufferConfigured bool
+
+	// Limits
+	CPULimitPolicy    string  // none, ratio
+	CPULimitRatio     float64 // CPU limit = request * ratio
+	MemoryLimitPolicy string  // request, peak
 	
 	// Output
 	OutputFormat string // text, json, yaml
@@ -33,20 +48,34 @@ func NewConfig() *Config {
 	return &Config{
 		PrometheusURL:       getEnv("PROMETHEUS_URL", "http://localhost:9090"),
 		StorageEnabled:      getEnvBool("STORAGE_ENABLED", true),
+		StorageType:         getEnv("STORAGE_TYPE", "postgres"),
 		DatabaseURL:         getEnv("DATABASE_URL", "host=localhost port=5432 user=costuser password=devpassword dbname=costoptimizer sslmode=disable"),
+		SQLitePath:          getEnv("SQLITE_PATH", "cost-optimizer.db"),
 		MetricsLookbackDays: lookbackDays,
 		MetricsDuration:     time.Duration(lookbackDays) * 24 * time.Hour,
 		SafetyBuffer:        getEnvFloat("SAFETY_BUFFER", 1.5), // 50% buffer
+		CPULimitPolicy:      getEnv("CPU_LIMIT_POLICY", "none"),
+		CPULimitRatio:       getEnvFloat("CPU_LIMIT_RATIO", 2.0),
+		MemoryLimitPolicy:   getEnv("MEMORY_LIMIT_POLICY", "request"),
 		OutputFormat:        "text",
 		Verbose:             false,
+		DatabaseConfigured:  os.Getenv("STORAGE_TYPE") != "" || os.Getenv("DATABASE_URL") != "",
+
+		SafetyBufferConfigured: os.Getenv("SAFETY_BUFFER") != "",
 	}
 }
 
 // Validate checks if configuration is valid
 func (c *Config) Validate() error {
-	if c.StorageEnabled && c.DatabaseURL == "" {
+	if c.StorageEnabled && c.StorageType == "sqlite" && c.SQLitePath == "" {
+		return fmt.Errorf("SQLITE_PATH must be set when using SQLite storage")
+	}
+	if c.StorageEnabled && c.StorageType == "postgres" && c.DatabaseURL == "" {
 		return fmt.Errorf("DATABASE_URL must be set when storage is enabled")
 	}
+	if c.StorageType != "postgres" && c.StorageType != "sqlite" && c.StorageType != "memory" {
+		return fmt.Errorf("STORAGE_TYPE must be postgres, sqlite or memory")
+	}
 	if c.MetricsLookbackDays < 1 {
 		return fmt.Errorf("metrics lookback must be at least 1 day")
 	}
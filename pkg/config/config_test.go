@@ -26,6 +26,10 @@ func TestNewConfigDefaults(t *testing.T) {
 	if cfg.SafetyBuffer != 1.5 {
 		t.Errorf("Expected safety buffer 1.5, got %.1f", cfg.SafetyBuffer)
 	}
+
+	if cfg.SafetyBufferConfigured {
+		t.Error("Expected safety buffer to be unconfigured without SAFETY_BUFFER")
+	}
 	
 	if cfg.PrometheusURL != "http://localhost:9090" {
 		t.Errorf("Expected default Prometheus URL, got %s", cfg.PrometheusURL)
@@ -50,6 +54,10 @@ func TestConfigFromEnvironment(t *testing.T) {
 	if cfg.SafetyBuffer != 2.0 {
 		t.Errorf("Expected safety buffer 2.0 from env, got %.1f", cfg.SafetyBuffer)
 	}
+
+	if !cfg.SafetyBufferConfigured {
+		t.Error("Expected safety buffer to be marked as configured")
+	}
 	
 	if cfg.PrometheusURL != "http://prometheus:9090" {
 		t.Errorf("Expected custom Prometheus URL, got %s", cfg.PrometheusURL)
@@ -171,6 +179,39 @@ func TestValidation(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "sqlite storage without path",
+			setupConfig: func(c *Config) {
+				c.StorageType = "sqlite"
+				c.SQLitePath = ""
+			},
+			expectError:   true,
+			errorContains: "SQLITE_PATH",
+		},
+		{
+			name: "sqlite storage without database URL",
+			setupConfig: func(c *Config) {
+				c.StorageType = "sqlite"
+				c.DatabaseURL = ""
+			},
+			expectError: false,
+		},
+		{
+			name: "memory storage without database URL",
+			setupConfig: func(c *Config) {
+				c.StorageType = "memory"
+				c.DatabaseURL = ""
+			},
+			expectError: false,
+		},
+		{
+			name: "unknown storage type",
+			setupConfig: func(c *Config) {
+				c.StorageType = "mysql"
+			},
+			expectError:   true,
+			errorContains: "STORAGE_TYPE",
+		},
 	}
 	
 	for _, tt := range tests {
@@ -226,6 +267,21 @@ func TestStorageConfiguration(t *testing.T) {
 	if cfg.DatabaseURL != "postgres://test" {
 		t.Errorf("Expected custom database URL, got %s", cfg.DatabaseURL)
 	}
+
+	if !cfg.DatabaseConfigured {
+		t.Error("Expected database to be marked as configured")
+	}
+}
+
+func TestDatabaseNotConfigured(t *testing.T) {
+	os.Unsetenv("STORAGE_TYPE")
+	os.Unsetenv("DATABASE_URL")
+
+	cfg := NewConfig()
+
+	if cfg.DatabaseConfigured {
+		t.Error("Expected database to be unconfigured without STORAGE_TYPE or DATABASE_URL")
+	}
 }
 
 func TestStorageValidation(t *testing.T) {
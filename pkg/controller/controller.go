@@ -0,0 +1,335 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/converter"
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Options configures a Controller
+type Options struct {
+	// Interval between periodic rescans
+	Interval time.Duration
+
+	// Debounce is how long workloads must stay unchanged before a
+	// change-triggered rescan, so a rollout causes one scan, not dozens
+	Debounce time.Duration
+
+	// ClusterID is recorded on saved recommendations
+	ClusterID string
+
+	// Recorders are told about every scan, in order
+	Recorders []Recorder
+}
+
+// Recorder observes scan results; exporter.Exporter and crd.Writer
+// implement it
+type Recorder interface {
+	RecordScan(recommendations []*models.Recommendation, duration time.Duration, err error)
+}
+
+// ScanFunc runs one scan of the cluster
+type ScanFunc func(ctx context.Context) ([]*recommender.Recommendation, error)
+
+// Controller rescans the cluster on a schedule and whenever workloads
+// change, persisting every result through a storage.Store
+type Controller struct {
+	factory informers.SharedInformerFactory
+	lister  *kube.InformerLister
+	scan    ScanFunc
+	store   storage.Store
+	opts    Options
+
+	// changes is signalled (without blocking) by the informer handlers
+	changes chan struct{}
+
+	// requests is signalled by Trigger
+	requests chan struct{}
+
+	// running is true while Run is, i.e. while this replica leads
+	running atomic.Bool
+
+	// saved holds the last recommendation saved for each container and
+	// type, so rescans only store what changed. It is seeded from the store
+	// once per workload (seeded), so a restart or a new leader does not
+	// save everything again.
+	saved  map[savedKey]*models.Recommendation
+	seeded map[workloadKey]bool
+}
+
+// savedKey identifies the recommendations of one type for a container
+type savedKey struct {
+	namespace, kind, workload, container string
+	recType                              models.RecommendationType
+}
+
+// workloadKey identifies a workload whose saved recommendations were read
+// from the store
+type workloadKey struct {
+	namespace, kind, workload string
+}
+
+// seedHistory is how many stored recommendations of a workload are read to
+// seed saved, newest first
+const seedHistory = 50
+
+func keyOf(rec *models.Recommendation) savedKey {
+	return savedKey{rec.Workload.Namespace, rec.Workload.Kind, rec.Workload.Deployment, rec.Workload.Container, rec.Type}
+}
+
+// New creates a controller watching the informers of factory. Scans should
+// read the cluster through Lister so they are served from the same caches.
+func New(factory informers.SharedInformerFactory, scan ScanFunc, store storage.Store, opts Options) *Controller {
+	c := &Controller{
+		factory:  factory,
+		lister:   kube.NewInformerLister(factory),
+		scan:     scan,
+		store:    store,
+		opts:     opts,
+		changes:  make(chan struct{}, 1),
+		requests: make(chan struct{}, 1),
+		saved:    make(map[savedKey]*models.Recommendation),
+		seeded:   make(map[workloadKey]bool),
+	}
+
+	apps := factory.Apps().V1()
+	workloads := []cache.SharedIndexInformer{
+		apps.Deployments().Informer(),
+		apps.StatefulSets().Informer(),
+		apps.DaemonSets().Informer(),
+		factory.Autoscaling().V2().HorizontalPodAutoscalers().Informer(),
+	}
+	for _, informer := range workloads {
+		informer.AddEventHandler(c.handler(true))
+	}
+
+	// Pod status changes constantly and requests are immutable, so only
+	// pods coming and going matter
+	factory.Core().V1().Pods().Informer().AddEventHandler(c.handler(false))
+
+	return c
+}
+
+// Lister returns the informer-backed lister scans should use
+func (c *Controller) Lister() kube.Lister {
+	return c.lister
+}
+
+// handler signals a change for objects added or deleted after the initial
+// list and, with specUpdates, for updates that bump metadata.generation
+func (c *Controller) handler(specUpdates bool) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				c.notify()
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if !specUpdates {
+				return
+			}
+			oldMeta, err := meta.Accessor(oldObj)
+			if err != nil {
+				return
+			}
+			newMeta, err := meta.Accessor(newObj)
+			if err != nil {
+				return
+			}
+			// Resyncs and status updates keep the generation
+			if oldMeta.GetGeneration() != newMeta.GetGeneration() {
+				c.notify()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.notify()
+		},
+	}
+}
+
+func (c *Controller) notify() {
+	select {
+	case c.changes <- struct{}{}:
+	default:
+	}
+}
+
+// Trigger requests a scan without waiting for the debounce period. Requests
+// made while a scan is pending are merged into it. It returns false when
+// Run is not running, e.g. on a replica that is not the leader, as no scan
+// would follow.
+func (c *Controller) Trigger() bool {
+	if !c.running.Load() {
+		return false
+	}
+	select {
+	case c.requests <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Run starts the informers, scans once the caches are filled and then
+// keeps rescanning until ctx is done
+func (c *Controller) Run(ctx context.Context) error {
+	if c.opts.Interval <= 0 {
+		return fmt.Errorf("scan interval must be positive")
+	}
+
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	c.factory.Start(ctx.Done())
+	defer c.factory.Shutdown()
+
+	fmt.Println("[INFO] Waiting for informer caches to sync")
+	if !c.lister.WaitForCacheSync(ctx) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to sync informer caches")
+	}
+
+	c.scanAndSave(ctx, "startup")
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	var debounce *time.Timer
+	var debounceC <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return nil
+
+		case <-ticker.C:
+			c.scanAndSave(ctx, "scheduled")
+
+		case <-c.changes:
+			// Restart the quiet period on every change
+			if debounce == nil {
+				debounce = time.NewTimer(c.opts.Debounce)
+				debounceC = debounce.C
+			} else {
+				debounce.Reset(c.opts.Debounce)
+			}
+
+		case <-debounceC:
+			debounce, debounceC = nil, nil
+			c.scanAndSave(ctx, "workload change")
+			// The scan covered everything that changed so far
+			ticker.Reset(c.opts.Interval)
+
+		case <-c.requests:
+			c.scanAndSave(ctx, "requested")
+			ticker.Reset(c.opts.Interval)
+		}
+	}
+}
+
+// scanAndSave runs one scan and saves the recommendations that call for a
+// change and differ from the last one saved for their container. Rescans
+// run on every workload change, so saving every result would grow the
+// store without bound. Failures are logged rather than returned so one
+// bad scan does not stop the controller.
+func (c *Controller) scanAndSave(ctx context.Context, reason string) {
+	fmt.Printf("[INFO] Starting %s scan\n", reason)
+
+	start := time.Now()
+	results, err := c.scan(ctx)
+	duration := time.Since(start)
+	if err != nil {
+		fmt.Printf("[WARN] Scan failed: %v\n", err)
+		c.record(nil, duration, err)
+		return
+	}
+
+	recommendations := make([]*models.Recommendation, 0, len(results))
+	saved := 0
+	totalSavings := 0.0
+	for _, oldRec := range results {
+		rec := converter.OldToNew(oldRec, c.opts.ClusterID)
+		recommendations = append(recommendations, rec)
+		totalSavings += rec.SavingsMonthly
+
+		if rec.Type == models.RecommendationNoAction {
+			continue
+		}
+		c.seedSaved(ctx, rec.Workload)
+		key := keyOf(rec)
+		if last, ok := c.saved[key]; ok && sameChange(last, rec) {
+			continue
+		}
+
+		if err := c.store.SaveRecommendation(ctx, rec); err != nil {
+			fmt.Printf("[WARN] Failed to save recommendation for %s/%s: %v\n",
+				rec.Workload.Namespace, rec.Workload.Deployment, err)
+			continue
+		}
+		c.saved[key] = rec
+		saved++
+	}
+
+	c.record(recommendations, duration, nil)
+
+	fmt.Printf("[INFO] Scan complete: %d recommendation(s), %d new or changed saved, $%.2f/month potential savings\n",
+		len(recommendations), saved, totalSavings)
+}
+
+// seedSaved adds the latest stored recommendation of each container and
+// type of workload to saved, the first time the workload is scanned. A
+// failed read is retried on the next scan.
+func (c *Controller) seedSaved(ctx context.Context, workload *models.Workload) {
+	wk := workloadKey{workload.Namespace, workload.Kind, workload.Deployment}
+	if c.seeded[wk] {
+		return
+	}
+
+	history, err := c.store.GetWorkloadHistory(ctx, workload.Namespace, workload.Deployment, seedHistory)
+	if err != nil {
+		fmt.Printf("[WARN] Failed to read saved recommendations for %s/%s: %v\n",
+			workload.Namespace, workload.Deployment, err)
+		return
+	}
+	c.seeded[wk] = true
+
+	for _, rec := range history {
+		if rec.Workload.ClusterID != c.opts.ClusterID || rec.Workload.Kind != workload.Kind {
+			continue
+		}
+		if _, ok := c.saved[keyOf(rec)]; !ok {
+			c.saved[keyOf(rec)] = rec
+		}
+	}
+}
+
+// sameChange reports whether two recommendations for the same container
+// and type recommend the same values
+func sameChange(a, b *models.Recommendation) bool {
+	return a.CurrentCPU == b.CurrentCPU && a.CurrentMemory == b.CurrentMemory &&
+		a.RecommendedCPU == b.RecommendedCPU && a.RecommendedMemory == b.RecommendedMemory &&
+		a.RecommendedCPULimit == b.RecommendedCPULimit && a.RecommendedMemoryLimit == b.RecommendedMemoryLimit &&
+		a.CurrentReplicas == b.CurrentReplicas && a.RecommendedReplicas == b.RecommendedReplicas &&
+		a.HPAName == b.HPAName && a.RecommendedTargetCPU == b.RecommendedTargetCPU &&
+		a.RecommendedMinReplicas == b.RecommendedMinReplicas
+}
+
+func (c *Controller) record(recommendations []*models.Recommendation, duration time.Duration, err error) {
+	for _, recorder := range c.opts.Recorders {
+		recorder.RecordScan(recommendations, duration, err)
+	}
+}
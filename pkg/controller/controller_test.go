@@ -0,0 +1,404 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// countingScan returns a ScanFunc reporting each call on the returned
+// channel and producing one recommendation for every deployment it lists
+func countingScan(c **Controller) (ScanFunc, <-chan int) {
+	calls := make(chan int, 10)
+	n := 0
+
+	return func(ctx context.Context) ([]*recommender.Recommendation, error) {
+		deployments, err := (*c).Lister().Deployments(ctx, "shop")
+		if err != nil {
+			return nil, err
+		}
+
+		var recs []*recommender.Recommendation
+		for _, d := range deployments {
+			recs = append(recs, &recommender.Recommendation{
+				Type:              recommender.RightSize,
+				DeploymentName:    d.Name,
+				ContainerName:     "app",
+				Namespace:         d.Namespace,
+				WorkloadType:      "Deployment",
+				CurrentCPU:        1000,
+				RecommendedCPU:    300,
+				CurrentMemory:     1 << 30,
+				RecommendedMemory: 256 << 20,
+				Savings:           10,
+				Risk:              "LOW",
+			})
+		}
+
+		n++
+		calls <- n
+		return recs, nil
+	}, calls
+}
+
+func waitForScan(t *testing.T, calls <-chan int, want int) {
+	t.Helper()
+
+	select {
+	case got := <-calls:
+		if got != want {
+			t.Fatalf("Expected scan #%d, got #%d", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for scan #%d", want)
+	}
+}
+
+func expectNoScan(t *testing.T, calls <-chan int, reason string) {
+	t.Helper()
+
+	select {
+	case got := <-calls:
+		t.Fatalf("Unexpected scan #%d after %s", got, reason)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestControllerRescansOnChanges(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Generation: 1}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-7d9f8b-abcde", Namespace: "shop"}},
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	mem := storage.NewMemoryStore()
+
+	var c *Controller
+	scan, calls := countingScan(&c)
+	c = New(factory, scan, mem, Options{
+		Interval:  time.Hour,
+		Debounce:  20 * time.Millisecond,
+		ClusterID: "test",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	// Initial scan once the caches are synced; the initial list does not
+	// trigger another one
+	waitForScan(t, calls, 1)
+	expectNoScan(t, calls, "initial list")
+
+	apps := clientset.AppsV1().Deployments("shop")
+
+	if _, err := apps.Create(ctx, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop", Generation: 1},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	waitForScan(t, calls, 2)
+
+	// Status-only updates keep the generation
+	web, err := apps.Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	web.Status.ReadyReplicas = 1
+	if web, err = apps.UpdateStatus(ctx, web, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	expectNoScan(t, calls, "status update")
+
+	pod, err := clientset.CoreV1().Pods("shop").Get(ctx, "web-7d9f8b-abcde", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get pod failed: %v", err)
+	}
+	pod.Status.Phase = corev1.PodRunning
+	if _, err := clientset.CoreV1().Pods("shop").UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("UpdateStatus pod failed: %v", err)
+	}
+	expectNoScan(t, calls, "pod update")
+
+	web.Generation = 2
+	if _, err := apps.Update(ctx, web, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	waitForScan(t, calls, 3)
+
+	if err := apps.Delete(ctx, "api", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	waitForScan(t, calls, 4)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+
+	// web and api, once each: rescans found nothing new
+	saved, err := mem.ListRecommendations(context.Background(), "shop", -1)
+	if err != nil {
+		t.Fatalf("ListRecommendations failed: %v", err)
+	}
+	if len(saved) != 2 {
+		t.Errorf("Expected 2 saved recommendations, got %d", len(saved))
+	}
+	for _, rec := range saved {
+		if rec.Workload.ClusterID != "test" {
+			t.Errorf("Expected cluster ID test, got %q", rec.Workload.ClusterID)
+		}
+	}
+}
+
+func TestControllerSavesChanges(t *testing.T) {
+	rightSize := func(cpu int64) *recommender.Recommendation {
+		return &recommender.Recommendation{
+			Type: recommender.RightSize, Namespace: "shop", WorkloadType: "Deployment", DeploymentName: "web", ContainerName: "app",
+			CurrentCPU: 1000, RecommendedCPU: cpu, CurrentMemory: 1 << 30, RecommendedMemory: 256 << 20, Savings: 10,
+		}
+	}
+	noAction := &recommender.Recommendation{
+		Type: recommender.NoAction, Namespace: "shop", WorkloadType: "Deployment", DeploymentName: "web", ContainerName: "istio-proxy",
+	}
+	scans := [][]*recommender.Recommendation{
+		{rightSize(300), noAction},
+		{rightSize(300), noAction},
+		{rightSize(400), noAction},
+	}
+
+	mem := storage.NewMemoryStore()
+	recorder := make(channelRecorder, len(scans))
+	scan := 0
+	c := New(informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0), func(ctx context.Context) ([]*recommender.Recommendation, error) {
+		scan++
+		return scans[scan-1], nil
+	}, mem, Options{Recorders: []Recorder{recorder}})
+
+	for range scans {
+		c.scanAndSave(context.Background(), "test")
+		if got := <-recorder; got.count != 2 {
+			t.Errorf("Expected every recommendation to be recorded, got %d", got.count)
+		}
+	}
+
+	saved, err := mem.ListRecommendations(context.Background(), "shop", -1)
+	if err != nil {
+		t.Fatalf("ListRecommendations failed: %v", err)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("Expected the first and the changed recommendation to be saved, got %d", len(saved))
+	}
+	for _, rec := range saved {
+		if rec.Type == models.RecommendationNoAction {
+			t.Error("Expected NO_ACTION not to be saved")
+		}
+	}
+}
+
+func TestControllerSeedsSavedFromStore(t *testing.T) {
+	rec := &recommender.Recommendation{
+		Type: recommender.RightSize, Namespace: "shop", WorkloadType: "Deployment", DeploymentName: "web", ContainerName: "app",
+		CurrentCPU: 1000, RecommendedCPU: 300, CurrentMemory: 1 << 30, RecommendedMemory: 256 << 20, Savings: 10,
+	}
+	scan := func(ctx context.Context) ([]*recommender.Recommendation, error) {
+		return []*recommender.Recommendation{rec}, nil
+	}
+
+	// A restarted controller, or a new leader, shares the store
+	mem := storage.NewMemoryStore()
+	for i := 0; i < 2; i++ {
+		c := New(informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0), scan, mem, Options{ClusterID: "test"})
+		c.scanAndSave(context.Background(), "test")
+	}
+
+	saved, err := mem.ListRecommendations(context.Background(), "shop", -1)
+	if err != nil {
+		t.Fatalf("ListRecommendations failed: %v", err)
+	}
+	if len(saved) != 1 {
+		t.Errorf("Expected the unchanged recommendation to be saved once, got %d", len(saved))
+	}
+}
+
+func TestControllerScheduledScans(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+
+	var c *Controller
+	scan, calls := countingScan(&c)
+	c = New(factory, scan, storage.NewMemoryStore(), Options{
+		Interval: 50 * time.Millisecond,
+		Debounce: time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	waitForScan(t, calls, 1)
+	waitForScan(t, calls, 2)
+	waitForScan(t, calls, 3)
+}
+
+func TestControllerTrigger(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+
+	var c *Controller
+	scan, calls := countingScan(&c)
+	c = New(factory, scan, storage.NewMemoryStore(), Options{
+		Interval: time.Hour,
+		Debounce: time.Hour,
+	})
+
+	// Without Run, e.g. on a replica that is not the leader, no scan follows
+	if c.Trigger() {
+		t.Error("Expected Trigger to fail before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	waitForScan(t, calls, 1)
+
+	if !c.Trigger() {
+		t.Error("Expected Trigger to succeed while running")
+	}
+	waitForScan(t, calls, 2)
+	expectNoScan(t, calls, "the triggered scan")
+}
+
+type recordedScan struct {
+	count int
+	err   error
+}
+
+type channelRecorder chan recordedScan
+
+func (r channelRecorder) RecordScan(recommendations []*models.Recommendation, duration time.Duration, err error) {
+	r <- recordedScan{count: len(recommendations), err: err}
+}
+
+func TestControllerRecordsScans(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}},
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	recorder := make(channelRecorder, 10)
+
+	var c *Controller
+	scan, calls := countingScan(&c)
+	failNext := true
+	c = New(factory, func(ctx context.Context) ([]*recommender.Recommendation, error) {
+		if failNext {
+			failNext = false
+			return nil, errors.New("metrics unavailable")
+		}
+		return scan(ctx)
+	}, storage.NewMemoryStore(), Options{
+		Interval:  time.Hour,
+		Debounce:  time.Hour,
+		Recorders: []Recorder{recorder},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	if got := <-recorder; got.err == nil {
+		t.Errorf("Expected the failed scan to be recorded, got %+v", got)
+	}
+
+	c.Trigger()
+	waitForScan(t, calls, 1)
+	if got := <-recorder; got.err != nil || got.count != 1 {
+		t.Errorf("Expected one recorded recommendation, got %+v", got)
+	}
+}
+
+func TestControllerRequiresInterval(t *testing.T) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	c := New(factory, func(context.Context) ([]*recommender.Recommendation, error) {
+		return nil, nil
+	}, storage.NewMemoryStore(), Options{})
+
+	if err := c.Run(context.Background()); err == nil {
+		t.Error("Expected error for zero scan interval")
+	}
+}
+
+func testLeaderElection(identity string) LeaderElection {
+	le := DefaultLeaderElection("cost-optimizer", identity)
+	le.LeaseDuration = time.Second
+	le.RenewDeadline = 500 * time.Millisecond
+	le.RetryPeriod = 100 * time.Millisecond
+	return le
+}
+
+func TestRunWithLeaderElection(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	leading := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- RunWithLeaderElection(ctx, clientset, testLeaderElection("replica-a"), func(ctx context.Context) error {
+			close(leading)
+			<-ctx.Done()
+			return nil
+		})
+	}()
+
+	select {
+	case <-leading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting to acquire the lease")
+	}
+
+	lease, err := clientset.CoordinationV1().Leases("cost-optimizer").Get(ctx, "cost-optimizer-controller", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get lease failed: %v", err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != "replica-a" {
+		t.Errorf("Expected replica-a to hold the lease, got %v", lease.Spec.HolderIdentity)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunWithLeaderElection did not return after cancel")
+	}
+}
+
+func TestRunWithLeaderElectionReturnsRunError(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	failure := errors.New("cache sync failed")
+
+	err := RunWithLeaderElection(context.Background(), clientset, testLeaderElection("replica-a"), func(context.Context) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected run error, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderElection configures RunWithLeaderElection
+type LeaderElection struct {
+	// Namespace and Name of the Lease used as the lock
+	Namespace string
+	Name      string
+
+	// Identity of this replica, usually the pod name
+	Identity string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// DefaultLeaderElection returns the client-go recommended timings
+func DefaultLeaderElection(namespace, identity string) LeaderElection {
+	return LeaderElection{
+		Namespace:     namespace,
+		Name:          "cost-optimizer-controller",
+		Identity:      identity,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+}
+
+// RunWithLeaderElection calls run only while this replica holds the Lease,
+// so that several replicas never scan at the same time. It returns when ctx
+// is done, when run returns, or with an error when the lease is lost.
+func RunWithLeaderElection(ctx context.Context, clientset kubernetes.Interface, le LeaderElection, run func(ctx context.Context) error) error {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: le.Namespace,
+			Name:      le.Name,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: le.Identity,
+		},
+	}
+
+	electionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var runErr error
+	started := make(chan struct{})
+	finished := make(chan struct{})
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   le.LeaseDuration,
+		RenewDeadline:   le.RenewDeadline,
+		RetryPeriod:     le.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            le.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				close(started)
+				defer close(finished)
+				fmt.Printf("[INFO] Acquired leader lease %s/%s as %s\n", le.Namespace, le.Name, le.Identity)
+
+				runErr = run(ctx)
+				// Stop renewing (and release the lease) once run gives up
+				cancel()
+			},
+			OnStoppedLeading: func() {
+				fmt.Printf("[INFO] Released leader lease %s/%s\n", le.Namespace, le.Name)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != le.Identity {
+					fmt.Printf("[INFO] Waiting for leader lease, currently held by %s\n", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up leader election: %w", err)
+	}
+
+	elector.Run(electionCtx)
+
+	select {
+	case <-started:
+		<-finished
+	default:
+		// Never led: ctx was done before the lease was acquired
+		return nil
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+	// run only stops cleanly when its context ends, which without ctx
+	// being done means the lease could not be renewed
+	if ctx.Err() == nil {
+		return fmt.Errorf("lost leader lease %s/%s", le.Namespace, le.Name)
+	}
+	return nil
+}
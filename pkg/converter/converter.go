@@ -1,9 +1,8 @@
 package converter
 
 import (
-	"fmt"
-
 	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/executor"
 	"github.com/opscart/k8s-cost-optimizer/pkg/models"
 	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
 )
@@ -17,6 +16,10 @@ func OldToNew(old *recommender.Recommendation, clusterID string) *models.Recomme
 		recType = models.RecommendationRightSize
 	case recommender.ScaleDown:
 		recType = models.RecommendationScaleDown
+	case recommender.ReplicaRightSize:
+		recType = models.RecommendationReplicaRightSize
+	case recommender.HPAAdjust:
+		recType = models.RecommendationHPAAdjust
 	case recommender.NoAction:
 		recType = models.RecommendationNoAction
 	default:
@@ -41,7 +44,10 @@ func OldToNew(old *recommender.Recommendation, clusterID string) *models.Recomme
 		ClusterID:  clusterID,
 		Namespace:  old.Namespace,
 		Deployment: old.DeploymentName,
+		Kind:       old.WorkloadType,
 		Pod:        old.DeploymentName,
+		Container:  old.ContainerName,
+		Owner:      old.Owner,
 	}
 
 	return &models.Recommendation{
@@ -56,50 +62,30 @@ func OldToNew(old *recommender.Recommendation, clusterID string) *models.Recomme
 		SavingsMonthly:    old.Savings,
 		Impact:            old.Impact,
 		Risk:              risk,
-		Command:           generateCommand(old),
+		Command:           executor.GenerateCommand(old),
 		// Week 9 Day 2: Add confidence fields
 		Confidence:        old.Confidence,
 		DataQuality:       old.DataQuality,
 		PatternInfo:       old.PatternInfo,
 		HasSufficientData: old.HasSufficientData,
-	}
-}
-
-// generateCommand creates kubectl command from recommendation
-func generateCommand(rec *recommender.Recommendation) string {
-	if rec.Type == recommender.NoAction {
-		return ""
-	}
-
-	cpuStr := fmt.Sprintf("%dm", rec.RecommendedCPU)
-	memStr := fmt.Sprintf("%dMi", rec.RecommendedMemory/(1024*1024))
-
-	// Get workload resource type
-	resourceType := getResourceType(rec.WorkloadType)
-
-	return fmt.Sprintf(
-		"kubectl set resources %s %s -n %s --requests=cpu=%s,memory=%s",
-		resourceType, // Changed from hardcoded "deployment"
-		rec.DeploymentName,
-		rec.Namespace,
-		cpuStr,
-		memStr,
-	)
-}
-
-// getResourceType converts workload type to kubectl resource type
-func getResourceType(workloadType string) string {
-	switch workloadType {
-	case "StatefulSet":
-		return "statefulset"
-	case "DaemonSet":
-		return "daemonset"
-	case "ReplicaSet":
-		return "replicaset"
-	case "Deployment":
-		return "deployment"
-	default:
-		return "deployment" // default fallback
+		// Limits
+		CurrentCPULimit:        old.CurrentCPULimit,
+		CurrentMemoryLimit:     old.CurrentMemoryLimit,
+		RecommendedCPULimit:    old.RecommendedCPULimit,
+		RecommendedMemoryLimit: old.RecommendedMemoryLimit,
+		// Replicas
+		CurrentReplicas:     old.CurrentReplicas,
+		RecommendedReplicas: old.RecommendedReplicas,
+		// HPA
+		HPAName:                old.HPAName,
+		CurrentTargetCPU:       old.CurrentTargetCPU,
+		RecommendedTargetCPU:   old.RecommendedTargetCPU,
+		CurrentMinReplicas:     old.CurrentMinReplicas,
+		RecommendedMinReplicas: old.RecommendedMinReplicas,
+		// VPA
+		VPAName:   old.VPAName,
+		VPACPU:    old.VPACPU,
+		VPAMemory: old.VPAMemory,
 	}
 }
 
@@ -0,0 +1,104 @@
+package crd
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// API group and version of the cost-optimizer.io custom resources
+const (
+	Group   = "cost-optimizer.io"
+	Version = "v1alpha1"
+)
+
+// CostRecommendationResource identifies CostRecommendations for the dynamic
+// client
+var CostRecommendationResource = schema.GroupVersionResource{
+	Group:    Group,
+	Version:  Version,
+	Resource: "costrecommendations",
+}
+
+// Phase is the lifecycle state of a CostRecommendation
+type Phase string
+
+const (
+	// PhasePending recommendations have not been acted on yet
+	PhasePending Phase = "Pending"
+	// PhaseApplied recommendations were applied by cost-scan apply
+	PhaseApplied Phase = "Applied"
+	// PhaseDismissed recommendations were rejected by the owning team and
+	// are not reopened by later scans
+	PhaseDismissed Phase = "Dismissed"
+)
+
+// CostRecommendation is the latest recommendation for one container of a
+// workload, written to the workload's namespace
+type CostRecommendation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CostRecommendationSpec   `json:"spec"`
+	Status CostRecommendationStatus `json:"status,omitempty"`
+}
+
+// CostRecommendationSpec is written by the scanner
+type CostRecommendationSpec struct {
+	ClusterID   string         `json:"clusterID,omitempty"`
+	Workload    WorkloadRef    `json:"workload"`
+	Type        string         `json:"type"`
+	Current     ResourceValues `json:"current"`
+	Recommended ResourceValues `json:"recommended"`
+
+	// SavingsMonthly is the estimated saving in USD per month
+	SavingsMonthly float64 `json:"savingsMonthly"`
+
+	Risk        string  `json:"risk,omitempty"`
+	Confidence  string  `json:"confidence,omitempty"`
+	DataQuality float64 `json:"dataQuality,omitempty"`
+	PatternInfo string  `json:"patternInfo,omitempty"`
+	Reason      string  `json:"reason,omitempty"`
+
+	// Owner is taken from the cost-optimizer.io/owner annotation
+	Owner string `json:"owner,omitempty"`
+
+	// HPAName is the autoscaler an HPA_ADJUST recommendation changes
+	HPAName string `json:"hpaName,omitempty"`
+}
+
+// WorkloadRef names the container a recommendation is for
+type WorkloadRef struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Container string `json:"container,omitempty"`
+}
+
+// ResourceValues are requests and limits as Kubernetes quantities. Empty
+// limits mean no limit.
+type ResourceValues struct {
+	CPU         string `json:"cpu,omitempty"`
+	Memory      string `json:"memory,omitempty"`
+	CPULimit    string `json:"cpuLimit,omitempty"`
+	MemoryLimit string `json:"memoryLimit,omitempty"`
+
+	// Replicas is set for REPLICA_RIGHT_SIZE recommendations, and is the
+	// projected count for HPA_ADJUST ones
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// HPA settings of HPA_ADJUST recommendations
+	MinReplicas          int32 `json:"minReplicas,omitempty"`
+	TargetCPUUtilization int32 `json:"targetCPUUtilization,omitempty"`
+}
+
+// CostRecommendationStatus tracks what happened to the recommendation
+type CostRecommendationStatus struct {
+	Phase Phase `json:"phase,omitempty"`
+
+	// RecommendationID is the ID of the latest saved recommendation, if the
+	// scan was saved to a database
+	RecommendationID string `json:"recommendationID,omitempty"`
+
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+	AppliedAt   *metav1.Time `json:"appliedAt,omitempty"`
+	AppliedBy   string       `json:"appliedBy,omitempty"`
+}
@@ -0,0 +1,292 @@
+package crd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+)
+
+// Labels set on every CostRecommendation
+const (
+	LabelManagedBy    = "app.kubernetes.io/managed-by"
+	LabelWorkloadKind = "cost-optimizer.io/workload-kind"
+	LabelWorkloadName = "cost-optimizer.io/workload-name"
+
+	managerName = "k8s-cost-optimizer"
+)
+
+// Writer creates and updates CostRecommendations through the dynamic
+// client, so the CRD is optional: nothing else needs its types registered
+type Writer struct {
+	client dynamic.Interface
+	now    func() time.Time
+}
+
+// NewWriter creates a Writer
+func NewWriter(client dynamic.Interface) *Writer {
+	return &Writer{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Name returns the CostRecommendation name for a workload container, e.g.
+// deployment-web-app. Names that would be invalid are shortened and made
+// unique with a hash.
+func Name(workload *models.Workload) string {
+	parts := []string{workload.Kind, workload.Deployment}
+	if workload.Container != "" {
+		parts = append(parts, workload.Container)
+	}
+	raw := strings.ToLower(strings.Join(parts, "-"))
+
+	// Workload and container names are already DNS labels; only kinds
+	// could add something else
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, raw)
+	name = strings.Trim(name, "-.")
+
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		sum := sha256.Sum256([]byte(raw))
+		suffix := hex.EncodeToString(sum[:])[:10]
+		name = strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength-len(suffix)-1], "-.") + "-" + suffix
+	}
+
+	return name
+}
+
+// Write creates or updates the CostRecommendation for rec in the workload's
+// namespace. A Dismissed recommendation stays dismissed, and an Applied one
+// goes back to Pending only if the recommended resources changed.
+func (w *Writer) Write(ctx context.Context, rec *models.Recommendation) error {
+	desired := w.build(rec)
+	resources := w.client.Resource(CostRecommendationResource).Namespace(desired.Namespace)
+
+	existing, err := resources.Get(ctx, desired.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		obj, err := toUnstructured(desired)
+		if err != nil {
+			return err
+		}
+		created, err := resources.Create(ctx, obj, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create CostRecommendation %s/%s: %w", desired.Namespace, desired.Name, err)
+		}
+		// The status subresource ignores status on create
+		return w.updateStatus(ctx, created, desired.Status)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get CostRecommendation %s/%s: %w", desired.Namespace, desired.Name, err)
+	}
+
+	current, err := fromUnstructured(existing)
+	if err != nil {
+		return err
+	}
+
+	status := desired.Status
+	switch current.Status.Phase {
+	case PhaseDismissed:
+		status.Phase = PhaseDismissed
+	case PhaseApplied:
+		if current.Spec.Recommended == desired.Spec.Recommended {
+			status.Phase = PhaseApplied
+			status.AppliedAt = current.Status.AppliedAt
+			status.AppliedBy = current.Status.AppliedBy
+		}
+	}
+
+	current.Labels = mergeLabels(current.Labels, desired.Labels)
+	current.Spec = desired.Spec
+	obj, err := toUnstructured(current)
+	if err != nil {
+		return err
+	}
+	updated, err := resources.Update(ctx, obj, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update CostRecommendation %s/%s: %w", desired.Namespace, desired.Name, err)
+	}
+
+	return w.updateStatus(ctx, updated, status)
+}
+
+// MarkApplied records that rec was applied. It does nothing if there is no
+// CostRecommendation for the workload (or the CRD is not installed).
+func (w *Writer) MarkApplied(ctx context.Context, rec *models.Recommendation) error {
+	resources := w.client.Resource(CostRecommendationResource).Namespace(rec.Workload.Namespace)
+
+	existing, err := resources.Get(ctx, Name(rec.Workload), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get CostRecommendation for %s/%s: %w", rec.Workload.Namespace, rec.Workload.Deployment, err)
+	}
+
+	current, err := fromUnstructured(existing)
+	if err != nil {
+		return err
+	}
+
+	status := current.Status
+	now := metav1.NewTime(w.now())
+	if rec.AppliedAt != nil {
+		now = metav1.NewTime(*rec.AppliedAt)
+	}
+	status.Phase = PhaseApplied
+	status.AppliedAt = &now
+	status.AppliedBy = rec.AppliedBy
+
+	return w.updateStatus(ctx, existing, status)
+}
+
+// RecordScan writes a CostRecommendation for every recommendation of a
+// successful scan. It implements controller.Recorder.
+func (w *Writer) RecordScan(recommendations []*models.Recommendation, duration time.Duration, err error) {
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	for _, rec := range recommendations {
+		if err := w.Write(ctx, rec); err != nil {
+			fmt.Printf("[WARN] %v\n", err)
+		}
+	}
+}
+
+func (w *Writer) updateStatus(ctx context.Context, obj *unstructured.Unstructured, status CostRecommendationStatus) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return fmt.Errorf("failed to convert CostRecommendation status: %w", err)
+	}
+	if err := unstructured.SetNestedMap(obj.Object, content, "status"); err != nil {
+		return err
+	}
+
+	_, err = w.client.Resource(CostRecommendationResource).Namespace(obj.GetNamespace()).
+		UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update status of CostRecommendation %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// build converts a recommendation into the desired CostRecommendation
+func (w *Writer) build(rec *models.Recommendation) *CostRecommendation {
+	workload := rec.Workload
+	now := metav1.NewTime(w.now())
+
+	labels := map[string]string{LabelManagedBy: managerName}
+	if len(validation.IsValidLabelValue(workload.Kind)) == 0 {
+		labels[LabelWorkloadKind] = workload.Kind
+	}
+	if len(validation.IsValidLabelValue(workload.Deployment)) == 0 {
+		labels[LabelWorkloadName] = workload.Deployment
+	}
+
+	cr := &CostRecommendation{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: Group + "/" + Version,
+			Kind:       "CostRecommendation",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      Name(workload),
+			Namespace: workload.Namespace,
+			Labels:    labels,
+		},
+		Spec: CostRecommendationSpec{
+			ClusterID: workload.ClusterID,
+			Workload: WorkloadRef{
+				Kind:      workload.Kind,
+				Name:      workload.Deployment,
+				Container: workload.Container,
+			},
+			Type:           string(rec.Type),
+			Current:        resourceValues(rec.CurrentCPU, rec.CurrentMemory, rec.CurrentCPULimit, rec.CurrentMemoryLimit, rec.CurrentReplicas),
+			Recommended:    resourceValues(rec.RecommendedCPU, rec.RecommendedMemory, rec.RecommendedCPULimit, rec.RecommendedMemoryLimit, rec.RecommendedReplicas),
+			SavingsMonthly: rec.SavingsMonthly,
+			Risk:           string(rec.Risk),
+			Confidence:     rec.Confidence,
+			DataQuality:    rec.DataQuality,
+			PatternInfo:    rec.PatternInfo,
+			Reason:         rec.Reason,
+			Owner:          workload.Owner,
+			HPAName:        rec.HPAName,
+		},
+		Status: CostRecommendationStatus{
+			Phase:            PhasePending,
+			RecommendationID: rec.ID,
+			LastUpdated:      &now,
+		},
+	}
+
+	cr.Spec.Current.MinReplicas = rec.CurrentMinReplicas
+	cr.Spec.Current.TargetCPUUtilization = rec.CurrentTargetCPU
+	cr.Spec.Recommended.MinReplicas = rec.RecommendedMinReplicas
+	cr.Spec.Recommended.TargetCPUUtilization = rec.RecommendedTargetCPU
+	return cr
+}
+
+// resourceValues formats millicores and bytes as quantities; zero values
+// are left empty
+func resourceValues(cpu, memory, cpuLimit, memoryLimit int64, replicas int32) ResourceValues {
+	values := ResourceValues{}
+	if cpu > 0 {
+		values.CPU = resource.NewMilliQuantity(cpu, resource.DecimalSI).String()
+	}
+	if memory > 0 {
+		values.Memory = resource.NewQuantity(memory, resource.BinarySI).String()
+	}
+	if cpuLimit > 0 {
+		values.CPULimit = resource.NewMilliQuantity(cpuLimit, resource.DecimalSI).String()
+	}
+	if memoryLimit > 0 {
+		values.MemoryLimit = resource.NewQuantity(memoryLimit, resource.BinarySI).String()
+	}
+	values.Replicas = replicas
+	return values
+}
+
+func mergeLabels(existing, desired map[string]string) map[string]string {
+	merged := make(map[string]string, len(existing)+len(desired))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range desired {
+		merged[k] = v
+	}
+	return merged
+}
+
+func toUnstructured(cr *CostRecommendation) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert CostRecommendation: %w", err)
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+func fromUnstructured(obj *unstructured.Unstructured) (*CostRecommendation, error) {
+	var cr CostRecommendation
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &cr); err != nil {
+		return nil, fmt.Errorf("failed to read CostRecommendation %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return &cr, nil
+}
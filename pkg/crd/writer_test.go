@@ -0,0 +1,182 @@
+package crd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newFakeClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{CostRecommendationResource: "CostRecommendationList"},
+		objects...)
+}
+
+func testRecommendation(recommendedCPU int64) *models.Recommendation {
+	return &models.Recommendation{
+		ID:   "rec-1",
+		Type: models.RecommendationRightSize,
+		Workload: &models.Workload{
+			ClusterID:  "prod",
+			Namespace:  "shop",
+			Deployment: "web",
+			Kind:       "Deployment",
+			Container:  "app",
+		},
+		CurrentCPU:             1000,
+		CurrentMemory:          1 << 30,
+		CurrentMemoryLimit:     2 << 30,
+		RecommendedCPU:         recommendedCPU,
+		RecommendedMemory:      256 << 20,
+		RecommendedMemoryLimit: 512 << 20,
+		SavingsMonthly:         30,
+		Risk:                   models.RiskLow,
+		Confidence:             "HIGH",
+		DataQuality:            0.95,
+		PatternInfo:            "steady",
+		Reason:                 "P95 CPU is well below the request",
+	}
+}
+
+func get(t *testing.T, client *dynamicfake.FakeDynamicClient, name string) *CostRecommendation {
+	t.Helper()
+
+	obj, err := client.Resource(CostRecommendationResource).Namespace("shop").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get %s failed: %v", name, err)
+	}
+	cr, err := fromUnstructured(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cr
+}
+
+func setPhase(t *testing.T, client *dynamicfake.FakeDynamicClient, name string, phase Phase) {
+	t.Helper()
+
+	resources := client.Resource(CostRecommendationResource).Namespace("shop")
+	obj, err := resources.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get %s failed: %v", name, err)
+	}
+	if err := unstructured.SetNestedField(obj.Object, string(phase), "status", "phase"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resources.UpdateStatus(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+}
+
+func TestName(t *testing.T) {
+	workload := &models.Workload{Kind: "Deployment", Deployment: "web", Container: "app"}
+	if got := Name(workload); got != "deployment-web-app" {
+		t.Errorf("Expected deployment-web-app, got %s", got)
+	}
+
+	long := &models.Workload{Kind: "StatefulSet", Deployment: strings.Repeat("a", 250), Container: "db"}
+	name := Name(long)
+	if len(name) > 253 || !strings.HasPrefix(name, "statefulset-aaa") {
+		t.Errorf("Expected a shortened name, got %s (%d chars)", name, len(name))
+	}
+	other := &models.Workload{Kind: "StatefulSet", Deployment: strings.Repeat("a", 250), Container: "cache"}
+	if Name(other) == name {
+		t.Error("Expected shortened names to stay unique")
+	}
+}
+
+func TestWriteCreatesRecommendation(t *testing.T) {
+	client := newFakeClient()
+	writer := NewWriter(client)
+
+	if err := writer.Write(context.Background(), testRecommendation(250)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	cr := get(t, client, "deployment-web-app")
+	if cr.Spec.Workload != (WorkloadRef{Kind: "Deployment", Name: "web", Container: "app"}) {
+		t.Errorf("Unexpected workload: %+v", cr.Spec.Workload)
+	}
+	if cr.Spec.Current != (ResourceValues{CPU: "1", Memory: "1Gi", MemoryLimit: "2Gi"}) {
+		t.Errorf("Unexpected current resources: %+v", cr.Spec.Current)
+	}
+	if cr.Spec.Recommended != (ResourceValues{CPU: "250m", Memory: "256Mi", MemoryLimit: "512Mi"}) {
+		t.Errorf("Unexpected recommended resources: %+v", cr.Spec.Recommended)
+	}
+	if cr.Spec.SavingsMonthly != 30 || cr.Spec.Risk != "LOW" || cr.Spec.Confidence != "HIGH" || cr.Spec.ClusterID != "prod" {
+		t.Errorf("Unexpected spec: %+v", cr.Spec)
+	}
+	if cr.Status.Phase != PhasePending || cr.Status.RecommendationID != "rec-1" || cr.Status.LastUpdated == nil {
+		t.Errorf("Unexpected status: %+v", cr.Status)
+	}
+	if cr.Labels[LabelManagedBy] != "k8s-cost-optimizer" || cr.Labels[LabelWorkloadName] != "web" {
+		t.Errorf("Unexpected labels: %v", cr.Labels)
+	}
+}
+
+func TestWriteKeepsDecisions(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeClient()
+	writer := NewWriter(client)
+
+	rec := testRecommendation(250)
+	if err := writer.Write(ctx, rec); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	appliedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rec.AppliedAt = &appliedAt
+	rec.AppliedBy = "alice"
+	if err := writer.MarkApplied(ctx, rec); err != nil {
+		t.Fatalf("MarkApplied failed: %v", err)
+	}
+	cr := get(t, client, "deployment-web-app")
+	if cr.Status.Phase != PhaseApplied || cr.Status.AppliedBy != "alice" || !cr.Status.AppliedAt.Time.Equal(appliedAt) {
+		t.Fatalf("Expected applied by alice, got %+v", cr.Status)
+	}
+
+	// The same recommendation again keeps it applied
+	if err := writer.Write(ctx, testRecommendation(250)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if cr := get(t, client, "deployment-web-app"); cr.Status.Phase != PhaseApplied || cr.Status.AppliedBy != "alice" {
+		t.Errorf("Expected recommendation to stay applied, got %+v", cr.Status)
+	}
+
+	// A different one reopens it
+	if err := writer.Write(ctx, testRecommendation(400)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	cr = get(t, client, "deployment-web-app")
+	if cr.Status.Phase != PhasePending || cr.Status.AppliedAt != nil {
+		t.Errorf("Expected a changed recommendation to be pending, got %+v", cr.Status)
+	}
+	if cr.Spec.Recommended.CPU != "400m" {
+		t.Errorf("Expected updated spec, got %+v", cr.Spec.Recommended)
+	}
+
+	// Dismissed recommendations stay dismissed
+	setPhase(t, client, "deployment-web-app", PhaseDismissed)
+	if err := writer.Write(ctx, testRecommendation(300)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if cr := get(t, client, "deployment-web-app"); cr.Status.Phase != PhaseDismissed {
+		t.Errorf("Expected recommendation to stay dismissed, got %+v", cr.Status)
+	}
+}
+
+func TestMarkAppliedWithoutRecommendation(t *testing.T) {
+	writer := NewWriter(newFakeClient())
+
+	if err := writer.MarkApplied(context.Background(), testRecommendation(250)); err != nil {
+		t.Errorf("Expected missing CostRecommendation to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+)
+
+// defaultTimeout bounds the availability check of Prometheus
+const defaultTimeout = 5 * time.Second
+
+// Chain is a DataSource that asks its sources in order of preference, and
+// falls back to the next one when a source fails or has no data
+type Chain struct {
+	sources []DataSource
+}
+
+// NewChain chains sources in order of preference
+func NewChain(sources ...DataSource) *Chain {
+	return &Chain{sources: sources}
+}
+
+// New chains the sources configured in cfg: Prometheus when PrometheusURL
+// is set and reachable within cfg.Timeout, the collected usage when
+// CollectedStore is set, then metricsServer when UseMetricsServer is set.
+// As the last resort, metricsServer is kept even when unreachable, so
+// scans report why it fails. skipped explains why Prometheus was left out.
+func New(ctx context.Context, cfg Config, metricsServer DataSource) (chain *Chain, skipped []error) {
+	chain = NewChain()
+
+	if cfg.PrometheusURL != "" {
+		timeout := cfg.Timeout
+		if timeout == 0 {
+			timeout = defaultTimeout
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		prom, err := NewPrometheusSource(cfg.PrometheusURL)
+		switch {
+		case err != nil:
+			skipped = append(skipped, fmt.Errorf("Prometheus initialization failed: %w", err))
+		case !prom.IsAvailable(checkCtx):
+			skipped = append(skipped, fmt.Errorf("Prometheus not reachable at %s", cfg.PrometheusURL))
+		default:
+			chain.sources = append(chain.sources, prom)
+		}
+	}
+
+	if cfg.CollectedStore != nil {
+		chain.sources = append(chain.sources, NewCollectedSource(cfg.CollectedStore, cfg.ClusterID))
+	}
+
+	if cfg.UseMetricsServer {
+		chain.sources = append(chain.sources, metricsServer)
+	}
+	return chain, skipped
+}
+
+// HistoryOf returns the source whose history can be analyzed: source
+// itself, or the first such source of a Chain. It is nil when there is
+// none, and only current usage is available.
+func HistoryOf(source DataSource) HistorySource {
+	switch s := source.(type) {
+	case HistorySource:
+		return s
+	case *Chain:
+		for _, member := range s.sources {
+			if history := HistoryOf(member); history != nil {
+				return history
+			}
+		}
+	}
+	return nil
+}
+
+// GetMetrics returns the metrics of the first source that has them
+func (c *Chain) GetMetrics(ctx context.Context, workload *models.Workload, duration time.Duration) (*models.Metrics, error) {
+	return first(c, func(source DataSource) (*models.Metrics, error) {
+		return source.GetMetrics(ctx, workload, duration)
+	})
+}
+
+// GetTimeseries returns the series of the first source that has it
+func (c *Chain) GetTimeseries(ctx context.Context, workload *models.Workload, duration time.Duration, metric string) ([]models.Sample, error) {
+	return first(c, func(source DataSource) ([]models.Sample, error) {
+		return source.GetTimeseries(ctx, workload, duration, metric)
+	})
+}
+
+// ListWorkloads returns the workloads discovered by the first source that
+// lists them
+func (c *Chain) ListWorkloads(ctx context.Context, namespace string) ([]*models.Workload, error) {
+	return first(c, func(source DataSource) ([]*models.Workload, error) {
+		return source.ListWorkloads(ctx, namespace)
+	})
+}
+
+// IsAvailable reports whether any source is available
+func (c *Chain) IsAvailable(ctx context.Context) bool {
+	for _, source := range c.sources {
+		if source.IsAvailable(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// Name lists the sources in order of preference
+func (c *Chain) Name() string {
+	if len(c.sources) == 0 {
+		return "none"
+	}
+	names := make([]string, len(c.sources))
+	for i, source := range c.sources {
+		names[i] = source.Name()
+	}
+	return strings.Join(names, " → ")
+}
+
+// first returns the result of get for the first source that succeeds.
+// When none does, the error is ErrNoData if no source had data, or the
+// failures of the sources otherwise.
+func first[T any](c *Chain, get func(DataSource) (T, error)) (T, error) {
+	var zero T
+	if len(c.sources) == 0 {
+		return zero, errors.New("no data source available")
+	}
+
+	var failures []error
+	for _, source := range c.sources {
+		result, err := get(source)
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, ErrNoData) {
+			failures = append(failures, fmt.Errorf("%s: %w", source.Name(), err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return zero, errors.Join(failures...)
+	}
+	return zero, fmt.Errorf("%w from %s", ErrNoData, c.Name())
+}
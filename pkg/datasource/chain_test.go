@@ -0,0 +1,93 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+)
+
+// stubSource returns the same metrics or error for every workload
+type stubSource struct {
+	name    string
+	metrics *models.Metrics
+	err     error
+	calls   int
+}
+
+func (s *stubSource) GetMetrics(ctx context.Context, workload *models.Workload, duration time.Duration) (*models.Metrics, error) {
+	s.calls++
+	return s.metrics, s.err
+}
+
+func (s *stubSource) GetTimeseries(ctx context.Context, workload *models.Workload, duration time.Duration, metric string) ([]models.Sample, error) {
+	s.calls++
+	return nil, s.err
+}
+
+func (s *stubSource) ListWorkloads(ctx context.Context, namespace string) ([]*models.Workload, error) {
+	s.calls++
+	return nil, s.err
+}
+
+func (s *stubSource) IsAvailable(ctx context.Context) bool { return s.err == nil }
+func (s *stubSource) Name() string                         { return s.name }
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	workload := &models.Workload{Namespace: "shop", Pod: "debug"}
+
+	empty := &stubSource{name: "empty", err: ErrNoData}
+	down := &stubSource{name: "down", err: errors.New("connection refused")}
+	current := &stubSource{name: "current", metrics: &models.Metrics{P95CPU: 120}}
+
+	metrics, err := NewChain(empty, down, current).GetMetrics(ctx, workload, 0)
+	if err != nil {
+		t.Fatalf("Expected the last source to answer, got %v", err)
+	}
+	if metrics.P95CPU != 120 || empty.calls != 1 || down.calls != 1 {
+		t.Errorf("Expected each source to be asked in order, got %+v", metrics)
+	}
+
+	_, err = NewChain(empty, down).GetMetrics(ctx, workload, 0)
+	if err == nil || errors.Is(err, ErrNoData) || !strings.Contains(err.Error(), "down: connection refused") {
+		t.Errorf("Expected the failure of down, got %v", err)
+	}
+
+	_, err = NewChain(empty, empty).GetMetrics(ctx, workload, 0)
+	if !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected ErrNoData when no source has data, got %v", err)
+	}
+
+	if _, err := NewChain().GetMetrics(ctx, workload, 0); err == nil {
+		t.Error("Expected an error without sources")
+	}
+
+	if name := NewChain(empty, current).Name(); name != "empty → current" {
+		t.Errorf("Expected the sources in order of preference, got %q", name)
+	}
+	if !NewChain(down, current).IsAvailable(ctx) || NewChain(down).IsAvailable(ctx) {
+		t.Error("Expected a chain to be available when any source is")
+	}
+}
+
+func TestHistoryOf(t *testing.T) {
+	prom, err := NewPrometheusSource("http://prometheus.monitoring:9090")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := &stubSource{name: "current"}
+
+	if HistoryOf(current) != nil {
+		t.Error("Expected a source without history to have none")
+	}
+	if HistoryOf(NewChain(current, prom)) != prom {
+		t.Error("Expected the Prometheus source of the chain")
+	}
+	if HistoryOf(prom) != prom {
+		t.Error("Expected a Prometheus source to be its own history")
+	}
+}
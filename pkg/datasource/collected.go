@@ -0,0 +1,195 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+)
+
+// collectedWorkloadWindow is how recently a workload must have been
+// collected to be listed
+const collectedWorkloadWindow = 24 * time.Hour
+
+// CollectedSource reads the usage that a Collector polled from the metrics
+// API into a store, so clusters without Prometheus still get history
+type CollectedSource struct {
+	store     storage.Store
+	clusterID string
+}
+
+// NewCollectedSource creates a source of the usage collected into store
+// for clusterID
+func NewCollectedSource(store storage.Store, clusterID string) *CollectedSource {
+	return &CollectedSource{store: store, clusterID: clusterID}
+}
+
+// GetMetrics returns the percentiles of the usage of the busiest pod of a
+// workload collected over duration. Current usage, a zero duration, is left
+// to metrics-server.
+func (c *CollectedSource) GetMetrics(ctx context.Context, workload *models.Workload, duration time.Duration) (*models.Metrics, error) {
+	if duration == 0 {
+		return nil, fmt.Errorf("%w: current usage is not collected", ErrNoData)
+	}
+
+	cpuSamples, memorySamples, err := c.series(ctx, workload, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	cpu, err := analyzer.CalculatePercentiles(toMetricSamples(cpuSamples))
+	if err != nil {
+		return nil, err
+	}
+	memory, err := analyzer.CalculatePercentiles(toMetricSamples(memorySamples))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Metrics{
+		P95CPU:      int64(cpu.P95),
+		P99CPU:      int64(cpu.P99),
+		MaxCPU:      int64(cpu.Peak),
+		AvgCPU:      int64(cpu.Average),
+		P95Memory:   int64(memory.P95),
+		P99Memory:   int64(memory.P99),
+		MaxMemory:   int64(memory.Peak),
+		AvgMemory:   int64(memory.Average),
+		SampleCount: len(cpuSamples),
+		CollectedAt: cpuSamples[len(cpuSamples)-1].Timestamp,
+		Duration:    duration,
+	}, nil
+}
+
+// GetTimeseries returns the CPU or memory usage of the busiest pod of a
+// workload at each collection. Throttling and restarts are not collected.
+func (c *CollectedSource) GetTimeseries(ctx context.Context, workload *models.Workload, duration time.Duration, metric string) ([]models.Sample, error) {
+	if metric != MetricCPU && metric != MetricMemory {
+		return nil, fmt.Errorf("%s is not collected", metric)
+	}
+
+	cpuSamples, memorySamples, err := c.series(ctx, workload, duration)
+	if err != nil {
+		return nil, err
+	}
+	if metric == MetricMemory {
+		return memorySamples, nil
+	}
+	return cpuSamples, nil
+}
+
+// ListWorkloads returns the workloads of namespace, or of all namespaces
+// when it is empty, collected in the last day
+func (c *CollectedSource) ListWorkloads(ctx context.Context, namespace string) ([]*models.Workload, error) {
+	samples, err := c.store.ListUsageSamples(ctx, c.clusterID, namespace, time.Now().Add(-collectedWorkloadWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collected usage: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var workloads []*models.Workload
+	for _, sample := range samples {
+		workload := &models.Workload{Namespace: sample.Namespace, Kind: sample.Kind, Deployment: sample.Workload, ClusterID: c.clusterID}
+		if sample.Kind == "Pod" {
+			workload.Pod = sample.Pod
+		}
+
+		key := workload.Namespace + "/" + workload.Kind + "/" + workload.Deployment
+		if !seen[key] {
+			seen[key] = true
+			workloads = append(workloads, workload)
+		}
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		a, b := workloads[i], workloads[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Deployment < b.Deployment
+	})
+	return workloads, nil
+}
+
+// IsAvailable reports whether the store can be reached
+func (c *CollectedSource) IsAvailable(ctx context.Context) bool {
+	return c.store.Ping(ctx) == nil
+}
+
+func (c *CollectedSource) Name() string {
+	return "collected"
+}
+
+// History returns an analyzer of the collected usage
+func (c *CollectedSource) History(verbose bool) analyzer.History {
+	return analyzer.NewCollectedAnalyzer(c.store, c.clusterID, verbose)
+}
+
+// series returns the CPU and memory usage of the busiest pod of workload
+// at each collection over duration, in time order. A pod's usage is the
+// sum of its containers, or that of workload.Container.
+func (c *CollectedSource) series(ctx context.Context, workload *models.Workload, duration time.Duration) (cpu, memory []models.Sample, err error) {
+	samples, err := c.store.ListUsageSamples(ctx, c.clusterID, workload.Namespace, time.Now().Add(-duration))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read collected usage: %w", err)
+	}
+
+	// Times are compared as instants, whatever their location
+	type podTime struct {
+		pod  string
+		time int64
+	}
+	podCPU := make(map[podTime]int64)
+	podMemory := make(map[podTime]int64)
+	for _, sample := range samples {
+		if workload.Pod != "" && sample.Pod != workload.Pod {
+			continue
+		}
+		if workload.Pod == "" && (sample.Workload != workload.Deployment || (workload.Kind != "" && sample.Kind != workload.Kind)) {
+			continue
+		}
+		if workload.Container != "" && sample.Container != workload.Container {
+			continue
+		}
+		key := podTime{pod: sample.Pod, time: sample.Timestamp.UnixNano()}
+		podCPU[key] += sample.CPU
+		podMemory[key] += sample.Memory
+	}
+	if len(podCPU) == 0 {
+		return nil, nil, fmt.Errorf("%w for %s", ErrNoData, describeWorkload(workload))
+	}
+
+	busiestCPU := make(map[int64]int64)
+	busiestMemory := make(map[int64]int64)
+	var times []int64
+	for key, value := range podCPU {
+		if _, ok := busiestCPU[key.time]; !ok {
+			times = append(times, key.time)
+		}
+		busiestCPU[key.time] = max(busiestCPU[key.time], value)
+		busiestMemory[key.time] = max(busiestMemory[key.time], podMemory[key])
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	for _, t := range times {
+		cpu = append(cpu, models.Sample{Timestamp: time.Unix(0, t), Value: float64(busiestCPU[t])})
+		memory = append(memory, models.Sample{Timestamp: time.Unix(0, t), Value: float64(busiestMemory[t])})
+	}
+	return cpu, memory, nil
+}
+
+// toMetricSamples converts samples for the analyzer's percentiles
+func toMetricSamples(samples []models.Sample) []analyzer.MetricSample {
+	converted := make([]analyzer.MetricSample, len(samples))
+	for i, sample := range samples {
+		converted[i] = analyzer.MetricSample{Timestamp: sample.Timestamp, Value: sample.Value}
+	}
+	return converted
+}
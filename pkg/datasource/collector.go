@@ -0,0 +1,106 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+)
+
+// Collection defaults. Five minutes matches the resolution historical
+// analysis uses with Prometheus.
+const (
+	DefaultCollectInterval  = 5 * time.Minute
+	DefaultCollectRetention = 14 * 24 * time.Hour
+)
+
+// CollectorOptions configure a Collector
+type CollectorOptions struct {
+	ClusterID string
+	// Namespace to collect; all namespaces when empty
+	Namespace string
+	// Interval between polls of the metrics API, at least the 15s
+	// resolution of metrics-server
+	Interval time.Duration
+	// Retention is how long samples are kept
+	Retention time.Duration
+}
+
+// Collector polls the metrics API and stores the usage of every container,
+// building the history a CollectedSource analyzes
+type Collector struct {
+	source *MetricsServerSource
+	store  storage.Store
+	opts   CollectorOptions
+}
+
+// NewCollector creates a Collector storing the usage read by source into
+// store
+func NewCollector(source *MetricsServerSource, store storage.Store, opts CollectorOptions) *Collector {
+	if opts.Interval == 0 {
+		opts.Interval = DefaultCollectInterval
+	}
+	if opts.Interval < metricsServerTTL {
+		opts.Interval = metricsServerTTL
+	}
+	if opts.Retention == 0 {
+		opts.Retention = DefaultCollectRetention
+	}
+	return &Collector{source: source, store: store, opts: opts}
+}
+
+// Interval returns the time between polls
+func (c *Collector) Interval() time.Duration {
+	return c.opts.Interval
+}
+
+// Collect polls the metrics API once, stores the samples and deletes those
+// older than the retention. It returns the number of samples stored and
+// deleted.
+func (c *Collector) Collect(ctx context.Context) (stored int, pruned int64, err error) {
+	samples, err := c.source.Samples(ctx, c.opts.Namespace)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, sample := range samples {
+		sample.ClusterID = c.opts.ClusterID
+	}
+
+	if err := c.store.SaveUsageSamples(ctx, samples); err != nil {
+		return 0, 0, fmt.Errorf("failed to save usage samples: %w", err)
+	}
+
+	pruned, err = c.store.PruneUsageSamples(ctx, time.Now().Add(-c.opts.Retention))
+	if err != nil {
+		return len(samples), 0, fmt.Errorf("failed to prune usage samples: %w", err)
+	}
+	return len(samples), pruned, nil
+}
+
+// Run collects on every interval until ctx is done. Failed polls are
+// reported and retried on the next interval.
+func (c *Collector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		stored, pruned, err := c.Collect(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			fmt.Printf("[WARN] Usage collection failed: %v\n", err)
+		case pruned > 0:
+			fmt.Printf("[INFO] Collected %d container sample(s), deleted %d past retention\n", stored, pruned)
+		default:
+			fmt.Printf("[INFO] Collected %d container sample(s)\n", stored)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	metricsServer, _ := fakeMetricsServer(t)
+	store := storage.NewMemoryStore()
+
+	// A sample past retention from an earlier run of the collector
+	old := &models.UsageSample{
+		ClusterID: "test", Namespace: "shop", Kind: "Deployment", Workload: "web", Pod: "web-5c4d3e-zyxwv", Container: "app",
+		Timestamp: time.Now().Add(-15 * 24 * time.Hour), CPU: 900,
+	}
+	if err := store.SaveUsageSamples(ctx, []*models.UsageSample{old}); err != nil {
+		t.Fatal(err)
+	}
+
+	collector := NewCollector(metricsServer, store, CollectorOptions{ClusterID: "test", Interval: time.Second})
+	if collector.Interval() != metricsServerTTL {
+		t.Errorf("Expected polls no faster than metrics-server resolves, got %s", collector.Interval())
+	}
+
+	for i := 0; i < 2; i++ {
+		stored, pruned, err := collector.Collect(ctx)
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		// The standalone pod has no usage yet
+		if stored != 4 {
+			t.Errorf("Expected the 4 containers of web to be sampled, got %d", stored)
+		}
+		if pruned != int64(1-i) {
+			t.Errorf("Expected the sample past retention to be deleted once, deleted %d", pruned)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	samples, err := store.ListUsageSamples(ctx, "test", "shop", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 8 {
+		t.Fatalf("Expected 8 samples from 2 polls, got %d", len(samples))
+	}
+	if s := samples[0]; s.Kind != "Deployment" || s.Workload != "web" || s.Pod != "web-7d9f8b-abcde" || s.CPU != 120 {
+		t.Errorf("Expected usage of web's first pod, got %+v", s)
+	}
+	if !samples[0].Timestamp.Equal(samples[3].Timestamp) || samples[0].Timestamp.Equal(samples[4].Timestamp) {
+		t.Error("Expected the samples of a poll to share its time")
+	}
+
+	source := NewCollectedSource(store, "test")
+
+	metrics, err := source.GetMetrics(ctx, &models.Workload{Namespace: "shop", Kind: "Deployment", Deployment: "web", Container: "app"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GetMetrics failed: %v", err)
+	}
+	if metrics.P95CPU != 180 || metrics.SampleCount != 2 {
+		t.Errorf("Expected the busiest pod at each of 2 polls, got P95 %dm over %d samples", metrics.P95CPU, metrics.SampleCount)
+	}
+	if _, err := source.GetMetrics(ctx, &models.Workload{Namespace: "shop", Pod: "debug"}, time.Hour); !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected ErrNoData for a pod never collected, got %v", err)
+	}
+	if _, err := source.GetMetrics(ctx, &models.Workload{Namespace: "shop", Deployment: "web"}, 0); !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected current usage to be left to metrics-server, got %v", err)
+	}
+
+	workloads, err := source.ListWorkloads(ctx, "")
+	if err != nil || len(workloads) != 1 || workloads[0].Deployment != "web" {
+		t.Errorf("Expected web to be listed, got %v (%v)", workloads, err)
+	}
+
+	history, err := source.History(false).GetWorkloadMetrics(ctx, &models.Workload{Namespace: "shop", Kind: "Deployment", Deployment: "web", Container: "app"}, 7)
+	if err != nil {
+		t.Fatalf("GetWorkloadMetrics failed: %v", err)
+	}
+	if history.Pods != 2 || len(history.CPUSamples) != 4 {
+		t.Errorf("Expected 2 pods sampled twice, got %d pod(s) and %d samples", history.Pods, len(history.CPUSamples))
+	}
+
+	chain, skipped := New(ctx, Config{UseMetricsServer: true, CollectedStore: store, ClusterID: "test"}, metricsServer)
+	if len(skipped) != 0 || chain.Name() != "collected → metrics-server" {
+		t.Errorf("Expected collected usage before metrics-server, got %q (%v)", chain.Name(), skipped)
+	}
+	if _, ok := HistoryOf(chain).(*CollectedSource); !ok {
+		t.Error("Expected the collected usage to be the history of the chain")
+	}
+}
@@ -2,12 +2,20 @@ package datasource
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
 	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
 )
 
-// DataSource defines the interface for collecting metrics
+// ErrNoData is returned (wrapped) when a source has no usage for a
+// workload, e.g. a pod that just started
+var ErrNoData = errors.New("no usage data")
+
+// DataSource defines the interface for collecting metrics. A zero duration
+// asks GetMetrics for the current usage.
 type DataSource interface {
 	GetMetrics(ctx context.Context, workload *models.Workload, duration time.Duration) (*models.Metrics, error)
 	GetTimeseries(ctx context.Context, workload *models.Workload, duration time.Duration, metric string) ([]models.Sample, error)
@@ -16,8 +24,28 @@ type DataSource interface {
 	Name() string
 }
 
+// HistorySource is a DataSource whose history can be analyzed in depth
+// (percentiles, usage patterns, growth)
+type HistorySource interface {
+	DataSource
+	History(verbose bool) analyzer.History
+}
+
+// Metrics returned by GetTimeseries
+const (
+	MetricCPU        = "cpu"        // millicores
+	MetricMemory     = "memory"     // bytes
+	MetricThrottling = "throttling" // fraction of CFS periods throttled
+	MetricRestarts   = "restarts"   // container restarts per sample
+)
+
 type Config struct {
 	PrometheusURL    string
 	UseMetricsServer bool
 	Timeout          time.Duration
+
+	// CollectedStore holds the usage collected by cost-scan collect for
+	// ClusterID, analyzed when Prometheus is not available
+	CollectedStore storage.Store
+	ClusterID      string
 }
@@ -0,0 +1,323 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// metricsServerTTL is how long the usage of a namespace is reused. It is
+// the default resolution of metrics-server, which has no newer usage
+// before then.
+const metricsServerTTL = 15 * time.Second
+
+// MetricsServerSource reads the current usage of pods from the metrics API
+// served by metrics-server. It has no history: metrics are those of the
+// last sample whatever the duration, and timeseries have a single sample.
+type MetricsServerSource struct {
+	metricsClient metricsv.Interface
+	lister        kube.Lister
+	owners        *kube.OwnerResolver
+
+	mu        sync.Mutex
+	snapshots map[string]*namespaceSnapshot
+}
+
+// namespaceSnapshot is the usage of the pods of a namespace at one time
+type namespaceSnapshot struct {
+	fetched time.Time
+	pods    []corev1.Pod
+
+	// usage by namespace/pod and container
+	usage     map[string]map[string]containerUsage
+	timestamp map[string]time.Time
+}
+
+// containerUsage is the usage of a container in millicores and bytes
+type containerUsage struct {
+	cpu    int64
+	memory int64
+}
+
+// NewMetricsServerSource creates a source that reads usage with
+// metricsClient, and pods and their owners with clientset
+func NewMetricsServerSource(clientset kubernetes.Interface, metricsClient metricsv.Interface) *MetricsServerSource {
+	return &MetricsServerSource{
+		metricsClient: metricsClient,
+		lister:        kube.NewClientLister(clientset),
+		owners:        kube.NewOwnerResolver(clientset),
+		snapshots:     make(map[string]*namespaceSnapshot),
+	}
+}
+
+// GetMetrics returns the current usage of a container, or of all
+// containers of a pod. Workloads without a Pod get the usage of their
+// busiest pod, as requests are sized per pod. Percentiles, peak and
+// average are all the current usage.
+func (m *MetricsServerSource) GetMetrics(ctx context.Context, workload *models.Workload, duration time.Duration) (*models.Metrics, error) {
+	usage, err := m.workloadUsage(ctx, workload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Metrics{
+		P95CPU:          usage.cpu,
+		P99CPU:          usage.cpu,
+		MaxCPU:          usage.cpu,
+		AvgCPU:          usage.cpu,
+		P95Memory:       usage.memory,
+		P99Memory:       usage.memory,
+		MaxMemory:       usage.memory,
+		AvgMemory:       usage.memory,
+		RequestedCPU:    usage.requestedCPU,
+		RequestedMemory: usage.requestedMemory,
+		SampleCount:     1,
+		CollectedAt:     usage.timestamp,
+	}, nil
+}
+
+// GetTimeseries returns the current CPU or memory usage as a single
+// sample. Throttling and restarts are not in the metrics API.
+func (m *MetricsServerSource) GetTimeseries(ctx context.Context, workload *models.Workload, duration time.Duration, metric string) ([]models.Sample, error) {
+	if metric != MetricCPU && metric != MetricMemory {
+		return nil, fmt.Errorf("%s is not available from metrics-server", metric)
+	}
+
+	usage, err := m.workloadUsage(ctx, workload)
+	if err != nil {
+		return nil, err
+	}
+
+	value := usage.cpu
+	if metric == MetricMemory {
+		value = usage.memory
+	}
+	return []models.Sample{{Timestamp: usage.timestamp, Value: float64(value)}}, nil
+}
+
+// ListWorkloads returns the workloads of the running pods of namespace,
+// or of all namespaces when it is empty. Pods without a controller are
+// workloads of kind Pod.
+func (m *MetricsServerSource) ListWorkloads(ctx context.Context, namespace string) ([]*models.Workload, error) {
+	pods, err := m.lister.Pods(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	m.owners.Reset()
+
+	seen := make(map[string]bool)
+	var workloads []*models.Workload
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		owner, err := m.owners.Resolve(ctx, pod.Namespace, pod.OwnerReferences)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the workload of pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+
+		workload := &models.Workload{Namespace: pod.Namespace, Kind: owner.Kind, Deployment: owner.Name}
+		if owner.Name == "" {
+			workload.Kind, workload.Deployment, workload.Pod = "Pod", pod.Name, pod.Name
+		}
+
+		key := workload.Namespace + "/" + workload.Kind + "/" + workload.Deployment
+		if !seen[key] {
+			seen[key] = true
+			workloads = append(workloads, workload)
+		}
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		a, b := workloads[i], workloads[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Deployment < b.Deployment
+	})
+	return workloads, nil
+}
+
+// IsAvailable reports whether the metrics API is served
+func (m *MetricsServerSource) IsAvailable(ctx context.Context) bool {
+	_, err := m.metricsClient.MetricsV1beta1().PodMetricses(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+	return err == nil
+}
+
+func (m *MetricsServerSource) Name() string {
+	return "metrics-server"
+}
+
+// Samples lists the current usage of every container of the pods of
+// namespace, or of all namespaces when it is empty, with the workload of
+// each pod. Samples of one call share its time, so replicas are sampled at
+// the same times.
+func (m *MetricsServerSource) Samples(ctx context.Context, namespace string) ([]*models.UsageSample, error) {
+	snapshot, err := m.snapshot(ctx, namespace, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []*models.UsageSample
+	for _, pod := range snapshot.pods {
+		containers, ok := snapshot.usage[pod.Namespace+"/"+pod.Name]
+		if !ok {
+			continue
+		}
+
+		// An owner that cannot be read leaves the owner below it, e.g. a
+		// ReplicaSet, as the workload
+		owner, _ := m.owners.Resolve(ctx, pod.Namespace, pod.OwnerReferences)
+		kind, name := owner.Kind, owner.Name
+		if name == "" {
+			kind, name = "Pod", pod.Name
+		}
+
+		for _, container := range pod.Spec.Containers {
+			usage, ok := containers[container.Name]
+			if !ok {
+				continue
+			}
+			samples = append(samples, &models.UsageSample{
+				Namespace: pod.Namespace,
+				Kind:      kind,
+				Workload:  name,
+				Pod:       pod.Name,
+				Container: container.Name,
+				Timestamp: snapshot.fetched,
+				CPU:       usage.cpu,
+				Memory:    usage.memory,
+			})
+		}
+	}
+	return samples, nil
+}
+
+// podUsage is the usage and requests of the containers of a pod that a
+// workload selects
+type podUsage struct {
+	containerUsage
+	requestedCPU    int64
+	requestedMemory int64
+	timestamp       time.Time
+}
+
+// workloadUsage returns the usage of the busiest pod of workload
+func (m *MetricsServerSource) workloadUsage(ctx context.Context, workload *models.Workload) (*podUsage, error) {
+	snapshot, err := m.snapshot(ctx, workload.Namespace, metricsServerTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	var busiest *podUsage
+	for _, pod := range snapshot.pods {
+		if workload.Pod != "" && pod.Name != workload.Pod {
+			continue
+		}
+		if workload.Pod == "" {
+			owner, err := m.owners.Resolve(ctx, pod.Namespace, pod.OwnerReferences)
+			if err != nil || owner.Name != workload.Deployment || (workload.Kind != "" && owner.Kind != workload.Kind) {
+				continue
+			}
+		}
+
+		containers, ok := snapshot.usage[pod.Namespace+"/"+pod.Name]
+		if !ok {
+			continue
+		}
+
+		usage := &podUsage{timestamp: snapshot.timestamp[pod.Namespace+"/"+pod.Name]}
+		found := false
+		for _, container := range pod.Spec.Containers {
+			if workload.Container != "" && container.Name != workload.Container {
+				continue
+			}
+			if c, ok := containers[container.Name]; ok {
+				usage.cpu += c.cpu
+				usage.memory += c.memory
+				found = true
+			}
+			usage.requestedCPU += container.Resources.Requests.Cpu().MilliValue()
+			usage.requestedMemory += container.Resources.Requests.Memory().Value()
+		}
+
+		if found && (busiest == nil || usage.cpu > busiest.cpu) {
+			busiest = usage
+		}
+	}
+
+	if busiest == nil {
+		return nil, fmt.Errorf("%w for %s", ErrNoData, describeWorkload(workload))
+	}
+	return busiest, nil
+}
+
+// snapshot returns the pods of namespace with their usage, listing them
+// again once maxAge has passed
+func (m *MetricsServerSource) snapshot(ctx context.Context, namespace string, maxAge time.Duration) (*namespaceSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if snapshot, ok := m.snapshots[namespace]; ok && time.Since(snapshot.fetched) < maxAge {
+		return snapshot, nil
+	}
+
+	podMetrics, err := m.metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod metrics: %w", err)
+	}
+	pods, err := m.lister.Pods(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	// Owners may have changed since the last snapshot too
+	m.owners.Reset()
+
+	snapshot := &namespaceSnapshot{
+		fetched:   time.Now(),
+		pods:      pods,
+		usage:     make(map[string]map[string]containerUsage, len(podMetrics.Items)),
+		timestamp: make(map[string]time.Time, len(podMetrics.Items)),
+	}
+	for _, pm := range podMetrics.Items {
+		containers := make(map[string]containerUsage, len(pm.Containers))
+		for _, container := range pm.Containers {
+			containers[container.Name] = containerUsage{
+				cpu:    container.Usage.Cpu().MilliValue(),
+				memory: container.Usage.Memory().Value(),
+			}
+		}
+		snapshot.usage[pm.Namespace+"/"+pm.Name] = containers
+		snapshot.timestamp[pm.Namespace+"/"+pm.Name] = pm.Timestamp.Time
+	}
+
+	m.snapshots[namespace] = snapshot
+	return snapshot, nil
+}
+
+// describeWorkload names a workload in errors
+func describeWorkload(workload *models.Workload) string {
+	name := workload.Namespace + "/"
+	if workload.Pod != "" {
+		name += "pod " + workload.Pod
+	} else {
+		name += workload.Kind + " " + workload.Deployment
+	}
+	if workload.Container != "" {
+		name += " container " + workload.Container
+	}
+	return name
+}
@@ -0,0 +1,180 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// fakeMetricsServer serves two pods of Deployment web, with an app and an
+// istio-proxy container, and a standalone pod that has no usage yet. The
+// number of metrics API lists is counted.
+func fakeMetricsServer(t *testing.T) (*MetricsServerSource, *int) {
+	t.Helper()
+
+	controller := true
+	requests := corev1.ResourceRequirements{Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("250m"),
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}}
+	pod := func(name string, owners []metav1.OwnerReference) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: name, OwnerReferences: owners},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", Resources: requests},
+				{Name: "istio-proxy", Resources: requests},
+			}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	web := []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-7d9f8b", Controller: &controller}}
+
+	clientset := fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "shop", Name: "web-7d9f8b",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Controller: &controller}},
+		}},
+		pod("web-7d9f8b-abcde", web),
+		pod("web-7d9f8b-fghij", web),
+		pod("debug", nil),
+	)
+
+	usage := func(name, cpu, memory string) metricsv1beta1.ContainerMetrics {
+		return metricsv1beta1.ContainerMetrics{Name: name, Usage: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}}
+	}
+	podMetrics := []metricsv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web-7d9f8b-abcde"},
+			Timestamp:  metav1.NewTime(time.Now()),
+			Containers: []metricsv1beta1.ContainerMetrics{usage("app", "120m", "200Mi"), usage("istio-proxy", "10m", "60Mi")},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web-7d9f8b-fghij"},
+			Timestamp:  metav1.NewTime(time.Now()),
+			Containers: []metricsv1beta1.ContainerMetrics{usage("app", "180m", "150Mi"), usage("istio-proxy", "5m", "50Mi")},
+		},
+	}
+
+	lists := 0
+	metricsClient := &metricsfake.Clientset{}
+	metricsClient.AddReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lists++
+		return true, &metricsv1beta1.PodMetricsList{Items: podMetrics}, nil
+	})
+
+	return NewMetricsServerSource(clientset, metricsClient), &lists
+}
+
+func TestMetricsServerGetMetrics(t *testing.T) {
+	source, lists := fakeMetricsServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		workload        *models.Workload
+		cpu             int64
+		memory          int64
+		requestedMemory int64
+	}{
+		{
+			name:            "container of a pod",
+			workload:        &models.Workload{Namespace: "shop", Pod: "web-7d9f8b-abcde", Container: "app"},
+			cpu:             120,
+			memory:          200 << 20,
+			requestedMemory: 256 << 20,
+		},
+		{
+			name:            "all containers of a pod",
+			workload:        &models.Workload{Namespace: "shop", Pod: "web-7d9f8b-abcde"},
+			cpu:             130,
+			memory:          260 << 20,
+			requestedMemory: 512 << 20,
+		},
+		{
+			name:            "busiest pod of a deployment",
+			workload:        &models.Workload{Namespace: "shop", Kind: "Deployment", Deployment: "web", Container: "app"},
+			cpu:             180,
+			memory:          150 << 20,
+			requestedMemory: 256 << 20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := source.GetMetrics(ctx, tt.workload, 0)
+			if err != nil {
+				t.Fatalf("GetMetrics failed: %v", err)
+			}
+			if metrics.P95CPU != tt.cpu || metrics.MaxCPU != tt.cpu {
+				t.Errorf("Expected CPU %dm, got P95 %dm and max %dm", tt.cpu, metrics.P95CPU, metrics.MaxCPU)
+			}
+			if metrics.P95Memory != tt.memory {
+				t.Errorf("Expected memory %d, got %d", tt.memory, metrics.P95Memory)
+			}
+			if metrics.RequestedMemory != tt.requestedMemory {
+				t.Errorf("Expected requested memory %d, got %d", tt.requestedMemory, metrics.RequestedMemory)
+			}
+			if metrics.SampleCount != 1 {
+				t.Errorf("Expected a single sample, got %d", metrics.SampleCount)
+			}
+		})
+	}
+
+	if *lists != 1 {
+		t.Errorf("Expected the usage of the namespace to be listed once, got %d lists", *lists)
+	}
+
+	for _, workload := range []*models.Workload{
+		{Namespace: "shop", Pod: "debug"},
+		{Namespace: "shop", Kind: "Deployment", Deployment: "api"},
+		{Namespace: "shop", Pod: "web-7d9f8b-abcde", Container: "sidecar"},
+	} {
+		if _, err := source.GetMetrics(ctx, workload, 0); !errors.Is(err, ErrNoData) {
+			t.Errorf("Expected ErrNoData for %s, got %v", describeWorkload(workload), err)
+		}
+	}
+
+	samples, err := source.GetTimeseries(ctx, &models.Workload{Namespace: "shop", Pod: "web-7d9f8b-fghij", Container: "app"}, time.Hour, MetricMemory)
+	if err != nil {
+		t.Fatalf("GetTimeseries failed: %v", err)
+	}
+	if len(samples) != 1 || samples[0].Value != 150<<20 {
+		t.Errorf("Expected the current memory as a single sample, got %v", samples)
+	}
+	if _, err := source.GetTimeseries(ctx, &models.Workload{Namespace: "shop", Pod: "debug"}, time.Hour, MetricRestarts); err == nil {
+		t.Error("Expected restarts not to be available")
+	}
+}
+
+func TestMetricsServerListWorkloads(t *testing.T) {
+	source, _ := fakeMetricsServer(t)
+
+	workloads, err := source.ListWorkloads(context.Background(), "shop")
+	if err != nil {
+		t.Fatalf("ListWorkloads failed: %v", err)
+	}
+	if len(workloads) != 2 {
+		t.Fatalf("Expected web and the standalone pod, got %d workloads", len(workloads))
+	}
+	if w := workloads[0]; w.Kind != "Deployment" || w.Deployment != "web" || w.Pod != "" {
+		t.Errorf("Expected Deployment web, got %+v", w)
+	}
+	if w := workloads[1]; w.Kind != "Pod" || w.Pod != "debug" {
+		t.Errorf("Expected the standalone pod debug, got %+v", w)
+	}
+}
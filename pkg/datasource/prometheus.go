@@ -3,6 +3,8 @@ package datasource
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
 	"time"
 
 	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
@@ -33,8 +35,13 @@ func NewPrometheusSource(url string) (*PrometheusSource, error) {
 	}, nil
 }
 
-// GetMetrics retrieves comprehensive metrics for a workload
+// GetMetrics retrieves comprehensive metrics for a workload, or its
+// current usage for a zero duration
 func (p *PrometheusSource) GetMetrics(ctx context.Context, workload *models.Workload, duration time.Duration) (*models.Metrics, error) {
+	if duration == 0 {
+		return p.currentMetrics(ctx, workload)
+	}
+
 	now := time.Now()
 
 	// Try historical queries first, fall back to instant if needed
@@ -44,7 +51,7 @@ func (p *PrometheusSource) GetMetrics(ctx context.Context, workload *models.Work
 	// Get requests from kube-state-metrics
 	reqCPU, reqMem, err := p.queryRequests(ctx, workload)
 	if err != nil {
-		fmt.Printf("[WARN] Failed to query resource requests: %v\n", err)
+		fmt.Fprintf(os.Stderr, "[WARN] Failed to query resource requests: %v\n", err)
 	}
 
 	return &models.Metrics{
@@ -69,7 +76,7 @@ func (p *PrometheusSource) getCPUMetrics(ctx context.Context, workload *models.W
 	p95, err := p.queryP95CPU(ctx, workload, duration)
 	if err != nil || p95 == 0 {
 		// Fallback to instant rate
-		fmt.Printf("[WARN] P95 CPU unavailable, using instant rate for %s\n", workload.Pod)
+		fmt.Fprintf(os.Stderr, "[WARN] P95 CPU unavailable, using instant rate for %s\n", workload.Pod)
 		instant, _ := p.queryInstantCPU(ctx, workload)
 		p95 = instant
 	}
@@ -101,7 +108,7 @@ func (p *PrometheusSource) getMemoryMetrics(ctx context.Context, workload *model
 	p95, err := p.queryP95Memory(ctx, workload, duration)
 	if err != nil || p95 == 0 {
 		// Fallback to instant
-		fmt.Printf("[WARN] P95 Memory unavailable, using instant value for %s\n", workload.Pod)
+		fmt.Fprintf(os.Stderr, "[WARN] P95 Memory unavailable, using instant value for %s\n", workload.Pod)
 		instant, _ := p.queryInstantMemory(ctx, workload)
 		p95 = instant
 	}
@@ -280,7 +287,7 @@ func (p *PrometheusSource) querySingleSum(ctx context.Context, query string) (fl
 	}
 
 	if len(warnings) > 0 {
-		fmt.Printf("[WARN] Prometheus: %v\n", warnings)
+		fmt.Fprintf(os.Stderr, "[WARN] Prometheus: %v\n", warnings)
 	}
 
 	vector, ok := result.(model.Vector)
@@ -307,13 +314,277 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dh", hours)
 }
 
-// Stub implementations
+// Timeseries are sampled at most every minute, within Prometheus' limit
+// of 11,000 points per series
+const (
+	minTimeseriesStep   = time.Minute
+	maxTimeseriesPoints = 10000
+)
+
+// GetTimeseries returns one of the Metric* series of a workload over the
+// past duration. CPU, memory and throttling are those of the busiest pod
+// at each time, as requests are sized per pod; restarts are those of all
+// pods. The workload is a single pod when Pod is set, otherwise every pod
+// its Kind and Deployment (name) owned, including pods replaced during
+// the duration. Container narrows the series to one container.
 func (p *PrometheusSource) GetTimeseries(ctx context.Context, workload *models.Workload, duration time.Duration, metric string) ([]models.Sample, error) {
-	return nil, fmt.Errorf("not implemented")
+	step := duration / maxTimeseriesPoints
+	if step < minTimeseriesStep {
+		step = minTimeseriesStep
+	}
+
+	query, err := timeseriesQuery(workload, metric, step)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now()
+	result, warnings, err := p.client.QueryRange(ctx, query, v1.Range{Start: end.Add(-duration), End: end, Step: step})
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintf(os.Stderr, "[WARN] Prometheus: %v\n", warnings)
+	}
+
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", result)
+	}
+
+	var samples []models.Sample
+	for _, series := range matrix {
+		for _, value := range series.Values {
+			samples = append(samples, models.Sample{Timestamp: value.Timestamp.Time(), Value: float64(value.Value)})
+		}
+	}
+	return samples, nil
 }
 
+// timeseriesQuery returns the query for one of the Metric* series of
+// workload, with restarts counted over step
+func timeseriesQuery(workload *models.Workload, metric string, step time.Duration) (string, error) {
+	selector := analyzer.WorkloadSelector(workload)
+
+	switch metric {
+	case MetricCPU:
+		return fmt.Sprintf(`max(sum by (pod) (%s)) * 1000`,
+			analyzer.OwnedBy(workload, fmt.Sprintf(`rate(container_cpu_usage_seconds_total{%s}[5m])`, selector))), nil
+	case MetricMemory:
+		return fmt.Sprintf(`max(sum by (pod) (%s))`,
+			analyzer.OwnedBy(workload, fmt.Sprintf(`container_memory_working_set_bytes{%s}`, selector))), nil
+	case MetricThrottling:
+		return fmt.Sprintf(`max(sum by (pod) (%s) / sum by (pod) (%s))`,
+			analyzer.OwnedBy(workload, fmt.Sprintf(`rate(container_cpu_cfs_throttled_periods_total{%s}[5m])`, selector)),
+			analyzer.OwnedBy(workload, fmt.Sprintf(`rate(container_cpu_cfs_periods_total{%s}[5m])`, selector))), nil
+	case MetricRestarts:
+		return fmt.Sprintf(`sum(%s)`,
+			analyzer.OwnedBy(workload, fmt.Sprintf(`increase(kube_pod_container_status_restarts_total{%s}[%s])`, selector, model.Duration(step)))), nil
+	}
+	return "", fmt.Errorf("unknown metric %q", metric)
+}
+
+// currentMetrics returns the current usage of workload, the usage of its
+// busiest pod when it has several, from the same queries as GetTimeseries
+func (p *PrometheusSource) currentMetrics(ctx context.Context, workload *models.Workload) (*models.Metrics, error) {
+	var usage [2]int64
+	for i, metric := range []string{MetricCPU, MetricMemory} {
+		query, err := timeseriesQuery(workload, metric, minTimeseriesStep)
+		if err != nil {
+			return nil, err
+		}
+		vector, err := p.queryVector(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("%w for %s", ErrNoData, describeWorkload(workload))
+		}
+		usage[i] = int64(vector[0].Value)
+	}
+	cpu, memory := usage[0], usage[1]
+
+	return &models.Metrics{
+		P95CPU:      cpu,
+		P99CPU:      cpu,
+		MaxCPU:      cpu,
+		AvgCPU:      cpu,
+		P95Memory:   memory,
+		P99Memory:   memory,
+		MaxMemory:   memory,
+		AvgMemory:   memory,
+		SampleCount: 1,
+		CollectedAt: time.Now(),
+	}, nil
+}
+
+// ContainerResources returns the requests and limits of the containers of
+// the running pods of workload recorded by kube-state-metrics, ordered by
+// pod and container, for scans without the API server. CPU is in
+// millicores and memory in bytes; resources a container does not set are
+// zero.
+func (p *PrometheusSource) ContainerResources(ctx context.Context, workload *models.Workload) ([]analyzer.ContainerResources, error) {
+	podMatchers := fmt.Sprintf(`namespace="%s"`, workload.Namespace)
+	if workload.Pod != "" {
+		podMatchers += fmt.Sprintf(`,pod="%s"`, workload.Pod)
+	}
+	running := analyzer.OwnedBy(workload, fmt.Sprintf(`(kube_pod_status_phase{%s,phase="Running"} == 1)`, podMatchers))
+	selector := analyzer.WorkloadSelector(workload)
+
+	type podContainer struct{ pod, container string }
+	resources := make(map[podContainer]*analyzer.ContainerResources)
+	containers, err := p.queryVector(ctx, fmt.Sprintf(`kube_pod_container_info{%s} * on (namespace, pod) group_left () (%s)`, selector, running))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	for _, sample := range containers {
+		key := podContainer{pod: string(sample.Metric["pod"]), container: string(sample.Metric["container"])}
+		resources[key] = &analyzer.ContainerResources{Pod: key.pod, Container: key.container}
+	}
+
+	for _, metric := range []string{"requests", "limits"} {
+		vector, err := p.queryVector(ctx, fmt.Sprintf(`kube_pod_container_resource_%s{%s,resource=~"cpu|memory"} * on (namespace, pod) group_left () (%s)`, metric, selector, running))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query resource %s: %w", metric, err)
+		}
+		for _, sample := range vector {
+			container, ok := resources[podContainer{pod: string(sample.Metric["pod"]), container: string(sample.Metric["container"])}]
+			if !ok {
+				continue
+			}
+			// CPU is recorded in cores, memory in bytes
+			value := float64(sample.Value)
+			switch {
+			case sample.Metric["resource"] == "cpu" && metric == "requests":
+				container.RequestedCPU = int64(value * 1000)
+			case sample.Metric["resource"] == "cpu":
+				container.LimitCPU = int64(value * 1000)
+			case metric == "requests":
+				container.RequestedMemory = int64(value)
+			default:
+				container.LimitMemory = int64(value)
+			}
+		}
+	}
+
+	result := make([]analyzer.ContainerResources, 0, len(resources))
+	for _, container := range resources {
+		result = append(result, *container)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Pod != result[j].Pod {
+			return result[i].Pod < result[j].Pod
+		}
+		return result[i].Container < result[j].Container
+	})
+	return result, nil
+}
+
+// ListWorkloads discovers the workloads of namespace, or of all namespaces
+// when it is empty, from kube-state-metrics: the owners of pods in
+// kube_pod_owner, resolved through ReplicaSets and Jobs to their
+// Deployments and CronJobs, and Deployments from kube_deployment_labels
+// even when they run no pods. Pods without a controller are workloads of
+// kind Pod.
 func (p *PrometheusSource) ListWorkloads(ctx context.Context, namespace string) ([]*models.Workload, error) {
-	return nil, fmt.Errorf("not implemented")
+	selector := ""
+	if namespace != "" {
+		selector = fmt.Sprintf(`namespace="%s"`, namespace)
+	}
+
+	// Owners of ReplicaSets and Jobs, by namespace/kind/name
+	parents := make(map[string]model.Metric)
+	owners := []struct{ kind, metric, label string }{
+		{kind: "ReplicaSet", metric: "kube_replicaset_owner", label: "replicaset"},
+		{kind: "Job", metric: "kube_job_owner", label: "job_name"},
+	}
+	for _, owner := range owners {
+		vector, err := p.queryVector(ctx, fmt.Sprintf(`%s{%s}`, owner.metric, selector))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s owners: %w", owner.kind, err)
+		}
+		for _, sample := range vector {
+			if isOwner(sample.Metric) {
+				name := sample.Metric[model.LabelName(owner.label)]
+				parents[string(sample.Metric["namespace"])+"/"+owner.kind+"/"+string(name)] = sample.Metric
+			}
+		}
+	}
+
+	workloads := make(map[string]*models.Workload)
+	add := func(ns, kind, name, pod string) {
+		key := ns + "/" + kind + "/" + name
+		if _, ok := workloads[key]; !ok {
+			workloads[key] = &models.Workload{Namespace: ns, Kind: kind, Deployment: name, Pod: pod}
+		}
+	}
+
+	pods, err := p.queryVector(ctx, fmt.Sprintf(`kube_pod_owner{%s}`, selector))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod owners: %w", err)
+	}
+	for _, sample := range pods {
+		ns, pod := string(sample.Metric["namespace"]), string(sample.Metric["pod"])
+		if !isOwner(sample.Metric) {
+			add(ns, "Pod", pod, pod)
+			continue
+		}
+
+		kind, name := string(sample.Metric["owner_kind"]), string(sample.Metric["owner_name"])
+		if parent, ok := parents[ns+"/"+kind+"/"+name]; ok {
+			kind, name = string(parent["owner_kind"]), string(parent["owner_name"])
+		}
+		add(ns, kind, name, "")
+	}
+
+	deployments, err := p.queryVector(ctx, fmt.Sprintf(`kube_deployment_labels{%s}`, selector))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, sample := range deployments {
+		add(string(sample.Metric["namespace"]), "Deployment", string(sample.Metric["deployment"]), "")
+	}
+
+	result := make([]*models.Workload, 0, len(workloads))
+	for _, workload := range workloads {
+		result = append(result, workload)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Deployment < b.Deployment
+	})
+	return result, nil
+}
+
+// isOwner reports whether a kube-state-metrics owner series names a
+// controller. Objects without one have an owner_kind of "<none>", and
+// static pods are owned by their Node.
+func isOwner(metric model.Metric) bool {
+	kind := metric["owner_kind"]
+	return kind != "" && kind != "<none>" && kind != "Node"
+}
+
+// queryVector executes an instant query
+func (p *PrometheusSource) queryVector(ctx context.Context, query string) (model.Vector, error) {
+	result, warnings, err := p.client.Query(ctx, query, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintf(os.Stderr, "[WARN] Prometheus: %v\n", warnings)
+	}
+
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", result)
+	}
+	return vector, nil
 }
 
 func (p *PrometheusSource) IsAvailable(ctx context.Context) bool {
@@ -335,6 +606,11 @@ func (p *PrometheusSource) GetHistoricalAnalyzer() *analyzer.HistoricalAnalyzer
 	return analyzer.NewHistoricalAnalyzer(p.apiClient, false) // Default to non-verbose
 }
 
+// History returns an analyzer of the Prometheus history
+func (p *PrometheusSource) History(verbose bool) analyzer.History {
+	return analyzer.NewHistoricalAnalyzer(p.apiClient, verbose)
+}
+
 // GetAPIClient returns the underlying Prometheus API client
 func (p *PrometheusSource) GetAPIClient() api.Client {
 	return p.apiClient
@@ -0,0 +1,221 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+)
+
+// fakeKubeStateMetrics answers instant queries for kube-state-metrics
+// series with the given samples, by metric name, and range queries with
+// one series of three samples. Queries are recorded.
+func fakeKubeStateMetrics(t *testing.T, series map[string][]string) (*PrometheusSource, *[]string) {
+	t.Helper()
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		queries = append(queries, query)
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.HasSuffix(r.URL.Path, "/query_range") {
+			start := time.Now().Add(-time.Hour).Unix()
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[%d,"120"],[%d,"150"],[%d,"90"]]}]}}`,
+				start, start+60, start+120)
+			return
+		}
+
+		var result []string
+		for _, labels := range series[query[:strings.Index(query, "{")]] {
+			result = append(result, fmt.Sprintf(`{"metric":{%s},"value":[%d,"1"]}`, labels, time.Now().Unix()))
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, strings.Join(result, ","))
+	}))
+	t.Cleanup(server.Close)
+
+	source, err := NewPrometheusSource(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source, &queries
+}
+
+func TestListWorkloads(t *testing.T) {
+	source, queries := fakeKubeStateMetrics(t, map[string][]string{
+		"kube_replicaset_owner": {
+			`"namespace":"shop","replicaset":"web-7d9f8b","owner_kind":"Deployment","owner_name":"web"`,
+			`"namespace":"shop","replicaset":"canary-5f6c4d","owner_kind":"Rollout","owner_name":"canary"`,
+		},
+		"kube_job_owner": {
+			`"namespace":"shop","job_name":"backup-28391040","owner_kind":"CronJob","owner_name":"backup"`,
+		},
+		"kube_pod_owner": {
+			`"namespace":"shop","pod":"web-7d9f8b-abcde","owner_kind":"ReplicaSet","owner_name":"web-7d9f8b"`,
+			`"namespace":"shop","pod":"web-7d9f8b-fghij","owner_kind":"ReplicaSet","owner_name":"web-7d9f8b"`,
+			`"namespace":"shop","pod":"canary-5f6c4d-klmno","owner_kind":"ReplicaSet","owner_name":"canary-5f6c4d"`,
+			`"namespace":"shop","pod":"backup-28391040-pqrst","owner_kind":"Job","owner_name":"backup-28391040"`,
+			`"namespace":"shop","pod":"db-0","owner_kind":"StatefulSet","owner_name":"db"`,
+			`"namespace":"shop","pod":"debug","owner_kind":"<none>","owner_name":"<none>"`,
+		},
+		"kube_deployment_labels": {
+			`"namespace":"shop","deployment":"web"`,
+			`"namespace":"shop","deployment":"idle"`,
+		},
+	})
+
+	workloads, err := source.ListWorkloads(context.Background(), "shop")
+	if err != nil {
+		t.Fatalf("ListWorkloads failed: %v", err)
+	}
+
+	expected := []string{"CronJob/backup", "Deployment/idle", "Deployment/web", "Pod/debug", "Rollout/canary", "StatefulSet/db"}
+	var got []string
+	for _, workload := range workloads {
+		got = append(got, workload.Kind+"/"+workload.Deployment)
+		if workload.Namespace != "shop" {
+			t.Errorf("Expected namespace shop for %s, got %s", workload.Deployment, workload.Namespace)
+		}
+	}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected workloads %v, got %v", expected, got)
+	}
+	if workloads[3].Pod != "debug" {
+		t.Errorf("Expected the standalone pod to be named, got %q", workloads[3].Pod)
+	}
+
+	for _, query := range *queries {
+		if !strings.Contains(query, `{namespace="shop"}`) {
+			t.Errorf("Expected every query to select the namespace: %s", query)
+		}
+	}
+}
+
+func TestGetTimeseries(t *testing.T) {
+	source, queries := fakeKubeStateMetrics(t, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		workload *models.Workload
+		metric   string
+		expected []string
+	}{
+		{
+			name:     "deployment CPU through its ReplicaSets",
+			workload: &models.Workload{Namespace: "shop", Kind: "Deployment", Deployment: "web", Container: "app"},
+			metric:   MetricCPU,
+			expected: []string{
+				`rate(container_cpu_usage_seconds_total{namespace="shop",container="app"}[5m])`,
+				`kube_pod_owner{namespace="shop",owner_kind="ReplicaSet"}`,
+				`label_replace(kube_replicaset_owner{namespace="shop",owner_kind="Deployment",owner_name="web"}, "owner_name", "$1", "replicaset", "(.+)")`,
+			},
+		},
+		{
+			name:     "statefulset memory",
+			workload: &models.Workload{Namespace: "shop", Kind: "StatefulSet", Deployment: "db"},
+			metric:   MetricMemory,
+			expected: []string{
+				`container_memory_working_set_bytes{namespace="shop",container!="POD",container!=""}`,
+				`kube_pod_owner{namespace="shop",owner_kind="StatefulSet",owner_name="db"}`,
+			},
+		},
+		{
+			name:     "cronjob throttling through its Jobs",
+			workload: &models.Workload{Namespace: "shop", Kind: "CronJob", Deployment: "backup"},
+			metric:   MetricThrottling,
+			expected: []string{
+				`container_cpu_cfs_throttled_periods_total`,
+				`container_cpu_cfs_periods_total`,
+				`kube_job_owner{namespace="shop",owner_kind="CronJob",owner_name="backup"}`,
+			},
+		},
+		{
+			name:     "pod restarts",
+			workload: &models.Workload{Namespace: "shop", Pod: "debug"},
+			metric:   MetricRestarts,
+			expected: []string{`increase(kube_pod_container_status_restarts_total{namespace="shop",pod="debug",container!="POD",container!=""}[1m])`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*queries = nil
+			samples, err := source.GetTimeseries(ctx, tt.workload, 24*time.Hour, tt.metric)
+			if err != nil {
+				t.Fatalf("GetTimeseries failed: %v", err)
+			}
+			if len(samples) != 3 || samples[1].Value != 150 {
+				t.Errorf("Expected the three samples of the series, got %v", samples)
+			}
+
+			if len(*queries) != 1 {
+				t.Fatalf("Expected one range query, got %v", *queries)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains((*queries)[0], expected) {
+					t.Errorf("Expected %s in query %s", expected, (*queries)[0])
+				}
+			}
+			if tt.workload.Pod != "" && strings.Contains((*queries)[0], "kube_pod_owner") {
+				t.Errorf("Expected a single pod not to be joined with its owner: %s", (*queries)[0])
+			}
+		})
+	}
+
+	if _, err := source.GetTimeseries(ctx, &models.Workload{Namespace: "shop", Deployment: "web"}, time.Hour, "disk"); err == nil {
+		t.Error("Expected an error for an unknown metric")
+	}
+}
+
+func TestContainerResources(t *testing.T) {
+	source, queries := fakeKubeStateMetrics(t, map[string][]string{
+		"kube_pod_container_info": {
+			`"namespace":"shop","pod":"web-7d9f8b-fghij","container":"app"`,
+			`"namespace":"shop","pod":"web-7d9f8b-abcde","container":"istio-proxy"`,
+			`"namespace":"shop","pod":"web-7d9f8b-abcde","container":"app"`,
+		},
+		"kube_pod_container_resource_requests": {
+			`"namespace":"shop","pod":"web-7d9f8b-abcde","container":"app","resource":"cpu"`,
+			`"namespace":"shop","pod":"web-7d9f8b-abcde","container":"app","resource":"memory"`,
+			`"namespace":"shop","pod":"web-7d9f8b-fghij","container":"app","resource":"cpu"`,
+			// A pod that stopped running since the containers were listed
+			`"namespace":"shop","pod":"web-7d9f8b-zzzzz","container":"app","resource":"cpu"`,
+		},
+		"kube_pod_container_resource_limits": {
+			`"namespace":"shop","pod":"web-7d9f8b-abcde","container":"app","resource":"memory"`,
+		},
+	})
+
+	resources, err := source.ContainerResources(context.Background(), &models.Workload{Namespace: "shop", Kind: "Deployment", Deployment: "web"})
+	if err != nil {
+		t.Fatalf("ContainerResources failed: %v", err)
+	}
+
+	// Every sample has a value of 1: 1 core and 1 byte
+	expected := []string{
+		"web-7d9f8b-abcde/app 1000m/1 limits 0m/1",
+		"web-7d9f8b-abcde/istio-proxy 0m/0 limits 0m/0",
+		"web-7d9f8b-fghij/app 1000m/0 limits 0m/0",
+	}
+	var got []string
+	for _, container := range resources {
+		got = append(got, fmt.Sprintf("%s/%s %dm/%d limits %dm/%d", container.Pod, container.Container,
+			container.RequestedCPU, container.RequestedMemory, container.LimitCPU, container.LimitMemory))
+	}
+	if strings.Join(got, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	for _, query := range *queries {
+		if !strings.Contains(query, `kube_pod_status_phase{namespace="shop",phase="Running"} == 1`) ||
+			!strings.Contains(query, `kube_replicaset_owner{namespace="shop",owner_kind="Deployment",owner_name="web"}`) {
+			t.Errorf("Expected the running pods of web to be selected: %s", query)
+		}
+	}
+}
@@ -0,0 +1,633 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrSnapshotNotSaved is returned (wrapped) by Apply when the workload was
+// changed but the snapshot needed to roll it back could not be saved. The
+// change is still logged and stamped as applied.
+var ErrSnapshotNotSaved = errors.New("failed to save snapshot for rollback")
+
+// Applier executes recommendations against the cluster and records the audit trail
+type Applier struct {
+	clientset kubernetes.Interface
+	store     storage.Store
+	user      string
+
+	// Custom workload kinds, e.g. Argo Rollouts
+	dynamic       dynamic.Interface
+	mapper        meta.RESTMapper
+	workloadKinds []kube.WorkloadKind
+}
+
+// NewApplier creates an applier that patches workloads through clientset
+func NewApplier(clientset kubernetes.Interface, store storage.Store) *Applier {
+	return &Applier{
+		clientset: clientset,
+		store:     store,
+		user:      currentUser(),
+	}
+}
+
+// WithUser overrides the identity recorded as AppliedBy/ExecutedBy
+func (a *Applier) WithUser(user string) *Applier {
+	if user != "" {
+		a.user = user
+	}
+	return a
+}
+
+// WithWorkloadKinds lets the applier update the configured custom workload
+// kinds through the dynamic client
+func (a *Applier) WithWorkloadKinds(client dynamic.Interface, mapper meta.RESTMapper, kinds []kube.WorkloadKind) *Applier {
+	a.dynamic = client
+	a.mapper = mapper
+	a.workloadKinds = kinds
+	return a
+}
+
+// Apply patches the workload targeted by rec, logs an APPLIED audit entry
+// and stamps the recommendation as applied.
+func (a *Applier) Apply(ctx context.Context, rec *models.Recommendation) error {
+	if rec.AppliedAt != nil {
+		return fmt.Errorf("recommendation %s was already applied at %s by %s",
+			rec.ID, rec.AppliedAt.Format("2006-01-02 15:04:05"), rec.AppliedBy)
+	}
+
+	applyErr := a.apply(ctx, rec)
+
+	// A change without a snapshot was still made, only rolling it back
+	// is impossible
+	var snapshotErr error
+	if errors.Is(applyErr, ErrSnapshotNotSaved) {
+		snapshotErr, applyErr = applyErr, nil
+	}
+
+	entry := &models.AuditEntry{
+		RecommendationID: rec.ID,
+		Action:           models.AuditActionApplied,
+		Status:           models.AuditStatusSuccess,
+		ExecutedBy:       a.user,
+		ExecutedAt:       time.Now(),
+	}
+	if applyErr != nil {
+		entry.Status = models.AuditStatusFailed
+		entry.ErrorMessage = applyErr.Error()
+	} else if snapshotErr != nil {
+		entry.ErrorMessage = snapshotErr.Error()
+	}
+
+	if err := a.store.LogAction(ctx, entry); err != nil {
+		if applyErr != nil {
+			return fmt.Errorf("%w (additionally failed to write audit log: %v)", applyErr, err)
+		}
+		return fmt.Errorf("applied but failed to write audit log: %w", err)
+	}
+
+	if applyErr != nil {
+		return applyErr
+	}
+
+	appliedAt := entry.ExecutedAt
+	rec.AppliedAt = &appliedAt
+	rec.AppliedBy = a.user
+	if err := a.store.UpdateRecommendation(ctx, rec); err != nil {
+		return fmt.Errorf("applied but failed to update recommendation: %w", err)
+	}
+
+	return snapshotErr
+}
+
+// apply performs the cluster change for a recommendation
+func (a *Applier) apply(ctx context.Context, rec *models.Recommendation) error {
+	if rec.Workload == nil || rec.Workload.Deployment == "" {
+		return fmt.Errorf("recommendation %s has no target workload", rec.ID)
+	}
+
+	switch rec.Type {
+	case models.RecommendationRightSize:
+		// The original is read before mutating so rollback can restore it
+		// exactly, and only saved once the update went through
+		var original []byte
+		err := a.updatePodTemplate(ctx, rec.Workload, func(template *corev1.PodTemplateSpec) error {
+			var err error
+			if original, err = encodeResources(template); err != nil {
+				return err
+			}
+			return setContainerResources(template, rec)
+		})
+		if err != nil {
+			return err
+		}
+		return a.saveSnapshot(ctx, rec, original, nil)
+	case models.RecommendationScaleDown:
+		return a.scaleWithSnapshot(ctx, rec, 0)
+	case models.RecommendationReplicaRightSize:
+		if rec.RecommendedReplicas < 1 {
+			return fmt.Errorf("recommendation %s has no recommended replica count", rec.ID)
+		}
+		return a.scaleWithSnapshot(ctx, rec, rec.RecommendedReplicas)
+	case models.RecommendationHPAAdjust:
+		return a.adjustHPA(ctx, rec)
+	default:
+		return fmt.Errorf("recommendation type %s has nothing to apply", rec.Type)
+	}
+}
+
+// Rollback restores the workload state snapshotted when rec was applied,
+// logs a ROLLED_BACK audit entry and clears the applied stamp.
+func (a *Applier) Rollback(ctx context.Context, rec *models.Recommendation) error {
+	if rec.AppliedAt == nil {
+		return fmt.Errorf("recommendation %s has not been applied", rec.ID)
+	}
+
+	rollbackErr := a.rollback(ctx, rec)
+
+	entry := &models.AuditEntry{
+		RecommendationID: rec.ID,
+		Action:           models.AuditActionRolledBack,
+		Status:           models.AuditStatusSuccess,
+		ExecutedBy:       a.user,
+		ExecutedAt:       time.Now(),
+	}
+	if rollbackErr != nil {
+		entry.Status = models.AuditStatusFailed
+		entry.ErrorMessage = rollbackErr.Error()
+	}
+
+	if err := a.store.LogAction(ctx, entry); err != nil {
+		if rollbackErr != nil {
+			return fmt.Errorf("%w (additionally failed to write audit log: %v)", rollbackErr, err)
+		}
+		return fmt.Errorf("rolled back but failed to write audit log: %w", err)
+	}
+
+	if rollbackErr != nil {
+		return rollbackErr
+	}
+
+	rec.AppliedAt = nil
+	rec.AppliedBy = ""
+	if err := a.store.UpdateRecommendation(ctx, rec); err != nil {
+		return fmt.Errorf("rolled back but failed to update recommendation: %w", err)
+	}
+
+	return nil
+}
+
+// rollback performs the cluster change that undoes a recommendation
+func (a *Applier) rollback(ctx context.Context, rec *models.Recommendation) error {
+	snapshot, err := a.store.GetSnapshot(ctx, rec.ID)
+	if err != nil {
+		return fmt.Errorf("cannot roll back without a snapshot: %w", err)
+	}
+
+	if rec.Type == models.RecommendationHPAAdjust {
+		return a.restoreHPA(ctx, rec, snapshot)
+	}
+
+	workload := &models.Workload{
+		Namespace:  snapshot.Namespace,
+		Deployment: snapshot.WorkloadName,
+		Kind:       snapshot.WorkloadKind,
+	}
+
+	if len(snapshot.Resources) > 0 {
+		var original map[string]corev1.ResourceRequirements
+		if err := json.Unmarshal(snapshot.Resources, &original); err != nil {
+			return fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+
+		err := a.updatePodTemplate(ctx, workload, func(template *corev1.PodTemplateSpec) error {
+			for i := range template.Spec.Containers {
+				if resources, ok := original[template.Spec.Containers[i].Name]; ok {
+					template.Spec.Containers[i].Resources = resources
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if snapshot.Replicas != nil {
+		if err := a.scale(ctx, workload, *snapshot.Replicas); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeResources encodes the resources block of every container in
+// template for a snapshot
+func encodeResources(template *corev1.PodTemplateSpec) ([]byte, error) {
+	original := make(map[string]corev1.ResourceRequirements, len(template.Spec.Containers))
+	for _, container := range template.Spec.Containers {
+		original[container.Name] = *container.Resources.DeepCopy()
+	}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// scaleWithSnapshot scales the workload of rec to replicas and snapshots
+// the replica count it had before
+func (a *Applier) scaleWithSnapshot(ctx context.Context, rec *models.Recommendation, replicas int32) error {
+	original, err := a.currentReplicas(ctx, rec.Workload)
+	if err != nil {
+		return err
+	}
+	if err := a.scale(ctx, rec.Workload, replicas); err != nil {
+		return err
+	}
+	return a.saveSnapshot(ctx, rec, nil, &original)
+}
+
+// currentReplicas reads the replica count of the workload
+func (a *Applier) currentReplicas(ctx context.Context, workload *models.Workload) (int32, error) {
+	ns, name := workload.Namespace, workload.Deployment
+
+	var scale *autoscalingv1.Scale
+	var err error
+	switch workloadKind(workload) {
+	case "Deployment":
+		scale, err = a.clientset.AppsV1().Deployments(ns).GetScale(ctx, name, metav1.GetOptions{})
+	case "StatefulSet":
+		scale, err = a.clientset.AppsV1().StatefulSets(ns).GetScale(ctx, name, metav1.GetOptions{})
+	default:
+		return a.customReplicas(ctx, workload)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read current scale of %s/%s: %w", ns, name, err)
+	}
+	return scale.Spec.Replicas, nil
+}
+
+// saveSnapshot records the state a recommendation changed. It is called
+// once the change was made, so no snapshot exists for failed changes, and
+// its errors wrap ErrSnapshotNotSaved.
+func (a *Applier) saveSnapshot(ctx context.Context, rec *models.Recommendation, resources []byte, replicas *int32) error {
+	snapshot := &models.ResourceSnapshot{
+		RecommendationID: rec.ID,
+		Namespace:        rec.Workload.Namespace,
+		WorkloadKind:     workloadKind(rec.Workload),
+		WorkloadName:     rec.Workload.Deployment,
+		Resources:        resources,
+		Replicas:         replicas,
+	}
+	if err := a.store.SaveSnapshot(ctx, snapshot); err != nil {
+		return fmt.Errorf("changed %s/%s but %w: %v", snapshot.Namespace, snapshot.WorkloadName, ErrSnapshotNotSaved, err)
+	}
+	return nil
+}
+
+// updatePodTemplate fetches the workload, lets mutate change its pod template
+// and writes it back
+func (a *Applier) updatePodTemplate(ctx context.Context, workload *models.Workload, mutate func(*corev1.PodTemplateSpec) error) error {
+	ns, name := workload.Namespace, workload.Deployment
+
+	switch workloadKind(workload) {
+	case "Deployment":
+		deploy, err := a.clientset.AppsV1().Deployments(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get deployment %s/%s: %w", ns, name, err)
+		}
+		if err := mutate(&deploy.Spec.Template); err != nil {
+			return err
+		}
+		if _, err := a.clientset.AppsV1().Deployments(ns).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update deployment %s/%s: %w", ns, name, err)
+		}
+	case "StatefulSet":
+		sts, err := a.clientset.AppsV1().StatefulSets(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get statefulset %s/%s: %w", ns, name, err)
+		}
+		if err := mutate(&sts.Spec.Template); err != nil {
+			return err
+		}
+		if _, err := a.clientset.AppsV1().StatefulSets(ns).Update(ctx, sts, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update statefulset %s/%s: %w", ns, name, err)
+		}
+	case "DaemonSet":
+		ds, err := a.clientset.AppsV1().DaemonSets(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get daemonset %s/%s: %w", ns, name, err)
+		}
+		if err := mutate(&ds.Spec.Template); err != nil {
+			return err
+		}
+		if _, err := a.clientset.AppsV1().DaemonSets(ns).Update(ctx, ds, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update daemonset %s/%s: %w", ns, name, err)
+		}
+	case "CronJob":
+		// Only runs started after the update are affected
+		cj, err := a.clientset.BatchV1().CronJobs(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get cronjob %s/%s: %w", ns, name, err)
+		}
+		if err := mutate(&cj.Spec.JobTemplate.Spec.Template); err != nil {
+			return err
+		}
+		if _, err := a.clientset.BatchV1().CronJobs(ns).Update(ctx, cj, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update cronjob %s/%s: %w", ns, name, err)
+		}
+	default:
+		return a.updateCustomPodTemplate(ctx, workload, mutate)
+	}
+
+	return nil
+}
+
+// customResource returns the configured kind of a custom workload and the
+// client for its resources
+func (a *Applier) customResource(workload *models.Workload) (kube.WorkloadKind, dynamic.ResourceInterface, error) {
+	kind, ok := kube.FindWorkloadKind(a.workloadKinds, workload.Kind)
+	if !ok || a.dynamic == nil {
+		return kind, nil, fmt.Errorf("unsupported workload kind: %s", workload.Kind)
+	}
+
+	gvk := kind.GroupVersionKind()
+	mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return kind, nil, fmt.Errorf("failed to find the %s resource: %w", kind.Kind, err)
+	}
+	return kind, a.dynamic.Resource(mapping.Resource).Namespace(workload.Namespace), nil
+}
+
+// updateCustomPodTemplate is updatePodTemplate for custom workload kinds,
+// whose pod template is found by the kind's podTemplatePath
+func (a *Applier) updateCustomPodTemplate(ctx context.Context, workload *models.Workload, mutate func(*corev1.PodTemplateSpec) error) error {
+	ns, name := workload.Namespace, workload.Deployment
+
+	kind, client, err := a.customResource(workload)
+	if err != nil {
+		return err
+	}
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get %s %s/%s: %w", kind.Kind, ns, name, err)
+	}
+	template, err := kind.PodTemplate(obj)
+	if err != nil {
+		return err
+	}
+	if err := mutate(&template); err != nil {
+		return err
+	}
+	if err := kind.SetPodTemplate(obj, &template); err != nil {
+		return err
+	}
+	if _, err := client.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update %s %s/%s: %w", kind.Kind, ns, name, err)
+	}
+	return nil
+}
+
+// customReplicas reads spec.replicas of a custom workload
+func (a *Applier) customReplicas(ctx context.Context, workload *models.Workload) (int32, error) {
+	ns, name := workload.Namespace, workload.Deployment
+
+	kind, client, err := a.customResource(workload)
+	if err != nil {
+		return 0, fmt.Errorf("cannot scale workload kind: %s", workload.Kind)
+	}
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read current scale of %s/%s: %w", ns, name, err)
+	}
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if err != nil || !found {
+		return 0, fmt.Errorf("cannot scale %s %s/%s: no spec.replicas", kind.Kind, ns, name)
+	}
+	return int32(replicas), nil
+}
+
+// scaleCustom sets spec.replicas of a custom workload
+func (a *Applier) scaleCustom(ctx context.Context, workload *models.Workload, replicas int32) error {
+	ns, name := workload.Namespace, workload.Deployment
+
+	kind, client, err := a.customResource(workload)
+	if err != nil {
+		return fmt.Errorf("cannot scale workload kind: %s", workload.Kind)
+	}
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get %s %s/%s: %w", kind.Kind, ns, name, err)
+	}
+	if err := unstructured.SetNestedField(obj.Object, int64(replicas), "spec", "replicas"); err != nil {
+		return err
+	}
+	if _, err := client.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to scale %s/%s: %w", ns, name, err)
+	}
+	return nil
+}
+
+// scale sets the replica count through the scale subresource
+func (a *Applier) scale(ctx context.Context, workload *models.Workload, replicas int32) error {
+	ns, name := workload.Namespace, workload.Deployment
+	scale := &autoscalingv1.Scale{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+	}
+
+	var err error
+	switch workloadKind(workload) {
+	case "Deployment":
+		_, err = a.clientset.AppsV1().Deployments(ns).UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+	case "StatefulSet":
+		_, err = a.clientset.AppsV1().StatefulSets(ns).UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+	default:
+		return a.scaleCustom(ctx, workload, replicas)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to scale %s/%s: %w", ns, name, err)
+	}
+
+	return nil
+}
+
+// adjustHPA sets the CPU target and minReplicas of the recommendation's
+// HPA, snapshotting its previous spec for rollback
+func (a *Applier) adjustHPA(ctx context.Context, rec *models.Recommendation) error {
+	if rec.HPAName == "" {
+		return fmt.Errorf("recommendation %s names no HPA", rec.ID)
+	}
+
+	ns := rec.Workload.Namespace
+	hpas := a.clientset.AutoscalingV2().HorizontalPodAutoscalers(ns)
+	hpa, err := hpas.Get(ctx, rec.HPAName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get hpa %s/%s: %w", ns, rec.HPAName, err)
+	}
+
+	original, err := json.Marshal(hpa.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	if rec.RecommendedMinReplicas > 0 {
+		minReplicas := rec.RecommendedMinReplicas
+		hpa.Spec.MinReplicas = &minReplicas
+	}
+	if rec.RecommendedTargetCPU > 0 {
+		if err := setTargetCPU(&hpa.Spec, rec.RecommendedTargetCPU); err != nil {
+			return fmt.Errorf("hpa %s/%s: %w", ns, rec.HPAName, err)
+		}
+	}
+
+	if _, err := hpas.Update(ctx, hpa, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update hpa %s/%s: %w", ns, rec.HPAName, err)
+	}
+	return a.saveSnapshot(ctx, rec, original, nil)
+}
+
+// restoreHPA puts back the HPA spec snapshotted by adjustHPA
+func (a *Applier) restoreHPA(ctx context.Context, rec *models.Recommendation, snapshot *models.ResourceSnapshot) error {
+	var original autoscalingv2.HorizontalPodAutoscalerSpec
+	if err := json.Unmarshal(snapshot.Resources, &original); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	ns := snapshot.Namespace
+	hpas := a.clientset.AutoscalingV2().HorizontalPodAutoscalers(ns)
+	hpa, err := hpas.Get(ctx, rec.HPAName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get hpa %s/%s: %w", ns, rec.HPAName, err)
+	}
+
+	hpa.Spec = original
+	if _, err := hpas.Update(ctx, hpa, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update hpa %s/%s: %w", ns, rec.HPAName, err)
+	}
+	return nil
+}
+
+// setTargetCPU changes the pod CPU utilization target of an HPA. HPAs
+// without metrics get one in place of their default target.
+func setTargetCPU(spec *autoscalingv2.HorizontalPodAutoscalerSpec, target int32) error {
+	for i := range spec.Metrics {
+		metric := &spec.Metrics[i]
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil &&
+			metric.Resource.Name == corev1.ResourceCPU && metric.Resource.Target.Type == autoscalingv2.UtilizationMetricType {
+			metric.Resource.Target.AverageUtilization = &target
+			return nil
+		}
+	}
+	if len(spec.Metrics) > 0 {
+		return fmt.Errorf("no CPU utilization target")
+	}
+
+	spec.Metrics = []autoscalingv2.MetricSpec{{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name:   corev1.ResourceCPU,
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &target},
+		},
+	}}
+	return nil
+}
+
+// setContainerResources updates CPU/memory requests and limits on the
+// recommendation's container, or on every container when none is set
+// (like kubectl set resources)
+func setContainerResources(template *corev1.PodTemplateSpec, rec *models.Recommendation) error {
+	containerName := rec.Workload.Container
+	// Recommendations saved before limits were tracked carry no limits at
+	// all; leave the existing limits alone for those
+	hasLimits := rec.RecommendedCPULimit > 0 || rec.RecommendedMemoryLimit > 0
+
+	found := false
+	for i := range template.Spec.Containers {
+		container := &template.Spec.Containers[i]
+		if containerName != "" && container.Name != containerName {
+			continue
+		}
+		found = true
+
+		cpu := *resource.NewMilliQuantity(rec.RecommendedCPU, resource.DecimalSI)
+		memory := *resource.NewQuantity(rec.RecommendedMemory, resource.BinarySI)
+
+		if container.Resources.Requests == nil {
+			container.Resources.Requests = corev1.ResourceList{}
+		}
+		container.Resources.Requests[corev1.ResourceCPU] = cpu
+		container.Resources.Requests[corev1.ResourceMemory] = memory
+
+		if hasLimits {
+			if container.Resources.Limits == nil {
+				container.Resources.Limits = corev1.ResourceList{}
+			}
+			setLimit(container.Resources.Limits, corev1.ResourceCPU, resource.NewMilliQuantity(rec.RecommendedCPULimit, resource.DecimalSI))
+			setLimit(container.Resources.Limits, corev1.ResourceMemory, resource.NewQuantity(rec.RecommendedMemoryLimit, resource.BinarySI))
+		}
+
+		// Requests may not exceed limits - raise any limit that would be violated
+		if limit, ok := container.Resources.Limits[corev1.ResourceCPU]; ok && limit.Cmp(cpu) < 0 {
+			container.Resources.Limits[corev1.ResourceCPU] = cpu
+		}
+		if limit, ok := container.Resources.Limits[corev1.ResourceMemory]; ok && limit.Cmp(memory) < 0 {
+			container.Resources.Limits[corev1.ResourceMemory] = memory
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("container %q not found in pod template", containerName)
+	}
+
+	return nil
+}
+
+// setLimit sets a limit, removing it when the quantity is zero
+func setLimit(limits corev1.ResourceList, name corev1.ResourceName, quantity *resource.Quantity) {
+	if quantity.IsZero() {
+		delete(limits, name)
+		return
+	}
+	limits[name] = *quantity
+}
+
+// workloadKind returns the workload kind, defaulting to Deployment for
+// recommendations saved before the kind was tracked
+func workloadKind(workload *models.Workload) string {
+	if workload.Kind == "" {
+		return "Deployment"
+	}
+	return workload.Kind
+}
+
+// currentUser returns the identity to record in the audit log
+func currentUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return "unknown"
+}
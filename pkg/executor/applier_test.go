@@ -0,0 +1,332 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/storage"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testTemplate is a pod template with an app container requesting
+// 500m/512Mi, limited to 1/1Gi, and an istio-proxy sidecar without
+// requests
+func testTemplate() corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("512Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("1"),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+					},
+				},
+				{Name: "istio-proxy"},
+			},
+		},
+	}
+}
+
+// testWorkloads returns a Deployment and a StatefulSet "web" in shop, both
+// with testTemplate
+func testWorkloads() []runtime.Object {
+	meta := metav1.ObjectMeta{Name: "web", Namespace: "shop"}
+	return []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: meta, Spec: appsv1.DeploymentSpec{Template: testTemplate()}},
+		&appsv1.StatefulSet{ObjectMeta: meta, Spec: appsv1.StatefulSetSpec{Template: testTemplate()}},
+	}
+}
+
+// savedRightSize saves a RIGHT_SIZE recommendation of the app container
+// of kind web to 200m/256Mi, limited to 400m/512Mi
+func savedRightSize(t *testing.T, store storage.Store, kind string) *models.Recommendation {
+	t.Helper()
+
+	rec := &models.Recommendation{
+		Type:                   models.RecommendationRightSize,
+		Workload:               &models.Workload{Namespace: "shop", Kind: kind, Deployment: "web", Container: "app"},
+		RecommendedCPU:         200,
+		RecommendedMemory:      256 << 20,
+		RecommendedCPULimit:    400,
+		RecommendedMemoryLimit: 512 << 20,
+	}
+	if err := store.SaveRecommendation(context.Background(), rec); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+// podTemplate reads the pod template of kind shop/web from the fake cluster
+func podTemplate(t *testing.T, clientset *fake.Clientset, kind string) corev1.PodTemplateSpec {
+	t.Helper()
+
+	ctx := context.Background()
+	switch kind {
+	case "StatefulSet":
+		sts, err := clientset.AppsV1().StatefulSets("shop").Get(ctx, "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sts.Spec.Template
+	default:
+		deploy, err := clientset.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deploy.Spec.Template
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		reject  string // resource whose updates the API server rejects
+		wantErr string
+	}{
+		{name: "deployment", kind: "Deployment"},
+		{name: "statefulset", kind: "StatefulSet"},
+		{name: "kind defaults to deployment", kind: ""},
+		{name: "rejected update", kind: "Deployment", reject: "deployments", wantErr: "failed to update deployment shop/web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := fake.NewSimpleClientset(testWorkloads()...)
+			if tt.reject != "" {
+				clientset.PrependReactor("update", tt.reject, func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("admission webhook denied the request")
+				})
+			}
+			store := storage.NewMemoryStore()
+			rec := savedRightSize(t, store, tt.kind)
+
+			err := NewApplier(clientset, store).WithUser("alice").Apply(ctx, rec)
+
+			audit, auditErr := store.GetAuditLog(ctx, rec.ID)
+			if auditErr != nil || len(audit) != 1 {
+				t.Fatalf("Expected one audit entry, got %d (%v)", len(audit), auditErr)
+			}
+			entry := audit[0]
+			if entry.Action != models.AuditActionApplied || entry.ExecutedBy != "alice" {
+				t.Errorf("Expected an APPLIED entry by alice, got %s by %s", entry.Action, entry.ExecutedBy)
+			}
+			stored, getErr := store.GetRecommendation(ctx, rec.ID)
+			if getErr != nil {
+				t.Fatal(getErr)
+			}
+			app := podTemplate(t, clientset, tt.kind).Spec.Containers[0].Resources
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if entry.Status != models.AuditStatusFailed || !strings.Contains(entry.ErrorMessage, "admission webhook denied") {
+					t.Errorf("Expected a FAILED entry with the API error, got %s: %q", entry.Status, entry.ErrorMessage)
+				}
+				if stored.AppliedAt != nil || stored.AppliedBy != "" || rec.AppliedAt != nil {
+					t.Errorf("Expected a failed recommendation not to be stamped, got %v by %q", stored.AppliedAt, stored.AppliedBy)
+				}
+				if app.Requests.Cpu().String() != "500m" {
+					t.Errorf("Expected the workload to be unchanged, got %s", app.Requests.Cpu())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if entry.Status != models.AuditStatusSuccess || entry.ErrorMessage != "" {
+				t.Errorf("Expected a SUCCESS entry, got %s: %q", entry.Status, entry.ErrorMessage)
+			}
+			if stored.AppliedAt == nil || !stored.AppliedAt.Equal(entry.ExecutedAt) || stored.AppliedBy != "alice" {
+				t.Errorf("Expected the recommendation to be stamped as applied by alice, got %v by %q", stored.AppliedAt, stored.AppliedBy)
+			}
+
+			if app.Requests.Cpu().String() != "200m" || app.Requests.Memory().String() != "256Mi" {
+				t.Errorf("Expected requests of 200m/256Mi, got %s/%s", app.Requests.Cpu(), app.Requests.Memory())
+			}
+			if app.Limits.Cpu().String() != "400m" || app.Limits.Memory().String() != "512Mi" {
+				t.Errorf("Expected limits of 400m/512Mi, got %s/%s", app.Limits.Cpu(), app.Limits.Memory())
+			}
+			if sidecar := podTemplate(t, clientset, tt.kind).Spec.Containers[1].Resources; len(sidecar.Requests) != 0 || len(sidecar.Limits) != 0 {
+				t.Errorf("Expected istio-proxy to be left alone, got %v", sidecar)
+			}
+
+			if err := NewApplier(clientset, store).Apply(ctx, rec); err == nil || !strings.Contains(err.Error(), "already applied") {
+				t.Errorf("Expected a second apply to be refused, got %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyMissingTarget(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+
+	tests := []struct {
+		name    string
+		rec     *models.Recommendation
+		wantErr string
+	}{
+		{
+			name:    "missing container",
+			rec:     &models.Recommendation{Type: models.RecommendationRightSize, Workload: &models.Workload{Namespace: "shop", Deployment: "web", Container: "worker"}, RecommendedCPU: 100},
+			wantErr: `container "worker" not found`,
+		},
+		{
+			name:    "missing workload",
+			rec:     &models.Recommendation{Type: models.RecommendationRightSize, Workload: &models.Workload{Namespace: "shop", Deployment: "api"}},
+			wantErr: "failed to get deployment shop/api",
+		},
+		{
+			name:    "nothing to apply",
+			rec:     &models.Recommendation{Type: models.RecommendationNoAction, Workload: &models.Workload{Namespace: "shop", Deployment: "web"}},
+			wantErr: "has nothing to apply",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.SaveRecommendation(ctx, tt.rec); err != nil {
+				t.Fatal(err)
+			}
+
+			err := NewApplier(fake.NewSimpleClientset(testWorkloads()...), store).Apply(ctx, tt.rec)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+
+			audit, _ := store.GetAuditLog(ctx, tt.rec.ID)
+			if len(audit) != 1 || audit[0].Status != models.AuditStatusFailed {
+				t.Errorf("Expected a FAILED audit entry, got %v", audit)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	for _, kind := range []string{"Deployment", "StatefulSet"} {
+		t.Run(kind, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := fake.NewSimpleClientset(testWorkloads()...)
+			store := storage.NewMemoryStore()
+			rec := savedRightSize(t, store, kind)
+			applier := NewApplier(clientset, store).WithUser("alice")
+
+			if err := applier.Rollback(ctx, rec); err == nil || !strings.Contains(err.Error(), "has not been applied") {
+				t.Fatalf("Expected rollback before apply to be refused, got %v", err)
+			}
+			if err := applier.Apply(ctx, rec); err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if err := applier.WithUser("bob").Rollback(ctx, rec); err != nil {
+				t.Fatalf("Rollback failed: %v", err)
+			}
+
+			want := testTemplate().Spec.Containers
+			got := podTemplate(t, clientset, kind).Spec.Containers
+			for i := range want {
+				if !equality.Semantic.DeepEqual(got[i].Resources, want[i].Resources) {
+					t.Errorf("Expected the original resources of %s to be restored, got %v", want[i].Name, got[i].Resources)
+				}
+			}
+
+			audit, err := store.GetAuditLog(ctx, rec.ID)
+			if err != nil || len(audit) != 2 {
+				t.Fatalf("Expected apply and rollback to be logged, got %d entries (%v)", len(audit), err)
+			}
+			var rolledBack *models.AuditEntry
+			for _, entry := range audit {
+				if entry.Action == models.AuditActionRolledBack {
+					rolledBack = entry
+				}
+			}
+			if rolledBack == nil || rolledBack.Status != models.AuditStatusSuccess || rolledBack.ExecutedBy != "bob" {
+				t.Errorf("Expected a successful ROLLED_BACK entry by bob, got %+v", rolledBack)
+			}
+
+			stored, err := store.GetRecommendation(ctx, rec.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.AppliedAt != nil || stored.AppliedBy != "" {
+				t.Errorf("Expected the applied stamp to be cleared, got %v by %q", stored.AppliedAt, stored.AppliedBy)
+			}
+		})
+	}
+}
+
+func TestApplyFailureLeavesNoSnapshot(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset(testWorkloads()...)
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("admission webhook denied the request")
+	})
+	store := storage.NewMemoryStore()
+	rec := savedRightSize(t, store, "Deployment")
+
+	if err := NewApplier(clientset, store).Apply(ctx, rec); err == nil {
+		t.Fatal("Expected the rejected update to fail")
+	}
+	if snapshot, err := store.GetSnapshot(ctx, rec.ID); err == nil {
+		t.Errorf("Expected no snapshot of a change that was never made, got %+v", snapshot)
+	}
+}
+
+// failingSnapshotStore is a store whose snapshots cannot be saved
+type failingSnapshotStore struct {
+	storage.Store
+}
+
+func (failingSnapshotStore) SaveSnapshot(ctx context.Context, snapshot *models.ResourceSnapshot) error {
+	return errors.New("disk full")
+}
+
+func TestApplySnapshotFailure(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset(testWorkloads()...)
+	store := failingSnapshotStore{Store: storage.NewMemoryStore()}
+	rec := savedRightSize(t, store, "Deployment")
+
+	err := NewApplier(clientset, store).WithUser("alice").Apply(ctx, rec)
+	if !errors.Is(err, ErrSnapshotNotSaved) || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("Expected ErrSnapshotNotSaved with the store error, got %v", err)
+	}
+
+	if app := podTemplate(t, clientset, "Deployment").Spec.Containers[0].Resources; app.Requests.Cpu().String() != "200m" {
+		t.Errorf("Expected the workload to be changed, got %s", app.Requests.Cpu())
+	}
+	audit, auditErr := store.GetAuditLog(ctx, rec.ID)
+	if auditErr != nil || len(audit) != 1 || audit[0].Status != models.AuditStatusSuccess {
+		t.Fatalf("Expected a SUCCESS audit entry for the change, got %v (%v)", audit, auditErr)
+	}
+	if !strings.Contains(audit[0].ErrorMessage, "disk full") {
+		t.Errorf("Expected the audit entry to note the missing snapshot, got %q", audit[0].ErrorMessage)
+	}
+	stored, getErr := store.GetRecommendation(ctx, rec.ID)
+	if getErr != nil {
+		t.Fatal(getErr)
+	}
+	if stored.AppliedAt == nil || stored.AppliedBy != "alice" {
+		t.Errorf("Expected the recommendation to be stamped as applied by alice, got %v by %q", stored.AppliedAt, stored.AppliedBy)
+	}
+}
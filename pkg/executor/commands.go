@@ -13,28 +13,54 @@ func GenerateCommand(rec *recommender.Recommendation) string {
 	case recommender.RightSize:
 		return generateRightSizeCommand(rec)
 	case recommender.ScaleDown:
-		return generateScaleDownCommand(rec)
+		return generateScaleCommand(rec, 0)
+	case recommender.ReplicaRightSize:
+		return generateScaleCommand(rec, rec.RecommendedReplicas)
+	case recommender.HPAAdjust:
+		return generateHPAPatchCommand(rec)
 	default:
 		return ""
 	}
 }
 
 func generateRightSizeCommand(rec *recommender.Recommendation) string {
+	if rec.WorkloadResource != "" {
+		return generateResourcesPatchCommand(rec)
+	}
+
+	switch rec.WorkloadType {
+	case "CronJob":
+		if rec.ContainerName != "" {
+			return generateCronJobPatchCommand(rec)
+		}
+	case "Job":
+		// A Job's pod template cannot be changed; whatever creates the Job
+		// has to be updated instead
+		return ""
+	}
+
 	cpuRequest := fmt.Sprintf("%dm", rec.RecommendedCPU)
 	memRequest := fmt.Sprintf("%dMi", rec.RecommendedMemory/(1024*1024))
 
-	// Set limits to 2x requests for burstability
-	cpuLimit := fmt.Sprintf("%dm", rec.RecommendedCPU*2)
-	memLimit := fmt.Sprintf("%dMi", rec.RecommendedMemory*2/(1024*1024))
+	// A zero limit tells kubectl to remove it
+	cpuLimit := "0"
+	if rec.RecommendedCPULimit > 0 {
+		cpuLimit = fmt.Sprintf("%dm", rec.RecommendedCPULimit)
+	}
+	memLimit := "0"
+	if rec.RecommendedMemoryLimit > 0 {
+		memLimit = fmt.Sprintf("%dMi", rec.RecommendedMemoryLimit/(1024*1024))
+	}
 
 	// Get workload resource type (deployment, statefulset, daemonset)
 	resourceType := getResourceType(rec.WorkloadType)
 
 	return fmt.Sprintf(
-		"kubectl set resources %s/%s -n %s --requests=cpu=%s,memory=%s --limits=cpu=%s,memory=%s",
+		"kubectl set resources %s/%s -n %s%s --requests=cpu=%s,memory=%s --limits=cpu=%s,memory=%s",
 		resourceType, // Changed from "deployment" to dynamic
 		rec.DeploymentName,
 		rec.Namespace,
+		containerFlag(rec.ContainerName),
 		cpuRequest,
 		memRequest,
 		cpuLimit,
@@ -42,17 +68,106 @@ func generateRightSizeCommand(rec *recommender.Recommendation) string {
 	)
 }
 
-func generateScaleDownCommand(rec *recommender.Recommendation) string {
+// generateCronJobPatchCommand patches the container in the CronJob's
+// jobTemplate, so the next runs get the new resources
+func generateCronJobPatchCommand(rec *recommender.Recommendation) string {
+	// null removes a limit in a strategic merge patch
+	cpuLimit := "null"
+	if rec.RecommendedCPULimit > 0 {
+		cpuLimit = fmt.Sprintf(`"%dm"`, rec.RecommendedCPULimit)
+	}
+	memLimit := "null"
+	if rec.RecommendedMemoryLimit > 0 {
+		memLimit = fmt.Sprintf(`"%dMi"`, rec.RecommendedMemoryLimit/(1024*1024))
+	}
+
+	patch := fmt.Sprintf(
+		`{"spec":{"jobTemplate":{"spec":{"template":{"spec":{"containers":[{"name":"%s","resources":{"requests":{"cpu":"%dm","memory":"%dMi"},"limits":{"cpu":%s,"memory":%s}}}]}}}}}}`,
+		rec.ContainerName,
+		rec.RecommendedCPU,
+		rec.RecommendedMemory/(1024*1024),
+		cpuLimit,
+		memLimit,
+	)
+
+	return fmt.Sprintf("kubectl patch cronjob/%s -n %s --type=strategic -p '%s'", rec.DeploymentName, rec.Namespace, patch)
+}
+
+// generateResourcesPatchCommand sets the resources of the container in a
+// custom workload kind, which kubectl set resources does not support.
+// Custom resources take no strategic merge patches, so the container's
+// resources are replaced with a JSON patch.
+func generateResourcesPatchCommand(rec *recommender.Recommendation) string {
+	if rec.ResourcesPath == "" {
+		return ""
+	}
+
+	limits := []string{}
+	if rec.RecommendedCPULimit > 0 {
+		limits = append(limits, fmt.Sprintf(`"cpu":"%dm"`, rec.RecommendedCPULimit))
+	}
+	if rec.RecommendedMemoryLimit > 0 {
+		limits = append(limits, fmt.Sprintf(`"memory":"%dMi"`, rec.RecommendedMemoryLimit/(1024*1024)))
+	}
+
+	patch := fmt.Sprintf(
+		`[{"op":"add","path":"%s","value":{"requests":{"cpu":"%dm","memory":"%dMi"},"limits":{%s}}}]`,
+		rec.ResourcesPath,
+		rec.RecommendedCPU,
+		rec.RecommendedMemory/(1024*1024),
+		strings.Join(limits, ","),
+	)
+
+	return fmt.Sprintf("kubectl patch %s/%s -n %s --type=json -p '%s'", rec.WorkloadResource, rec.DeploymentName, rec.Namespace, patch)
+}
+
+// generateHPAPatchCommand sets the CPU target and minReplicas of the HPA.
+// HPAs without metrics get an explicit CPU target replacing the default.
+func generateHPAPatchCommand(rec *recommender.Recommendation) string {
+	var ops []string
+	if rec.RecommendedMinReplicas != rec.CurrentMinReplicas {
+		ops = append(ops, fmt.Sprintf(`{"op":"replace","path":"/spec/minReplicas","value":%d}`, rec.RecommendedMinReplicas))
+	}
+	if rec.RecommendedTargetCPU != rec.CurrentTargetCPU {
+		if rec.HPAMetricIndex >= 0 {
+			ops = append(ops, fmt.Sprintf(`{"op":"replace","path":"/spec/metrics/%d/resource/target/averageUtilization","value":%d}`,
+				rec.HPAMetricIndex, rec.RecommendedTargetCPU))
+		} else {
+			ops = append(ops, fmt.Sprintf(`{"op":"add","path":"/spec/metrics","value":[{"type":"Resource","resource":{"name":"cpu","target":{"type":"Utilization","averageUtilization":%d}}}]}`,
+				rec.RecommendedTargetCPU))
+		}
+	}
+	if len(ops) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("kubectl patch hpa/%s -n %s --type=json -p '[%s]'", rec.HPAName, rec.Namespace, strings.Join(ops, ","))
+}
+
+// generateScaleCommand sets the replica count, to 0 for idle workloads
+func generateScaleCommand(rec *recommender.Recommendation, replicas int32) string {
 	resourceType := getResourceType(rec.WorkloadType)
+	if rec.WorkloadResource != "" {
+		resourceType = rec.WorkloadResource
+	}
 
 	return fmt.Sprintf(
-		"kubectl scale %s/%s -n %s --replicas=0",
+		"kubectl scale %s/%s -n %s --replicas=%d",
 		resourceType, // Changed from "deployment" to dynamic
 		rec.DeploymentName,
 		rec.Namespace,
+		replicas,
 	)
 }
 
+// containerFlag restricts kubectl set resources to a single container
+func containerFlag(containerName string) string {
+	if containerName == "" {
+		return ""
+	}
+	return " -c " + containerName
+}
+
 // getResourceType converts workload type to kubectl resource type
 func getResourceType(workloadType string) string {
 	switch workloadType {
@@ -62,6 +177,10 @@ func getResourceType(workloadType string) string {
 		return "daemonset"
 	case "ReplicaSet":
 		return "replicaset"
+	case "CronJob":
+		return "cronjob"
+	case "Job":
+		return "job"
 	case "Deployment":
 		return "deployment"
 	default:
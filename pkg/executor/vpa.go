@@ -0,0 +1,136 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/crd"
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Smallest bounds of generated VPAs, matching the minimum recommendation
+const (
+	minAllowedCPU    = 10               // millicores
+	minAllowedMemory = 10 * 1024 * 1024 // bytes
+)
+
+// builtinAPIVersions are the API versions of the built-in workload kinds
+var builtinAPIVersions = map[string]string{
+	"Deployment":  "apps/v1",
+	"StatefulSet": "apps/v1",
+	"DaemonSet":   "apps/v1",
+	"CronJob":     "batch/v1",
+	"Job":         "batch/v1",
+}
+
+// GenerateVPAs creates one VerticalPodAutoscaler per workload, bounding
+// each analyzed container between its P95 usage and its peak (P99) usage
+// with the safety buffer, or the recommended request if that is higher.
+// Containers without usage data are left alone by the VPA. Workloads that
+// already have a VPA are skipped, as two VPAs would fight over their pods.
+// kinds provide the API versions of custom workload kinds.
+func GenerateVPAs(recs []*recommender.Recommendation, mode kube.VPAUpdateMode, kinds []kube.WorkloadKind) []kube.VerticalPodAutoscaler {
+	var vpas []kube.VerticalPodAutoscaler
+	index := make(map[string]int)
+
+	for _, rec := range recs {
+		if rec.ContainerName == "" || (rec.Type != recommender.RightSize && rec.Type != recommender.NoAction) {
+			continue
+		}
+		if rec.VPAName != "" {
+			continue
+		}
+
+		apiVersion, ok := builtinAPIVersions[rec.WorkloadType]
+		if kind, found := kube.FindWorkloadKind(kinds, rec.WorkloadType); found {
+			apiVersion, ok = kind.GroupVersionKind().GroupVersion().String(), true
+		}
+		if !ok {
+			continue
+		}
+
+		key := rec.Namespace + "/" + rec.WorkloadType + "/" + rec.DeploymentName
+		i, exists := index[key]
+		if !exists {
+			i = len(vpas)
+			index[key] = i
+			vpas = append(vpas, newVPA(rec, apiVersion, mode))
+		}
+
+		vpas[i].Spec.ResourcePolicy.ContainerPolicies = append(vpas[i].Spec.ResourcePolicy.ContainerPolicies, containerPolicy(rec))
+	}
+	return vpas
+}
+
+// newVPA creates the VPA for the workload of rec, without container policies
+func newVPA(rec *recommender.Recommendation, apiVersion string, mode kube.VPAUpdateMode) kube.VerticalPodAutoscaler {
+	return kube.VerticalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: kube.VerticalPodAutoscalerResource.GroupVersion().String(),
+			Kind:       "VerticalPodAutoscaler",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      rec.DeploymentName,
+			Namespace: rec.Namespace,
+			Labels:    map[string]string{crd.LabelManagedBy: "k8s-cost-optimizer"},
+		},
+		Spec: kube.VPASpec{
+			TargetRef: &autoscalingv1.CrossVersionObjectReference{
+				APIVersion: apiVersion,
+				Kind:       rec.WorkloadType,
+				Name:       rec.DeploymentName,
+			},
+			UpdatePolicy:   &kube.VPAUpdatePolicy{UpdateMode: mode},
+			ResourcePolicy: &kube.VPAResourcePolicy{},
+		},
+	}
+}
+
+// containerPolicy bounds the container of rec
+func containerPolicy(rec *recommender.Recommendation) kube.VPAContainerPolicy {
+	policy := kube.VPAContainerPolicy{ContainerName: rec.ContainerName}
+	if rec.UsageCPU == 0 && rec.UsageMemory == 0 {
+		policy.Mode = kube.VPAContainerModeOff
+		return policy
+	}
+
+	buffer := math.Max(rec.SafetyBuffer, 1)
+	minCPU := max(rec.UsageCPU, minAllowedCPU)
+	minMemory := max(rec.UsageMemory, minAllowedMemory)
+	maxCPU := max(int64(float64(rec.PeakCPU)*buffer), rec.RecommendedCPU, minCPU)
+	maxMemory := max(int64(float64(rec.PeakMemory)*buffer), rec.RecommendedMemory, minMemory)
+
+	policy.MinAllowed = resourceList(minCPU, minMemory)
+	policy.MaxAllowed = resourceList(maxCPU, maxMemory)
+	return policy
+}
+
+// resourceList returns CPU in millicores and memory in Mi, rounded up
+func resourceList(millicores, bytes int64) corev1.ResourceList {
+	mebibytes := (bytes + 1024*1024 - 1) / (1024 * 1024)
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(millicores, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(mebibytes*1024*1024, resource.BinarySI),
+	}
+}
+
+// WriteVPAs writes vpas as a multi-document YAML stream for kubectl apply
+func WriteVPAs(w io.Writer, vpas []kube.VerticalPodAutoscaler) error {
+	for _, vpa := range vpas {
+		data, err := yaml.Marshal(vpa)
+		if err != nil {
+			return fmt.Errorf("failed to encode VerticalPodAutoscaler %s/%s: %w", vpa.Namespace, vpa.Name, err)
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
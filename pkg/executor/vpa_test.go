@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGenerateVPAs(t *testing.T) {
+	recs := []*recommender.Recommendation{
+		{
+			Type: recommender.RightSize, Namespace: "shop", WorkloadType: "Deployment", DeploymentName: "web", ContainerName: "app",
+			RecommendedCPU: 360, RecommendedMemory: 460 << 20,
+			UsageCPU: 200, UsageMemory: 256 << 20, PeakCPU: 300, PeakMemory: 300 << 20, SafetyBuffer: 1.8,
+		},
+		{
+			Type: recommender.NoAction, Namespace: "shop", WorkloadType: "Deployment", DeploymentName: "web", ContainerName: "istio-proxy",
+			RecommendedCPU: 100, RecommendedMemory: 128 << 20,
+			UsageCPU: 5, UsageMemory: 64 << 20, PeakCPU: 5, PeakMemory: 64 << 20, SafetyBuffer: 1.8,
+		},
+		{
+			Type: recommender.RightSize, Namespace: "shop", WorkloadType: "Rollout", DeploymentName: "canary", ContainerName: "app",
+			RecommendedCPU: 100, RecommendedMemory: 128 << 20, UsageCPU: 50, UsageMemory: 64 << 20, SafetyBuffer: 1.5,
+		},
+		{Type: recommender.ReplicaRightSize, Namespace: "shop", WorkloadType: "Deployment", DeploymentName: "web"},
+		{Type: recommender.RightSize, Namespace: "shop", WorkloadType: "Pod", DeploymentName: "debug", ContainerName: "app", UsageCPU: 50},
+		{
+			Type: recommender.RightSize, Namespace: "shop", WorkloadType: "Deployment", DeploymentName: "api", ContainerName: "app",
+			RecommendedCPU: 100, RecommendedMemory: 128 << 20, UsageCPU: 50, UsageMemory: 64 << 20, SafetyBuffer: 1.5, VPAName: "api",
+		},
+	}
+	kinds := []kube.WorkloadKind{{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout", PodTemplatePath: ".spec.template"}}
+
+	vpas := GenerateVPAs(recs, kube.VPAUpdateModeInitial, kinds)
+	if len(vpas) != 2 {
+		t.Fatalf("Expected a VPA for web and canary but not api, which has one, got %d", len(vpas))
+	}
+
+	web := vpas[0]
+	if web.Name != "web" || web.Spec.TargetRef.APIVersion != "apps/v1" || web.Spec.UpdatePolicy.UpdateMode != kube.VPAUpdateModeInitial {
+		t.Errorf("Unexpected VPA for web: %+v", web)
+	}
+	policies := web.Spec.ResourcePolicy.ContainerPolicies
+	if len(policies) != 2 {
+		t.Fatalf("Expected both containers to be bounded, got %+v", policies)
+	}
+	// P95 usage to P99 with the safety buffer, or the recommendation
+	minAllowed, maxAllowed := policies[0].MinAllowed, policies[0].MaxAllowed
+	if cpu := minAllowed[corev1.ResourceCPU]; cpu.String() != "200m" {
+		t.Errorf("Expected minAllowed cpu 200m, got %s", cpu.String())
+	}
+	if cpu := maxAllowed[corev1.ResourceCPU]; cpu.String() != "540m" {
+		t.Errorf("Expected maxAllowed cpu 540m, got %s", cpu.String())
+	}
+	if mem := maxAllowed[corev1.ResourceMemory]; mem.String() != "540Mi" {
+		t.Errorf("Expected maxAllowed memory 540Mi, got %s", mem.String())
+	}
+	if cpu := policies[1].MinAllowed[corev1.ResourceCPU]; cpu.String() != "10m" {
+		t.Errorf("Expected the minimum cpu of 10m for istio-proxy, got %s", cpu.String())
+	}
+	if cpu := policies[1].MaxAllowed[corev1.ResourceCPU]; cpu.String() != "100m" {
+		t.Errorf("Expected the recommended cpu as maxAllowed for istio-proxy, got %s", cpu.String())
+	}
+
+	if ref := vpas[1].Spec.TargetRef; ref.APIVersion != "argoproj.io/v1alpha1" || ref.Kind != "Rollout" {
+		t.Errorf("Expected the Rollout's API version, got %+v", ref)
+	}
+
+	var out bytes.Buffer
+	if err := WriteVPAs(&out, vpas); err != nil {
+		t.Fatalf("WriteVPAs failed: %v", err)
+	}
+	manifests := out.String()
+	if strings.Count(manifests, "---\n") != 2 || strings.Contains(manifests, "status:") {
+		t.Errorf("Expected two manifests without status, got:\n%s", manifests)
+	}
+	for _, expected := range []string{"apiVersion: autoscaling.k8s.io/v1", "kind: VerticalPodAutoscaler", "updateMode: Initial", "containerName: istio-proxy"} {
+		if !strings.Contains(manifests, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, manifests)
+		}
+	}
+}
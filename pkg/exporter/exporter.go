@@ -0,0 +1,196 @@
+package exporter
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cost_optimizer"
+
+// A workload can have a recommendation of each type, e.g. both
+// REPLICA_RIGHT_SIZE and HPA_ADJUST, so type is part of every series
+var workloadLabels = []string{"namespace", "workload", "kind", "container", "type"}
+
+// confidenceValues maps recommendation confidence to a gauge value so that
+// alerts can use thresholds (0 when unknown)
+var confidenceValues = map[string]float64{
+	"LOW":    1,
+	"MEDIUM": 2,
+	"HIGH":   3,
+}
+
+// Exporter publishes the recommendations of the latest scan, and counters
+// about the scans themselves, as Prometheus metrics
+type Exporter struct {
+	registry *prometheus.Registry
+
+	mu              sync.RWMutex
+	recommendations []*models.Recommendation
+
+	cpu         *prometheus.Desc
+	memory      *prometheus.Desc
+	replicas    *prometheus.Desc
+	savings     *prometheus.Desc
+	confidence  *prometheus.Desc
+	dataQuality *prometheus.Desc
+	total       *prometheus.Desc
+	count       *prometheus.Desc
+
+	scans        prometheus.Counter
+	scanErrors   prometheus.Counter
+	scanDuration prometheus.Histogram
+	lastScan     prometheus.Gauge
+}
+
+// New creates an exporter whose metrics all carry a cluster label
+func New(clusterID string) *Exporter {
+	constLabels := prometheus.Labels{"cluster": clusterID}
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, constLabels)
+	}
+	stateLabels := append(append([]string{}, workloadLabels...), "state")
+
+	e := &Exporter{
+		registry: prometheus.NewRegistry(),
+
+		cpu:         desc("workload_cpu_request_millicores", "Current and recommended CPU request per container (state=current|recommended)", stateLabels...),
+		memory:      desc("workload_memory_request_bytes", "Current and recommended memory request per container (state=current|recommended)", stateLabels...),
+		replicas:    desc("workload_replicas", "Current and recommended replicas of replica and HPA recommendations (state=current|recommended)", stateLabels...),
+		savings:     desc("workload_potential_savings_monthly_usd", "Monthly savings if the recommendation is applied", workloadLabels...),
+		confidence:  desc("workload_recommendation_confidence", "Recommendation confidence: 1=LOW, 2=MEDIUM, 3=HIGH, 0=unknown", workloadLabels...),
+		dataQuality: desc("workload_data_quality_ratio", "Completeness of the metrics behind the recommendation (0-1)", workloadLabels...),
+		total:       desc("potential_savings_monthly_usd", "Total monthly savings of all current recommendations"),
+		count:       desc("recommendations", "Number of current recommendations by type", "type"),
+
+		scans: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "scans_total",
+			Help:        "Scans run since startup",
+			ConstLabels: constLabels,
+		}),
+		scanErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "scan_errors_total",
+			Help:        "Scans that failed since startup",
+			ConstLabels: constLabels,
+		}),
+		scanDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "scan_duration_seconds",
+			Help:        "Time taken by each scan",
+			ConstLabels: constLabels,
+			Buckets:     []float64{1, 5, 10, 30, 60, 120, 300, 600},
+		}),
+		lastScan: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "last_successful_scan_timestamp_seconds",
+			Help:        "Unix time the last successful scan finished",
+			ConstLabels: constLabels,
+		}),
+	}
+
+	e.registry.MustRegister(
+		e,
+		e.scans,
+		e.scanErrors,
+		e.scanDuration,
+		e.lastScan,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return e
+}
+
+// RecordScan publishes the result of a scan. A failed scan only counts the
+// error: the previous recommendations stay published.
+func (e *Exporter) RecordScan(recommendations []*models.Recommendation, duration time.Duration, err error) {
+	e.scans.Inc()
+	e.scanDuration.Observe(duration.Seconds())
+
+	if err != nil {
+		e.scanErrors.Inc()
+		return
+	}
+	e.lastScan.SetToCurrentTime()
+
+	e.mu.Lock()
+	e.recommendations = recommendations
+	e.mu.Unlock()
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (e *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+}
+
+// Describe implements prometheus.Collector
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.cpu
+	ch <- e.memory
+	ch <- e.replicas
+	ch <- e.savings
+	ch <- e.confidence
+	ch <- e.dataQuality
+	ch <- e.total
+	ch <- e.count
+}
+
+// Collect implements prometheus.Collector. Workload series are built from
+// the latest scan on every scrape, so workloads that no longer have a
+// recommendation disappear instead of going stale.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	total := 0.0
+	counts := make(map[models.RecommendationType]int)
+
+	// A workload can be reported more than once per scan; duplicate series
+	// would fail the whole scrape, so the first one wins
+	seen := make(map[[5]string]bool)
+
+	for _, rec := range e.recommendations {
+		total += rec.SavingsMonthly
+		counts[rec.Type]++
+
+		w := rec.Workload
+		key := [5]string{w.Namespace, w.Deployment, w.Kind, w.Container, string(rec.Type)}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		labels := key[:]
+
+		gauge := func(desc *prometheus.Desc, value float64, extra ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labels[:len(labels):len(labels)], extra...)...)
+		}
+
+		// Replica and HPA recommendations carry the requests of a whole
+		// pod, which are not container requests
+		switch rec.Type {
+		case models.RecommendationReplicaRightSize, models.RecommendationHPAAdjust:
+			gauge(e.replicas, float64(rec.CurrentReplicas), "current")
+			gauge(e.replicas, float64(rec.RecommendedReplicas), "recommended")
+		default:
+			gauge(e.cpu, float64(rec.CurrentCPU), "current")
+			gauge(e.cpu, float64(rec.RecommendedCPU), "recommended")
+			gauge(e.memory, float64(rec.CurrentMemory), "current")
+			gauge(e.memory, float64(rec.RecommendedMemory), "recommended")
+		}
+		gauge(e.savings, rec.SavingsMonthly)
+		gauge(e.confidence, confidenceValues[rec.Confidence])
+		gauge(e.dataQuality, rec.DataQuality)
+	}
+
+	ch <- prometheus.MustNewConstMetric(e.total, prometheus.GaugeValue, total)
+	for recType, n := range counts {
+		ch <- prometheus.MustNewConstMetric(e.count, prometheus.GaugeValue, float64(n), string(recType))
+	}
+}
@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+)
+
+func recommendation(deployment string, savings float64, confidence string) *models.Recommendation {
+	return &models.Recommendation{
+		Type: models.RecommendationRightSize,
+		Workload: &models.Workload{
+			Namespace:  "shop",
+			Deployment: deployment,
+			Kind:       "Deployment",
+			Container:  "app",
+		},
+		CurrentCPU:        1000,
+		RecommendedCPU:    250,
+		CurrentMemory:     1 << 30,
+		RecommendedMemory: 256 << 20,
+		SavingsMonthly:    savings,
+		Confidence:        confidence,
+		DataQuality:       0.9,
+	}
+}
+
+// scrape returns the exposition text served by the exporter
+func scrape(t *testing.T, e *Exporter) string {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Scrape failed with status %d: %s", rr.Code, rr.Body.String())
+	}
+
+	body, err := io.ReadAll(rr.Body)
+	if err != nil {
+		t.Fatalf("Reading scrape failed: %v", err)
+	}
+	return string(body)
+}
+
+func assertMetrics(t *testing.T, output string, expected ...string) {
+	t.Helper()
+
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected metrics to contain %q", e)
+		}
+	}
+}
+
+func TestExporterPublishesLatestScan(t *testing.T) {
+	e := New("prod")
+
+	e.RecordScan([]*models.Recommendation{
+		recommendation("web", 12.5, "HIGH"),
+		recommendation("api", 30, "LOW"),
+	}, 3*time.Second, nil)
+
+	workload := `cluster="prod",container="app",kind="Deployment",namespace="shop",type="RIGHT_SIZE",workload="web"`
+	state := func(state string) string {
+		return `cluster="prod",container="app",kind="Deployment",namespace="shop",state="` + state + `",type="RIGHT_SIZE",workload="web"`
+	}
+	assertMetrics(t, scrape(t, e),
+		`cost_optimizer_workload_cpu_request_millicores{`+state("current")+`} 1000`,
+		`cost_optimizer_workload_cpu_request_millicores{`+state("recommended")+`} 250`,
+		`cost_optimizer_workload_memory_request_bytes{`+state("recommended")+`} 2.68435456e+08`,
+		`cost_optimizer_workload_potential_savings_monthly_usd{`+workload+`} 12.5`,
+		`cost_optimizer_workload_recommendation_confidence{`+workload+`} 3`,
+		`cost_optimizer_workload_data_quality_ratio{`+workload+`} 0.9`,
+		`cost_optimizer_potential_savings_monthly_usd{cluster="prod"} 42.5`,
+		`cost_optimizer_recommendations{cluster="prod",type="RIGHT_SIZE"} 2`,
+		`cost_optimizer_scans_total{cluster="prod"} 1`,
+		`cost_optimizer_scan_errors_total{cluster="prod"} 0`,
+		`cost_optimizer_scan_duration_seconds_count{cluster="prod"} 1`,
+	)
+
+	// The next scan replaces the workload series
+	e.RecordScan([]*models.Recommendation{recommendation("api", 30, "LOW")}, time.Second, nil)
+	output := scrape(t, e)
+	if strings.Contains(output, `workload="web"`) {
+		t.Error("Expected web to disappear once it has no recommendation")
+	}
+	assertMetrics(t, output, `cost_optimizer_potential_savings_monthly_usd{cluster="prod"} 30`)
+}
+
+func TestExporterKeepsResultsOnFailedScan(t *testing.T) {
+	e := New("prod")
+
+	e.RecordScan([]*models.Recommendation{recommendation("web", 12.5, "MEDIUM")}, time.Second, nil)
+	e.RecordScan(nil, time.Second, errors.New("metrics-server unavailable"))
+
+	assertMetrics(t, scrape(t, e),
+		`workload="web"} 12.5`,
+		`cost_optimizer_scans_total{cluster="prod"} 2`,
+		`cost_optimizer_scan_errors_total{cluster="prod"} 1`,
+	)
+}
+
+func TestExporterSkipsDuplicateWorkloads(t *testing.T) {
+	e := New("prod")
+
+	e.RecordScan([]*models.Recommendation{
+		recommendation("web", 12.5, "HIGH"),
+		recommendation("web", 5, "LOW"),
+	}, time.Second, nil)
+
+	output := scrape(t, e)
+	if strings.Count(output, "cost_optimizer_workload_potential_savings_monthly_usd{") != 1 {
+		t.Errorf("Expected a single series for the duplicated workload, got:\n%s", output)
+	}
+	assertMetrics(t, output, `cost_optimizer_recommendations{cluster="prod",type="RIGHT_SIZE"} 2`)
+}
+
+func TestExporterSeparatesRecommendationTypes(t *testing.T) {
+	e := New("prod")
+
+	replicas := &models.Recommendation{
+		Type:                models.RecommendationReplicaRightSize,
+		Workload:            &models.Workload{Namespace: "shop", Deployment: "web", Kind: "Deployment"},
+		CurrentCPU:          1500,
+		RecommendedCPU:      1500,
+		CurrentReplicas:     6,
+		RecommendedReplicas: 3,
+		SavingsMonthly:      40,
+	}
+	hpa := &models.Recommendation{
+		Type:                models.RecommendationHPAAdjust,
+		Workload:            &models.Workload{Namespace: "shop", Deployment: "web", Kind: "Deployment"},
+		CurrentCPU:          1500,
+		CurrentReplicas:     6,
+		RecommendedReplicas: 4,
+		SavingsMonthly:      25,
+	}
+	e.RecordScan([]*models.Recommendation{replicas, hpa, recommendation("web", 12.5, "HIGH")}, time.Second, nil)
+
+	series := func(recType string) string {
+		return `cluster="prod",container="",kind="Deployment",namespace="shop",type="` + recType + `",workload="web"`
+	}
+	state := func(recType, state string) string {
+		return `cluster="prod",container="",kind="Deployment",namespace="shop",state="` + state + `",type="` + recType + `",workload="web"`
+	}
+	output := scrape(t, e)
+	assertMetrics(t, output,
+		`cost_optimizer_workload_potential_savings_monthly_usd{`+series("REPLICA_RIGHT_SIZE")+`} 40`,
+		`cost_optimizer_workload_potential_savings_monthly_usd{`+series("HPA_ADJUST")+`} 25`,
+		`cost_optimizer_workload_replicas{`+state("REPLICA_RIGHT_SIZE", "recommended")+`} 3`,
+		`cost_optimizer_workload_replicas{`+state("HPA_ADJUST", "recommended")+`} 4`,
+		`cost_optimizer_workload_cpu_request_millicores{cluster="prod",container="app",kind="Deployment",namespace="shop",state="current",type="RIGHT_SIZE",workload="web"} 1000`,
+	)
+	if strings.Contains(output, `cost_optimizer_workload_cpu_request_millicores{cluster="prod",container="",`) {
+		t.Errorf("Expected no container request series for pod-level recommendations, got:\n%s", output)
+	}
+}
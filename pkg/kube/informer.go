@@ -0,0 +1,246 @@
+package kube
+
+import (
+	"context"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	autoscalinglisters "k8s.io/client-go/listers/autoscaling/v2"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// InformerLister serves objects from shared informer caches, so repeated
+// scans do not re-list the cluster
+type InformerLister struct {
+	namespaces   corelisters.NamespaceLister
+	deployments  appslisters.DeploymentLister
+	statefulSets appslisters.StatefulSetLister
+	daemonSets   appslisters.DaemonSetLister
+	replicaSets  appslisters.ReplicaSetLister
+	pods         corelisters.PodLister
+	hpas         autoscalinglisters.HorizontalPodAutoscalerLister
+	jobs         batchlisters.JobLister
+	cronJobs     batchlisters.CronJobLister
+	pdbs         policylisters.PodDisruptionBudgetLister
+
+	synced []cache.InformerSynced
+}
+
+// NewInformerLister registers the informers a scan needs with factory.
+// It must be called before factory.Start.
+func NewInformerLister(factory informers.SharedInformerFactory) *InformerLister {
+	core := factory.Core().V1()
+	apps := factory.Apps().V1()
+	hpas := factory.Autoscaling().V2().HorizontalPodAutoscalers()
+	batch := factory.Batch().V1()
+	pdbs := factory.Policy().V1().PodDisruptionBudgets()
+
+	l := &InformerLister{
+		namespaces:   core.Namespaces().Lister(),
+		deployments:  apps.Deployments().Lister(),
+		statefulSets: apps.StatefulSets().Lister(),
+		daemonSets:   apps.DaemonSets().Lister(),
+		replicaSets:  apps.ReplicaSets().Lister(),
+		pods:         core.Pods().Lister(),
+		hpas:         hpas.Lister(),
+		jobs:         batch.Jobs().Lister(),
+		cronJobs:     batch.CronJobs().Lister(),
+		pdbs:         pdbs.Lister(),
+	}
+
+	l.synced = []cache.InformerSynced{
+		core.Namespaces().Informer().HasSynced,
+		apps.Deployments().Informer().HasSynced,
+		apps.StatefulSets().Informer().HasSynced,
+		apps.DaemonSets().Informer().HasSynced,
+		apps.ReplicaSets().Informer().HasSynced,
+		core.Pods().Informer().HasSynced,
+		hpas.Informer().HasSynced,
+		batch.Jobs().Informer().HasSynced,
+		batch.CronJobs().Informer().HasSynced,
+		pdbs.Informer().HasSynced,
+	}
+
+	return l
+}
+
+// WaitForCacheSync blocks until every cache is filled or ctx is done
+func (l *InformerLister) WaitForCacheSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), l.synced...)
+}
+
+// Cached objects are shared with the informer, so every method returns
+// copies sorted by namespace and name, like a List call would. An empty
+// namespace (metav1.NamespaceAll) lists every namespace.
+
+func (l *InformerLister) Namespaces(ctx context.Context) ([]corev1.Namespace, error) {
+	objs, err := l.namespaces.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]corev1.Namespace, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+func (l *InformerLister) Namespace(ctx context.Context, name string) (*corev1.Namespace, error) {
+	obj, err := l.namespaces.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return obj.DeepCopy(), nil
+}
+
+func (l *InformerLister) Deployments(ctx context.Context, namespace string) ([]appsv1.Deployment, error) {
+	objs, err := l.deployments.Deployments(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]appsv1.Deployment, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) StatefulSets(ctx context.Context, namespace string) ([]appsv1.StatefulSet, error) {
+	objs, err := l.statefulSets.StatefulSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]appsv1.StatefulSet, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) DaemonSets(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error) {
+	objs, err := l.daemonSets.DaemonSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]appsv1.DaemonSet, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) ReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+	objs, err := l.replicaSets.ReplicaSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]appsv1.ReplicaSet, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) Pods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	objs, err := l.pods.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) HorizontalPodAutoscalers(ctx context.Context, namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	objs, err := l.hpas.HorizontalPodAutoscalers(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]autoscalingv2.HorizontalPodAutoscaler, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) Jobs(ctx context.Context, namespace string) ([]batchv1.Job, error) {
+	objs, err := l.jobs.Jobs(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]batchv1.Job, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) CronJobs(ctx context.Context, namespace string) ([]batchv1.CronJob, error) {
+	objs, err := l.cronJobs.CronJobs(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]batchv1.CronJob, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) PodDisruptionBudgets(ctx context.Context, namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	objs, err := l.pdbs.PodDisruptionBudgets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]policyv1.PodDisruptionBudget, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i].Namespace, items[i].Name, items[j].Namespace, items[j].Name) })
+	return items, nil
+}
+
+func (l *InformerLister) ReplicaSet(ctx context.Context, namespace, name string) (*appsv1.ReplicaSet, error) {
+	obj, err := l.replicaSets.ReplicaSets(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return obj.DeepCopy(), nil
+}
+
+func (l *InformerLister) Job(ctx context.Context, namespace, name string) (*batchv1.Job, error) {
+	obj, err := l.jobs.Jobs(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return obj.DeepCopy(), nil
+}
+
+// less orders objects by namespace, then name
+func less(namespaceA, nameA, namespaceB, nameB string) bool {
+	if namespaceA != namespaceB {
+		return namespaceA < namespaceB
+	}
+	return nameA < nameB
+}
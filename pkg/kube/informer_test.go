@@ -0,0 +1,142 @@
+package kube
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func names[T any](items []T, name func(T) string) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, name(item))
+	}
+	return out
+}
+
+func TestInformerListerMatchesClientLister(t *testing.T) {
+	meta := func(namespace, name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: namespace, Name: name}
+	}
+	clientset := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: meta("", "shop")},
+		&corev1.Namespace{ObjectMeta: meta("", "billing")},
+		&appsv1.Deployment{ObjectMeta: meta("shop", "web")},
+		&appsv1.Deployment{ObjectMeta: meta("shop", "api")},
+		&appsv1.Deployment{ObjectMeta: meta("billing", "invoices")},
+		&appsv1.StatefulSet{ObjectMeta: meta("shop", "db")},
+		&appsv1.DaemonSet{ObjectMeta: meta("billing", "agent")},
+		&appsv1.ReplicaSet{ObjectMeta: meta("shop", "web-7d9f8b")},
+		&corev1.Pod{ObjectMeta: meta("shop", "web-7d9f8b-abcde")},
+		&corev1.Pod{ObjectMeta: meta("billing", "agent-xyz12")},
+		&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: meta("shop", "web")},
+		&batchv1.CronJob{ObjectMeta: meta("billing", "invoice-run")},
+		&batchv1.Job{ObjectMeta: meta("billing", "invoice-run-28391040")},
+		&policyv1.PodDisruptionBudget{ObjectMeta: meta("shop", "web")},
+	)
+
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	informerLister := NewInformerLister(factory)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	factory.Start(ctx.Done())
+	defer func() {
+		cancel()
+		factory.Shutdown()
+	}()
+	if !informerLister.WaitForCacheSync(ctx) {
+		t.Fatal("Caches did not sync")
+	}
+
+	clientLister := NewClientLister(clientset)
+
+	for _, namespace := range []string{"", "shop", "billing", "missing"} {
+		check := func(kind string, fromInformer, fromClient []string) {
+			t.Helper()
+			if !reflect.DeepEqual(fromInformer, fromClient) {
+				t.Errorf("%s in %q: informer listed %v, client listed %v", kind, namespace, fromInformer, fromClient)
+			}
+		}
+
+		deployments, err := informerLister.Deployments(ctx, namespace)
+		if err != nil {
+			t.Fatalf("Deployments failed: %v", err)
+		}
+		want, _ := clientLister.Deployments(ctx, namespace)
+		name := func(d appsv1.Deployment) string { return d.Namespace + "/" + d.Name }
+		check("deployments", names(deployments, name), names(want, name))
+
+		pods, err := informerLister.Pods(ctx, namespace)
+		if err != nil {
+			t.Fatalf("Pods failed: %v", err)
+		}
+		wantPods, _ := clientLister.Pods(ctx, namespace)
+		podName := func(p corev1.Pod) string { return p.Namespace + "/" + p.Name }
+		check("pods", names(pods, podName), names(wantPods, podName))
+
+		hpas, err := informerLister.HorizontalPodAutoscalers(ctx, namespace)
+		if err != nil {
+			t.Fatalf("HorizontalPodAutoscalers failed: %v", err)
+		}
+		wantHPAs, _ := clientLister.HorizontalPodAutoscalers(ctx, namespace)
+		hpaName := func(h autoscalingv2.HorizontalPodAutoscaler) string { return h.Namespace + "/" + h.Name }
+		check("hpas", names(hpas, hpaName), names(wantHPAs, hpaName))
+
+		cronJobs, err := informerLister.CronJobs(ctx, namespace)
+		if err != nil {
+			t.Fatalf("CronJobs failed: %v", err)
+		}
+		wantCronJobs, _ := clientLister.CronJobs(ctx, namespace)
+		cronJobName := func(c batchv1.CronJob) string { return c.Namespace + "/" + c.Name }
+		check("cronjobs", names(cronJobs, cronJobName), names(wantCronJobs, cronJobName))
+
+		jobs, err := informerLister.Jobs(ctx, namespace)
+		if err != nil {
+			t.Fatalf("Jobs failed: %v", err)
+		}
+		wantJobs, _ := clientLister.Jobs(ctx, namespace)
+		jobName := func(j batchv1.Job) string { return j.Namespace + "/" + j.Name }
+		check("jobs", names(jobs, jobName), names(wantJobs, jobName))
+
+		pdbs, err := informerLister.PodDisruptionBudgets(ctx, namespace)
+		if err != nil {
+			t.Fatalf("PodDisruptionBudgets failed: %v", err)
+		}
+		wantPDBs, _ := clientLister.PodDisruptionBudgets(ctx, namespace)
+		pdbName := func(p policyv1.PodDisruptionBudget) string { return p.Namespace + "/" + p.Name }
+		check("pdbs", names(pdbs, pdbName), names(wantPDBs, pdbName))
+	}
+
+	namespaces, err := informerLister.Namespaces(ctx)
+	if err != nil {
+		t.Fatalf("Namespaces failed: %v", err)
+	}
+	nsName := func(n corev1.Namespace) string { return n.Name }
+	if got := names(namespaces, nsName); !reflect.DeepEqual(got, []string{"billing", "shop"}) {
+		t.Errorf("Expected sorted namespaces [billing shop], got %v", got)
+	}
+	if ns, err := informerLister.Namespace(ctx, "shop"); err != nil || ns.Name != "shop" {
+		t.Errorf("Expected namespace shop, got %v, %v", ns, err)
+	}
+	if _, err := informerLister.Namespace(ctx, "missing"); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected NotFound for a missing namespace, got %v", err)
+	}
+
+	// Returned objects are copies: changing them leaves the cache intact
+	deployments, _ := informerLister.Deployments(ctx, "shop")
+	deployments[0].Labels = map[string]string{"changed": "true"}
+	again, _ := informerLister.Deployments(ctx, "shop")
+	if again[0].Labels != nil {
+		t.Error("Expected cached deployment to be unaffected by caller changes")
+	}
+}
@@ -0,0 +1,131 @@
+package kube
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Lister reads the cluster objects a scan needs. An empty namespace means
+// all namespaces.
+type Lister interface {
+	Namespaces(ctx context.Context) ([]corev1.Namespace, error)
+	Namespace(ctx context.Context, name string) (*corev1.Namespace, error)
+	Deployments(ctx context.Context, namespace string) ([]appsv1.Deployment, error)
+	StatefulSets(ctx context.Context, namespace string) ([]appsv1.StatefulSet, error)
+	DaemonSets(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error)
+	ReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error)
+	Pods(ctx context.Context, namespace string) ([]corev1.Pod, error)
+	HorizontalPodAutoscalers(ctx context.Context, namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error)
+	Jobs(ctx context.Context, namespace string) ([]batchv1.Job, error)
+	CronJobs(ctx context.Context, namespace string) ([]batchv1.CronJob, error)
+	PodDisruptionBudgets(ctx context.Context, namespace string) ([]policyv1.PodDisruptionBudget, error)
+}
+
+// ClientLister lists objects straight from the API server
+type ClientLister struct {
+	clientset kubernetes.Interface
+}
+
+// NewClientLister creates a Lister that queries the API server on every call
+func NewClientLister(clientset kubernetes.Interface) *ClientLister {
+	return &ClientLister{clientset: clientset}
+}
+
+func (l *ClientLister) Namespaces(ctx context.Context) ([]corev1.Namespace, error) {
+	list, err := l.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) Namespace(ctx context.Context, name string) (*corev1.Namespace, error) {
+	return l.clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+}
+
+func (l *ClientLister) Deployments(ctx context.Context, namespace string) ([]appsv1.Deployment, error) {
+	list, err := l.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) StatefulSets(ctx context.Context, namespace string) ([]appsv1.StatefulSet, error) {
+	list, err := l.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) DaemonSets(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error) {
+	list, err := l.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) ReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+	list, err := l.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) Pods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	list, err := l.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) HorizontalPodAutoscalers(ctx context.Context, namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	list, err := l.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) Jobs(ctx context.Context, namespace string) ([]batchv1.Job, error) {
+	list, err := l.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) CronJobs(ctx context.Context, namespace string) ([]batchv1.CronJob, error) {
+	list, err := l.clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) PodDisruptionBudgets(ctx context.Context, namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	list, err := l.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (l *ClientLister) ReplicaSet(ctx context.Context, namespace, name string) (*appsv1.ReplicaSet, error) {
+	return l.clientset.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (l *ClientLister) Job(ctx context.Context, namespace, name string) (*batchv1.Job, error) {
+	return l.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+}
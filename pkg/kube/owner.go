@@ -0,0 +1,223 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxOwnerDepth bounds the walk up owner references, in case of cycles
+const maxOwnerDepth = 10
+
+// Workload identifies the controller a pod belongs to
+type Workload struct {
+	APIVersion string
+	Kind       string
+	Name       string
+}
+
+// builtinWorkloadKinds are the built-in kinds recommendations are applied
+// to. The walk stops at them even if an operator owns them.
+var builtinWorkloadKinds = map[schema.GroupKind]bool{
+	{Group: "apps", Kind: "Deployment"}:  true,
+	{Group: "apps", Kind: "StatefulSet"}: true,
+	{Group: "apps", Kind: "DaemonSet"}:   true,
+	{Group: "batch", Kind: "CronJob"}:    true,
+}
+
+// OwnerGetter reads the ReplicaSets and Jobs between pods and their
+// workloads. ClientLister gets them from the API server, InformerLister
+// from its caches.
+type OwnerGetter interface {
+	ReplicaSet(ctx context.Context, namespace, name string) (*appsv1.ReplicaSet, error)
+	Job(ctx context.Context, namespace, name string) (*batchv1.Job, error)
+}
+
+// ownerKey identifies an owner within a scan
+type ownerKey struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+// OwnerResolver finds the workload of a pod by following controller owner
+// references through the API: Pod -> ReplicaSet -> Deployment,
+// Pod -> Job -> CronJob, Pod -> ReplicaSet -> Rollout and so on. Resolved
+// owners are cached until Reset, which scans call before they start.
+type OwnerResolver struct {
+	getter  OwnerGetter
+	dynamic dynamic.Interface
+	mapper  meta.RESTMapper
+
+	// workloadKinds are the kinds the walk stops at
+	workloadKinds map[schema.GroupKind]bool
+
+	mu    sync.Mutex
+	cache map[ownerKey]Workload
+}
+
+// NewOwnerResolver creates a resolver that reads ReplicaSets and Jobs with
+// clientset. Other owners are taken as the workload unless a dynamic
+// client is configured.
+func NewOwnerResolver(clientset kubernetes.Interface) *OwnerResolver {
+	workloadKinds := make(map[schema.GroupKind]bool, len(builtinWorkloadKinds))
+	for gk := range builtinWorkloadKinds {
+		workloadKinds[gk] = true
+	}
+	return &OwnerResolver{
+		getter:        NewClientLister(clientset),
+		workloadKinds: workloadKinds,
+		cache:         make(map[ownerKey]Workload),
+	}
+}
+
+// WithOwnerGetter makes the resolver read ReplicaSets and Jobs through
+// getter, e.g. an InformerLister, so repeated scans do not get every owner
+// from the API server again
+func (r *OwnerResolver) WithOwnerGetter(getter OwnerGetter) *OwnerResolver {
+	r.getter = getter
+	return r
+}
+
+// WithDynamicClient lets the resolver read owners of any kind, e.g. custom
+// resources that own other custom resources. mapper maps their kinds to
+// API resources.
+func (r *OwnerResolver) WithDynamicClient(client dynamic.Interface, mapper meta.RESTMapper) *OwnerResolver {
+	r.dynamic = client
+	r.mapper = mapper
+	return r
+}
+
+// WithWorkloadKinds makes the walk stop at additional workload kinds, as it
+// does at Deployments, so their owners are not taken as the workload
+func (r *OwnerResolver) WithWorkloadKinds(kinds []WorkloadKind) *OwnerResolver {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range kinds {
+		r.workloadKinds[k.GroupVersionKind().GroupKind()] = true
+	}
+	r.cache = make(map[ownerKey]Workload)
+	return r
+}
+
+// Reset forgets every resolved owner
+func (r *OwnerResolver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = make(map[ownerKey]Workload)
+}
+
+// Resolve returns the top-level controller of an object in namespace with
+// the given owner references, or an empty Workload if it has none. If an
+// owner cannot be read, the owner below it is returned with the error.
+func (r *OwnerResolver) Resolve(ctx context.Context, namespace string, refs []metav1.OwnerReference) (Workload, error) {
+	ref := controllerOf(refs)
+	if ref == nil {
+		return Workload{}, nil
+	}
+
+	var visited []ownerKey
+	workload, err := r.walk(ctx, namespace, *ref, &visited)
+
+	// Failures are cached too, so each owner is only reported once a scan
+	r.mu.Lock()
+	for _, key := range visited {
+		r.cache[key] = workload
+	}
+	r.mu.Unlock()
+
+	return workload, err
+}
+
+func (r *OwnerResolver) walk(ctx context.Context, namespace string, ref metav1.OwnerReference, visited *[]ownerKey) (Workload, error) {
+	for depth := 0; ; depth++ {
+		current := Workload{APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name}
+		key := ownerKey{namespace: namespace, kind: ref.Kind, name: ref.Name}
+
+		r.mu.Lock()
+		cached, ok := r.cache[key]
+		r.mu.Unlock()
+		if ok {
+			return cached, nil
+		}
+		*visited = append(*visited, key)
+
+		gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+		r.mu.Lock()
+		stop := r.workloadKinds[gvk.GroupKind()]
+		r.mu.Unlock()
+		if stop || depth == maxOwnerDepth {
+			return current, nil
+		}
+
+		parents, err := r.ownerReferences(ctx, namespace, gvk, ref.Name)
+		if err != nil {
+			return current, fmt.Errorf("failed to get %s %s/%s: %w", ref.Kind, namespace, ref.Name, err)
+		}
+		parent := controllerOf(parents)
+		if parent == nil {
+			return current, nil
+		}
+		ref = *parent
+	}
+}
+
+// ownerReferences reads the owner references of an owner. Owners that
+// cannot be read (no dynamic client) are treated as having none.
+func (r *OwnerResolver) ownerReferences(ctx context.Context, namespace string, gvk schema.GroupVersionKind, name string) ([]metav1.OwnerReference, error) {
+	switch gvk.GroupKind() {
+	case schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}:
+		rs, err := r.getter.ReplicaSet(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		return rs.OwnerReferences, nil
+	case schema.GroupKind{Group: "batch", Kind: "Job"}:
+		job, err := r.getter.Job(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		return job.OwnerReferences, nil
+	}
+
+	if r.dynamic == nil {
+		return nil, nil
+	}
+
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	resource := r.dynamic.Resource(mapping.Resource)
+	var getter dynamic.ResourceInterface = resource
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		getter = resource.Namespace(namespace)
+	}
+	obj, err := getter.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return obj.GetOwnerReferences(), nil
+}
+
+// controllerOf returns the managing controller among refs, falling back
+// to the first reference for objects without one
+func controllerOf(refs []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
+		}
+	}
+	if len(refs) > 0 {
+		return &refs[0]
+	}
+	return nil
+}
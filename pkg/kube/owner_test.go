@@ -0,0 +1,166 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func controlledBy(apiVersion, kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, Controller: &controller}}
+}
+
+func TestOwnerResolver(t *testing.T) {
+	ctx := context.Background()
+	objectMeta := func(name string, owners []metav1.OwnerReference) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: "shop", Name: name, OwnerReferences: owners}
+	}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{ObjectMeta: objectMeta("web-7d9f8b", controlledBy("apps/v1", "Deployment", "web"))},
+		// Deployment-like names are not trusted: this ReplicaSet has no owner
+		&appsv1.ReplicaSet{ObjectMeta: objectMeta("legacy-5c6d", nil)},
+		&batchv1.Job{ObjectMeta: objectMeta("backup-28391040", controlledBy("batch/v1", "CronJob", "backup"))},
+		&batchv1.Job{ObjectMeta: objectMeta("migrate", nil)},
+		&appsv1.ReplicaSet{ObjectMeta: objectMeta("canary-6f7c8d", controlledBy("argoproj.io/v1alpha1", "Rollout", "canary"))},
+	)
+	rollout := &unstructured.Unstructured{}
+	rollout.SetAPIVersion("argoproj.io/v1alpha1")
+	rollout.SetKind("Rollout")
+	rollout.SetNamespace("shop")
+	rollout.SetName("canary")
+
+	rolloutGVR := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{rolloutGVR: "RolloutList"}, rollout)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(rolloutGVR.GroupVersion().WithKind("Rollout"), meta.RESTScopeNamespace)
+
+	resolver := NewOwnerResolver(clientset).WithDynamicClient(dynamicClient, mapper)
+
+	tests := []struct {
+		name     string
+		owners   []metav1.OwnerReference
+		expected Workload
+	}{
+		{"deployment", controlledBy("apps/v1", "ReplicaSet", "web-7d9f8b"), Workload{"apps/v1", "Deployment", "web"}},
+		{"standalone replicaset", controlledBy("apps/v1", "ReplicaSet", "legacy-5c6d"), Workload{"apps/v1", "ReplicaSet", "legacy-5c6d"}},
+		{"statefulset", controlledBy("apps/v1", "StatefulSet", "db"), Workload{"apps/v1", "StatefulSet", "db"}},
+		{"cronjob", controlledBy("batch/v1", "Job", "backup-28391040"), Workload{"batch/v1", "CronJob", "backup"}},
+		{"job", controlledBy("batch/v1", "Job", "migrate"), Workload{"batch/v1", "Job", "migrate"}},
+		{"rollout", controlledBy("apps/v1", "ReplicaSet", "canary-6f7c8d"), Workload{"argoproj.io/v1alpha1", "Rollout", "canary"}},
+		{"standalone pod", nil, Workload{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workload, err := resolver.Resolve(ctx, "shop", tt.owners)
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if workload != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, workload)
+			}
+		})
+	}
+}
+
+func TestOwnerResolverCache(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset(&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "shop",
+		Name:            "web-7d9f8b",
+		OwnerReferences: controlledBy("apps/v1", "Deployment", "web"),
+	}})
+	resolver := NewOwnerResolver(clientset)
+	pod := controlledBy("apps/v1", "ReplicaSet", "web-7d9f8b")
+
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.Resolve(ctx, "shop", pod); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+	}
+	if gets := len(clientset.Actions()); gets != 1 {
+		t.Errorf("Expected one lookup per scan, got %d", gets)
+	}
+
+	resolver.Reset()
+	if _, err := resolver.Resolve(ctx, "shop", pod); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if gets := len(clientset.Actions()); gets != 2 {
+		t.Errorf("Expected Reset to clear the cache, got %d lookups", gets)
+	}
+
+	// A missing owner is reported and the pod's direct owner is used
+	workload, err := resolver.Resolve(ctx, "shop", controlledBy("apps/v1", "ReplicaSet", "gone-1a2b"))
+	if err == nil || workload.Kind != "ReplicaSet" || workload.Name != "gone-1a2b" {
+		t.Errorf("Expected the ReplicaSet and an error, got %+v (%v)", workload, err)
+	}
+}
+
+func TestOwnerResolverWithInformers(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "shop",
+			Name:            "web-7d9f8b",
+			OwnerReferences: controlledBy("apps/v1", "Deployment", "web"),
+		}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "shop",
+			Name:            "report-28391040",
+			OwnerReferences: controlledBy("batch/v1", "CronJob", "report"),
+		}},
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	lister := NewInformerLister(factory)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	factory.Start(ctx.Done())
+	defer func() {
+		cancel()
+		factory.Shutdown()
+	}()
+	if !lister.WaitForCacheSync(ctx) {
+		t.Fatal("Caches did not sync")
+	}
+	clientset.ClearActions()
+
+	resolver := NewOwnerResolver(clientset).WithOwnerGetter(lister)
+	tests := []struct {
+		owners   []metav1.OwnerReference
+		expected Workload
+	}{
+		{controlledBy("apps/v1", "ReplicaSet", "web-7d9f8b"), Workload{"apps/v1", "Deployment", "web"}},
+		{controlledBy("batch/v1", "Job", "report-28391040"), Workload{"batch/v1", "CronJob", "report"}},
+	}
+	// Every scan resets the resolver, so each one reads the owners again
+	for scan := 0; scan < 2; scan++ {
+		resolver.Reset()
+		for _, tt := range tests {
+			workload, err := resolver.Resolve(ctx, "shop", tt.owners)
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if workload != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, workload)
+			}
+		}
+	}
+
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "get" {
+			t.Errorf("Expected owners to be read from the caches, got a get of %s", action.GetResource().Resource)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// VerticalPodAutoscalerResource identifies VerticalPodAutoscalers for the
+// dynamic client. The VPA CRD is optional, so only the fields used here are
+// declared below instead of depending on the autoscaler module.
+var VerticalPodAutoscalerResource = schema.GroupVersionResource{
+	Group:    "autoscaling.k8s.io",
+	Version:  "v1",
+	Resource: "verticalpodautoscalers",
+}
+
+// VPAUpdateMode is how a VerticalPodAutoscaler applies its recommendation
+type VPAUpdateMode string
+
+const (
+	// VPAUpdateModeOff only computes recommendations
+	VPAUpdateModeOff VPAUpdateMode = "Off"
+	// VPAUpdateModeInitial sets requests when pods are created, but never
+	// evicts running pods
+	VPAUpdateModeInitial VPAUpdateMode = "Initial"
+)
+
+// VerticalPodAutoscaler is an autoscaling.k8s.io/v1 VerticalPodAutoscaler
+type VerticalPodAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VPASpec    `json:"spec"`
+	Status *VPAStatus `json:"status,omitempty"`
+}
+
+// VPASpec selects the workload and bounds its recommendations
+type VPASpec struct {
+	TargetRef      *autoscalingv1.CrossVersionObjectReference `json:"targetRef"`
+	UpdatePolicy   *VPAUpdatePolicy                           `json:"updatePolicy,omitempty"`
+	ResourcePolicy *VPAResourcePolicy                         `json:"resourcePolicy,omitempty"`
+}
+
+// VPAUpdatePolicy sets the update mode
+type VPAUpdatePolicy struct {
+	UpdateMode VPAUpdateMode `json:"updateMode,omitempty"`
+}
+
+// VPAResourcePolicy holds the per-container bounds
+type VPAResourcePolicy struct {
+	ContainerPolicies []VPAContainerPolicy `json:"containerPolicies,omitempty"`
+}
+
+// VPAContainerPolicy bounds the recommendation for one container
+type VPAContainerPolicy struct {
+	ContainerName string              `json:"containerName"`
+	Mode          VPAContainerMode    `json:"mode,omitempty"`
+	MinAllowed    corev1.ResourceList `json:"minAllowed,omitempty"`
+	MaxAllowed    corev1.ResourceList `json:"maxAllowed,omitempty"`
+}
+
+// VPAContainerMode turns a VPA on or off for one container
+type VPAContainerMode string
+
+// VPAContainerModeOff leaves the container's requests alone
+const VPAContainerModeOff VPAContainerMode = "Off"
+
+// VPAStatus holds the recommendation computed by the VPA recommender
+type VPAStatus struct {
+	Recommendation *VPARecommendation `json:"recommendation,omitempty"`
+}
+
+// VPARecommendation is the recommendation for every container of the pods
+type VPARecommendation struct {
+	ContainerRecommendations []VPAContainerRecommendation `json:"containerRecommendations,omitempty"`
+}
+
+// VPAContainerRecommendation is the recommended requests for one container.
+// LowerBound and UpperBound are the range the VPA is confident in.
+type VPAContainerRecommendation struct {
+	ContainerName string              `json:"containerName"`
+	Target        corev1.ResourceList `json:"target"`
+	LowerBound    corev1.ResourceList `json:"lowerBound,omitempty"`
+	UpperBound    corev1.ResourceList `json:"upperBound,omitempty"`
+}
+
+// Container returns the recommendation for container, nil when the VPA has
+// none yet
+func (v *VerticalPodAutoscaler) Container(container string) *VPAContainerRecommendation {
+	if v.Status == nil || v.Status.Recommendation == nil {
+		return nil
+	}
+	for i, rec := range v.Status.Recommendation.ContainerRecommendations {
+		if rec.ContainerName == container {
+			return &v.Status.Recommendation.ContainerRecommendations[i]
+		}
+	}
+	return nil
+}
+
+// ListVerticalPodAutoscalers lists the VPAs in namespace. It returns none
+// when the VPA CRD is not installed.
+func ListVerticalPodAutoscalers(ctx context.Context, client dynamic.Interface, namespace string) ([]VerticalPodAutoscaler, error) {
+	list, err := client.Resource(VerticalPodAutoscalerResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list verticalpodautoscalers: %w", err)
+	}
+
+	vpas := make([]VerticalPodAutoscaler, 0, len(list.Items))
+	for _, item := range list.Items {
+		var vpa VerticalPodAutoscaler
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &vpa); err != nil {
+			return nil, fmt.Errorf("invalid verticalpodautoscaler %s: %w", item.GetName(), err)
+		}
+		vpas = append(vpas, vpa)
+	}
+	return vpas, nil
+}
@@ -0,0 +1,145 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// WorkloadKind is an additional kind of workload, such as an Argo Rollout,
+// that is read through the dynamic client
+type WorkloadKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+
+	// PodTemplatePath is the JSON path of the pod template, e.g.
+	// .spec.template. Only field names are supported, no filters or indexes.
+	PodTemplatePath string `json:"podTemplatePath"`
+
+	// TreatAs is the built-in kind whose safety buffers and defaults apply
+	// (default Deployment)
+	TreatAs string `json:"treatAs,omitempty"`
+}
+
+// GroupVersionKind returns the kind's group, version and kind
+func (k WorkloadKind) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: k.Group, Version: k.Version, Kind: k.Kind}
+}
+
+// Validate checks that the kind and its pod template path are set
+func (k WorkloadKind) Validate() error {
+	if k.Version == "" || k.Kind == "" {
+		return fmt.Errorf("workload kind %q: version and kind are required", k.Kind)
+	}
+	if len(k.templateFields()) == 0 {
+		return fmt.Errorf("workload kind %s: podTemplatePath is required", k.Kind)
+	}
+	for _, field := range k.templateFields() {
+		if field == "" || strings.ContainsAny(field, "[]*?@()") {
+			return fmt.Errorf("workload kind %s: unsupported podTemplatePath %q", k.Kind, k.PodTemplatePath)
+		}
+	}
+	return nil
+}
+
+// templateFields splits PodTemplatePath into field names, accepting both
+// .spec.template and {.spec.template}
+func (k WorkloadKind) templateFields() []string {
+	path := strings.TrimSpace(k.PodTemplatePath)
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// PodTemplatePointer returns the JSON pointer of the pod template for JSON
+// patches, e.g. /spec/template
+func (k WorkloadKind) PodTemplatePointer() string {
+	return "/" + strings.Join(k.templateFields(), "/")
+}
+
+// PodTemplate reads the pod template of obj
+func (k WorkloadKind) PodTemplate(obj *unstructured.Unstructured) (corev1.PodTemplateSpec, error) {
+	var template corev1.PodTemplateSpec
+
+	fields, found, err := unstructured.NestedMap(obj.Object, k.templateFields()...)
+	if err != nil {
+		return template, fmt.Errorf("%s %s: %w", k.Kind, obj.GetName(), err)
+	}
+	if !found {
+		return template, fmt.Errorf("%s %s has no pod template at %s", k.Kind, obj.GetName(), k.PodTemplatePath)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(fields, &template); err != nil {
+		return template, fmt.Errorf("%s %s: invalid pod template: %w", k.Kind, obj.GetName(), err)
+	}
+	return template, nil
+}
+
+// SetPodTemplate replaces the pod template of obj
+func (k WorkloadKind) SetPodTemplate(obj *unstructured.Unstructured, template *corev1.PodTemplateSpec) error {
+	fields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(template)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", k.Kind, obj.GetName(), err)
+	}
+	return unstructured.SetNestedMap(obj.Object, fields, k.templateFields()...)
+}
+
+// FindWorkloadKind returns the configured kind named kind
+func FindWorkloadKind(kinds []WorkloadKind, kind string) (WorkloadKind, bool) {
+	for _, k := range kinds {
+		if k.Kind == kind {
+			return k, true
+		}
+	}
+	return WorkloadKind{}, false
+}
+
+// LoadWorkloadKinds reads a YAML or JSON list of workload kinds from a file
+func LoadWorkloadKinds(path string) ([]WorkloadKind, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workload kinds file: %w", err)
+	}
+	defer f.Close()
+
+	kinds, err := DecodeWorkloadKinds(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return kinds, nil
+}
+
+// DecodeWorkloadKinds reads a YAML or JSON list of workload kinds
+func DecodeWorkloadKinds(r io.Reader) ([]WorkloadKind, error) {
+	var kinds []WorkloadKind
+	err := yaml.NewYAMLOrJSONDecoder(r, 4096).Decode(&kinds)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse workload kinds: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range kinds {
+		if err := kinds[i].Validate(); err != nil {
+			return nil, err
+		}
+		if seen[kinds[i].Kind] {
+			return nil, fmt.Errorf("workload kind %s is listed twice", kinds[i].Kind)
+		}
+		seen[kinds[i].Kind] = true
+		if kinds[i].TreatAs == "" {
+			kinds[i].TreatAs = "Deployment"
+		}
+	}
+	return kinds, nil
+}
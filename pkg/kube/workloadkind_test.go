@@ -0,0 +1,89 @@
+package kube
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDecodeWorkloadKinds(t *testing.T) {
+	kinds, err := DecodeWorkloadKinds(strings.NewReader(`
+- group: argoproj.io
+  version: v1alpha1
+  kind: Rollout
+  podTemplatePath: "{.spec.template}"
+- group: example.com
+  version: v1
+  kind: Worker
+  podTemplatePath: .spec.worker.template
+  treatAs: StatefulSet
+`))
+	if err != nil {
+		t.Fatalf("DecodeWorkloadKinds failed: %v", err)
+	}
+	if len(kinds) != 2 {
+		t.Fatalf("Expected 2 kinds, got %d", len(kinds))
+	}
+	if kinds[0].TreatAs != "Deployment" || kinds[0].PodTemplatePointer() != "/spec/template" {
+		t.Errorf("Unexpected Rollout kind: %+v (%s)", kinds[0], kinds[0].PodTemplatePointer())
+	}
+	if kinds[1].TreatAs != "StatefulSet" || kinds[1].PodTemplatePointer() != "/spec/worker/template" {
+		t.Errorf("Unexpected Worker kind: %+v (%s)", kinds[1], kinds[1].PodTemplatePointer())
+	}
+
+	invalid := []string{
+		`[{version: v1, kind: Worker}]`,
+		`[{version: v1, kind: Worker, podTemplatePath: ".spec.templates[0]"}]`,
+		`[{version: v1, kind: Worker, podTemplatePath: .spec.template}, {version: v2, kind: Worker, podTemplatePath: .spec.template}]`,
+	}
+	for _, doc := range invalid {
+		if _, err := DecodeWorkloadKinds(strings.NewReader(doc)); err == nil {
+			t.Errorf("Expected an error for %s", doc)
+		}
+	}
+}
+
+func TestWorkloadKindPodTemplate(t *testing.T) {
+	kind := WorkloadKind{Version: "v1alpha1", Kind: "Rollout", PodTemplatePath: ".spec.template"}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"strategy": map[string]interface{}{"canary": map[string]interface{}{}},
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"name": "app", "image": "web:1.2"}},
+				},
+			},
+		},
+	}}
+
+	template, err := kind.PodTemplate(obj)
+	if err != nil {
+		t.Fatalf("PodTemplate failed: %v", err)
+	}
+	if len(template.Spec.Containers) != 1 || template.Spec.Containers[0].Image != "web:1.2" {
+		t.Fatalf("Unexpected template: %+v", template)
+	}
+
+	template.Spec.Containers[0].Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}
+	if err := kind.SetPodTemplate(obj, &template); err != nil {
+		t.Fatalf("SetPodTemplate failed: %v", err)
+	}
+
+	updated, err := kind.PodTemplate(obj)
+	if err != nil {
+		t.Fatalf("PodTemplate failed: %v", err)
+	}
+	if cpu := updated.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU]; cpu.String() != "250m" {
+		t.Errorf("Expected the updated request, got %s", cpu.String())
+	}
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "strategy"); !found {
+		t.Error("Expected the rest of the object to be kept")
+	}
+
+	if _, err := (WorkloadKind{Kind: "Rollout", PodTemplatePath: ".spec.missing"}).PodTemplate(obj); err == nil {
+		t.Error("Expected an error for a missing pod template")
+	}
+}
@@ -4,58 +4,58 @@ import "time"
 
 // SavingsTrend represents cost savings trend over time
 type SavingsTrend struct {
-	Namespace             string
-	Days                  int
-	DataPoints            []SavingsDataPoint
-	TotalPotentialSavings float64
-	TotalRealizedSavings  float64
-	TotalRecommendations  int
-	TotalApplied          int
-	AdoptionRate          float64
+	Namespace             string             `json:"namespace"`
+	Days                  int                `json:"days"`
+	DataPoints            []SavingsDataPoint `json:"data_points"`
+	TotalPotentialSavings float64            `json:"total_potential_savings"`
+	TotalRealizedSavings  float64            `json:"total_realized_savings"`
+	TotalRecommendations  int                `json:"total_recommendations"`
+	TotalApplied          int                `json:"total_applied"`
+	AdoptionRate          float64            `json:"adoption_rate"`
 }
 
 // SavingsDataPoint represents a single day's data
 type SavingsDataPoint struct {
-	Date                time.Time
-	RecommendationCount int
-	PotentialSavings    float64
-	AppliedCount        int
-	RealizedSavings     float64
+	Date                time.Time `json:"date"`
+	RecommendationCount int       `json:"recommendation_count"`
+	PotentialSavings    float64   `json:"potential_savings"`
+	AppliedCount        int       `json:"applied_count"`
+	RealizedSavings     float64   `json:"realized_savings"`
 }
 
 // DashboardStats represents aggregate statistics for dashboard
 type DashboardStats struct {
-	Namespace                   string
-	PeriodDays                  int
-	TotalRecommendations        int
-	AppliedCount                int
-	PotentialSavings            float64
-	RealizedSavings             float64
-	UniqueWorkloads             int
-	AvgSavingsPerRecommendation float64
-	AdoptionRate                float64
+	Namespace                   string  `json:"namespace"`
+	PeriodDays                  int     `json:"period_days"`
+	TotalRecommendations        int     `json:"total_recommendations"`
+	AppliedCount                int     `json:"applied_count"`
+	PotentialSavings            float64 `json:"potential_savings"`
+	RealizedSavings             float64 `json:"realized_savings"`
+	UniqueWorkloads             int     `json:"unique_workloads"`
+	AvgSavingsPerRecommendation float64 `json:"avg_savings_per_recommendation"`
+	AdoptionRate                float64 `json:"adoption_rate"`
 }
 
 // PerformanceComparison compares current vs previous period
 type PerformanceComparison struct {
-	CurrentPeriodDays       int
-	CurrentRecommendations  int
-	CurrentSavings          float64
-	PreviousRecommendations int
-	PreviousSavings         float64
-	RecommendationChange    float64
-	SavingsChange           float64
+	CurrentPeriodDays       int     `json:"current_period_days"`
+	CurrentRecommendations  int     `json:"current_recommendations"`
+	CurrentSavings          float64 `json:"current_savings"`
+	PreviousRecommendations int     `json:"previous_recommendations"`
+	PreviousSavings         float64 `json:"previous_savings"`
+	RecommendationChange    float64 `json:"recommendation_change"`
+	SavingsChange           float64 `json:"savings_change"`
 }
 
 // WorkloadTrend tracks a single workload over time
 type WorkloadTrend struct {
-	Namespace            string
-	Deployment           string
-	Recommendations      []*Recommendation
-	FirstSeen            time.Time
-	LastSeen             time.Time
-	TotalRecommendations int
-	AppliedCount         int
-	AvgSavings           float64
-	Status               string
+	Namespace            string            `json:"namespace"`
+	Deployment           string            `json:"deployment"`
+	Recommendations      []*Recommendation `json:"recommendations"`
+	FirstSeen            time.Time         `json:"first_seen"`
+	LastSeen             time.Time         `json:"last_seen"`
+	TotalRecommendations int               `json:"total_recommendations"`
+	AppliedCount         int               `json:"applied_count"`
+	AvgSavings           float64           `json:"avg_savings"`
+	Status               string            `json:"status"`
 }
@@ -6,54 +6,102 @@ import "time"
 type RecommendationType string
 
 const (
-	RecommendationRightSize RecommendationType = "RIGHT_SIZE"
-	RecommendationScaleDown RecommendationType = "SCALE_DOWN"
-	RecommendationNoAction  RecommendationType = "NO_ACTION"
+	RecommendationRightSize        RecommendationType = "RIGHT_SIZE"
+	RecommendationScaleDown        RecommendationType = "SCALE_DOWN"
+	RecommendationReplicaRightSize RecommendationType = "REPLICA_RIGHT_SIZE"
+	RecommendationHPAAdjust        RecommendationType = "HPA_ADJUST"
+	RecommendationNoAction         RecommendationType = "NO_ACTION"
 )
 
 // Recommendation represents an optimization recommendation
 type Recommendation struct {
-	ID          string
-	Type        RecommendationType
-	Workload    *Workload
-	Environment string
+	ID          string             `json:"id"`
+	Type        RecommendationType `json:"type"`
+	Workload    *Workload          `json:"workload"`
+	Environment string             `json:"environment"`
 
 	// Current state
-	CurrentCPU    int64
-	CurrentMemory int64
+	CurrentCPU    int64 `json:"current_cpu"`
+	CurrentMemory int64 `json:"current_memory"`
+
+	CurrentCPULimit    int64 `json:"current_cpu_limit"` // 0 means no limit
+	CurrentMemoryLimit int64 `json:"current_memory_limit"`
 
 	// Recommended state
-	RecommendedCPU    int64
-	RecommendedMemory int64
+	RecommendedCPU    int64 `json:"recommended_cpu"`
+	RecommendedMemory int64 `json:"recommended_memory"`
+
+	RecommendedCPULimit    int64 `json:"recommended_cpu_limit"` // 0 means no limit
+	RecommendedMemoryLimit int64 `json:"recommended_memory_limit"`
+
+	// Replica counts of REPLICA_RIGHT_SIZE recommendations, and the
+	// projected ones of HPA_ADJUST recommendations (0 otherwise)
+	CurrentReplicas     int32 `json:"current_replicas,omitempty"`
+	RecommendedReplicas int32 `json:"recommended_replicas,omitempty"`
+
+	// HPA changes of HPA_ADJUST recommendations
+	HPAName                string `json:"hpa_name,omitempty"`
+	CurrentTargetCPU       int32  `json:"current_target_cpu,omitempty"`     // percent
+	RecommendedTargetCPU   int32  `json:"recommended_target_cpu,omitempty"` // percent
+	CurrentMinReplicas     int32  `json:"current_min_replicas,omitempty"`
+	RecommendedMinReplicas int32  `json:"recommended_min_replicas,omitempty"`
+
+	// Target of an existing VerticalPodAutoscaler for the container, shown
+	// next to ours. Not stored: the reason records it.
+	VPAName   string `json:"vpa_name,omitempty"`
+	VPACPU    int64  `json:"vpa_cpu,omitempty"`
+	VPAMemory int64  `json:"vpa_memory,omitempty"`
 
 	// Analysis
-	Reason         string
-	SavingsMonthly float64
-	Impact         string // HIGH, MEDIUM, LOW
-	Risk           RiskLevel
+	Reason         string    `json:"reason"`
+	SavingsMonthly float64   `json:"savings_monthly"`
+	Impact         string    `json:"impact"` // HIGH, MEDIUM, LOW
+	Risk           RiskLevel `json:"risk"`
 
 	// Week 9 Day 2: Confidence scoring
-	Confidence        string  // HIGH, MEDIUM, LOW
-	DataQuality       float64 // 0.0-1.0
-	PatternInfo       string  // Human-readable pattern description
-	HasSufficientData bool
+	Confidence        string  `json:"confidence"`   // HIGH, MEDIUM, LOW
+	DataQuality       float64 `json:"data_quality"` // 0.0-1.0
+	PatternInfo       string  `json:"pattern_info"` // Human-readable pattern description
+	HasSufficientData bool    `json:"has_sufficient_data"`
 
 	// Generated command
-	Command string
+	Command string `json:"command,omitempty"`
 
 	// Metadata
-	CreatedAt time.Time
-	AppliedAt *time.Time
-	AppliedBy string
+	CreatedAt time.Time  `json:"created_at"`
+	AppliedAt *time.Time `json:"applied_at"`
+	AppliedBy string     `json:"applied_by,omitempty"`
 }
 
+// Audit actions and statuses recorded in the audit log
+const (
+	AuditActionApplied    = "APPLIED"
+	AuditActionRolledBack = "ROLLED_BACK"
+
+	AuditStatusSuccess = "SUCCESS"
+	AuditStatusFailed  = "FAILED"
+)
+
 // AuditEntry represents an action taken
 type AuditEntry struct {
+	ID               string    `json:"id"`
+	RecommendationID string    `json:"recommendation_id"`
+	Action           string    `json:"action"` // APPLIED, ROLLED_BACK
+	Status           string    `json:"status"` // SUCCESS, FAILED
+	ErrorMessage     string    `json:"error_message,omitempty"`
+	ExecutedBy       string    `json:"executed_by"`
+	ExecutedAt       time.Time `json:"executed_at"`
+}
+
+// ResourceSnapshot captures a workload's state before a recommendation was
+// applied so that the change can be rolled back exactly
+type ResourceSnapshot struct {
 	ID               string
 	RecommendationID string
-	Action           string // APPLIED, ROLLED_BACK
-	Status           string // SUCCESS, FAILED
-	ErrorMessage     string
-	ExecutedBy       string
-	ExecutedAt       time.Time
+	Namespace        string
+	WorkloadKind     string
+	WorkloadName     string
+	Resources        []byte // JSON map of container name to its resources block
+	Replicas         *int32 // Set when the recommendation changed the replica count
+	CreatedAt        time.Time
 }
@@ -4,11 +4,13 @@ import "time"
 
 // Workload represents a Kubernetes workload
 type Workload struct {
-	Namespace   string
-	Deployment  string
-	Pod         string
-	Container   string
-	ClusterID   string
+	Namespace  string `json:"namespace"`
+	Deployment string `json:"deployment"`
+	Kind       string `json:"kind,omitempty"` // Deployment, StatefulSet, DaemonSet, ...
+	Pod        string `json:"pod,omitempty"`
+	Container  string `json:"container,omitempty"`
+	ClusterID  string `json:"cluster_id,omitempty"`
+	Owner      string `json:"owner,omitempty"` // cost-optimizer.io/owner annotation
 }
 
 // Metrics represents usage metrics for a workload
@@ -41,6 +43,20 @@ type Sample struct {
 	Value     float64
 }
 
+// UsageSample is the usage of a container at one time, as collected from
+// the metrics API by cost-scan collect
+type UsageSample struct {
+	ClusterID string
+	Namespace string
+	Kind      string // of the workload, or Pod for standalone pods
+	Workload  string
+	Pod       string
+	Container string
+	Timestamp time.Time
+	CPU       int64 // millicores
+	Memory    int64 // bytes
+}
+
 // RiskLevel represents the risk of applying a recommendation
 type RiskLevel string
 
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Annotations read from workloads and namespaces. Workload annotations win
+// over namespace annotations, which win over every OptimizationPolicy.
+const (
+	// AnnotationIgnore set to "true" skips the workload (or every workload
+	// of the namespace)
+	AnnotationIgnore = "cost-optimizer.io/ignore"
+
+	AnnotationSafetyBuffer = "cost-optimizer.io/safety-buffer"
+	AnnotationMinCPU       = "cost-optimizer.io/min-cpu"
+	AnnotationMinMemory    = "cost-optimizer.io/min-memory"
+
+	// AnnotationOwner is recorded on recommendations, e.g. a team or email
+	AnnotationOwner = "cost-optimizer.io/owner"
+)
+
+// Ignored reports whether annotations opt out of recommendations
+func Ignored(annotations map[string]string) bool {
+	ignore, err := strconv.ParseBool(annotations[AnnotationIgnore])
+	return err == nil && ignore
+}
+
+// Owner returns the owner annotation of the workload, falling back to the
+// namespace
+func Owner(namespaceAnnotations, workloadAnnotations map[string]string) string {
+	if owner := workloadAnnotations[AnnotationOwner]; owner != "" {
+		return owner
+	}
+	return namespaceAnnotations[AnnotationOwner]
+}
+
+// ValidateAnnotations checks the override annotations; invalid ones are
+// ignored when resolving settings
+func ValidateAnnotations(annotations map[string]string) error {
+	_, err := parseAnnotations(annotations)
+	return err
+}
+
+// annotationOverride is one parsed override annotation
+type annotationOverride struct {
+	name  string // annotation name without the cost-optimizer.io/ prefix
+	value string
+	apply func(*Settings)
+}
+
+// parseAnnotations returns the valid overrides in a fixed order, and an
+// error describing the first invalid one
+func parseAnnotations(annotations map[string]string) ([]annotationOverride, error) {
+	var overrides []annotationOverride
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if v, ok := annotations[AnnotationSafetyBuffer]; ok {
+		buffer, err := strconv.ParseFloat(v, 64)
+		if err != nil || buffer < 1.0 {
+			fail(fmt.Errorf("%s must be a number >= 1.0, got %q", AnnotationSafetyBuffer, v))
+		} else {
+			overrides = append(overrides, annotationOverride{"safety-buffer", v, func(s *Settings) {
+				s.SafetyBuffer = &buffer
+			}})
+		}
+	}
+	if v, ok := annotations[AnnotationMinCPU]; ok {
+		q, err := resource.ParseQuantity(v)
+		if err != nil || q.Sign() < 0 {
+			fail(fmt.Errorf("%s must be a CPU quantity, got %q", AnnotationMinCPU, v))
+		} else {
+			overrides = append(overrides, annotationOverride{"min-cpu", v, func(s *Settings) {
+				s.MinCPU = q.MilliValue()
+			}})
+		}
+	}
+	if v, ok := annotations[AnnotationMinMemory]; ok {
+		q, err := resource.ParseQuantity(v)
+		if err != nil || q.Sign() < 0 {
+			fail(fmt.Errorf("%s must be a memory quantity, got %q", AnnotationMinMemory, v))
+		} else {
+			overrides = append(overrides, annotationOverride{"min-memory", v, func(s *Settings) {
+				s.MinMemory = q.Value()
+			}})
+		}
+	}
+
+	return overrides, firstErr
+}
+
+// applyAnnotations applies the valid override annotations to s
+func (s *Settings) applyAnnotations(annotations map[string]string) {
+	overrides, _ := parseAnnotations(annotations)
+	for _, o := range overrides {
+		o.apply(s)
+		s.Overrides = append(s.Overrides, fmt.Sprintf("%s=%s", o.name, o.value))
+	}
+}
@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveAnnotations(t *testing.T) {
+	set := loadSet(t)
+	target := Target{
+		Namespace:            "shop",
+		NamespaceLabels:      map[string]string{"environment": "production"},
+		Kind:                 "Deployment",
+		Name:                 "web",
+		NamespaceAnnotations: map[string]string{AnnotationSafetyBuffer: "1.5", AnnotationOwner: "shop-team"},
+		Annotations:          map[string]string{AnnotationSafetyBuffer: "1.2", AnnotationMinMemory: "512Mi"},
+	}
+
+	settings := set.Resolve(target)
+	if *settings.SafetyBuffer != 1.2 {
+		t.Errorf("Expected the workload annotation to win, got %.1f", *settings.SafetyBuffer)
+	}
+	if settings.ClampMemory(1) != 512<<20 {
+		t.Errorf("Expected memory floor of 512Mi, got %d", settings.ClampMemory(1))
+	}
+	if *settings.MinDataDays != 7 {
+		t.Errorf("Expected policy settings to be kept, got %+v", settings)
+	}
+	expected := []string{"safety-buffer=1.5", "safety-buffer=1.2", "min-memory=512Mi"}
+	if !reflect.DeepEqual(settings.Overrides, expected) {
+		t.Errorf("Expected overrides %v, got %v", expected, settings.Overrides)
+	}
+
+	if owner := Owner(target.NamespaceAnnotations, target.Annotations); owner != "shop-team" {
+		t.Errorf("Expected the namespace owner, got %q", owner)
+	}
+}
+
+func TestAnnotationsValidate(t *testing.T) {
+	var set *Set
+	invalid := map[string]string{AnnotationSafetyBuffer: "0.5", AnnotationMinCPU: "lots", AnnotationIgnore: "yes"}
+
+	if err := ValidateAnnotations(invalid); err == nil {
+		t.Error("Expected invalid annotations to be reported")
+	}
+	if settings := set.Resolve(Target{Annotations: invalid}); settings.SafetyBuffer != nil || len(settings.Overrides) != 0 {
+		t.Errorf("Expected invalid annotations to be ignored, got %+v", settings)
+	}
+	if Ignored(invalid) || !Ignored(map[string]string{AnnotationIgnore: "true"}) {
+		t.Error("Expected only a true ignore annotation to opt out")
+	}
+}
@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/crd"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// LoadFile reads OptimizationPolicy documents from a YAML file. Documents
+// are separated by "---", as with kubectl apply.
+func LoadFile(path string) ([]OptimizationPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open policy file: %w", err)
+	}
+	defer f.Close()
+
+	policies, err := Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policies, nil
+}
+
+// Decode reads OptimizationPolicy documents from YAML or JSON
+func Decode(r io.Reader) ([]OptimizationPolicy, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+
+	var policies []OptimizationPolicy
+	for {
+		var p OptimizationPolicy
+		err := decoder.Decode(&p)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse policy: %w", err)
+		}
+		// Empty documents, e.g. a trailing "---"
+		if p.Kind == "" && p.Name == "" {
+			continue
+		}
+		if p.Kind != Kind {
+			return nil, fmt.Errorf("unexpected kind %q (expected %s)", p.Kind, Kind)
+		}
+		if p.APIVersion != "" && p.APIVersion != crd.Group+"/"+crd.Version {
+			return nil, fmt.Errorf("policy %s: unsupported apiVersion %q", p.Name, p.APIVersion)
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// LoadCluster lists the OptimizationPolicy resources of the cluster,
+// ordered by name. It returns nothing if the CRD is not installed.
+func LoadCluster(ctx context.Context, client dynamic.Interface) ([]OptimizationPolicy, error) {
+	list, err := client.Resource(Resource).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OptimizationPolicies: %w", err)
+	}
+
+	policies := make([]OptimizationPolicy, 0, len(list.Items))
+	for _, item := range list.Items {
+		var p OptimizationPolicy
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &p); err != nil {
+			return nil, fmt.Errorf("failed to read OptimizationPolicy %s: %w", item.GetName(), err)
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
@@ -0,0 +1,356 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/crd"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Kind of OptimizationPolicy documents and resources
+const Kind = "OptimizationPolicy"
+
+// Resource identifies the cluster-scoped OptimizationPolicy CRD for the
+// dynamic client
+var Resource = schema.GroupVersionResource{
+	Group:    crd.Group,
+	Version:  crd.Version,
+	Resource: "optimizationpolicies",
+}
+
+// Recommendation types a policy can allow
+const (
+	TypeRightSize        = "RIGHT_SIZE"
+	TypeScaleDown        = "SCALE_DOWN"
+	TypeReplicaRightSize = "REPLICA_RIGHT_SIZE"
+	TypeHPAAdjust        = "HPA_ADJUST"
+)
+
+// OptimizationPolicy overrides how recommendations are made for the
+// workloads it selects. The same document is read from policy files and
+// from OptimizationPolicy resources.
+type OptimizationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec Spec `json:"spec"`
+}
+
+// Spec holds the selector and the settings it overrides. Unset settings
+// keep the built-in workload and environment defaults.
+type Spec struct {
+	Selector Selector `json:"selector,omitempty"`
+
+	// SafetyBuffer multiplies observed usage, replacing the workload,
+	// environment and usage pattern buffers
+	SafetyBuffer *float64 `json:"safetyBuffer,omitempty"`
+
+	// MinDataDays is the history required before anything is recommended
+	MinDataDays *int `json:"minDataDays,omitempty"`
+
+	// Floors and ceilings for recommended requests
+	MinCPU    *resource.Quantity `json:"minCPU,omitempty"`
+	MaxCPU    *resource.Quantity `json:"maxCPU,omitempty"`
+	MinMemory *resource.Quantity `json:"minMemory,omitempty"`
+	MaxMemory *resource.Quantity `json:"maxMemory,omitempty"`
+
+	// AllowedTypes limits recommendations to RIGHT_SIZE, SCALE_DOWN,
+	// REPLICA_RIGHT_SIZE and/or HPA_ADJUST
+	AllowedTypes []string `json:"allowedTypes,omitempty"`
+
+	// Optimize enables or disables recommendations entirely, overriding the
+	// workload type default (e.g. DaemonSets are off by default)
+	Optimize *bool `json:"optimize,omitempty"`
+}
+
+// Selector picks the workloads a policy applies to. Every field that is
+// set must match; an empty selector matches everything.
+type Selector struct {
+	Namespaces        []string              `json:"namespaces,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// LabelSelector matches the labels of the workload's pods
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+
+	// Workloads are names, optionally qualified by kind (StatefulSet/db)
+	Workloads []string `json:"workloads,omitempty"`
+}
+
+// Target describes the workload a recommendation is being made for
+type Target struct {
+	Namespace       string
+	NamespaceLabels map[string]string
+	Kind            string
+	Name            string
+	Labels          map[string]string
+
+	// Override annotations of the namespace and workload
+	NamespaceAnnotations map[string]string
+	Annotations          map[string]string
+}
+
+// Settings are the merged overrides of every policy matching a target.
+// Zero values and nil pointers mean the built-in defaults apply.
+type Settings struct {
+	// Policies are the names of the matching policies, least specific first
+	Policies []string
+
+	// Overrides describe the annotations applied, e.g. safety-buffer=2.0
+	Overrides []string
+
+	SafetyBuffer *float64
+	MinDataDays  *int
+
+	MinCPU    int64 // millicores
+	MaxCPU    int64 // millicores
+	MinMemory int64 // bytes
+	MaxMemory int64 // bytes
+
+	// AllowedTypes is nil when every type is allowed
+	AllowedTypes []string
+
+	Optimize *bool
+}
+
+// Allows reports whether the recommendation type may be recommended
+func (s Settings) Allows(recType string) bool {
+	if s.AllowedTypes == nil {
+		return true
+	}
+	for _, allowed := range s.AllowedTypes {
+		if allowed == recType {
+			return true
+		}
+	}
+	return false
+}
+
+// ClampCPU applies the CPU floor and ceiling to a recommended request
+func (s Settings) ClampCPU(millicores int64) int64 {
+	return clamp(millicores, s.MinCPU, s.MaxCPU)
+}
+
+// ClampMemory applies the memory floor and ceiling to a recommended request
+func (s Settings) ClampMemory(bytes int64) int64 {
+	return clamp(bytes, s.MinMemory, s.MaxMemory)
+}
+
+// Name describes the matching policies for reasons, e.g. "team-a, db"
+func (s Settings) Name() string {
+	return strings.Join(s.Policies, ", ")
+}
+
+// clamp applies the ceiling first, so a floor set by an annotation wins
+// over a policy ceiling below it
+func clamp(value, min, max int64) int64 {
+	if max > 0 && value > max {
+		value = max
+	}
+	if min > 0 && value < min {
+		value = min
+	}
+	return value
+}
+
+// Specificity of a selector; more specific policies win
+const (
+	specificityDefault = iota
+	specificityNamespace
+	specificityLabels
+	specificityWorkload
+)
+
+// compiled is a validated policy with parsed selectors
+type compiled struct {
+	policy            OptimizationPolicy
+	namespaceSelector labels.Selector
+	labelSelector     labels.Selector
+	specificity       int
+}
+
+// Set resolves the policies that apply to a workload. A nil *Set has no
+// policies but still applies annotations.
+type Set struct {
+	policies []compiled
+}
+
+// NewSet validates policies and prepares them for matching. When policies
+// are equally specific, later ones override earlier ones.
+func NewSet(policies []OptimizationPolicy) (*Set, error) {
+	set := &Set{}
+	for _, p := range policies {
+		c, err := compile(p)
+		if err != nil {
+			return nil, err
+		}
+		set.policies = append(set.policies, c)
+	}
+	return set, nil
+}
+
+// Validate checks a policy the way NewSet does, so that callers can skip
+// an invalid policy instead of rejecting the whole set
+func Validate(p OptimizationPolicy) error {
+	_, err := compile(p)
+	return err
+}
+
+// Len returns the number of policies in the set
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.policies)
+}
+
+// Resolve merges the settings of every policy matching target, applying
+// the least specific first: namespace selectors, then label selectors,
+// then workload names, then namespace and workload annotations
+func (s *Set) Resolve(target Target) Settings {
+	var settings Settings
+
+	if s != nil {
+		for specificity := specificityDefault; specificity <= specificityWorkload; specificity++ {
+			for _, c := range s.policies {
+				if c.specificity == specificity && c.matches(target) {
+					settings.apply(c.policy)
+				}
+			}
+		}
+	}
+
+	settings.applyAnnotations(target.NamespaceAnnotations)
+	settings.applyAnnotations(target.Annotations)
+	return settings
+}
+
+func (s *Settings) apply(p OptimizationPolicy) {
+	spec := p.Spec
+	s.Policies = append(s.Policies, p.Name)
+
+	if spec.SafetyBuffer != nil {
+		s.SafetyBuffer = spec.SafetyBuffer
+	}
+	if spec.MinDataDays != nil {
+		s.MinDataDays = spec.MinDataDays
+	}
+	if spec.MinCPU != nil {
+		s.MinCPU = spec.MinCPU.MilliValue()
+	}
+	if spec.MaxCPU != nil {
+		s.MaxCPU = spec.MaxCPU.MilliValue()
+	}
+	if spec.MinMemory != nil {
+		s.MinMemory = spec.MinMemory.Value()
+	}
+	if spec.MaxMemory != nil {
+		s.MaxMemory = spec.MaxMemory.Value()
+	}
+	if spec.AllowedTypes != nil {
+		s.AllowedTypes = spec.AllowedTypes
+	}
+	if spec.Optimize != nil {
+		s.Optimize = spec.Optimize
+	}
+}
+
+func compile(p OptimizationPolicy) (compiled, error) {
+	c := compiled{policy: p}
+	spec := p.Spec
+
+	if p.Name == "" {
+		return c, fmt.Errorf("%s is missing metadata.name", Kind)
+	}
+	if spec.SafetyBuffer != nil && *spec.SafetyBuffer < 1.0 {
+		return c, fmt.Errorf("policy %s: safetyBuffer must be >= 1.0", p.Name)
+	}
+	if spec.MinDataDays != nil && *spec.MinDataDays < 0 {
+		return c, fmt.Errorf("policy %s: minDataDays must not be negative", p.Name)
+	}
+	if spec.MinCPU != nil && spec.MaxCPU != nil && spec.MinCPU.Cmp(*spec.MaxCPU) > 0 {
+		return c, fmt.Errorf("policy %s: minCPU is greater than maxCPU", p.Name)
+	}
+	if spec.MinMemory != nil && spec.MaxMemory != nil && spec.MinMemory.Cmp(*spec.MaxMemory) > 0 {
+		return c, fmt.Errorf("policy %s: minMemory is greater than maxMemory", p.Name)
+	}
+	for _, t := range spec.AllowedTypes {
+		switch t {
+		case TypeRightSize, TypeScaleDown, TypeReplicaRightSize, TypeHPAAdjust:
+		default:
+			return c, fmt.Errorf("policy %s: unknown allowed type %q (expected %s, %s, %s or %s)",
+				p.Name, t, TypeRightSize, TypeScaleDown, TypeReplicaRightSize, TypeHPAAdjust)
+		}
+	}
+
+	selector := spec.Selector
+	var err error
+	if selector.NamespaceSelector != nil {
+		c.namespaceSelector, err = metav1.LabelSelectorAsSelector(selector.NamespaceSelector)
+		if err != nil {
+			return c, fmt.Errorf("policy %s: invalid namespaceSelector: %w", p.Name, err)
+		}
+	}
+	if selector.LabelSelector != nil {
+		c.labelSelector, err = metav1.LabelSelectorAsSelector(selector.LabelSelector)
+		if err != nil {
+			return c, fmt.Errorf("policy %s: invalid labelSelector: %w", p.Name, err)
+		}
+	}
+
+	switch {
+	case len(selector.Workloads) > 0:
+		c.specificity = specificityWorkload
+	case selector.LabelSelector != nil:
+		c.specificity = specificityLabels
+	case len(selector.Namespaces) > 0 || selector.NamespaceSelector != nil:
+		c.specificity = specificityNamespace
+	default:
+		c.specificity = specificityDefault
+	}
+
+	return c, nil
+}
+
+func (c compiled) matches(target Target) bool {
+	selector := c.policy.Spec.Selector
+
+	if len(selector.Namespaces) > 0 && !contains(selector.Namespaces, target.Namespace) {
+		return false
+	}
+	if c.namespaceSelector != nil && !c.namespaceSelector.Matches(labels.Set(target.NamespaceLabels)) {
+		return false
+	}
+	if c.labelSelector != nil && !c.labelSelector.Matches(labels.Set(target.Labels)) {
+		return false
+	}
+	if len(selector.Workloads) > 0 && !matchesWorkload(selector.Workloads, target) {
+		return false
+	}
+	return true
+}
+
+func matchesWorkload(workloads []string, target Target) bool {
+	for _, w := range workloads {
+		kind, name, qualified := strings.Cut(w, "/")
+		if !qualified {
+			name, kind = kind, ""
+		}
+		if name == target.Name && (kind == "" || strings.EqualFold(kind, target.Kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
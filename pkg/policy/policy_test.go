@@ -0,0 +1,217 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const testPolicies = `
+apiVersion: cost-optimizer.io/v1alpha1
+kind: OptimizationPolicy
+metadata:
+  name: production
+spec:
+  selector:
+    namespaceSelector:
+      matchLabels:
+        environment: production
+  safetyBuffer: 2.0
+  minDataDays: 7
+  allowedTypes: [RIGHT_SIZE]
+---
+apiVersion: cost-optimizer.io/v1alpha1
+kind: OptimizationPolicy
+metadata:
+  name: databases
+spec:
+  selector:
+    labelSelector:
+      matchLabels:
+        tier: db
+  minMemory: 1Gi
+  maxCPU: "2"
+---
+apiVersion: cost-optimizer.io/v1alpha1
+kind: OptimizationPolicy
+metadata:
+  name: legacy
+spec:
+  selector:
+    namespaces: [shop]
+    workloads: [StatefulSet/legacy-db]
+  optimize: false
+  safetyBuffer: 3
+---
+`
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadSet(t *testing.T) *Set {
+	t.Helper()
+
+	policies, err := LoadFile(writeFile(t, testPolicies))
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	set, err := NewSet(policies)
+	if err != nil {
+		t.Fatalf("NewSet failed: %v", err)
+	}
+	return set
+}
+
+func TestLoadFile(t *testing.T) {
+	policies, err := LoadFile(writeFile(t, testPolicies))
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(policies) != 3 {
+		t.Fatalf("Expected 3 policies, got %d", len(policies))
+	}
+
+	p := policies[1]
+	if p.Name != "databases" || p.Spec.MinMemory.String() != "1Gi" || p.Spec.MaxCPU.MilliValue() != 2000 {
+		t.Errorf("Unexpected policy: %+v", p)
+	}
+
+	if _, err := LoadFile(writeFile(t, "kind: ConfigMap\nmetadata:\n  name: x\n")); err == nil {
+		t.Error("Expected other kinds to be rejected")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	set := loadSet(t)
+	production := map[string]string{"environment": "production"}
+
+	tests := []struct {
+		name     string
+		target   Target
+		policies []string
+		check    func(Settings) bool
+	}{
+		{
+			name:   "no match",
+			target: Target{Namespace: "dev", Kind: "Deployment", Name: "web"},
+			check: func(s Settings) bool {
+				return s.SafetyBuffer == nil && s.Allows(TypeScaleDown) && s.ClampCPU(5) == 5
+			},
+		},
+		{
+			name:     "namespace selector",
+			target:   Target{Namespace: "shop", NamespaceLabels: production, Kind: "Deployment", Name: "web"},
+			policies: []string{"production"},
+			check: func(s Settings) bool {
+				return *s.SafetyBuffer == 2.0 && *s.MinDataDays == 7 &&
+					s.Allows(TypeRightSize) && !s.Allows(TypeScaleDown)
+			},
+		},
+		{
+			name: "label selector adds floors",
+			target: Target{Namespace: "shop", NamespaceLabels: production, Kind: "StatefulSet", Name: "db",
+				Labels: map[string]string{"tier": "db"}},
+			policies: []string{"production", "databases"},
+			check: func(s Settings) bool {
+				return *s.SafetyBuffer == 2.0 && s.ClampMemory(1) == 1<<30 && s.ClampCPU(4000) == 2000
+			},
+		},
+		{
+			name: "workload overrides namespace",
+			target: Target{Namespace: "shop", NamespaceLabels: production, Kind: "StatefulSet", Name: "legacy-db",
+				Labels: map[string]string{"tier": "db"}},
+			policies: []string{"production", "databases", "legacy"},
+			check: func(s Settings) bool {
+				return *s.SafetyBuffer == 3 && !*s.Optimize
+			},
+		},
+		{
+			name:   "workload kind must match",
+			target: Target{Namespace: "shop", Kind: "Deployment", Name: "legacy-db"},
+			check: func(s Settings) bool {
+				return s.Optimize == nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := set.Resolve(tt.target)
+			if !reflect.DeepEqual(settings.Policies, tt.policies) {
+				t.Errorf("Expected policies %v, got %v", tt.policies, settings.Policies)
+			}
+			if !tt.check(settings) {
+				t.Errorf("Unexpected settings: %+v", settings)
+			}
+		})
+	}
+}
+
+func TestResolveNilSet(t *testing.T) {
+	var set *Set
+	if settings := set.Resolve(Target{Namespace: "shop"}); len(settings.Policies) != 0 || !settings.Allows(TypeRightSize) {
+		t.Errorf("Expected no overrides, got %+v", settings)
+	}
+}
+
+func TestNewSetValidates(t *testing.T) {
+	tests := map[string]string{
+		"safety buffer": "safetyBuffer: 0.5",
+		"cpu range":     "minCPU: 2\n  maxCPU: 500m",
+		"allowed types": "allowedTypes: [DELETE]",
+		"selector":      "selector:\n    labelSelector:\n      matchExpressions: [{key: a, operator: Bogus}]",
+	}
+
+	for name, spec := range tests {
+		t.Run(name, func(t *testing.T) {
+			policies, err := Decode(strings.NewReader("kind: OptimizationPolicy\nmetadata:\n  name: bad\nspec:\n  " + spec + "\n"))
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if _, err := NewSet(policies); err == nil {
+				t.Error("Expected validation error")
+			}
+			if err := Validate(policies[0]); err == nil {
+				t.Error("Expected Validate to fail")
+			}
+		})
+	}
+}
+
+func TestLoadCluster(t *testing.T) {
+	policy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cost-optimizer.io/v1alpha1",
+		"kind":       Kind,
+		"metadata":   map[string]interface{}{"name": "team-a"},
+		"spec": map[string]interface{}{
+			"selector":     map[string]interface{}{"namespaces": []interface{}{"team-a"}},
+			"safetyBuffer": int64(2),
+			"minCPU":       "50m",
+		},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{Resource: "OptimizationPolicyList"}, policy)
+
+	policies, err := LoadCluster(context.Background(), client)
+	if err != nil {
+		t.Fatalf("LoadCluster failed: %v", err)
+	}
+	if len(policies) != 1 || *policies[0].Spec.SafetyBuffer != 2 || policies[0].Spec.MinCPU.MilliValue() != 50 {
+		t.Errorf("Unexpected policies: %+v", policies)
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // DetectProvider attempts to detect the cloud provider from Kubernetes node labels
-func DetectProvider(ctx context.Context, clientset *kubernetes.Clientset) (string, string, error) {
+func DetectProvider(ctx context.Context, clientset kubernetes.Interface) (string, string, error) {
 	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{Limit: 1})
 	if err != nil {
 		return "default", "unknown", err
@@ -8,7 +8,7 @@ import (
 )
 
 // NewProvider creates a pricing provider based on cloud detection or config
-func NewProvider(ctx context.Context, clientset *kubernetes.Clientset, config *Config) (Provider, error) {
+func NewProvider(ctx context.Context, clientset kubernetes.Interface, config *Config) (Provider, error) {
 	var provider string
 	var region string
 
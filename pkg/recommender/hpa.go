@@ -0,0 +1,149 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+)
+
+// minTargetIncrease is the smallest HPA target change worth recommending,
+// in percentage points
+const minTargetIncrease = 10
+
+// AnalyzeHPA recommends a higher CPU target and a lower minReplicas for an
+// HPA that keeps more replicas than the workload's load needs. The target
+// leaves the same headroom as the safety buffer, e.g. 66% for 1.5x, and
+// minReplicas is only lowered while the HPA is held at it. HPAs that also
+// scale on memory or other metrics are skipped.
+//
+// Savings come from the replicas the HPA is projected to remove at the
+// current load. Pods are assumed to be sized as containerRecs recommend;
+// those keep the requests the HPA scales on, so both can be applied.
+func (r *Recommender) AnalyzeHPA(analyses []analyzer.PodAnalysis, deploymentName string, containerRecs []*Recommendation) *Recommendation {
+	ctx := context.Background()
+
+	if len(analyses) == 0 || analyses[0].HPA == nil || analyses[0].HPA.TargetCPUUtilization == 0 {
+		return nil
+	}
+	hpa := analyses[0].HPA
+	// The HPA runs the most replicas any of its metrics asks for, so a
+	// higher CPU target removes none while memory or another metric
+	// holds them
+	if hpa.ScalesOnMemory || hpa.ScalesOnOtherMetrics {
+		return nil
+	}
+
+	w := r.resolveWorkload(analyses, deploymentName)
+	if !w.allows(HPAAdjust, analyses) {
+		return nil
+	}
+
+	// Pod CPU utilization, as the HPA computes it
+	pods := make(map[string]bool)
+	var usedCPU, requestedCPU int64
+	for _, analysis := range analyses {
+		pods[analysis.Name] = true
+		usedCPU += analysis.ActualCPU
+		requestedCPU += analysis.RequestedCPU
+	}
+	replicas := int32(len(pods))
+	if requestedCPU == 0 || hpa.CurrentReplicas != replicas {
+		// Scaling is in progress or some replicas report no metrics
+		return nil
+	}
+	utilization := float64(usedCPU) / float64(requestedCPU) * 100
+
+	target := hpa.TargetCPUUtilization
+	recommendedTarget := target
+	if headroom := int32(100 / w.safetyBuffer); headroom >= target+minTargetIncrease {
+		recommendedTarget = headroom
+	}
+
+	// Replicas the HPA wants at the current load with the new target
+	desired := int32(math.Ceil(float64(replicas) * utilization / float64(recommendedTarget)))
+	recommendedMin := hpa.MinReplicas
+	if desired < hpa.MinReplicas {
+		recommendedMin = max(desired, min(hpa.MinReplicas, minAvailableReplicas), 1)
+	}
+	if recommendedTarget == target && recommendedMin == hpa.MinReplicas {
+		return nil
+	}
+
+	projected := min(max(desired, recommendedMin), hpa.MaxReplicas)
+	if projected >= replicas {
+		return nil
+	}
+
+	currentCPU, currentMem, podCPU, podMem := podRequests(analyses, containerRecs)
+	savings := r.calculateMonthlyCost(ctx, podCPU, podMem) * float64(replicas-projected)
+	if savings < 1.0 {
+		return nil
+	}
+
+	rec := &Recommendation{
+		Type:                   HPAAdjust,
+		DeploymentName:         deploymentName,
+		Namespace:              analyses[0].Namespace,
+		WorkloadType:           w.workloadType,
+		Environment:            w.environment,
+		Provider:               r.pricingProvider.Name(),
+		CurrentCPU:             currentCPU,
+		CurrentMemory:          currentMem,
+		RecommendedCPU:         podCPU,
+		RecommendedMemory:      podMem,
+		CurrentReplicas:        replicas,
+		RecommendedReplicas:    projected,
+		HPAName:                hpa.Name,
+		HPAMetricIndex:         hpa.CPUMetricIndex,
+		CurrentTargetCPU:       target,
+		RecommendedTargetCPU:   recommendedTarget,
+		CurrentMinReplicas:     hpa.MinReplicas,
+		RecommendedMinReplicas: recommendedMin,
+		Savings:                savings,
+		Owner:                  w.owner,
+		Confidence:             calculateConfidence(analyses[0].DataQuality, analyses[0].HasSufficientData, analyses[0].CPUPattern.Type),
+		DataQuality:            analyses[0].DataQuality,
+		PatternInfo:            buildPatternInfo(analyses[0].CPUPattern, analyses[0].MemoryPattern, analyses[0].CPUGrowth),
+		HasSufficientData:      analyses[0].HasSufficientData,
+	}
+
+	reasonParts := []string{
+		fmt.Sprintf("HPA %s holds %d replicas at %.0f%% CPU utilization (target %d%%, minReplicas %d), ~%d are enough",
+			hpa.Name, replicas, utilization, target, hpa.MinReplicas, projected),
+	}
+	if recommendedTarget != target {
+		reasonParts = append(reasonParts, fmt.Sprintf("Target: %d%% → %d%%", target, recommendedTarget))
+	}
+	if recommendedMin != hpa.MinReplicas {
+		reasonParts = append(reasonParts, fmt.Sprintf("minReplicas: %d → %d", hpa.MinReplicas, recommendedMin))
+	}
+	reasonParts = append(reasonParts, fmt.Sprintf("Workload: %s, Safety: %.1fx, Env: %s", w.workloadType, w.safetyBuffer, w.environment))
+	if len(w.settings.Policies) > 0 {
+		reasonParts = append(reasonParts, fmt.Sprintf("Policy: %s", w.settings.Name()))
+	}
+	reasonParts = withOverrides(reasonParts, w.settings)
+	rec.Reason = strings.Join(reasonParts, " | ")
+
+	if rec.Savings > 50 {
+		rec.Impact = "HIGH"
+	} else if rec.Savings > 20 {
+		rec.Impact = "MEDIUM"
+	} else {
+		rec.Impact = "LOW"
+	}
+
+	// Fewer replicas leave less room for spikes before the HPA reacts
+	reduction := 1 - float64(projected)/float64(replicas)
+	if reduction > 0.5 {
+		rec.Risk = "HIGH"
+	} else if reduction > 0.25 {
+		rec.Risk = "MEDIUM"
+	} else {
+		rec.Risk = w.config.RiskLevel
+	}
+
+	return rec
+}
@@ -0,0 +1,127 @@
+package recommender
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+)
+
+// hpaPods returns replicaPods scaled by an HPA on CPU
+func hpaPods(replicas int, cpu, memory int64, hpa analyzer.HPAInfo) []analyzer.PodAnalysis {
+	pods := replicaPods(replicas, cpu, memory)
+	hpa.CurrentReplicas = int32(replicas)
+	for i := range pods {
+		pods[i].HasHPA = true
+		pods[i].HPAName = hpa.Name
+		pods[i].HPA = &hpa
+	}
+	return pods
+}
+
+func TestAnalyzeContainersWithHPA(t *testing.T) {
+	r := New()
+
+	pods := hpaPods(6, 200, 256<<20, analyzer.HPAInfo{
+		Name: "web", MinReplicas: 2, MaxReplicas: 10, ScalesOnCPU: true, TargetCPUUtilization: 50,
+	})
+	recs := r.AnalyzeContainers(pods, "web")
+	if len(recs) != 1 || recs[0].Type != RightSize {
+		t.Fatalf("Expected a RIGHT_SIZE recommendation, got %+v", recs)
+	}
+	rec := recs[0]
+	if rec.RecommendedCPU != rec.CurrentCPU {
+		t.Errorf("Expected the CPU request the HPA scales on to be kept, got %dm -> %dm", rec.CurrentCPU, rec.RecommendedCPU)
+	}
+	if rec.RecommendedMemory >= rec.CurrentMemory || !strings.Contains(rec.Reason, "HPA web: CPU request kept") {
+		t.Errorf("Expected memory to be right-sized, got %d -> %d (%s)", rec.CurrentMemory, rec.RecommendedMemory, rec.Reason)
+	}
+
+	// Nothing is left to right-size when the HPA scales on both resources
+	pods = hpaPods(6, 200, 256<<20, analyzer.HPAInfo{
+		Name: "web", MinReplicas: 2, MaxReplicas: 10, ScalesOnCPU: true, ScalesOnMemory: true, TargetCPUUtilization: 50,
+	})
+	recs = r.AnalyzeContainers(pods, "web")
+	if len(recs) != 1 || recs[0].Type != NoAction || !strings.Contains(recs[0].Reason, "HPA web") {
+		t.Errorf("Expected NO_ACTION for an HPA scaling on CPU and memory, got %+v", recs[0])
+	}
+}
+
+func TestAnalyzeHPA(t *testing.T) {
+	r := New()
+
+	// Six replicas at 20% CPU are pinned to minReplicas
+	pods := hpaPods(6, 200, 256<<20, analyzer.HPAInfo{
+		Name: "web", MinReplicas: 6, MaxReplicas: 10, ScalesOnCPU: true, TargetCPUUtilization: 50, CPUMetricIndex: 0,
+	})
+	containerRecs := r.AnalyzeContainers(pods, "web")
+
+	rec := r.AnalyzeHPA(pods, "web", containerRecs)
+	if rec == nil {
+		t.Fatal("Expected an HPA recommendation")
+	}
+	if rec.Type != HPAAdjust || rec.HPAName != "web" || rec.ContainerName != "" {
+		t.Errorf("Expected HPA_ADJUST for HPA web, got %s for %q", rec.Type, rec.HPAName)
+	}
+	if rec.RecommendedTargetCPU != 50 || rec.CurrentMinReplicas != 6 || rec.RecommendedMinReplicas != 3 {
+		t.Errorf("Expected minReplicas 6 -> 3 at a 50%% target, got %d -> %d at %d%%",
+			rec.CurrentMinReplicas, rec.RecommendedMinReplicas, rec.RecommendedTargetCPU)
+	}
+	if rec.CurrentReplicas != 6 || rec.RecommendedReplicas != 3 {
+		t.Errorf("Expected 6 -> 3 replicas, got %d -> %d", rec.CurrentReplicas, rec.RecommendedReplicas)
+	}
+
+	// Savings count the removed replicas at their right-sized requests
+	if rec.RecommendedMemory != containerRecs[0].RecommendedMemory {
+		t.Errorf("Expected right-sized pods, got %d bytes", rec.RecommendedMemory)
+	}
+	expected := r.calculateMonthlyCost(context.Background(), rec.RecommendedCPU, rec.RecommendedMemory) * 3
+	if rec.Savings != expected {
+		t.Errorf("Expected $%.2f savings, got $%.2f", expected, rec.Savings)
+	}
+
+	// A low target is raised to the safety buffer's headroom
+	pods = hpaPods(6, 200, 256<<20, analyzer.HPAInfo{
+		Name: "web", MinReplicas: 2, MaxReplicas: 10, ScalesOnCPU: true, TargetCPUUtilization: 30, CPUMetricIndex: 1,
+	})
+	rec = r.AnalyzeHPA(pods, "web", nil)
+	if rec == nil {
+		t.Fatal("Expected an HPA recommendation")
+	}
+	if rec.CurrentTargetCPU != 30 || rec.RecommendedTargetCPU != 55 || rec.HPAMetricIndex != 1 {
+		t.Errorf("Expected target 30%% -> 55%% on metric 1, got %d%% -> %d%% on metric %d",
+			rec.CurrentTargetCPU, rec.RecommendedTargetCPU, rec.HPAMetricIndex)
+	}
+	if rec.RecommendedMinReplicas != 2 || rec.RecommendedReplicas != 3 {
+		t.Errorf("Expected minReplicas 2 and ~3 replicas, got %d and %d", rec.RecommendedMinReplicas, rec.RecommendedReplicas)
+	}
+
+	// Already tuned
+	pods = hpaPods(3, 500, 256<<20, analyzer.HPAInfo{
+		Name: "web", MinReplicas: 2, MaxReplicas: 10, ScalesOnCPU: true, TargetCPUUtilization: 50,
+	})
+	if rec := r.AnalyzeHPA(pods, "web", nil); rec != nil {
+		t.Errorf("Expected no recommendation for a tuned HPA, got %s", rec.Reason)
+	}
+
+	// Another metric may hold the replicas whatever the CPU target
+	for _, hpa := range []analyzer.HPAInfo{
+		{Name: "web", MinReplicas: 6, MaxReplicas: 10, ScalesOnCPU: true, ScalesOnMemory: true, TargetCPUUtilization: 50},
+		{Name: "web", MinReplicas: 6, MaxReplicas: 10, ScalesOnCPU: true, ScalesOnOtherMetrics: true, TargetCPUUtilization: 50},
+	} {
+		pods = hpaPods(6, 200, 256<<20, hpa)
+		if rec := r.AnalyzeHPA(pods, "web", nil); rec != nil {
+			t.Errorf("Expected no recommendation for an HPA scaling on more than CPU, got %s", rec.Reason)
+		}
+	}
+
+	// Scaling in progress
+	pods = hpaPods(6, 200, 256<<20, analyzer.HPAInfo{
+		Name: "web", MinReplicas: 6, MaxReplicas: 10, ScalesOnCPU: true, TargetCPUUtilization: 50,
+	})
+	pods[0].HPA.CurrentReplicas = 8
+	if rec := r.AnalyzeHPA(pods, "web", nil); rec != nil {
+		t.Errorf("Expected no recommendation while scaling, got %s", rec.Reason)
+	}
+}
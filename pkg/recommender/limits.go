@@ -0,0 +1,82 @@
+package recommender
+
+import "fmt"
+
+// CPU limit policies
+const (
+	CPULimitNone  = "none"  // Leave CPU unlimited to avoid throttling
+	CPULimitRatio = "ratio" // Limit = request * ratio
+)
+
+// Memory limit policies
+const (
+	MemoryLimitRequest = "request" // Limit = request
+	MemoryLimitPeak    = "peak"    // Limit based on observed P99 peak
+)
+
+// peakHeadroom is added on top of the observed peak for peak-based limits
+const peakHeadroom = 1.2
+
+// LimitPolicy controls how recommended limits are derived from requests
+// and observed peaks
+type LimitPolicy struct {
+	CPU      string  // none, ratio
+	CPURatio float64 // used with the ratio policy
+	Memory   string  // request, peak
+}
+
+// DefaultLimitPolicy leaves CPU unlimited and sets memory limit = request
+func DefaultLimitPolicy() LimitPolicy {
+	return LimitPolicy{
+		CPU:      CPULimitNone,
+		CPURatio: 2.0,
+		Memory:   MemoryLimitRequest,
+	}
+}
+
+// Validate checks the policy names and ratio
+func (p LimitPolicy) Validate() error {
+	switch p.CPU {
+	case CPULimitNone:
+	case CPULimitRatio:
+		if p.CPURatio < 1.0 {
+			return fmt.Errorf("CPU limit ratio must be >= 1.0")
+		}
+	default:
+		return fmt.Errorf("unknown CPU limit policy %q (expected %s or %s)", p.CPU, CPULimitNone, CPULimitRatio)
+	}
+
+	switch p.Memory {
+	case MemoryLimitRequest, MemoryLimitPeak:
+	default:
+		return fmt.Errorf("unknown memory limit policy %q (expected %s or %s)", p.Memory, MemoryLimitRequest, MemoryLimitPeak)
+	}
+
+	return nil
+}
+
+// cpuLimit returns the recommended CPU limit in millicores, 0 for no limit.
+// The limit never falls below the observed peak.
+func (p LimitPolicy) cpuLimit(request, peak int64) int64 {
+	if p.CPU != CPULimitRatio {
+		return 0
+	}
+	limit := int64(float64(request) * p.CPURatio)
+	if limit < peak {
+		limit = peak
+	}
+	return limit
+}
+
+// memoryLimit returns the recommended memory limit in bytes. It is never
+// below the request, since the kubelet rejects limits lower than requests.
+func (p LimitPolicy) memoryLimit(request, peak int64) int64 {
+	if p.Memory != MemoryLimitPeak {
+		return request
+	}
+	limit := int64(float64(peak) * peakHeadroom)
+	if limit < request {
+		limit = request
+	}
+	return limit
+}
@@ -0,0 +1,137 @@
+package recommender
+
+import (
+	"testing"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+)
+
+func TestLimitPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        LimitPolicy
+		cpuRequest    int64
+		cpuPeak       int64
+		expectedCPU   int64
+		memoryRequest int64
+		memoryPeak    int64
+		expectedMem   int64
+	}{
+		{
+			name:          "Default leaves CPU unlimited and memory at request",
+			policy:        DefaultLimitPolicy(),
+			cpuRequest:    200,
+			cpuPeak:       500,
+			expectedCPU:   0,
+			memoryRequest: 256,
+			memoryPeak:    200,
+			expectedMem:   256,
+		},
+		{
+			name:          "CPU ratio",
+			policy:        LimitPolicy{CPU: CPULimitRatio, CPURatio: 2.0, Memory: MemoryLimitRequest},
+			cpuRequest:    200,
+			cpuPeak:       300,
+			expectedCPU:   400,
+			memoryRequest: 256,
+			memoryPeak:    200,
+			expectedMem:   256,
+		},
+		{
+			name:          "CPU ratio never below peak",
+			policy:        LimitPolicy{CPU: CPULimitRatio, CPURatio: 1.5, Memory: MemoryLimitRequest},
+			cpuRequest:    200,
+			cpuPeak:       500,
+			expectedCPU:   500,
+			memoryRequest: 256,
+			memoryPeak:    200,
+			expectedMem:   256,
+		},
+		{
+			name:          "Memory from peak",
+			policy:        LimitPolicy{CPU: CPULimitNone, Memory: MemoryLimitPeak},
+			cpuRequest:    200,
+			cpuPeak:       300,
+			expectedCPU:   0,
+			memoryRequest: 256,
+			memoryPeak:    500,
+			expectedMem:   600,
+		},
+		{
+			name:          "Memory from peak never below request",
+			policy:        LimitPolicy{CPU: CPULimitNone, Memory: MemoryLimitPeak},
+			cpuRequest:    200,
+			cpuPeak:       300,
+			expectedCPU:   0,
+			memoryRequest: 256,
+			memoryPeak:    100,
+			expectedMem:   256,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.cpuLimit(tt.cpuRequest, tt.cpuPeak); got != tt.expectedCPU {
+				t.Errorf("CPU limit: got %d, expected %d", got, tt.expectedCPU)
+			}
+			if got := tt.policy.memoryLimit(tt.memoryRequest, tt.memoryPeak); got != tt.expectedMem {
+				t.Errorf("Memory limit: got %d, expected %d", got, tt.expectedMem)
+			}
+		})
+	}
+}
+
+func TestLimitPolicyValidate(t *testing.T) {
+	if err := DefaultLimitPolicy().Validate(); err != nil {
+		t.Errorf("Default policy should be valid: %v", err)
+	}
+
+	invalid := []LimitPolicy{
+		{CPU: "always", Memory: MemoryLimitRequest},
+		{CPU: CPULimitRatio, CPURatio: 0.5, Memory: MemoryLimitRequest},
+		{CPU: CPULimitNone, Memory: "p50"},
+	}
+	for _, policy := range invalid {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Expected error for policy %+v", policy)
+		}
+	}
+}
+
+func TestRightSizeRecommendsLimits(t *testing.T) {
+	rec := New().WithLimitPolicy(LimitPolicy{CPU: CPULimitRatio, CPURatio: 2.0, Memory: MemoryLimitRequest})
+
+	analyses := []analyzer.PodAnalysis{
+		{
+			Name:            "overprovision-pod",
+			Namespace:       "default",
+			RequestedCPU:    1000,
+			RequestedMemory: 1024 * 1024 * 1024,
+			LimitCPU:        2000,
+			LimitMemory:     2 * 1024 * 1024 * 1024,
+			ActualCPU:       200,
+			ActualMemory:    256 * 1024 * 1024,
+			PeakCPU:         250,
+			PeakMemory:      300 * 1024 * 1024,
+		},
+	}
+
+	recommendation := rec.Analyze(analyses, "overprovision-deployment")
+
+	if recommendation.Type != RightSize {
+		t.Fatalf("Expected RIGHT_SIZE, got %s", recommendation.Type)
+	}
+
+	if recommendation.CurrentCPULimit != 2000 || recommendation.CurrentMemoryLimit != 2*1024*1024*1024 {
+		t.Errorf("Expected current limits to be read from the analyses, got CPU=%d Memory=%d",
+			recommendation.CurrentCPULimit, recommendation.CurrentMemoryLimit)
+	}
+
+	if recommendation.RecommendedCPULimit != recommendation.RecommendedCPU*2 {
+		t.Errorf("Expected CPU limit 2x request (%d), got %d", recommendation.RecommendedCPU*2, recommendation.RecommendedCPULimit)
+	}
+
+	if recommendation.RecommendedMemoryLimit != recommendation.RecommendedMemory {
+		t.Errorf("Expected memory limit = request (%d), got %d", recommendation.RecommendedMemory, recommendation.RecommendedMemoryLimit)
+	}
+}
@@ -0,0 +1,119 @@
+package recommender
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/policy"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func withPolicy(t *testing.T, spec policy.Spec) *Recommender {
+	t.Helper()
+
+	set, err := policy.NewSet([]policy.OptimizationPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		Spec:       spec,
+	}})
+	if err != nil {
+		t.Fatalf("NewSet failed: %v", err)
+	}
+	return New().WithPolicies(set)
+}
+
+func overprovisioned() []analyzer.PodAnalysis {
+	return []analyzer.PodAnalysis{{
+		Name:            "web-1",
+		Namespace:       "shop",
+		ContainerName:   "app",
+		WorkloadType:    "Deployment",
+		RequestedCPU:    1000,
+		RequestedMemory: 1 << 30,
+		ActualCPU:       200,
+		ActualMemory:    256 << 20,
+	}}
+}
+
+func TestPolicySafetyBuffer(t *testing.T) {
+	buffer := 1.2
+	rec := withPolicy(t, policy.Spec{SafetyBuffer: &buffer}).Analyze(overprovisioned(), "web")
+
+	if rec.Type != RightSize || rec.RecommendedCPU != 240 {
+		t.Errorf("Expected 240m with a 1.2x buffer, got %s %dm", rec.Type, rec.RecommendedCPU)
+	}
+	if !strings.Contains(rec.Reason, "Policy: team") {
+		t.Errorf("Expected the reason to name the policy, got %q", rec.Reason)
+	}
+	if !strings.Contains(rec.String(), "(with 1.2x safety buffer)") {
+		t.Errorf("Expected the policy buffer to be shown, got %q", rec.String())
+	}
+}
+
+func TestPolicyFloorsAndCeilings(t *testing.T) {
+	minCPU := resource.MustParse("500m")
+	maxMemory := resource.MustParse("256Mi")
+	rec := withPolicy(t, policy.Spec{MinCPU: &minCPU, MaxMemory: &maxMemory}).Analyze(overprovisioned(), "web")
+
+	if rec.RecommendedCPU != 500 {
+		t.Errorf("Expected CPU floor of 500m, got %dm", rec.RecommendedCPU)
+	}
+	if rec.RecommendedMemory != 256<<20 {
+		t.Errorf("Expected memory ceiling of 256Mi, got %d", rec.RecommendedMemory)
+	}
+}
+
+func TestPolicyAllowedTypes(t *testing.T) {
+	idle := overprovisioned()
+	idle[0].ActualCPU = 10
+
+	rec := withPolicy(t, policy.Spec{AllowedTypes: []string{policy.TypeRightSize}}).Analyze(idle, "web")
+	if rec.Type != RightSize {
+		t.Errorf("Expected RIGHT_SIZE instead of a disallowed SCALE_DOWN, got %s", rec.Type)
+	}
+
+	rec = withPolicy(t, policy.Spec{AllowedTypes: []string{policy.TypeScaleDown}}).Analyze(overprovisioned(), "web")
+	if rec.Type != NoAction || !strings.Contains(rec.Reason, "not allowed by policy team") {
+		t.Errorf("Expected NO_ACTION for a disallowed RIGHT_SIZE, got %s: %s", rec.Type, rec.Reason)
+	}
+}
+
+func TestPolicyOptimizeAndMinDataDays(t *testing.T) {
+	disabled := false
+	rec := withPolicy(t, policy.Spec{Optimize: &disabled}).Analyze(overprovisioned(), "web")
+	if rec.Type != NoAction || !strings.Contains(rec.Reason, "disabled by policy team") {
+		t.Errorf("Expected optimization to be disabled, got %s: %s", rec.Type, rec.Reason)
+	}
+
+	// DaemonSets are off by default but can be enabled
+	enabled := true
+	daemons := overprovisioned()
+	daemons[0].WorkloadType = "DaemonSet"
+	if rec := withPolicy(t, policy.Spec{Optimize: &enabled}).Analyze(daemons, "agent"); rec.Type != RightSize {
+		t.Errorf("Expected the policy to enable DaemonSet optimization, got %s", rec.Type)
+	}
+
+	days := 7
+	short := overprovisioned()
+	short[0].DataDays = 2
+	rec = withPolicy(t, policy.Spec{MinDataDays: &days}).Analyze(short, "web")
+	if rec.Type != NoAction || !strings.Contains(rec.Reason, "requires 7") {
+		t.Errorf("Expected NO_ACTION with too little data, got %s: %s", rec.Type, rec.Reason)
+	}
+}
+
+func TestAnnotationOverrideReason(t *testing.T) {
+	analyses := overprovisioned()
+	analyses[0].ActualCPU = 700
+	analyses[0].ActualMemory = 700 << 20
+	analyses[0].WorkloadAnnotations = map[string]string{policy.AnnotationMinCPU: "100m"}
+
+	rec := New().Analyze(analyses, "web")
+	if rec.Type != NoAction {
+		t.Fatalf("Expected NO_ACTION, got %s: %s", rec.Type, rec.Reason)
+	}
+	if !strings.Contains(rec.Reason, "Override: min-cpu=100m (annotation)") {
+		t.Errorf("Expected the reason to show the override, got %q", rec.Reason)
+	}
+}
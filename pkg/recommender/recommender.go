@@ -6,20 +6,24 @@ import (
 	"strings"
 
 	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/policy"
 	"github.com/opscart/k8s-cost-optimizer/pkg/pricing"
 )
 
 type RecommendationType string
 
 const (
-	RightSize RecommendationType = "RIGHT_SIZE"
-	ScaleDown RecommendationType = "SCALE_DOWN"
-	NoAction  RecommendationType = "NO_ACTION"
+	RightSize        RecommendationType = "RIGHT_SIZE"
+	ScaleDown        RecommendationType = "SCALE_DOWN"
+	ReplicaRightSize RecommendationType = "REPLICA_RIGHT_SIZE"
+	HPAAdjust        RecommendationType = "HPA_ADJUST"
+	NoAction         RecommendationType = "NO_ACTION"
 )
 
 type Recommendation struct {
 	Type              RecommendationType
 	DeploymentName    string
+	ContainerName     string
 	Namespace         string
 	WorkloadType      string
 	Environment       string
@@ -36,17 +40,63 @@ type Recommendation struct {
 	DataQuality       float64
 	PatternInfo       string
 	HasSufficientData bool
+
+	// Limits (0 means no limit)
+	CurrentCPULimit        int64
+	CurrentMemoryLimit     int64
+	RecommendedCPULimit    int64
+	RecommendedMemoryLimit int64
+
+	// Replica counts of REPLICA_RIGHT_SIZE recommendations, and the
+	// projected ones of HPA_ADJUST recommendations (0 otherwise)
+	CurrentReplicas     int32
+	RecommendedReplicas int32
+
+	// HPA changes of HPA_ADJUST recommendations. HPAMetricIndex is the index
+	// of the CPU target in the HPA's spec.metrics, -1 when it has none.
+	HPAName                string
+	HPAMetricIndex         int
+	CurrentTargetCPU       int32 // percent
+	RecommendedTargetCPU   int32 // percent
+	CurrentMinReplicas     int32
+	RecommendedMinReplicas int32
+
+	// Per-pod usage the recommendation is based on: P95 and peak (P99)
+	// with Prometheus history, the latest sample otherwise
+	UsageCPU     int64
+	UsageMemory  int64
+	PeakCPU      int64
+	PeakMemory   int64
+	SafetyBuffer float64
+
+	// Target of an existing VerticalPodAutoscaler for the container, shown
+	// next to ours (VPAName is empty without one)
+	VPAName   string
+	VPACPU    int64
+	VPAMemory int64
+
+	// Owner from the cost-optimizer.io/owner annotation, if any
+	Owner string
+
+	// WorkloadResource and ResourcesPath locate the container of custom
+	// workload kinds for kubectl patch, e.g. rollouts.argoproj.io and
+	// /spec/template/spec/containers/0/resources
+	WorkloadResource string
+	ResourcesPath    string
 }
 
 type Recommender struct {
 	pricingProvider pricing.Provider
 	safetyBuffer    float64
+	limitPolicy     LimitPolicy
+	policies        *policy.Set
 }
 
 func New() *Recommender {
 	return &Recommender{
 		pricingProvider: pricing.NewDefaultProvider(23.0, 3.0),
 		safetyBuffer:    1.5,
+		limitPolicy:     DefaultLimitPolicy(),
 	}
 }
 
@@ -54,45 +104,121 @@ func NewWithPricing(provider pricing.Provider) *Recommender {
 	return &Recommender{
 		pricingProvider: provider,
 		safetyBuffer:    1.5,
+		limitPolicy:     DefaultLimitPolicy(),
 	}
 }
 
-func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName string) *Recommendation {
-	ctx := context.Background()
+// WithLimitPolicy sets how recommended limits are derived
+func (r *Recommender) WithLimitPolicy(policy LimitPolicy) *Recommender {
+	r.limitPolicy = policy
+	return r
+}
 
-	if len(analyses) == 0 {
-		return nil
+// WithPolicies sets the optimization policies overriding the built-in
+// safety buffers, thresholds and recommendation types
+func (r *Recommender) WithPolicies(policies *policy.Set) *Recommender {
+	r.policies = policies
+	return r
+}
+
+// ContainerAnalyzeFunc produces a recommendation for the pods of a single
+// container. allowScaleDown is false when other containers in the same pod
+// are busy, so the container can only be right-sized.
+type ContainerAnalyzeFunc func(analyses []analyzer.PodAnalysis, allowScaleDown bool) *Recommendation
+
+// AnalyzeContainers produces one recommendation per container of a workload,
+// so sidecars and main containers are sized independently
+func (r *Recommender) AnalyzeContainers(analyses []analyzer.PodAnalysis, deploymentName string) []*Recommendation {
+	return CombineContainers(analyzer.GroupByContainer(analyses), func(group []analyzer.PodAnalysis, allowScaleDown bool) *Recommendation {
+		return r.analyze(group, deploymentName, allowScaleDown)
+	})
+}
+
+// CombineContainers runs analyze for each container group of a workload.
+// Scaling down affects the whole pod, so it is only kept when every
+// container is idle, in which case a single workload-level recommendation
+// is returned. Idle containers next to busy ones are right-sized instead.
+func CombineContainers(groups [][]analyzer.PodAnalysis, analyze ContainerAnalyzeFunc) []*Recommendation {
+	recs := make([]*Recommendation, len(groups))
+	count, idle := 0, 0
+	for i, group := range groups {
+		recs[i] = analyze(group, true)
+		if recs[i] == nil {
+			continue
+		}
+		count++
+		if recs[i].Type == ScaleDown {
+			idle++
+		}
 	}
 
-	// Get workload type and environment
-	workloadType := analyses[0].WorkloadType
-	if workloadType == "" {
-		workloadType = "Deployment"
+	var recommendations []*Recommendation
+	for i, rec := range recs {
+		if rec == nil {
+			continue
+		}
+		if rec.Type == ScaleDown && idle < count {
+			if rec = analyze(groups[i], false); rec == nil {
+				continue
+			}
+		}
+		recommendations = append(recommendations, rec)
 	}
 
-	environment := string(analyses[0].Environment)
-	if environment == "" {
-		environment = string(analyzer.EnvironmentUnknown)
+	if idle > 1 && idle == count {
+		return []*Recommendation{mergeScaleDown(recommendations)}
 	}
 
-	workloadConfig := analyzer.GetWorkloadConfig(analyzer.WorkloadType(workloadType))
+	return recommendations
+}
 
-	// Calculate combined safety buffer
-	baseSafetyBuffer := analyzer.GetCombinedSafetyBuffer(
-		analyzer.WorkloadType(workloadType),
-		analyzer.Environment(environment),
-	)
+// mergeScaleDown folds per-container SCALE_DOWN recommendations into a
+// single workload-level recommendation
+func mergeScaleDown(recommendations []*Recommendation) *Recommendation {
+	merged := *recommendations[0]
+	merged.ContainerName = ""
+	for _, rec := range recommendations[1:] {
+		merged.CurrentCPU += rec.CurrentCPU
+		merged.CurrentMemory += rec.CurrentMemory
+		merged.CurrentCPULimit += rec.CurrentCPULimit
+		merged.CurrentMemoryLimit += rec.CurrentMemoryLimit
+		merged.Savings += rec.Savings
+	}
+	return &merged
+}
 
-	// Adjust safety buffer based on pattern analysis (Week 9)
-	safetyBuffer := baseSafetyBuffer
-	if analyses[0].HasSufficientData {
-		safetyBuffer = adjustSafetyBufferForPattern(
-			baseSafetyBuffer,
-			analyses[0].CPUPattern,
-			analyses[0].MemoryPattern,
-		)
+// Analyze produces a recommendation for a single container of a workload.
+// analyses should hold one entry per pod for that container.
+func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName string) *Recommendation {
+	return r.analyze(analyses, deploymentName, true)
+}
+
+// AnalyzeRightSize is like Analyze but never recommends scaling the
+// workload down, for containers that share a pod with busy ones
+func (r *Recommender) AnalyzeRightSize(analyses []analyzer.PodAnalysis, deploymentName string) *Recommendation {
+	return r.analyze(analyses, deploymentName, false)
+}
+
+func (r *Recommender) analyze(analyses []analyzer.PodAnalysis, deploymentName string, allowScaleDown bool) *Recommendation {
+	rec := r.analyzeContainer(analyses, deploymentName, allowScaleDown)
+	if rec != nil && analyses[0].VPA != nil {
+		compareVPA(rec, analyses[0].VPA)
+	}
+	return rec
+}
+
+func (r *Recommender) analyzeContainer(analyses []analyzer.PodAnalysis, deploymentName string, allowScaleDown bool) *Recommendation {
+	ctx := context.Background()
+
+	if len(analyses) == 0 {
+		return nil
 	}
 
+	w := r.resolveWorkload(analyses, deploymentName)
+	workloadType, environment := w.workloadType, w.environment
+	workloadConfig, settings, owner := w.config, w.settings, w.owner
+	safetyBuffer := w.safetyBuffer
+
 	// Calculate confidence and pattern info (Week 9 Day 2)
 	confidence := calculateConfidence(
 		analyses[0].DataQuality,
@@ -105,76 +231,106 @@ func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName st
 		analyses[0].CPUGrowth,
 	)
 
-	// Check if workload has HPA - skip optimization
-	if analyses[0].HasHPA {
-		return &Recommendation{
-			Type:              NoAction,
-			DeploymentName:    deploymentName,
-			Namespace:         analyses[0].Namespace,
-			WorkloadType:      workloadType,
-			Environment:       environment,
-			Provider:          r.pricingProvider.Name(),
-			CurrentCPU:        0,
-			CurrentMemory:     0,
-			RecommendedCPU:    0,
-			RecommendedMemory: 0,
-			Reason:            fmt.Sprintf("⚠️  Workload managed by HPA '%s' - Auto-scaling enabled, manual optimization not recommended", analyses[0].HPAName),
-			Savings:           0,
-			Impact:            "N/A",
-			Risk:              "N/A",
-			Confidence:        "N/A",
-			DataQuality:       0,
-			PatternInfo:       "HPA-managed",
-			HasSufficientData: false,
-		}
+	// The HPA decides the replica count, scaling to zero would fight it
+	hpa := analyses[0].HPA
+	if hpa != nil {
+		allowScaleDown = false
 	}
 
 	// Calculate averages
 	var totalRequestedCPU, totalActualCPU int64
 	var totalRequestedMem, totalActualMem int64
+	var totalLimitCPU, totalLimitMem int64
+	var peakCPU, peakMem int64
 
 	for _, analysis := range analyses {
 		totalRequestedCPU += analysis.RequestedCPU
 		totalActualCPU += analysis.ActualCPU
 		totalRequestedMem += analysis.RequestedMemory
 		totalActualMem += analysis.ActualMemory
+		totalLimitCPU += analysis.LimitCPU
+		totalLimitMem += analysis.LimitMemory
+		if analysis.PeakCPU > peakCPU {
+			peakCPU = analysis.PeakCPU
+		}
+		if analysis.PeakMemory > peakMem {
+			peakMem = analysis.PeakMemory
+		}
 	}
 
 	avgRequestedCPU := totalRequestedCPU / int64(len(analyses))
 	avgRequestedMem := totalRequestedMem / int64(len(analyses))
 	avgActualCPU := totalActualCPU / int64(len(analyses))
 	avgActualMem := totalActualMem / int64(len(analyses))
+	avgLimitCPU := totalLimitCPU / int64(len(analyses))
+	avgLimitMem := totalLimitMem / int64(len(analyses))
 
 	rec := &Recommendation{
 		DeploymentName: deploymentName,
+		ContainerName:  analyses[0].ContainerName,
 		Namespace:      analyses[0].Namespace,
 		WorkloadType:   workloadType,
 		Environment:    environment,
 		Provider:       r.pricingProvider.Name(),
 		CurrentCPU:     avgRequestedCPU,
 		CurrentMemory:  avgRequestedMem,
+
+		CurrentCPULimit:    avgLimitCPU,
+		CurrentMemoryLimit: avgLimitMem,
+
+		UsageCPU:     avgActualCPU,
+		UsageMemory:  avgActualMem,
+		PeakCPU:      peakCPU,
+		PeakMemory:   peakMem,
+		SafetyBuffer: safetyBuffer,
+
+		Owner: owner,
+
+		Confidence:        confidence,
+		DataQuality:       analyses[0].DataQuality,
+		PatternInfo:       patternInfo,
+		HasSufficientData: analyses[0].HasSufficientData,
 	}
 
 	// Check if workload type should be optimized
-	if !workloadConfig.OptimizeEnabled {
-		rec.Type = NoAction
-		rec.Reason = fmt.Sprintf("Workload type %s (%s) - optimization disabled for safety",
+	optimizeEnabled := workloadConfig.OptimizeEnabled
+	if settings.Optimize != nil {
+		optimizeEnabled = *settings.Optimize
+	}
+	disabledReason := ""
+	switch {
+	case !optimizeEnabled && settings.Optimize != nil:
+		disabledReason = fmt.Sprintf("Optimization disabled by policy %s", settings.Name())
+	case !optimizeEnabled:
+		disabledReason = fmt.Sprintf("Workload type %s (%s) - optimization disabled for safety",
 			workloadType, workloadConfig.Description)
+	case settings.MinDataDays != nil && analyses[0].DataDays < float64(*settings.MinDataDays):
+		disabledReason = fmt.Sprintf("Only %.1f day(s) of metrics, policy %s requires %d",
+			analyses[0].DataDays, settings.Name(), *settings.MinDataDays)
+	}
+	if disabledReason != "" {
+		rec.Type = NoAction
+		rec.Reason = strings.Join(withOverrides([]string{disabledReason}, settings), " - ")
 		rec.RecommendedCPU = avgRequestedCPU
 		rec.RecommendedMemory = avgRequestedMem
+		rec.RecommendedCPULimit = avgLimitCPU
+		rec.RecommendedMemoryLimit = avgLimitMem
 		rec.Risk = workloadConfig.RiskLevel
 		rec.Impact = "N/A"
 		rec.Savings = 0
-		rec.Confidence = confidence
-		rec.DataQuality = analyses[0].DataQuality
-		rec.PatternInfo = patternInfo
-		rec.HasSufficientData = analyses[0].HasSufficientData
 		return rec
 	}
 
+	// Containers without requests, often sidecars, have no utilization to
+	// compare usage against. They are never idle, only sized from usage.
+	missingRequests := avgRequestedCPU == 0 || avgRequestedMem == 0
+
 	// Check if workload is idle
-	cpuUtil := float64(avgActualCPU) / float64(avgRequestedCPU)
-	if cpuUtil < 0.05 {
+	cpuUtil := 0.0
+	if avgRequestedCPU > 0 {
+		cpuUtil = float64(avgActualCPU) / float64(avgRequestedCPU)
+	}
+	if !missingRequests && cpuUtil < 0.05 && allowScaleDown && settings.Allows(string(ScaleDown)) {
 		rec.Type = ScaleDown
 
 		// Build reason with pattern context
@@ -190,6 +346,7 @@ func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName st
 		}
 
 		reasonParts = append(reasonParts, fmt.Sprintf("Workload: %s, Environment: %s", workloadType, environment))
+		reasonParts = withOverrides(reasonParts, settings)
 
 		rec.Reason = strings.Join(reasonParts, " - ")
 		rec.RecommendedCPU = 0
@@ -197,10 +354,6 @@ func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName st
 		rec.Impact = "HIGH"
 		rec.Risk = workloadConfig.RiskLevel
 		rec.Savings = r.calculateMonthlyCost(ctx, avgRequestedCPU, avgRequestedMem) * float64(len(analyses))
-		rec.Confidence = confidence
-		rec.DataQuality = analyses[0].DataQuality
-		rec.PatternInfo = patternInfo
-		rec.HasSufficientData = analyses[0].HasSufficientData
 		return rec
 	}
 
@@ -222,14 +375,51 @@ func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName st
 		recMem = 10 * 1024 * 1024
 	}
 
+	// Policy floors and ceilings
+	recCPU = settings.ClampCPU(recCPU)
+	recMem = settings.ClampMemory(recMem)
+
+	// Keep the requests the HPA scales on, so it adds and removes replicas
+	// at the same load and the replica count stays as it is. Its target
+	// is tuned by AnalyzeHPA instead.
+	var hpaKept []string
+	if hpa != nil && hpa.ScalesOnCPU && avgRequestedCPU > 0 {
+		recCPU = avgRequestedCPU
+		hpaKept = append(hpaKept, "CPU")
+	}
+	if hpa != nil && hpa.ScalesOnMemory && avgRequestedMem > 0 {
+		recMem = avgRequestedMem
+		hpaKept = append(hpaKept, "memory")
+	}
+
+	if missingRequests {
+		return r.recommendRequests(ctx, rec, w, recCPU, recMem, len(analyses))
+	}
+
 	// Check if right-sizing is beneficial
 	cpuReduction := (float64(avgRequestedCPU) - float64(recCPU)) / float64(avgRequestedCPU) * 100
 	memReduction := (float64(avgRequestedMem) - float64(recMem)) / float64(avgRequestedMem) * 100
 
+	if (cpuReduction > 25 || memReduction > 25) && !settings.Allows(string(RightSize)) {
+		rec.Type = NoAction
+		rec.Reason = strings.Join(withOverrides([]string{
+			fmt.Sprintf("Right-sizing not allowed by policy %s", settings.Name()),
+		}, settings), " - ")
+		rec.RecommendedCPU = avgRequestedCPU
+		rec.RecommendedMemory = avgRequestedMem
+		rec.RecommendedCPULimit = avgLimitCPU
+		rec.RecommendedMemoryLimit = avgLimitMem
+		rec.Impact = "NONE"
+		rec.Risk = "NONE"
+		return rec
+	}
+
 	if cpuReduction > 25 || memReduction > 25 {
 		rec.Type = RightSize
 		rec.RecommendedCPU = recCPU
 		rec.RecommendedMemory = recMem
+		rec.RecommendedCPULimit = r.limitPolicy.cpuLimit(recCPU, peakCPU)
+		rec.RecommendedMemoryLimit = r.limitPolicy.memoryLimit(recMem, peakMem)
 
 		// Build reason with pattern and growth context (Week 9)
 		reasonParts := []string{
@@ -248,6 +438,13 @@ func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName st
 
 		reasonParts = append(reasonParts,
 			fmt.Sprintf("Workload: %s, Safety: %.1fx, Env: %s", workloadType, safetyBuffer, environment))
+		if len(hpaKept) > 0 {
+			reasonParts = append(reasonParts, fmt.Sprintf("HPA %s: %s request kept", hpa.Name, strings.Join(hpaKept, " and ")))
+		}
+		if len(settings.Policies) > 0 {
+			reasonParts = append(reasonParts, fmt.Sprintf("Policy: %s", settings.Name()))
+		}
+		reasonParts = withOverrides(reasonParts, settings)
 
 		rec.Reason = strings.Join(reasonParts, " | ")
 
@@ -258,13 +455,14 @@ func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName st
 		// Skip if savings negligible
 		if rec.Savings < 1.0 {
 			rec.Type = NoAction
-			rec.Reason = fmt.Sprintf("Savings too small to justify change ($%.2f/month) - Change overhead not worth minimal benefit", rec.Savings)
+			rec.RecommendedCPULimit = avgLimitCPU
+			rec.RecommendedMemoryLimit = avgLimitMem
+			rec.Reason = strings.Join(withOverrides([]string{
+				fmt.Sprintf("Savings too small to justify change ($%.2f/month)", rec.Savings),
+				"Change overhead not worth minimal benefit",
+			}, settings), " - ")
 			rec.Impact = "NONE"
 			rec.Risk = "NONE"
-			rec.Confidence = confidence
-			rec.DataQuality = analyses[0].DataQuality
-			rec.PatternInfo = patternInfo
-			rec.HasSufficientData = analyses[0].HasSufficientData
 			return rec
 		}
 
@@ -287,11 +485,6 @@ func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName st
 			rec.Risk = workloadConfig.RiskLevel
 		}
 
-		rec.Confidence = confidence
-		rec.DataQuality = analyses[0].DataQuality
-		rec.PatternInfo = patternInfo
-		rec.HasSufficientData = analyses[0].HasSufficientData
-
 		return rec
 	}
 
@@ -306,21 +499,180 @@ func (r *Recommender) Analyze(analyses []analyzer.PodAnalysis, deploymentName st
 	if confidence == "HIGH" && analyses[0].CPUPattern.Type != "" {
 		reasonParts = append(reasonParts, fmt.Sprintf("Pattern: %s (consistent)", analyses[0].CPUPattern.Type))
 	}
+	if len(hpaKept) > 0 {
+		reasonParts = append(reasonParts, fmt.Sprintf("HPA %s scales on %s", hpa.Name, strings.Join(hpaKept, " and ")))
+	}
+	reasonParts = withOverrides(reasonParts, settings)
 
 	rec.Reason = strings.Join(reasonParts, " - ")
 	rec.RecommendedCPU = avgRequestedCPU
 	rec.RecommendedMemory = avgRequestedMem
+	rec.RecommendedCPULimit = avgLimitCPU
+	rec.RecommendedMemoryLimit = avgLimitMem
 	rec.Savings = 0
 	rec.Impact = "NONE"
 	rec.Risk = "NONE"
-	rec.Confidence = confidence
-	rec.DataQuality = analyses[0].DataQuality
-	rec.PatternInfo = patternInfo
-	rec.HasSufficientData = analyses[0].HasSufficientData
 
 	return rec
 }
 
+// recommendRequests completes rec for a container missing a CPU or memory
+// request. The missing requests are set from usage so the scheduler
+// reserves what the container needs; an existing request is only lowered
+// when it is over-provisioned, like in a right-size. Savings count those
+// reductions alone.
+func (r *Recommender) recommendRequests(ctx context.Context, rec *Recommendation, w workload, recCPU, recMem int64, pods int) *Recommendation {
+	var missing []string
+	if rec.CurrentCPU == 0 {
+		missing = append(missing, "CPU")
+	}
+	if rec.CurrentMemory == 0 {
+		missing = append(missing, "memory")
+	}
+	reason := fmt.Sprintf("No %s request set - requests recommended from usage", strings.Join(missing, " or "))
+
+	if !w.settings.Allows(string(RightSize)) {
+		rec.Type = NoAction
+		rec.Reason = strings.Join(withOverrides([]string{
+			fmt.Sprintf("%s, but right-sizing is not allowed by policy %s", reason, w.settings.Name()),
+		}, w.settings), " | ")
+		rec.RecommendedCPU = rec.CurrentCPU
+		rec.RecommendedMemory = rec.CurrentMemory
+		rec.RecommendedCPULimit = rec.CurrentCPULimit
+		rec.RecommendedMemoryLimit = rec.CurrentMemoryLimit
+		rec.Impact = "NONE"
+		rec.Risk = "NONE"
+		return rec
+	}
+
+	// Existing requests below the right-size threshold are kept
+	if rec.CurrentCPU > 0 && float64(recCPU) > float64(rec.CurrentCPU)*0.75 {
+		recCPU = rec.CurrentCPU
+	}
+	if rec.CurrentMemory > 0 && float64(recMem) > float64(rec.CurrentMemory)*0.75 {
+		recMem = rec.CurrentMemory
+	}
+
+	rec.Type = RightSize
+	rec.RecommendedCPU = recCPU
+	rec.RecommendedMemory = recMem
+	rec.RecommendedCPULimit = r.limitPolicy.cpuLimit(recCPU, rec.PeakCPU)
+	rec.RecommendedMemoryLimit = r.limitPolicy.memoryLimit(recMem, rec.PeakMemory)
+	rec.Reason = strings.Join(withOverrides([]string{
+		reason,
+		fmt.Sprintf("Usage: %dm CPU, %dMi memory", rec.UsageCPU, rec.UsageMemory/(1024*1024)),
+		fmt.Sprintf("Workload: %s, Safety: %.1fx, Env: %s", w.workloadType, rec.SafetyBuffer, w.environment),
+	}, w.settings), " | ")
+
+	var savedCPU, savedMem int64
+	if rec.CurrentCPU > 0 {
+		savedCPU = rec.CurrentCPU - recCPU
+	}
+	if rec.CurrentMemory > 0 {
+		savedMem = rec.CurrentMemory - recMem
+	}
+	rec.Savings = r.calculateMonthlyCost(ctx, savedCPU, savedMem) * float64(pods)
+
+	rec.Impact = "LOW"
+	rec.Risk = w.config.RiskLevel
+	return rec
+}
+
+// withOverrides appends the annotations overriding a workload's settings
+// to the parts of a reason, so every recommendation type shows them
+func withOverrides(reasonParts []string, settings policy.Settings) []string {
+	if len(settings.Overrides) == 0 {
+		return reasonParts
+	}
+	return append(reasonParts, fmt.Sprintf("Override: %s (annotation)", strings.Join(settings.Overrides, ", ")))
+}
+
+// workload holds the settings that apply to a workload's recommendations
+type workload struct {
+	workloadType string
+	environment  string
+	config       analyzer.WorkloadConfig
+	settings     policy.Settings
+	owner        string
+	safetyBuffer float64
+}
+
+// resolveWorkload derives the workload type, environment, policies and
+// safety buffer for the pods of a workload
+func (r *Recommender) resolveWorkload(analyses []analyzer.PodAnalysis, deploymentName string) workload {
+	// Get workload type and environment
+	workloadType := analyses[0].WorkloadType
+	if workloadType == "" {
+		workloadType = "Deployment"
+	}
+
+	environment := string(analyses[0].Environment)
+	if environment == "" {
+		environment = string(analyzer.EnvironmentUnknown)
+	}
+
+	// Custom workload kinds take the defaults of the built-in type they
+	// resemble
+	configType := workloadType
+	if analyses[0].TreatAs != "" {
+		configType = analyses[0].TreatAs
+	}
+
+	workloadConfig := analyzer.GetWorkloadConfig(analyzer.WorkloadType(configType))
+	settings := r.policies.Resolve(policy.Target{
+		Namespace:       analyses[0].Namespace,
+		NamespaceLabels: analyses[0].NamespaceLabels,
+		Kind:            workloadType,
+		Name:            deploymentName,
+		Labels:          analyses[0].Labels,
+
+		NamespaceAnnotations: analyses[0].NamespaceAnnotations,
+		Annotations:          analyses[0].WorkloadAnnotations,
+	})
+	owner := policy.Owner(analyses[0].NamespaceAnnotations, analyses[0].WorkloadAnnotations)
+
+	// Calculate combined safety buffer
+	baseSafetyBuffer := analyzer.GetCombinedSafetyBuffer(
+		analyzer.WorkloadType(configType),
+		analyzer.Environment(environment),
+	)
+
+	// Adjust safety buffer based on pattern analysis (Week 9). A policy or
+	// annotation buffer is used as-is.
+	safetyBuffer := baseSafetyBuffer
+	if settings.SafetyBuffer != nil {
+		safetyBuffer = *settings.SafetyBuffer
+	} else if analyses[0].HasSufficientData {
+		safetyBuffer = adjustSafetyBufferForPattern(
+			baseSafetyBuffer,
+			analyses[0].CPUPattern,
+			analyses[0].MemoryPattern,
+		)
+	}
+
+	return workload{
+		workloadType: workloadType,
+		environment:  environment,
+		config:       workloadConfig,
+		settings:     settings,
+		owner:        owner,
+		safetyBuffer: safetyBuffer,
+	}
+}
+
+// allows reports whether recommendations of type t may be made for the
+// workload, given its settings and how much data its pods have
+func (w workload) allows(t RecommendationType, analyses []analyzer.PodAnalysis) bool {
+	optimizeEnabled := w.config.OptimizeEnabled
+	if w.settings.Optimize != nil {
+		optimizeEnabled = *w.settings.Optimize
+	}
+	if !optimizeEnabled || !w.settings.Allows(string(t)) {
+		return false
+	}
+	return w.settings.MinDataDays == nil || analyses[0].DataDays >= float64(*w.settings.MinDataDays)
+}
+
 func (r *Recommender) calculateMonthlyCost(ctx context.Context, cpuMillicores int64, memoryBytes int64) float64 {
 	costInfo, err := r.pricingProvider.GetCostInfo(ctx, "", "")
 	if err != nil {
@@ -340,6 +692,47 @@ func (r *Recommendation) String() string {
 		return fmt.Sprintf("[%s] %s: %s", r.Impact, r.DeploymentName, r.Reason)
 	}
 
+	if r.Type == ReplicaRightSize {
+		return fmt.Sprintf(
+			"[%s] %s: %s\n"+
+				"  Current: %d replicas\n"+
+				"  Recommendation: Scale to %d replicas\n"+
+				"  Savings: $%.2f/month (%s pricing)\n"+
+				"  Risk: %s",
+			r.Impact,
+			r.DeploymentName,
+			r.Reason,
+			r.CurrentReplicas,
+			r.RecommendedReplicas,
+			r.Savings,
+			r.Provider,
+			r.Risk,
+		)
+	}
+
+	if r.Type == HPAAdjust {
+		return fmt.Sprintf(
+			"[%s] %s: %s\n"+
+				"  Current: HPA %s target %d%% CPU, minReplicas %d, %d replicas\n"+
+				"  Recommendation: Target %d%% CPU, minReplicas %d, ~%d replicas\n"+
+				"  Savings: $%.2f/month (%s pricing)\n"+
+				"  Risk: %s",
+			r.Impact,
+			r.DeploymentName,
+			r.Reason,
+			r.HPAName,
+			r.CurrentTargetCPU,
+			r.CurrentMinReplicas,
+			r.CurrentReplicas,
+			r.RecommendedTargetCPU,
+			r.RecommendedMinReplicas,
+			r.RecommendedReplicas,
+			r.Savings,
+			r.Provider,
+			r.Risk,
+		)
+	}
+
 	if r.Type == ScaleDown {
 		return fmt.Sprintf(
 			"[%s] %s: %s\n"+
@@ -358,10 +751,15 @@ func (r *Recommendation) String() string {
 		)
 	}
 
+	buffer := ""
+	if r.SafetyBuffer > 0 {
+		buffer = fmt.Sprintf(" (with %.1fx safety buffer)", r.SafetyBuffer)
+	}
+
 	return fmt.Sprintf(
 		"[%s] %s: %s\n"+
 			"  Current: %dm CPU, %dMi memory\n"+
-			"  Recommended: %dm CPU, %dMi memory (with 1.5x safety buffer)\n"+
+			"  Recommended: %dm CPU, %dMi memory%s\n"+
 			"  Savings: $%.2f/month (%s pricing)\n"+
 			"  Risk: %s",
 		r.Impact,
@@ -371,6 +769,7 @@ func (r *Recommendation) String() string {
 		r.CurrentMemory/(1024*1024),
 		r.RecommendedCPU,
 		r.RecommendedMemory/(1024*1024),
+		buffer,
 		r.Savings,
 		r.Provider,
 		r.Risk,
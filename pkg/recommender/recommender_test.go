@@ -1,6 +1,8 @@
 package recommender
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
@@ -227,3 +229,165 @@ func TestMultiplePods(t *testing.T) {
 		t.Errorf("Expected positive savings, got %.2f", recommendation.Savings)
 	}
 }
+
+func TestAnalyzeContainers(t *testing.T) {
+	rec := New()
+
+	analyses := []analyzer.PodAnalysis{
+		{
+			Name:            "web-1",
+			Namespace:       "default",
+			ContainerName:   "app",
+			RequestedCPU:    1000,
+			RequestedMemory: 1024 * 1024 * 1024,
+			ActualCPU:       200,
+			ActualMemory:    256 * 1024 * 1024,
+		},
+		{
+			Name:            "web-1",
+			Namespace:       "default",
+			ContainerName:   "proxy",
+			RequestedCPU:    100,
+			RequestedMemory: 128 * 1024 * 1024,
+			ActualCPU:       75,
+			ActualMemory:    100 * 1024 * 1024,
+		},
+	}
+
+	recommendations := rec.AnalyzeContainers(analyses, "web")
+
+	if len(recommendations) != 2 {
+		t.Fatalf("Expected 2 recommendations, got %d", len(recommendations))
+	}
+
+	if recommendations[0].ContainerName != "app" || recommendations[0].Type != RightSize {
+		t.Errorf("Expected RIGHT_SIZE for app, got %s for %s", recommendations[0].Type, recommendations[0].ContainerName)
+	}
+
+	if recommendations[1].ContainerName != "proxy" || recommendations[1].Type != NoAction {
+		t.Errorf("Expected NO_ACTION for proxy, got %s for %s", recommendations[1].Type, recommendations[1].ContainerName)
+	}
+}
+
+func TestAnalyzeContainersIdleSidecar(t *testing.T) {
+	rec := New()
+
+	analyses := []analyzer.PodAnalysis{
+		{
+			Name:            "web-1",
+			Namespace:       "default",
+			ContainerName:   "app",
+			RequestedCPU:    100,
+			RequestedMemory: 128 * 1024 * 1024,
+			ActualCPU:       75,
+			ActualMemory:    100 * 1024 * 1024,
+		},
+		{
+			Name:            "web-1",
+			Namespace:       "default",
+			ContainerName:   "sidecar",
+			RequestedCPU:    1000,
+			RequestedMemory: 1024 * 1024 * 1024,
+			ActualCPU:       20,
+			ActualMemory:    50 * 1024 * 1024,
+		},
+	}
+
+	recommendations := rec.AnalyzeContainers(analyses, "web")
+
+	for _, r := range recommendations {
+		if r.Type == ScaleDown {
+			t.Errorf("Expected no SCALE_DOWN while another container is busy, got one for %s", r.ContainerName)
+		}
+	}
+}
+
+func TestAnalyzeContainersAllIdle(t *testing.T) {
+	rec := New()
+
+	analyses := []analyzer.PodAnalysis{
+		{
+			Name:            "worker-1",
+			Namespace:       "default",
+			ContainerName:   "app",
+			RequestedCPU:    1000,
+			RequestedMemory: 1024 * 1024 * 1024,
+			ActualCPU:       20,
+			ActualMemory:    50 * 1024 * 1024,
+		},
+		{
+			Name:            "worker-1",
+			Namespace:       "default",
+			ContainerName:   "sidecar",
+			RequestedCPU:    500,
+			RequestedMemory: 512 * 1024 * 1024,
+			ActualCPU:       5,
+			ActualMemory:    20 * 1024 * 1024,
+		},
+	}
+
+	recommendations := rec.AnalyzeContainers(analyses, "worker")
+
+	if len(recommendations) != 1 {
+		t.Fatalf("Expected a single workload-level recommendation, got %d", len(recommendations))
+	}
+
+	if recommendations[0].Type != ScaleDown {
+		t.Errorf("Expected SCALE_DOWN, got %s", recommendations[0].Type)
+	}
+
+	if recommendations[0].ContainerName != "" {
+		t.Errorf("Expected no container on a workload-level recommendation, got %s", recommendations[0].ContainerName)
+	}
+
+	if recommendations[0].CurrentCPU != 1500 {
+		t.Errorf("Expected combined current CPU 1500m, got %d", recommendations[0].CurrentCPU)
+	}
+}
+
+func TestAnalyzeContainersWithoutRequests(t *testing.T) {
+	rec := New()
+
+	analyses := []analyzer.PodAnalysis{
+		{
+			Name:          "web-1",
+			Namespace:     "default",
+			ContainerName: "istio-proxy",
+			ActualCPU:     20,
+			ActualMemory:  60 * 1024 * 1024,
+		},
+		{
+			Name:            "web-1",
+			Namespace:       "default",
+			ContainerName:   "log-shipper",
+			RequestedMemory: 1024 * 1024 * 1024,
+			ActualCPU:       0,
+			ActualMemory:    60 * 1024 * 1024,
+		},
+	}
+
+	recommendations := rec.AnalyzeContainers(analyses, "web")
+	if len(recommendations) != 2 {
+		t.Fatalf("Expected 2 recommendations, got %d", len(recommendations))
+	}
+
+	for _, r := range recommendations {
+		if strings.Contains(r.Reason, "NaN") || strings.Contains(r.Reason, "Inf") || math.IsNaN(r.Savings) || math.IsInf(r.Savings, 0) {
+			t.Errorf("Expected no ratio against a missing request for %s, got %q ($%.2f)", r.ContainerName, r.Reason, r.Savings)
+		}
+		if r.Type != RightSize || !strings.Contains(r.Reason, "request set") {
+			t.Errorf("Expected requests to be recommended for %s, got %s: %s", r.ContainerName, r.Type, r.Reason)
+		}
+		if r.RecommendedCPU <= 0 || r.RecommendedMemory <= 0 {
+			t.Errorf("Expected requests for %s, got %dm/%d", r.ContainerName, r.RecommendedCPU, r.RecommendedMemory)
+		}
+	}
+
+	proxy, shipper := recommendations[0], recommendations[1]
+	if proxy.Savings != 0 {
+		t.Errorf("Expected no savings from adding requests, got $%.2f", proxy.Savings)
+	}
+	if shipper.RecommendedMemory >= shipper.CurrentMemory || shipper.Savings <= 0 {
+		t.Errorf("Expected the over-provisioned memory request to be lowered, got %d with $%.2f savings", shipper.RecommendedMemory, shipper.Savings)
+	}
+}
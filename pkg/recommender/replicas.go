@@ -0,0 +1,160 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+)
+
+// minAvailableReplicas is the lowest replica count recommended for
+// workloads running several replicas, so one pod can be disrupted without
+// an outage
+const minAvailableReplicas = 2
+
+// ReplicaFloor is the lowest replica count a workload may be scaled to,
+// e.g. to satisfy a PodDisruptionBudget
+type ReplicaFloor struct {
+	Replicas int32
+	Reason   string // what sets the floor, e.g. "PodDisruptionBudget web (minAvailable 3)"
+}
+
+// AnalyzeReplicas recommends fewer replicas for a workload whose replicas
+// are under-used as a whole: the combined usage of every pod, with the
+// safety buffer, fits in fewer pods. Pods are assumed to be sized as
+// containerRecs recommend, so applying both keeps enough capacity.
+//
+// It returns nil when the replica count is appropriate or cannot be
+// judged, e.g. for HPA-managed workloads or while not every replica is
+// reporting metrics.
+func (r *Recommender) AnalyzeReplicas(analyses []analyzer.PodAnalysis, deploymentName string, replicas int32, floor ReplicaFloor, containerRecs []*Recommendation) *Recommendation {
+	ctx := context.Background()
+
+	if len(analyses) == 0 || analyses[0].HasHPA || replicas <= minAvailableReplicas || replicas <= floor.Replicas {
+		return nil
+	}
+	for _, rec := range containerRecs {
+		if rec.Type == ScaleDown {
+			return nil
+		}
+	}
+
+	w := r.resolveWorkload(analyses, deploymentName)
+	if !w.allows(ReplicaRightSize, analyses) {
+		return nil
+	}
+
+	// Combined usage of every replica
+	pods := make(map[string]bool)
+	var usedCPU, usedMem int64
+	for _, analysis := range analyses {
+		pods[analysis.Name] = true
+		usedCPU += analysis.ActualCPU
+		usedMem += analysis.ActualMemory
+	}
+	if int32(len(pods)) != replicas {
+		return nil
+	}
+
+	currentCPU, currentMem, podCPU, podMem := podRequests(analyses, containerRecs)
+	if podCPU == 0 || podMem == 0 {
+		return nil
+	}
+
+	neededCPU := int32(math.Ceil(float64(usedCPU) * w.safetyBuffer / float64(podCPU)))
+	neededMem := int32(math.Ceil(float64(usedMem) * w.safetyBuffer / float64(podMem)))
+	recommended := max(neededCPU, neededMem, floor.Replicas, minAvailableReplicas)
+	if recommended >= replicas {
+		return nil
+	}
+
+	savings := r.calculateMonthlyCost(ctx, podCPU, podMem) * float64(replicas-recommended)
+	if savings < 1.0 {
+		return nil
+	}
+
+	rec := &Recommendation{
+		Type:                ReplicaRightSize,
+		DeploymentName:      deploymentName,
+		Namespace:           analyses[0].Namespace,
+		WorkloadType:        w.workloadType,
+		Environment:         w.environment,
+		Provider:            r.pricingProvider.Name(),
+		CurrentCPU:          currentCPU,
+		CurrentMemory:       currentMem,
+		RecommendedCPU:      podCPU,
+		RecommendedMemory:   podMem,
+		CurrentReplicas:     replicas,
+		RecommendedReplicas: recommended,
+		Savings:             savings,
+		Owner:               w.owner,
+		Confidence:          calculateConfidence(analyses[0].DataQuality, analyses[0].HasSufficientData, analyses[0].CPUPattern.Type),
+		DataQuality:         analyses[0].DataQuality,
+		PatternInfo:         buildPatternInfo(analyses[0].CPUPattern, analyses[0].MemoryPattern, analyses[0].CPUGrowth),
+		HasSufficientData:   analyses[0].HasSufficientData,
+	}
+
+	reasonParts := []string{
+		fmt.Sprintf("Replicas under-utilized: %d replicas use %.0f%% of their CPU and %.0f%% of their memory, %d are enough",
+			replicas,
+			float64(usedCPU)/float64(podCPU*int64(replicas))*100,
+			float64(usedMem)/float64(podMem*int64(replicas))*100,
+			recommended),
+		fmt.Sprintf("Workload: %s, Safety: %.1fx, Env: %s", w.workloadType, w.safetyBuffer, w.environment),
+	}
+	if floor.Reason != "" {
+		reasonParts = append(reasonParts, fmt.Sprintf("Floor: %d (%s)", floor.Replicas, floor.Reason))
+	}
+	if len(w.settings.Policies) > 0 {
+		reasonParts = append(reasonParts, fmt.Sprintf("Policy: %s", w.settings.Name()))
+	}
+	reasonParts = withOverrides(reasonParts, w.settings)
+	rec.Reason = strings.Join(reasonParts, " | ")
+
+	if rec.Savings > 50 {
+		rec.Impact = "HIGH"
+	} else if rec.Savings > 20 {
+		rec.Impact = "MEDIUM"
+	} else {
+		rec.Impact = "LOW"
+	}
+
+	// Removing most replicas leaves little room for a failing pod
+	reduction := 1 - float64(recommended)/float64(replicas)
+	if reduction > 0.5 {
+		rec.Risk = "HIGH"
+	} else if reduction > 0.25 {
+		rec.Risk = "MEDIUM"
+	} else {
+		rec.Risk = w.config.RiskLevel
+	}
+
+	return rec
+}
+
+// podRequests returns the requests of one replica, now and once its
+// containers are right-sized as containerRecs recommend
+func podRequests(analyses []analyzer.PodAnalysis, containerRecs []*Recommendation) (currentCPU, currentMem, podCPU, podMem int64) {
+	for _, group := range analyzer.GroupByContainer(analyses) {
+		var cpu, mem int64
+		for _, analysis := range group {
+			cpu += analysis.RequestedCPU
+			mem += analysis.RequestedMemory
+		}
+		cpu /= int64(len(group))
+		mem /= int64(len(group))
+		currentCPU += cpu
+		currentMem += mem
+
+		for _, rec := range containerRecs {
+			if rec.Type == RightSize && rec.ContainerName == group[0].ContainerName {
+				cpu, mem = rec.RecommendedCPU, rec.RecommendedMemory
+			}
+		}
+		podCPU += cpu
+		podMem += mem
+	}
+	return currentCPU, currentMem, podCPU, podMem
+}
@@ -0,0 +1,94 @@
+package recommender
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+)
+
+// replicaPods returns one app container per replica, each requesting 1 CPU
+// and 1Gi and using cpu and memory
+func replicaPods(replicas int, cpu, memory int64) []analyzer.PodAnalysis {
+	var pods []analyzer.PodAnalysis
+	for i := 0; i < replicas; i++ {
+		pods = append(pods, analyzer.PodAnalysis{
+			Name:            fmt.Sprintf("web-%d", i),
+			Namespace:       "shop",
+			ContainerName:   "app",
+			WorkloadType:    "Deployment",
+			RequestedCPU:    1000,
+			RequestedMemory: 1 << 30,
+			ActualCPU:       cpu,
+			ActualMemory:    memory,
+		})
+	}
+	return pods
+}
+
+func TestAnalyzeReplicas(t *testing.T) {
+	r := New()
+
+	// Each replica uses too much to be right-sized, but ten of them are
+	// more than the workload needs
+	pods := replicaPods(10, 420, 440<<20)
+	containerRecs := r.AnalyzeContainers(pods, "web")
+	if len(containerRecs) != 1 || containerRecs[0].Type != NoAction {
+		t.Fatalf("Expected the containers to be left alone, got %+v", containerRecs)
+	}
+
+	rec := r.AnalyzeReplicas(pods, "web", 10, ReplicaFloor{}, containerRecs)
+	if rec == nil {
+		t.Fatal("Expected a replica recommendation")
+	}
+	if rec.Type != ReplicaRightSize || rec.CurrentReplicas != 10 || rec.RecommendedReplicas != 8 {
+		t.Errorf("Expected REPLICA_RIGHT_SIZE from 10 to 8 replicas, got %s %d -> %d", rec.Type, rec.CurrentReplicas, rec.RecommendedReplicas)
+	}
+	if rec.RecommendedCPU != 1000 || rec.ContainerName != "" || rec.Savings <= 0 {
+		t.Errorf("Unexpected recommendation: cpu=%dm container=%q savings=%.2f", rec.RecommendedCPU, rec.ContainerName, rec.Savings)
+	}
+
+	floor := ReplicaFloor{Replicas: 9, Reason: "PodDisruptionBudget web (minAvailable 8)"}
+	rec = r.AnalyzeReplicas(pods, "web", 10, floor, containerRecs)
+	if rec == nil || rec.RecommendedReplicas != 9 || !strings.Contains(rec.Reason, "Floor: 9 (PodDisruptionBudget web") {
+		t.Errorf("Expected the floor to keep 9 replicas, got %+v", rec)
+	}
+
+	if rec := r.AnalyzeReplicas(pods, "web", 10, ReplicaFloor{Replicas: 10}, containerRecs); rec != nil {
+		t.Errorf("Expected no recommendation at the floor, got %d replicas", rec.RecommendedReplicas)
+	}
+
+	// Not every replica reports metrics
+	if rec := r.AnalyzeReplicas(pods, "web", 12, ReplicaFloor{}, containerRecs); rec != nil {
+		t.Errorf("Expected no recommendation with missing replicas, got %d replicas", rec.RecommendedReplicas)
+	}
+
+	scaled := replicaPods(10, 420, 440<<20)
+	for i := range scaled {
+		scaled[i].HasHPA = true
+	}
+	if rec := r.AnalyzeReplicas(scaled, "web", 10, ReplicaFloor{}, containerRecs); rec != nil {
+		t.Error("Expected no recommendation for an HPA-managed Deployment")
+	}
+}
+
+func TestAnalyzeReplicasAfterRightSize(t *testing.T) {
+	r := New()
+
+	// Right-sizing already removes the spare capacity, so the replica count
+	// must not shrink as well
+	pods := replicaPods(6, 200, 256<<20)
+	containerRecs := r.AnalyzeContainers(pods, "web")
+	if len(containerRecs) != 1 || containerRecs[0].Type != RightSize {
+		t.Fatalf("Expected a RIGHT_SIZE recommendation, got %+v", containerRecs)
+	}
+	if rec := r.AnalyzeReplicas(pods, "web", 6, ReplicaFloor{}, containerRecs); rec != nil {
+		t.Errorf("Expected no replica recommendation on top of right-sizing, got %d replicas", rec.RecommendedReplicas)
+	}
+
+	// Replicas are never reduced below two
+	if rec := r.AnalyzeReplicas(pods[:2], "web", 2, ReplicaFloor{}, nil); rec != nil {
+		t.Errorf("Expected no recommendation for two replicas, got %d", rec.RecommendedReplicas)
+	}
+}
@@ -0,0 +1,68 @@
+package recommender
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+)
+
+// vpaTolerance is how far, in percent, a request may be from the VPA's
+// target when the VPA reports no bounds, before it counts as a disagreement
+const vpaTolerance = 25
+
+// compareVPA shows the recommendation of an existing VPA next to rec and
+// flags recommended requests outside the range the VPA is confident in
+func compareVPA(rec *Recommendation, vpa *analyzer.VPAInfo) {
+	rec.VPAName = vpa.Name
+	rec.VPACPU = vpa.TargetCPU
+	rec.VPAMemory = vpa.TargetMemory
+
+	reasonParts := []string{
+		fmt.Sprintf("VPA %s (%s): CPU %dm, Memory %dMi", vpa.Name, vpa.UpdateMode, vpa.TargetCPU, vpa.TargetMemory/(1024*1024)),
+	}
+
+	// SCALE_DOWN recommendations, and NO_ACTION ones for containers without
+	// a CPU request, carry no container requests to compare against the VPA
+	if rec.Type == RightSize || (rec.Type == NoAction && rec.RecommendedCPU > 0) {
+		var disagreements []string
+		if d, bound := vpaDisagreement(rec.RecommendedCPU, vpa.TargetCPU, vpa.LowerBoundCPU, vpa.UpperBoundCPU); d != "" {
+			disagreements = append(disagreements, fmt.Sprintf("CPU %dm %s %dm", rec.RecommendedCPU, d, bound))
+		}
+		if d, bound := vpaDisagreement(rec.RecommendedMemory, vpa.TargetMemory, vpa.LowerBoundMemory, vpa.UpperBoundMemory); d != "" {
+			disagreements = append(disagreements, fmt.Sprintf("Memory %dMi %s %dMi", rec.RecommendedMemory/(1024*1024), d, bound/(1024*1024)))
+		}
+		if len(disagreements) > 0 {
+			reasonParts = append(reasonParts, "⚠️ Disagrees with VPA: "+strings.Join(disagreements, ", "))
+		}
+	}
+
+	rec.Reason = strings.Join(append([]string{rec.Reason}, reasonParts...), " | ")
+}
+
+// vpaDisagreement describes how value falls outside the VPA's bounds, or
+// strays from its target when it reports none, with the VPA value it is
+// compared to. The description is empty when they agree.
+func vpaDisagreement(value, target, lower, upper int64) (string, int64) {
+	if target == 0 {
+		return "", 0
+	}
+	if lower > 0 && upper > 0 {
+		switch {
+		case value < lower:
+			return "below lower bound", lower
+		case value > upper:
+			return "above upper bound", upper
+		}
+		return "", 0
+	}
+
+	diff := float64(value-target) / float64(target) * 100
+	switch {
+	case diff > vpaTolerance:
+		return "above target", target
+	case diff < -vpaTolerance:
+		return "below target", target
+	}
+	return "", 0
+}
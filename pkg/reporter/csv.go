@@ -15,12 +15,18 @@ func GenerateCSV(report *Report, writer io.Writer) error {
 	header := []string{
 		"Namespace",
 		"Workload",
+		"Container",
 		"Environment",
 		"Type",
 		"Current CPU (m)",
 		"Current Memory (Mi)",
 		"Recommended CPU (m)",
 		"Recommended Memory (Mi)",
+		"Recommended CPU Limit",
+		"Recommended Memory Limit",
+		"Current Replicas",
+		"Recommended Replicas",
+		"HPA Change",
 		"Monthly Savings ($)",
 		"Risk",
 		"Impact",
@@ -35,12 +41,18 @@ func GenerateCSV(report *Report, writer io.Writer) error {
 		row := []string{
 			rec.Workload.Namespace,
 			rec.Workload.Deployment,
+			rec.Workload.Container,
 			rec.Environment,
 			string(rec.Type),
 			fmt.Sprintf("%d", rec.CurrentCPU),
 			fmt.Sprintf("%d", rec.CurrentMemory/(1024*1024)),
 			fmt.Sprintf("%d", rec.RecommendedCPU),
 			fmt.Sprintf("%d", rec.RecommendedMemory/(1024*1024)),
+			cpuLimit(rec.RecommendedCPULimit),
+			memoryLimit(rec.RecommendedMemoryLimit),
+			replicaCount(rec.CurrentReplicas),
+			replicaCount(rec.RecommendedReplicas),
+			hpaChange(rec),
 			fmt.Sprintf("%.2f", rec.SavingsMonthly),
 			string(rec.Risk),
 			rec.Impact,
@@ -228,6 +228,14 @@ const htmlTemplate = `
             background: #fef7e0;
             color: #f9ab00;
         }
+        .type-replica_right_size {
+            background: #e6f4ea;
+            color: #188038;
+        }
+        .type-hpa_adjust {
+            background: #e8f0fe;
+            color: #1967d2;
+        }
         .type-no_action {
             background: #f1f3f4;
             color: #5f6368;
@@ -383,7 +391,7 @@ const htmlTemplate = `
                     {{range .Recommendations}}
                     <tr>
                         <td>
-                            <strong>{{.Workload.Namespace}}/{{.Workload.Deployment}}</strong>
+                            <strong>{{displayName .Workload}}</strong>
                         </td>
                         <td>
                             <span class="env-badge env-{{.Environment}}">{{.Environment}}</span>
@@ -392,12 +400,16 @@ const htmlTemplate = `
                             <span class="type-badge type-{{.Type | lower}}">{{.Type}}</span>
                         </td>
                         <td>
+                            {{if or (eq .Type "REPLICA_RIGHT_SIZE") (eq .Type "HPA_ADJUST")}}{{.CurrentReplicas}} replicas of<br>{{end}}
                             {{.CurrentCPU}}m CPU<br>
                             {{div .CurrentMemory 1048576}}Mi RAM
                         </td>
                         <td>
+                            {{if or (eq .Type "REPLICA_RIGHT_SIZE") (eq .Type "HPA_ADJUST")}}{{.RecommendedReplicas}} replicas of<br>{{end}}
                             {{.RecommendedCPU}}m CPU<br>
                             {{div .RecommendedMemory 1048576}}Mi RAM
+                            {{if eq .Type "RIGHT_SIZE"}}<br><small>Limits: {{cpuLimit .RecommendedCPULimit}} CPU, {{memoryLimit .RecommendedMemoryLimit}} RAM</small>{{end}}
+                            {{with hpaChange .}}<br><small>{{.}}</small>{{end}}
                         </td>
                         <td>
                             <strong style="color: #34a853;">${{printf "%.2f" .SavingsMonthly}}</strong>
@@ -425,6 +437,10 @@ const htmlTemplate = `
 func GenerateHTML(report *Report, writer io.Writer) error {
 	// Parse template
 	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"displayName": displayName,
+		"cpuLimit":    cpuLimit,
+		"memoryLimit": memoryLimit,
+		"hpaChange":   hpaChange,
 		"lower": func(s interface{}) string {
 			return strings.ToLower(fmt.Sprintf("%v", s))
 		},
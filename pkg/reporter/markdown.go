@@ -52,9 +52,19 @@ func GenerateMarkdown(report *Report, writer io.Writer) error {
 	sb.WriteString("|----------|-------------|------|---------|-------------|---------|------|\n")
 
 	for _, rec := range report.Recommendations {
-		workloadName := fmt.Sprintf("%s/%s", rec.Workload.Namespace, rec.Workload.Deployment)
+		workloadName := displayName(rec.Workload)
 		currentResources := fmt.Sprintf("%dm CPU, %dMi RAM", rec.CurrentCPU, rec.CurrentMemory/(1024*1024))
 		recommendedResources := fmt.Sprintf("%dm CPU, %dMi RAM", rec.RecommendedCPU, rec.RecommendedMemory/(1024*1024))
+		if rec.Type == models.RecommendationRightSize {
+			recommendedResources += fmt.Sprintf(" (limits: %s CPU, %s RAM)", cpuLimit(rec.RecommendedCPULimit), memoryLimit(rec.RecommendedMemoryLimit))
+		}
+		if rec.Type == models.RecommendationReplicaRightSize || rec.Type == models.RecommendationHPAAdjust {
+			currentResources = fmt.Sprintf("%d × %s", rec.CurrentReplicas, currentResources)
+			recommendedResources = fmt.Sprintf("%d × %s", rec.RecommendedReplicas, recommendedResources)
+		}
+		if change := hpaChange(rec); change != "" {
+			recommendedResources += " (" + change + ")"
+		}
 
 		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | $%.2f | %s |\n",
 			workloadName,
@@ -74,10 +84,9 @@ func GenerateMarkdown(report *Report, writer io.Writer) error {
 	topRecs := getTopRecommendations(report.Recommendations, 5)
 	if len(topRecs) > 0 {
 		for i, rec := range topRecs {
-			sb.WriteString(fmt.Sprintf("%d. **%s/%s** - $%.2f/month\n",
+			sb.WriteString(fmt.Sprintf("%d. **%s** - $%.2f/month\n",
 				i+1,
-				rec.Workload.Namespace,
-				rec.Workload.Deployment,
+				displayName(rec.Workload),
 				rec.SavingsMonthly,
 			))
 			sb.WriteString(fmt.Sprintf("   - Type: %s | Risk: %s\n", rec.Type, rec.Risk))
@@ -1,6 +1,8 @@
 package reporter
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/opscart/k8s-cost-optimizer/pkg/models"
@@ -104,9 +106,10 @@ func (r *Reporter) calculateStats(report *Report) {
 		}
 
 		// Workload type stats
-		workloadType := rec.Workload.Deployment // This contains workload name, need type
-		// For now, we'll track by deployment name
-		// TODO: Add WorkloadType to models.Recommendation if not present
+		workloadType := rec.Workload.Kind
+		if workloadType == "" {
+			workloadType = "Unknown"
+		}
 		if _, exists := report.WorkloadTypeStats[workloadType]; !exists {
 			report.WorkloadTypeStats[workloadType] = &WorkloadTypeStats{
 				WorkloadType: workloadType,
@@ -127,3 +130,55 @@ func (r *Reporter) calculateStats(report *Report) {
 		}
 	}
 }
+
+// displayName formats a workload as namespace/name, adding the container
+// when the recommendation targets a single container
+func displayName(workload *models.Workload) string {
+	name := fmt.Sprintf("%s/%s", workload.Namespace, workload.Deployment)
+	if workload.Container != "" {
+		name = fmt.Sprintf("%s (%s)", name, workload.Container)
+	}
+	return name
+}
+
+// cpuLimit renders a CPU limit, 0 meaning unlimited
+func cpuLimit(millicores int64) string {
+	if millicores == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%dm", millicores)
+}
+
+// replicaCount renders the replica count of REPLICA_RIGHT_SIZE and
+// HPA_ADJUST recommendations, empty for other types
+func replicaCount(replicas int32) string {
+	if replicas == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", replicas)
+}
+
+// hpaChange describes the HPA settings an HPA_ADJUST recommendation
+// changes, empty for other types
+func hpaChange(rec *models.Recommendation) string {
+	if rec.Type != models.RecommendationHPAAdjust {
+		return ""
+	}
+
+	var changes []string
+	if rec.RecommendedTargetCPU != rec.CurrentTargetCPU {
+		changes = append(changes, fmt.Sprintf("CPU target %d%% → %d%%", rec.CurrentTargetCPU, rec.RecommendedTargetCPU))
+	}
+	if rec.RecommendedMinReplicas != rec.CurrentMinReplicas {
+		changes = append(changes, fmt.Sprintf("minReplicas %d → %d", rec.CurrentMinReplicas, rec.RecommendedMinReplicas))
+	}
+	return fmt.Sprintf("HPA %s: %s", rec.HPAName, strings.Join(changes, ", "))
+}
+
+// memoryLimit renders a memory limit, 0 meaning unlimited
+func memoryLimit(bytes int64) string {
+	if bytes == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%dMi", bytes/(1024*1024))
+}
@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// batchRecommendations sizes the CronJobs and standalone Jobs of a
+// namespace from the runs recorded in their history, as their pods have
+// usually finished by the time of a scan. Batch workloads are never
+// scaled down.
+func (s *Scanner) batchRecommendations(
+	ctx context.Context,
+	namespace string,
+	histAnalyzer analyzer.History,
+	lookbackDays int,
+) []*recommender.Recommendation {
+
+	var recommendations []*recommender.Recommendation
+
+	cronJobs, err := s.lister.CronJobs(ctx, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] Failed to list cronjobs in %s: %v\n", namespace, err)
+	}
+	for _, cj := range cronJobs {
+		recommendations = append(recommendations, s.batchWorkloadRecommendations(ctx, "CronJob", cj.ObjectMeta,
+			cj.Spec.JobTemplate.Spec.Template, histAnalyzer, lookbackDays)...)
+	}
+
+	jobs, err := s.lister.Jobs(ctx, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] Failed to list jobs in %s: %v\n", namespace, err)
+	}
+	for _, job := range jobs {
+		// Runs of a CronJob, or Jobs managed by another controller
+		if len(job.OwnerReferences) > 0 {
+			continue
+		}
+		recommendations = append(recommendations, s.batchWorkloadRecommendations(ctx, "Job", job.ObjectMeta,
+			job.Spec.Template, histAnalyzer, lookbackDays)...)
+	}
+
+	return recommendations
+}
+
+// batchWorkloadRecommendations creates one RIGHT_SIZE or NO_ACTION
+// recommendation per container of a batch workload's pod template
+func (s *Scanner) batchWorkloadRecommendations(
+	ctx context.Context,
+	kind string,
+	workload metav1.ObjectMeta,
+	template corev1.PodTemplateSpec,
+	histAnalyzer analyzer.History,
+	lookbackDays int,
+) []*recommender.Recommendation {
+
+	pods := s.annotatedPods(s.analyzer.AnalyzeTemplate(ctx, workload.Namespace, kind, workload.Name, template), kind, workload)
+
+	var recommendations []*recommender.Recommendation
+	for _, pod := range pods {
+		histMetrics, err := histAnalyzer.GetBatchMetrics(ctx, &models.Workload{
+			Namespace:  workload.Namespace,
+			Kind:       kind,
+			Deployment: workload.Name,
+			Container:  pod.ContainerName,
+		}, lookbackDays)
+		if err != nil {
+			if s.verbose {
+				fmt.Printf("[DEBUG] Historical data unavailable for %s %s/%s: %v\n", kind, workload.Namespace, workload.Name, err)
+			}
+			continue
+		}
+		if len(histMetrics.CPUSamples) == 0 || len(histMetrics.MemorySamples) == 0 {
+			if s.verbose {
+				fmt.Printf("[DEBUG] No runs of %s %s/%s in the last %d days\n", kind, workload.Namespace, workload.Name, lookbackDays)
+			}
+			continue
+		}
+
+		cpuPercentiles, err := analyzer.CalculatePercentiles(histMetrics.CPUSamples)
+		if err != nil {
+			continue
+		}
+		memPercentiles, err := analyzer.CalculatePercentiles(histMetrics.MemorySamples)
+		if err != nil {
+			continue
+		}
+
+		// A run that exceeds its memory is killed and retried, so memory
+		// is sized for the P99 of every run rather than the P95
+		pod.ActualCPU = int64(cpuPercentiles.P95)
+		pod.ActualMemory = int64(memPercentiles.P99)
+		pod.PeakCPU = int64(cpuPercentiles.P99)
+		pod.PeakMemory = int64(memPercentiles.Peak)
+
+		pod.CPUPattern = histMetrics.CPUPattern
+		pod.MemoryPattern = histMetrics.MemoryPattern
+		pod.DataQuality = histMetrics.DataQuality
+		pod.HasSufficientData = histMetrics.HasSufficientData
+		pod.DataDays = histMetrics.DataDays()
+
+		rec := s.recommender.AnalyzeRightSize([]analyzer.PodAnalysis{pod}, workload.Name)
+		if rec == nil {
+			continue
+		}
+
+		// Requests are only reserved while a run is in progress
+		dutyCycle := math.Min(histMetrics.ActiveTime().Hours()/
+			(time.Duration(lookbackDays)*24*time.Hour).Hours(), 1.0)
+		rec.Savings *= dutyCycle
+
+		rec.Reason = fmt.Sprintf("%s (Based on %d run(s) in %d days, running %.0f%% of the time: P95 CPU %.0fm, P99 Memory %.0fMi)",
+			rec.Reason, histMetrics.Runs, lookbackDays, dutyCycle*100,
+			cpuPercentiles.P95, memPercentiles.P99/(1024*1024))
+
+		recommendations = append(recommendations, rec)
+	}
+
+	return recommendations
+}
@@ -0,0 +1,166 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	"github.com/prometheus/client_golang/api"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakePrometheus answers range queries with one series per pod of each run,
+// with parallelism pods per run: CPU queries get cpu millicores and memory
+// queries get memory bytes. Queries without runOwner, the owner matchers
+// of the runs, get no series.
+func fakePrometheus(t *testing.T, runOwner string, runs, parallelism int, cpu, memory float64) (api.Client, *[]string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		value := memory
+		if strings.Contains(query, "container_cpu_usage_seconds_total") {
+			value = cpu
+		}
+
+		var series []string
+		if strings.Contains(query, runOwner) {
+			start := time.Now().Add(-72 * time.Hour)
+			for run := 0; run < runs; run++ {
+				runStart := start.Add(time.Duration(run) * 24 * time.Hour)
+				var values []string
+				for i := 0; i < 10; i++ {
+					values = append(values, fmt.Sprintf(`[%d,"%g"]`, runStart.Add(time.Duration(i)*time.Minute).Unix(), value))
+				}
+				for pod := 0; pod < parallelism; pod++ {
+					series = append(series, fmt.Sprintf(`{"metric":{"pod":"backup-%d-%d"},"values":[%s]}`, run, pod, strings.Join(values, ",")))
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, strings.Join(series, ","))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &queries
+}
+
+func TestScanCronJobHistory(t *testing.T) {
+	controller := true
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+		Name: "dump",
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		}},
+	}}}}
+
+	clientset, metricsClient := testCluster("shop", nil,
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "shop"},
+			Spec:       batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template}}},
+		},
+		// A run of the CronJob, which is not analyzed on its own
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup-28391040", Namespace: "shop", OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "batch/v1", Kind: "CronJob", Name: "backup", Controller: &controller},
+			}},
+			Spec: batchv1.JobSpec{Template: template},
+		},
+		// A standalone Job without recorded runs
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "shop"},
+			Spec:       batchv1.JobSpec{Template: template},
+		},
+	)
+	promClient, queries := fakePrometheus(t, `kube_job_owner{namespace="shop",owner_kind="CronJob",owner_name="backup"}`, 4, 1, 200, 256*1024*1024)
+
+	s := NewWithClients(clientset, metricsClient, false)
+	recommendations, err := s.ScanAndRecommendWithHistory(context.Background(), "shop", false, promClient, 7)
+	if err != nil {
+		t.Fatalf("ScanAndRecommendWithHistory failed: %v", err)
+	}
+
+	if len(recommendations) != 1 {
+		t.Fatalf("Expected one recommendation for the CronJob, got %d", len(recommendations))
+	}
+	rec := recommendations[0]
+	if rec.Type != recommender.RightSize || rec.WorkloadType != "CronJob" || rec.DeploymentName != "backup" || rec.ContainerName != "dump" {
+		t.Errorf("Expected RIGHT_SIZE for CronJob backup/dump, got %s for %s %s/%s", rec.Type, rec.WorkloadType, rec.DeploymentName, rec.ContainerName)
+	}
+	if rec.RecommendedCPU >= 2000 || rec.RecommendedCPU < 200 {
+		t.Errorf("Expected CPU sized from the 200m runs, got %dm", rec.RecommendedCPU)
+	}
+	if !strings.Contains(rec.Reason, "4 run(s)") {
+		t.Errorf("Expected the reason to count the runs, got %q", rec.Reason)
+	}
+
+	var sawJobQuery bool
+	for _, query := range *queries {
+		if strings.Contains(query, `kube_pod_owner{namespace="shop",owner_kind="Job",owner_name="migrate"}`) {
+			sawJobQuery = true
+		}
+		if strings.Contains(query, "backup-28391040") {
+			t.Errorf("Expected CronJob runs not to be queried as Jobs: %s", query)
+		}
+	}
+	if !sawJobQuery {
+		t.Errorf("Expected the standalone Job to be queried, got %v", *queries)
+	}
+}
+
+func TestScanParallelJobDutyCycle(t *testing.T) {
+	parallelism := int32(3)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "shop"},
+		Spec: batchv1.JobSpec{Parallelism: &parallelism, Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "dump",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("2Gi"),
+			}},
+		}}}}},
+	}
+
+	// Pods running side by side reserve their requests over the same time
+	// as a single pod, so they do not add to the duty cycle
+	savings := make(map[int]float64)
+	for _, pods := range []int{1, int(parallelism)} {
+		clientset, metricsClient := testCluster("shop", nil, job)
+		promClient, _ := fakePrometheus(t, `kube_pod_owner{namespace="shop",owner_kind="Job",owner_name="backup"}`, 4, pods, 200, 256*1024*1024)
+
+		recommendations, err := NewWithClients(clientset, metricsClient, false).
+			ScanAndRecommendWithHistory(context.Background(), "shop", false, promClient, 7)
+		if err != nil {
+			t.Fatalf("ScanAndRecommendWithHistory failed: %v", err)
+		}
+		if len(recommendations) != 1 {
+			t.Fatalf("Expected one recommendation for the Job, got %d", len(recommendations))
+		}
+		savings[pods] = recommendations[0].Savings
+	}
+
+	if savings[1] <= 0 || savings[int(parallelism)] != savings[1] {
+		t.Errorf("Expected parallel pods not to change the savings, got %v", savings)
+	}
+}
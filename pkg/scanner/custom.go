@@ -0,0 +1,137 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// customWorkload is an object of a configured workload kind
+type customWorkload struct {
+	kind     kube.WorkloadKind
+	resource string // kubectl resource, e.g. rollouts.argoproj.io
+	meta     metav1.ObjectMeta
+	template corev1.PodTemplateSpec
+}
+
+// WithDynamicClient lets the scanner read custom resources: owners of
+// pods, e.g. Argo Rollouts, the configured workload kinds and existing
+// VerticalPodAutoscalers
+func (s *Scanner) WithDynamicClient(client dynamic.Interface, mapper meta.RESTMapper) *Scanner {
+	s.dynamic = client
+	s.mapper = mapper
+	s.analyzer.WithDynamicClient(client, mapper)
+	return s
+}
+
+// WithWorkloadKinds makes scans recommend resources for additional workload
+// kinds, such as Argo Rollouts, like they do for Deployments
+func (s *Scanner) WithWorkloadKinds(kinds []kube.WorkloadKind) *Scanner {
+	s.workloadKinds = kinds
+	s.analyzer.Owners().WithWorkloadKinds(kinds)
+	return s
+}
+
+// customRecommendations runs analyze on the pods of every object of the
+// configured workload kinds in namespace
+func (s *Scanner) customRecommendations(
+	ctx context.Context,
+	namespace string,
+	workloadPods map[string][]analyzer.PodAnalysis,
+	analyze func(pods []analyzer.PodAnalysis, name string) []*recommender.Recommendation,
+) []*recommender.Recommendation {
+
+	var recommendations []*recommender.Recommendation
+	for _, workload := range s.customWorkloads(ctx, namespace) {
+		pods := s.annotatedPods(workloadPods[workloadKey(workload.kind.Kind, workload.meta.Name)], workload.kind.Kind, workload.meta)
+		if len(pods) == 0 {
+			continue
+		}
+		for i := range pods {
+			pods[i].TreatAs = workload.kind.TreatAs
+		}
+
+		recs := analyze(pods, workload.meta.Name)
+		for _, rec := range recs {
+			workload.locate(rec)
+		}
+		recommendations = append(recommendations, recs...)
+	}
+	return recommendations
+}
+
+// customWorkloads lists the objects of the configured workload kinds.
+// Kinds whose CRD is not installed are skipped.
+func (s *Scanner) customWorkloads(ctx context.Context, namespace string) []customWorkload {
+	if len(s.workloadKinds) == 0 {
+		return nil
+	}
+	if s.dynamic == nil {
+		fmt.Fprintln(os.Stderr, "[WARN] Additional workload kinds are configured but no dynamic client is available")
+		return nil
+	}
+
+	var workloads []customWorkload
+	for _, kind := range s.workloadKinds {
+		gvk := kind.GroupVersionKind()
+		mapping, err := s.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if s.verbose {
+				fmt.Printf("[DEBUG] Skipping workload kind %s: %v\n", kind.Kind, err)
+			}
+			continue
+		}
+
+		list, err := s.dynamic.Resource(mapping.Resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] Failed to list %s in %s: %v\n", mapping.Resource.Resource, namespace, err)
+			continue
+		}
+
+		resource := mapping.Resource.Resource
+		if gvk.Group != "" {
+			resource += "." + gvk.Group
+		}
+
+		for i := range list.Items {
+			obj := &list.Items[i]
+			template, err := kind.PodTemplate(obj)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] %v\n", err)
+				continue
+			}
+			workloads = append(workloads, customWorkload{
+				kind:     kind,
+				resource: resource,
+				meta: metav1.ObjectMeta{
+					Name:            obj.GetName(),
+					Namespace:       obj.GetNamespace(),
+					Labels:          obj.GetLabels(),
+					Annotations:     obj.GetAnnotations(),
+					OwnerReferences: obj.GetOwnerReferences(),
+				},
+				template: template,
+			})
+		}
+	}
+	return workloads
+}
+
+// locate records where kubectl patch finds the recommendation's container
+func (w customWorkload) locate(rec *recommender.Recommendation) {
+	rec.WorkloadResource = w.resource
+	for i, container := range w.template.Spec.Containers {
+		if container.Name == rec.ContainerName {
+			rec.ResourcesPath = fmt.Sprintf("%s/spec/containers/%d/resources", w.kind.PodTemplatePointer(), i)
+			return
+		}
+	}
+}
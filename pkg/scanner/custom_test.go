@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/executor"
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestScanRollouts(t *testing.T) {
+	ctx := context.Background()
+	clientset, metricsClient := testCluster("shop", map[string][]testContainer{
+		"canary": {
+			{name: "istio-proxy", cpu: "100m", memory: "128Mi", usageCPU: "80m", usageMem: "100Mi"},
+			{name: "app", cpu: "1", memory: "1Gi", usageCPU: "200m", usageMem: "256Mi"},
+		},
+	})
+
+	// Hand the pods' ReplicaSet over from the Deployment to a Rollout
+	if err := clientset.AppsV1().Deployments("shop").Delete(ctx, "canary", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := clientset.AppsV1().ReplicaSets("shop").Get(ctx, "canary-7d9f8b", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.OwnerReferences[0].APIVersion = "argoproj.io/v1alpha1"
+	rs.OwnerReferences[0].Kind = "Rollout"
+	if _, err := clientset.AppsV1().ReplicaSets("shop").Update(ctx, rs, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]interface{}{"name": "canary", "namespace": "shop"},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "istio-proxy"},
+						map[string]interface{}{"name": "app"},
+					},
+				},
+			},
+		},
+	}}
+	rolloutGVR := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			rolloutGVR:                         "RolloutList",
+			kube.VerticalPodAutoscalerResource: "VerticalPodAutoscalerList",
+		}, rollout)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(rolloutGVR.GroupVersion().WithKind("Rollout"), meta.RESTScopeNamespace)
+
+	s := NewWithClients(clientset, metricsClient, false).
+		WithDynamicClient(dynamicClient, mapper).
+		WithWorkloadKinds([]kube.WorkloadKind{{
+			Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout",
+			PodTemplatePath: ".spec.template", TreatAs: "Deployment",
+		}})
+
+	recommendations, err := s.ScanAndRecommend("shop", false)
+	if err != nil {
+		t.Fatalf("ScanAndRecommend failed: %v", err)
+	}
+
+	var rec *recommender.Recommendation
+	for _, r := range recommendations {
+		if r.ContainerName == "app" {
+			rec = r
+		}
+	}
+	if rec == nil {
+		t.Fatalf("Expected a recommendation for the Rollout's app container, got %d recommendation(s)", len(recommendations))
+	}
+	if rec.Type != recommender.RightSize || rec.WorkloadType != "Rollout" || rec.DeploymentName != "canary" {
+		t.Errorf("Expected RIGHT_SIZE for Rollout canary, got %s for %s %s", rec.Type, rec.WorkloadType, rec.DeploymentName)
+	}
+	if rec.ResourcesPath != "/spec/template/spec/containers/1/resources" {
+		t.Errorf("Unexpected resources path %q", rec.ResourcesPath)
+	}
+
+	command := executor.GenerateCommand(rec)
+	if !strings.HasPrefix(command, "kubectl patch rollouts.argoproj.io/canary -n shop --type=json") ||
+		!strings.Contains(command, `"path":"/spec/template/spec/containers/1/resources"`) {
+		t.Errorf("Unexpected command: %s", command)
+	}
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/datasource"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+)
+
+// NewFromPrometheus creates a scanner for clusters whose API server cannot
+// be reached, e.g. from outside the cluster network. Workloads and the
+// requests of their running pods are read from the kube-state-metrics in
+// source, and usage from its timeseries. Labels, annotations, autoscalers
+// and PodDisruptionBudgets are unknown to such scans, so they only
+// recommend container resources.
+func NewFromPrometheus(source *datasource.PrometheusSource, verbose bool) *Scanner {
+	return &Scanner{
+		source:      source,
+		prometheus:  source,
+		analyzer:    analyzer.New(nil),
+		recommender: recommender.New(),
+		verbose:     verbose,
+	}
+}
+
+// scanPrometheus scans namespace, or every namespace, from Prometheus
+// alone: each workload kube-state-metrics lists is sized from the usage
+// timeseries of its pods over lookbackDays, or from their current usage
+// without history. Batch workloads, whose requests are in the templates
+// of their Jobs, and pods without a controller are left out.
+func (s *Scanner) scanPrometheus(ctx context.Context, namespace string, allNamespaces bool, lookbackDays int) ([]*recommender.Recommendation, error) {
+	if allNamespaces {
+		namespace = ""
+	}
+	workloads, err := s.prometheus.ListWorkloads(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workloads: %w", err)
+	}
+	if s.verbose {
+		fmt.Printf("[DEBUG] Found %d workload(s) in Prometheus\n", len(workloads))
+	}
+
+	history := timeseriesHistory{source: s.prometheus, verbose: s.verbose}
+
+	var recommendations []*recommender.Recommendation
+	for _, workload := range workloads {
+		switch workload.Kind {
+		case "Pod", "Job", "CronJob":
+			continue
+		}
+
+		resources, err := s.prometheus.ContainerResources(ctx, workload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] Failed to read the pods of %s %s/%s: %v\n", workload.Kind, workload.Namespace, workload.Deployment, err)
+			continue
+		}
+		pods := analyzer.AnalyzeResources(workload.Namespace, workload.Kind, workload.Deployment, resources)
+		if len(pods) == 0 {
+			continue
+		}
+
+		recs, _ := s.generateHistoricalRecommendations(ctx, workload.Deployment, pods, history, lookbackDays)
+		recommendations = append(recommendations, recs...)
+	}
+
+	return recommendations, nil
+}
+
+// timeseriesHistory is the history of the CPU and memory timeseries of a
+// data source, which follow the busiest pod of a workload at each time
+type timeseriesHistory struct {
+	source  datasource.DataSource
+	verbose bool
+}
+
+func (h timeseriesHistory) GetWorkloadMetrics(ctx context.Context, workload *models.Workload, days int) (*analyzer.HistoricalMetrics, error) {
+	duration := time.Duration(days) * 24 * time.Hour
+
+	cpu, err := h.source.GetTimeseries(ctx, workload, duration, datasource.MetricCPU)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CPU usage: %w", err)
+	}
+	memory, err := h.source.GetTimeseries(ctx, workload, duration, datasource.MetricMemory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory usage: %w", err)
+	}
+
+	return analyzer.NewSampledMetrics(workload, days, metricSamples(cpu), metricSamples(memory), h.verbose), nil
+}
+
+// GetBatchMetrics is unsupported: batch workloads are not scanned without
+// the API server
+func (h timeseriesHistory) GetBatchMetrics(ctx context.Context, workload *models.Workload, days int) (*analyzer.HistoricalMetrics, error) {
+	return nil, errors.New("batch history is not available from timeseries")
+}
+
+// metricSamples converts timeseries samples for the analyzer
+func metricSamples(samples []models.Sample) []analyzer.MetricSample {
+	converted := make([]analyzer.MetricSample, len(samples))
+	for i, sample := range samples {
+		converted[i] = analyzer.MetricSample{Timestamp: sample.Timestamp, Value: sample.Value}
+	}
+	return converted
+}
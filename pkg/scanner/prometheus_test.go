@@ -0,0 +1,147 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/datasource"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+)
+
+// kubeStateMetrics are the kube-state-metrics series of shop: Deployment
+// web with two running pods whose app container requests 1 CPU and 1Gi,
+// a run of CronJob backup and a pod without a controller
+var kubeStateMetrics = map[string][]string{
+	"kube_replicaset_owner": {`"namespace":"shop","replicaset":"web-7d9f8b","owner_kind":"Deployment","owner_name":"web"`},
+	"kube_job_owner":        {`"namespace":"shop","job_name":"backup-28391040","owner_kind":"CronJob","owner_name":"backup"`},
+	"kube_pod_owner": {
+		`"namespace":"shop","pod":"web-7d9f8b-abcde","owner_kind":"ReplicaSet","owner_name":"web-7d9f8b"`,
+		`"namespace":"shop","pod":"web-7d9f8b-fghij","owner_kind":"ReplicaSet","owner_name":"web-7d9f8b"`,
+		`"namespace":"shop","pod":"backup-28391040-xyz12","owner_kind":"Job","owner_name":"backup-28391040"`,
+		`"namespace":"shop","pod":"debug","owner_kind":"<none>","owner_name":"<none>"`,
+	},
+	"kube_deployment_labels": {`"namespace":"shop","deployment":"web"`},
+	"kube_pod_container_info": {
+		`"namespace":"shop","pod":"web-7d9f8b-abcde","container":"app"`,
+		`"namespace":"shop","pod":"web-7d9f8b-fghij","container":"app"`,
+	},
+	"kube_pod_container_resource_requests": {
+		`"namespace":"shop","pod":"web-7d9f8b-abcde","container":"app","resource":"cpu"} 1`,
+		`"namespace":"shop","pod":"web-7d9f8b-abcde","container":"app","resource":"memory"} 1073741824`,
+		`"namespace":"shop","pod":"web-7d9f8b-fghij","container":"app","resource":"cpu"} 1`,
+		`"namespace":"shop","pod":"web-7d9f8b-fghij","container":"app","resource":"memory"} 1073741824`,
+	},
+}
+
+// fakePrometheusCluster answers instant queries for kubeStateMetrics by
+// metric name, with a value of 1 unless the labels are followed by one,
+// and usage queries with cpu millicores and memory bytes: range queries
+// with 4 days of samples when history is true, instant queries always.
+// Queries are recorded.
+func fakePrometheusCluster(t *testing.T, history bool, cpu, memory float64) (*datasource.PrometheusSource, *[]string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		usage := memory
+		if strings.Contains(query, "container_cpu_usage_seconds_total") {
+			usage = cpu
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/query_range") {
+			var values []string
+			if history {
+				end := time.Now()
+				for at := end.Add(-96 * time.Hour); at.Before(end); at = at.Add(5 * time.Minute) {
+					values = append(values, fmt.Sprintf(`[%d,"%g"]`, at.Unix(), usage))
+				}
+			}
+			series := ""
+			if len(values) > 0 {
+				series = fmt.Sprintf(`{"metric":{},"values":[%s]}`, strings.Join(values, ","))
+			}
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, series)
+			return
+		}
+
+		var result []string
+		if strings.HasPrefix(query, "max(") {
+			result = append(result, fmt.Sprintf(`{"metric":{},"value":[%d,"%g"]}`, time.Now().Unix(), usage))
+		} else if i := strings.Index(query, "{"); i > 0 {
+			for _, sample := range kubeStateMetrics[query[:i]] {
+				labels, value, found := strings.Cut(sample, "} ")
+				if !found {
+					value = "1"
+				}
+				result = append(result, fmt.Sprintf(`{"metric":{%s},"value":[%d,"%s"]}`, labels, time.Now().Unix(), value))
+			}
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, strings.Join(result, ","))
+	}))
+	t.Cleanup(server.Close)
+
+	source, err := datasource.NewPrometheusSource(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source, &queries
+}
+
+func TestScanPrometheusOnly(t *testing.T) {
+	tests := []struct {
+		name       string
+		history    bool
+		wantReason string
+	}{
+		{name: "history", history: true, wantReason: "Based on 7-day P95: CPU 100m, Memory 128Mi"},
+		{name: "current usage", history: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, queries := fakePrometheusCluster(t, tt.history, 100, 128<<20)
+
+			recs, err := NewFromPrometheus(source, false).Scan(context.Background(), "shop", false, 7)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+
+			if len(recs) != 1 {
+				t.Fatalf("Expected one recommendation for web, got %d", len(recs))
+			}
+			rec := recs[0]
+			if rec.DeploymentName != "web" || rec.WorkloadType != "Deployment" || rec.ContainerName != "app" {
+				t.Errorf("Expected a recommendation for Deployment web/app, got %s %s/%s", rec.WorkloadType, rec.DeploymentName, rec.ContainerName)
+			}
+			if rec.Type != recommender.RightSize || rec.CurrentCPU != 1000 || rec.RecommendedCPU >= 1000 {
+				t.Errorf("Expected web to be right-sized from 1000m, got %s from %dm to %dm", rec.Type, rec.CurrentCPU, rec.RecommendedCPU)
+			}
+			if rec.UsageCPU != 100 || rec.UsageMemory != 128<<20 {
+				t.Errorf("Expected usage of 100m/128Mi, got %dm/%d", rec.UsageCPU, rec.UsageMemory)
+			}
+			if tt.wantReason != "" && !strings.Contains(rec.Reason, tt.wantReason) {
+				t.Errorf("Expected the reason to contain %q, got %q", tt.wantReason, rec.Reason)
+			}
+
+			// Batch workloads and pods without a controller are not sized
+			for _, query := range *queries {
+				if strings.Contains(query, "backup") || strings.Contains(query, `pod="debug"`) {
+					t.Errorf("Expected only web to be queried, got %s", query)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// replicaRecommendation recommends fewer replicas for a Deployment whose
+// replicas are under-used, keeping what its PodDisruptionBudgets require
+func (s *Scanner) replicaRecommendation(
+	deploy appsv1.Deployment,
+	pods []analyzer.PodAnalysis,
+	containerRecs []*recommender.Recommendation,
+	pdbs []policyv1.PodDisruptionBudget,
+) *recommender.Recommendation {
+
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+
+	floor := replicaFloor(pdbs, deploy.Spec.Template.Labels, replicas)
+	return s.recommender.AnalyzeReplicas(pods, deploy.Name, replicas, floor, containerRecs)
+}
+
+// withHPARecommendation adds the HPA_ADJUST recommendation of an
+// autoscaled workload to its container recommendations
+func (s *Scanner) withHPARecommendation(pods []analyzer.PodAnalysis, name string, recs []*recommender.Recommendation) []*recommender.Recommendation {
+	if rec := s.recommender.AnalyzeHPA(pods, name, recs); rec != nil {
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+// replicaFloor returns the fewest replicas that still let the
+// PodDisruptionBudgets selecting podLabels allow one eviction, so node
+// drains keep working after scaling down
+func replicaFloor(pdbs []policyv1.PodDisruptionBudget, podLabels map[string]string, replicas int32) recommender.ReplicaFloor {
+	var floor recommender.ReplicaFloor
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+
+		var n int32
+		var rule string
+		switch {
+		case pdb.Spec.MinAvailable != nil:
+			rule = "minAvailable " + pdb.Spec.MinAvailable.String()
+			n = replicas
+			for candidate := int32(1); candidate < replicas; candidate++ {
+				minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, int(candidate), true)
+				if err == nil && int(candidate)-minAvailable >= 1 {
+					n = candidate
+					break
+				}
+			}
+		case pdb.Spec.MaxUnavailable != nil:
+			// Only a zero maxUnavailable blocks evictions; it does at any size
+			maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, int(replicas), true)
+			if err != nil || maxUnavailable > 0 {
+				continue
+			}
+			rule = "maxUnavailable " + pdb.Spec.MaxUnavailable.String()
+			n = replicas
+		default:
+			continue
+		}
+
+		if n > floor.Replicas {
+			floor = recommender.ReplicaFloor{
+				Replicas: n,
+				Reason:   fmt.Sprintf("PodDisruptionBudget %s (%s)", pdb.Name, rule),
+			}
+		}
+	}
+	return floor
+}
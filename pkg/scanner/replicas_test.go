@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"testing"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestReplicaFloor(t *testing.T) {
+	pdb := func(name string, selector map[string]string, minAvailable, maxUnavailable *intstr.IntOrString) policyv1.PodDisruptionBudget {
+		return policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector:       &metav1.LabelSelector{MatchLabels: selector},
+				MinAvailable:   minAvailable,
+				MaxUnavailable: maxUnavailable,
+			},
+		}
+	}
+	value := func(v intstr.IntOrString) *intstr.IntOrString { return &v }
+	web := map[string]string{"app": "web"}
+
+	tests := []struct {
+		name   string
+		pdbs   []policyv1.PodDisruptionBudget
+		want   int32
+		reason string
+	}{
+		{"no budgets", nil, 0, ""},
+		{"other pods", []policyv1.PodDisruptionBudget{pdb("api", map[string]string{"app": "api"}, value(intstr.FromInt32(5)), nil)}, 0, ""},
+		{"minAvailable count", []policyv1.PodDisruptionBudget{pdb("web", web, value(intstr.FromInt32(3)), nil)}, 4, "PodDisruptionBudget web (minAvailable 3)"},
+		{"minAvailable percent", []policyv1.PodDisruptionBudget{pdb("web", web, value(intstr.FromString("75%")), nil)}, 4, "PodDisruptionBudget web (minAvailable 75%)"},
+		{"minAvailable 100%", []policyv1.PodDisruptionBudget{pdb("web", web, value(intstr.FromString("100%")), nil)}, 10, ""},
+		{"maxUnavailable", []policyv1.PodDisruptionBudget{pdb("web", web, nil, value(intstr.FromInt32(1)))}, 0, ""},
+		{"maxUnavailable zero", []policyv1.PodDisruptionBudget{pdb("web", web, nil, value(intstr.FromInt32(0)))}, 10, "PodDisruptionBudget web (maxUnavailable 0)"},
+		{"highest floor wins", []policyv1.PodDisruptionBudget{
+			pdb("web", web, value(intstr.FromInt32(2)), nil),
+			pdb("all", map[string]string{}, value(intstr.FromInt32(5)), nil),
+		}, 6, "PodDisruptionBudget all (minAvailable 5)"},
+	}
+
+	for _, tt := range tests {
+		floor := replicaFloor(tt.pdbs, map[string]string{"app": "web", "tier": "frontend"}, 10)
+		if floor.Replicas != tt.want {
+			t.Errorf("%s: expected a floor of %d, got %d", tt.name, tt.want, floor.Replicas)
+		}
+		if tt.reason != "" && floor.Reason != tt.reason {
+			t.Errorf("%s: expected reason %q, got %q", tt.name, tt.reason, floor.Reason)
+		}
+	}
+}
@@ -2,27 +2,47 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"os"
 	"path/filepath"
 
 	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/datasource"
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/policy"
 	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
 	"github.com/prometheus/client_golang/api"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 type Scanner struct {
-	clientset     *kubernetes.Clientset
-	metricsClient *metricsv.Clientset
-	analyzer      *analyzer.Analyzer
-	recommender   *recommender.Recommender
-	verbose       bool
+	config      *rest.Config
+	clientset   kubernetes.Interface
+	source      datasource.DataSource
+	lister      kube.Lister
+	analyzer    *analyzer.Analyzer
+	recommender *recommender.Recommender
+	verbose     bool
+
+	// Without the API server, workloads are read from Prometheus alone
+	prometheus *datasource.PrometheusSource
+
+	// Custom resources, e.g. Argo Rollouts
+	dynamic       dynamic.Interface
+	mapper        meta.RESTMapper
+	workloadKinds []kube.WorkloadKind
 }
 
 func New(kubeconfigPath string, verbose bool) (*Scanner, error) {
@@ -52,16 +72,94 @@ func New(kubeconfigPath string, verbose bool) (*Scanner, error) {
 	}
 
 	metricsClient, err := metricsv.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics client: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	s := NewWithClients(clientset, metricsClient, verbose)
+	s.config = config
+
+	// Follow owner references through custom resources, e.g. Argo Rollouts
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+	s.WithDynamicClient(dynamicClient, mapper)
 
+	return s, nil
+}
+
+// NewWithClients creates a scanner from existing clients, e.g. the fake
+// clientsets in tests. Usage is read from metrics-server until
+// WithDataSource sets another source.
+func NewWithClients(clientset kubernetes.Interface, metricsClient metricsv.Interface, verbose bool) *Scanner {
 	return &Scanner{
-		clientset:     clientset,
-		metricsClient: metricsClient,
-		analyzer:      analyzer.New(clientset, metricsClient),
-		recommender:   recommender.New(),
-		verbose:       verbose,
-	}, nil
+		clientset:   clientset,
+		source:      datasource.NewMetricsServerSource(clientset, metricsClient),
+		lister:      kube.NewClientLister(clientset),
+		analyzer:    analyzer.New(clientset),
+		recommender: recommender.New(),
+		verbose:     verbose,
+	}
+}
+
+// WithDataSource makes scans read usage from source, e.g. a
+// datasource.Chain of Prometheus and metrics-server. Scans analyze
+// history when source has some.
+func (s *Scanner) WithDataSource(source datasource.DataSource) *Scanner {
+	s.source = source
+	return s
+}
+
+// DataSource returns the source scans read usage from
+func (s *Scanner) DataSource() datasource.DataSource {
+	return s.source
 }
 
+// WithLister makes scans read workloads, pods, HPAs and owners through lister
+// (e.g. informer caches) instead of listing them from the API server
+func (s *Scanner) WithLister(lister kube.Lister) *Scanner {
+	s.lister = lister
+	s.analyzer.WithLister(lister)
+	return s
+}
+
+// listNamespaces returns the names of every namespace
+func (s *Scanner) listNamespaces(ctx context.Context) ([]string, error) {
+	nsList, err := s.lister.Namespaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	namespaces := []string{}
+	for _, ns := range nsList {
+		namespaces = append(namespaces, ns.Name)
+	}
+	return namespaces, nil
+}
+
+// Scan scans namespace, or every namespace, from the history of the data
+// source over lookbackDays when it has history, e.g. Prometheus. Otherwise,
+// or when the historical scan fails, it scans the current usage. Scanners
+// created with NewFromPrometheus scan Prometheus alone.
+func (s *Scanner) Scan(ctx context.Context, namespace string, allNamespaces bool, lookbackDays int) ([]*recommender.Recommendation, error) {
+	if s.prometheus != nil {
+		return s.scanPrometheus(ctx, namespace, allNamespaces, lookbackDays)
+	}
+	if history := datasource.HistoryOf(s.source); history != nil {
+		recommendations, err := s.scanWithHistory(ctx, namespace, allNamespaces, history.History(s.verbose), lookbackDays)
+		if err == nil {
+			return recommendations, nil
+		}
+		fmt.Fprintf(os.Stderr, "[WARN] Historical scan failed: %v, falling back to current usage\n", err)
+	}
+	return s.ScanAndRecommend(namespace, allNamespaces)
+}
+
+// ScanAndRecommend scans namespace, or every namespace, from the current
+// usage of the data source
 func (s *Scanner) ScanAndRecommend(namespace string, allNamespaces bool) ([]*recommender.Recommendation, error) {
 	ctx := context.Background()
 
@@ -70,21 +168,21 @@ func (s *Scanner) ScanAndRecommend(namespace string, allNamespaces bool) ([]*rec
 		return nil, fmt.Errorf("failed to connect to cluster: %w", err)
 	}
 
-	fmt.Printf("[INFO] Connected to cluster (version: %s)\n", version.GitVersion)
+	if s.verbose {
+		fmt.Printf("[DEBUG] Connected to cluster (version: %s)\n", version.GitVersion)
+	}
+
+	s.analyzer.Owners().Reset()
 
 	namespaces := []string{namespace}
 	if allNamespaces {
-		nsList, err := s.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		namespaces, err = s.listNamespaces(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list namespaces: %w", err)
-		}
-		namespaces = []string{}
-		for _, ns := range nsList.Items {
-			namespaces = append(namespaces, ns.Name)
+			return nil, err
 		}
-		fmt.Printf("[INFO] Scanning %d namespaces\n", len(namespaces))
-	} else {
-		fmt.Printf("[INFO] Scanning namespace: %s\n", namespace)
+	}
+	if s.verbose {
+		fmt.Printf("[DEBUG] Scanning %d namespace(s)\n", len(namespaces))
 	}
 
 	var allRecommendations []*recommender.Recommendation
@@ -92,7 +190,7 @@ func (s *Scanner) ScanAndRecommend(namespace string, allNamespaces bool) ([]*rec
 	for _, ns := range namespaces {
 		recommendations, err := s.scanNamespace(ctx, ns)
 		if err != nil {
-			fmt.Printf("[WARN] Error scanning namespace %s: %v\n", ns, err)
+			fmt.Fprintf(os.Stderr, "[WARN] Error scanning namespace %s: %v\n", ns, err)
 			continue
 		}
 		allRecommendations = append(allRecommendations, recommendations...)
@@ -103,125 +201,162 @@ func (s *Scanner) ScanAndRecommend(namespace string, allNamespaces bool) ([]*rec
 
 func (s *Scanner) scanNamespace(ctx context.Context, namespace string) ([]*recommender.Recommendation, error) {
 	// Get all workload types
-	deployments, err := s.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	deployments, err := s.lister.Deployments(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list deployments: %w", err)
 	}
 
-	statefulSets, err := s.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	statefulSets, err := s.lister.StatefulSets(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
 	}
 
-	daemonSets, err := s.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	daemonSets, err := s.lister.DaemonSets(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
 	}
 
-	replicaSets, err := s.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	replicaSets, err := s.lister.ReplicaSets(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list replicasets: %w", err)
 	}
 
+	// Replica recommendations need the PodDisruptionBudgets; the rest of
+	// the scan does not
+	pdbs, pdbErr := s.lister.PodDisruptionBudgets(ctx, namespace)
+	if pdbErr != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] Failed to list poddisruptionbudgets in %s, skipping replica recommendations: %v\n", namespace, pdbErr)
+	}
+
 	// Get pod analyses
 	analyses, err := s.analyzer.AnalyzePods(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze pods: %w", err)
 	}
+	if err := s.withCurrentUsage(ctx, analyses); err != nil {
+		return nil, fmt.Errorf("failed to get current usage: %w", err)
+	}
 
 	// Group pods by their parent workload
-	workloadPods := make(map[string][]analyzer.PodAnalysis)
-
-	for _, analysis := range analyses {
-		// Use the workload name from the analysis (already extracted in analyzer)
-		workloadKey := analysis.WorkloadName
-		if workloadKey == "" {
-			// Fallback: try to extract from pod name
-			workloadKey = extractWorkloadName(analysis.Name)
-		}
-
-		if workloadKey != "" {
-			workloadPods[workloadKey] = append(workloadPods[workloadKey], analysis)
-		}
-	}
+	workloadPods := groupByWorkload(analyses)
 
 	var recommendations []*recommender.Recommendation
 
 	// Generate recommendations for Deployments
-	for _, deploy := range deployments.Items {
-		if pods, exists := workloadPods[deploy.Name]; exists && len(pods) > 0 {
-			rec := s.recommender.Analyze(pods, deploy.Name)
-			if rec != nil {
-				recommendations = append(recommendations, rec)
+	for _, deploy := range deployments {
+		if pods := s.annotatedPods(workloadPods[workloadKey("Deployment", deploy.Name)], "Deployment", deploy.ObjectMeta); len(pods) > 0 {
+			recs := s.withHPARecommendation(pods, deploy.Name, s.recommender.AnalyzeContainers(pods, deploy.Name))
+			if pdbErr == nil {
+				if rec := s.replicaRecommendation(deploy, pods, recs, pdbs); rec != nil {
+					recs = append(recs, rec)
+				}
 			}
+			recommendations = append(recommendations, recs...)
 		}
 	}
 
 	// Generate recommendations for StatefulSets
-	for _, sts := range statefulSets.Items {
-		if pods, exists := workloadPods[sts.Name]; exists && len(pods) > 0 {
-			rec := s.recommender.Analyze(pods, sts.Name)
-			if rec != nil {
-				recommendations = append(recommendations, rec)
-			}
+	for _, sts := range statefulSets {
+		if pods := s.annotatedPods(workloadPods[workloadKey("StatefulSet", sts.Name)], "StatefulSet", sts.ObjectMeta); len(pods) > 0 {
+			recommendations = append(recommendations, s.withHPARecommendation(pods, sts.Name, s.recommender.AnalyzeContainers(pods, sts.Name))...)
 		}
 	}
 
 	// Generate recommendations for DaemonSets
-	for _, ds := range daemonSets.Items {
-		if pods, exists := workloadPods[ds.Name]; exists && len(pods) > 0 {
-			rec := s.recommender.Analyze(pods, ds.Name)
-			if rec != nil {
-				recommendations = append(recommendations, rec)
-			}
+	for _, ds := range daemonSets {
+		if pods := s.annotatedPods(workloadPods[workloadKey("DaemonSet", ds.Name)], "DaemonSet", ds.ObjectMeta); len(pods) > 0 {
+			recommendations = append(recommendations, s.recommender.AnalyzeContainers(pods, ds.Name)...)
 		}
 	}
 
 	// Generate recommendations for standalone ReplicaSets (not owned by Deployments)
-	for _, rs := range replicaSets.Items {
+	for _, rs := range replicaSets {
 		// Skip ReplicaSets owned by Deployments (already handled above)
-		if len(rs.OwnerReferences) > 0 && rs.OwnerReferences[0].Kind == "Deployment" {
+		// or other controllers, whose pods belong to the owner
+		if len(rs.OwnerReferences) > 0 {
 			continue
 		}
 
-		if pods, exists := workloadPods[rs.Name]; exists && len(pods) > 0 {
-			rec := s.recommender.Analyze(pods, rs.Name)
-			if rec != nil {
-				recommendations = append(recommendations, rec)
-			}
+		if pods := s.annotatedPods(workloadPods[workloadKey("ReplicaSet", rs.Name)], "ReplicaSet", rs.ObjectMeta); len(pods) > 0 {
+			recommendations = append(recommendations, s.recommender.AnalyzeContainers(pods, rs.Name)...)
 		}
 	}
 
+	// Generate recommendations for the configured custom workload kinds
+	recommendations = append(recommendations, s.customRecommendations(ctx, namespace, workloadPods,
+		func(pods []analyzer.PodAnalysis, name string) []*recommender.Recommendation {
+			return s.withHPARecommendation(pods, name, s.recommender.AnalyzeContainers(pods, name))
+		})...)
+
 	return recommendations, nil
 }
 
-// extractWorkloadName extracts workload name from pod name
-// Handles formats like: "workload-name-7d9f8b-xyz" (Deployment) or "workload-name-0" (StatefulSet)
-func extractWorkloadName(podName string) string {
-	// For StatefulSets: "postgres-test-0" -> "postgres-test"
-	// For Deployments: "api-server-7d9f8b-xyz" -> "api-server"
-
-	// Try StatefulSet pattern first (ends with -<number>)
-	if len(podName) > 2 && podName[len(podName)-2] == '-' {
-		// Check if last char is a digit
-		lastChar := podName[len(podName)-1]
-		if lastChar >= '0' && lastChar <= '9' {
-			return podName[:len(podName)-2]
+// withCurrentUsage sets the current usage of each container from the data
+// source. Containers it has no data for, e.g. of pods that just started,
+// are left without usage.
+func (s *Scanner) withCurrentUsage(ctx context.Context, analyses []analyzer.PodAnalysis) error {
+	for i := range analyses {
+		analysis := &analyses[i]
+		metrics, err := s.source.GetMetrics(ctx, &models.Workload{
+			Namespace:  analysis.Namespace,
+			Kind:       analysis.WorkloadType,
+			Deployment: analysis.WorkloadName,
+			Pod:        analysis.Name,
+			Container:  analysis.ContainerName,
+		}, 0)
+		if errors.Is(err, datasource.ErrNoData) {
+			continue
 		}
+		if err != nil {
+			return err
+		}
+		analysis.SetUsage(metrics.P95CPU, metrics.P95Memory, metrics.P99CPU, metrics.P99Memory)
 	}
+	return nil
+}
 
-	// Try Deployment pattern (remove last two dash-separated segments)
-	dashCount := 0
-	for i := len(podName) - 1; i >= 0; i-- {
-		if podName[i] == '-' {
-			dashCount++
-			if dashCount == 2 {
-				return podName[:i]
-			}
+// annotatedPods attaches the workload's cost-optimizer.io annotations to its
+// pods. It returns nil for workloads opted out by their own or their
+// namespace's ignore annotation.
+func (s *Scanner) annotatedPods(pods []analyzer.PodAnalysis, kind string, workload metav1.ObjectMeta) []analyzer.PodAnalysis {
+	if len(pods) == 0 {
+		return nil
+	}
+	if policy.Ignored(workload.Annotations) || policy.Ignored(pods[0].NamespaceAnnotations) {
+		if s.verbose {
+			fmt.Printf("[DEBUG] Skipping %s %s/%s: %s annotation\n", kind, workload.Namespace, workload.Name, policy.AnnotationIgnore)
 		}
+		return nil
+	}
+	if err := policy.ValidateAnnotations(workload.Annotations); err != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] %s %s/%s: ignoring invalid annotation: %v\n", kind, workload.Namespace, workload.Name, err)
 	}
 
-	return podName
+	annotated := make([]analyzer.PodAnalysis, len(pods))
+	for i, pod := range pods {
+		pod.WorkloadAnnotations = workload.Annotations
+		annotated[i] = pod
+	}
+	return annotated
+}
+
+// workloadKey identifies a workload within a namespace
+func workloadKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// groupByWorkload groups analyses by the workload that owns their pod.
+// Standalone pods have no workload and are left out.
+func groupByWorkload(analyses []analyzer.PodAnalysis) map[string][]analyzer.PodAnalysis {
+	workloadPods := make(map[string][]analyzer.PodAnalysis)
+	for _, analysis := range analyses {
+		if analysis.WorkloadName == "" {
+			continue
+		}
+		key := workloadKey(analysis.WorkloadType, analysis.WorkloadName)
+		workloadPods[key] = append(workloadPods[key], analysis)
+	}
+	return workloadPods
 }
 
 // GetAnalyzer returns the analyzer for direct use
@@ -230,11 +365,30 @@ func (s *Scanner) GetAnalyzer() *analyzer.Analyzer {
 }
 
 // GetClientset returns the Kubernetes clientset for direct access
-func (s *Scanner) GetClientset() *kubernetes.Clientset {
+func (s *Scanner) GetClientset() kubernetes.Interface {
 	return s.clientset
 }
 
-// Add this new method to Scanner struct
+// GetRESTConfig returns the config the clients were built from, for creating
+// further clients (nil for scanners built with NewWithClients)
+func (s *Scanner) GetRESTConfig() *rest.Config {
+	return s.config
+}
+
+// GetDynamicClient returns the client for custom resources (nil for
+// scanners built with NewWithClients)
+func (s *Scanner) GetDynamicClient() dynamic.Interface {
+	return s.dynamic
+}
+
+// GetRESTMapper returns the mapper from kinds to API resources used with
+// the dynamic client
+func (s *Scanner) GetRESTMapper() meta.RESTMapper {
+	return s.mapper
+}
+
+// ScanAndRecommendWithHistory scans namespace, or every namespace, from
+// the Prometheus history over lookbackDays
 func (s *Scanner) ScanAndRecommendWithHistory(
 	ctx context.Context,
 	namespace string,
@@ -242,29 +396,36 @@ func (s *Scanner) ScanAndRecommendWithHistory(
 	promClient api.Client,
 	lookbackDays int,
 ) ([]*recommender.Recommendation, error) {
+	return s.scanWithHistory(ctx, namespace, allNamespaces, analyzer.NewHistoricalAnalyzer(promClient, s.verbose), lookbackDays)
+}
+
+// scanWithHistory scans namespace, or every namespace, from history over
+// lookbackDays
+func (s *Scanner) scanWithHistory(
+	ctx context.Context,
+	namespace string,
+	allNamespaces bool,
+	histAnalyzer analyzer.History,
+	lookbackDays int,
+) ([]*recommender.Recommendation, error) {
 
 	// Get list of namespaces to scan
 	namespaces := []string{namespace}
 	if allNamespaces {
-		nsList, err := s.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		var err error
+		namespaces, err = s.listNamespaces(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list namespaces: %w", err)
-		}
-		namespaces = []string{}
-		for _, ns := range nsList.Items {
-			namespaces = append(namespaces, ns.Name)
+			return nil, err
 		}
 	}
 
 	var allRecommendations []*recommender.Recommendation
-
-	// Create historical analyzer
-	histAnalyzer := analyzer.NewHistoricalAnalyzer(promClient, s.verbose)
+	s.analyzer.Owners().Reset()
 
 	for _, ns := range namespaces {
 		recommendations, err := s.scanNamespaceWithHistory(ctx, ns, histAnalyzer, lookbackDays)
 		if err != nil {
-			fmt.Printf("[WARN] Error scanning namespace %s: %v\n", ns, err)
+			fmt.Fprintf(os.Stderr, "[WARN] Error scanning namespace %s: %v\n", ns, err)
 			continue
 		}
 		allRecommendations = append(allRecommendations, recommendations...)
@@ -277,14 +438,18 @@ func (s *Scanner) ScanAndRecommendWithHistory(
 func (s *Scanner) scanNamespaceWithHistory(
 	ctx context.Context,
 	namespace string,
-	histAnalyzer *analyzer.HistoricalAnalyzer,
+	histAnalyzer analyzer.History,
 	lookbackDays int,
 ) ([]*recommender.Recommendation, error) {
 
 	// Get all workload types
-	deployments, _ := s.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
-	statefulSets, _ := s.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
-	daemonSets, _ := s.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	deployments, _ := s.lister.Deployments(ctx, namespace)
+	statefulSets, _ := s.lister.StatefulSets(ctx, namespace)
+	daemonSets, _ := s.lister.DaemonSets(ctx, namespace)
+	pdbs, pdbErr := s.lister.PodDisruptionBudgets(ctx, namespace)
+	if pdbErr != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] Failed to list poddisruptionbudgets in %s, skipping replica recommendations: %v\n", namespace, pdbErr)
+	}
 
 	// Get current pod analyses (for workload type, environment, etc.)
 	currentAnalyses, err := s.analyzer.AnalyzePods(ctx, namespace)
@@ -293,104 +458,143 @@ func (s *Scanner) scanNamespaceWithHistory(
 	}
 
 	// Group by workload
-	workloadPods := make(map[string][]analyzer.PodAnalysis)
-	for _, analysis := range currentAnalyses {
-		workloadKey := analysis.WorkloadName
-		if workloadKey == "" {
-			workloadKey = extractWorkloadName(analysis.Name)
-		}
-		if workloadKey != "" {
-			workloadPods[workloadKey] = append(workloadPods[workloadKey], analysis)
-		}
-	}
+	workloadPods := groupByWorkload(currentAnalyses)
 
 	var recommendations []*recommender.Recommendation
 
 	// Process deployments
-	for _, deploy := range deployments.Items {
-		if pods, exists := workloadPods[deploy.Name]; exists && len(pods) > 0 {
-			rec := s.generateHistoricalRecommendation(ctx, deploy.Name, pods, histAnalyzer, lookbackDays)
-			if rec != nil {
-				recommendations = append(recommendations, rec)
+	for _, deploy := range deployments {
+		if pods := s.annotatedPods(workloadPods[workloadKey("Deployment", deploy.Name)], "Deployment", deploy.ObjectMeta); len(pods) > 0 {
+			recs, histPods := s.generateHistoricalRecommendations(ctx, deploy.Name, pods, histAnalyzer, lookbackDays)
+			recs = s.withHPARecommendation(histPods, deploy.Name, recs)
+			if pdbErr == nil {
+				if rec := s.replicaRecommendation(deploy, histPods, recs, pdbs); rec != nil {
+					recs = append(recs, rec)
+				}
 			}
+			recommendations = append(recommendations, recs...)
 		}
 	}
 
 	// Process StatefulSets
-	for _, sts := range statefulSets.Items {
-		if pods, exists := workloadPods[sts.Name]; exists && len(pods) > 0 {
-			rec := s.generateHistoricalRecommendation(ctx, sts.Name, pods, histAnalyzer, lookbackDays)
-			if rec != nil {
-				recommendations = append(recommendations, rec)
-			}
+	for _, sts := range statefulSets {
+		if pods := s.annotatedPods(workloadPods[workloadKey("StatefulSet", sts.Name)], "StatefulSet", sts.ObjectMeta); len(pods) > 0 {
+			recs, histPods := s.generateHistoricalRecommendations(ctx, sts.Name, pods, histAnalyzer, lookbackDays)
+			recommendations = append(recommendations, s.withHPARecommendation(histPods, sts.Name, recs)...)
 		}
 	}
 
 	// Process DaemonSets
-	for _, ds := range daemonSets.Items {
-		if pods, exists := workloadPods[ds.Name]; exists && len(pods) > 0 {
-			rec := s.generateHistoricalRecommendation(ctx, ds.Name, pods, histAnalyzer, lookbackDays)
-			if rec != nil {
-				recommendations = append(recommendations, rec)
-			}
+	for _, ds := range daemonSets {
+		if pods := s.annotatedPods(workloadPods[workloadKey("DaemonSet", ds.Name)], "DaemonSet", ds.ObjectMeta); len(pods) > 0 {
+			recs, _ := s.generateHistoricalRecommendations(ctx, ds.Name, pods, histAnalyzer, lookbackDays)
+			recommendations = append(recommendations, recs...)
 		}
 	}
 
+	// Process the configured custom workload kinds
+	recommendations = append(recommendations, s.customRecommendations(ctx, namespace, workloadPods,
+		func(pods []analyzer.PodAnalysis, name string) []*recommender.Recommendation {
+			recs, histPods := s.generateHistoricalRecommendations(ctx, name, pods, histAnalyzer, lookbackDays)
+			return s.withHPARecommendation(histPods, name, recs)
+		})...)
+
+	// Process CronJobs and Jobs from their past runs
+	recommendations = append(recommendations, s.batchRecommendations(ctx, namespace, histAnalyzer, lookbackDays)...)
+
 	return recommendations, nil
 }
 
-// generateHistoricalRecommendation creates recommendation using historical data
+// generateHistoricalRecommendations creates one recommendation per container
+// of a workload using historical data. It also returns the pods with the
+// usage the recommendations are based on: the historical P95 where
+// available, the current usage otherwise.
+func (s *Scanner) generateHistoricalRecommendations(
+	ctx context.Context,
+	workloadName string,
+	pods []analyzer.PodAnalysis,
+	histAnalyzer analyzer.History,
+	lookbackDays int,
+) ([]*recommender.Recommendation, []analyzer.PodAnalysis) {
+	groups := analyzer.GroupByContainer(pods)
+	recs := recommender.CombineContainers(groups, func(containerPods []analyzer.PodAnalysis, allowScaleDown bool) *recommender.Recommendation {
+		return s.generateHistoricalRecommendation(ctx, workloadName, containerPods, histAnalyzer, lookbackDays, allowScaleDown)
+	})
+
+	var analyzed []analyzer.PodAnalysis
+	for _, group := range groups {
+		analyzed = append(analyzed, group...)
+	}
+	return recs, analyzed
+}
+
+// generateHistoricalRecommendation creates recommendation for a single
+// container using historical data
 func (s *Scanner) generateHistoricalRecommendation(
 	ctx context.Context,
 	workloadName string,
 	pods []analyzer.PodAnalysis,
-	histAnalyzer *analyzer.HistoricalAnalyzer,
+	histAnalyzer analyzer.History,
 	lookbackDays int,
+	allowScaleDown bool,
 ) *recommender.Recommendation {
 
-	// Use first pod as representative (they should have similar patterns)
+	analyze := s.recommender.Analyze
+	if !allowScaleDown {
+		analyze = s.recommender.AnalyzeRightSize
+	}
+
 	pod := pods[0]
 
-	// Get historical metrics for this pod
-	histMetrics, err := histAnalyzer.GetHistoricalMetrics(
-		ctx,
-		pod.Namespace,
-		pod.Name,
-		pod.ContainerName,
-		lookbackDays,
-	)
+	// Without history, the current usage is all there is to go by
+	analyzeCurrent := func() *recommender.Recommendation {
+		if err := s.withCurrentUsage(ctx, pods); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] Current usage unavailable for %s/%s: %v\n", pod.Namespace, workloadName, err)
+			return nil
+		}
+		return analyze(pods, workloadName)
+	}
+
+	// Get historical metrics for every pod of the workload, past and present
+	histMetrics, err := histAnalyzer.GetWorkloadMetrics(ctx, historyWorkload(pods), lookbackDays)
 
 	// Check for errors or insufficient data
 	if err != nil || len(histMetrics.CPUSamples) == 0 || len(histMetrics.MemorySamples) == 0 {
 		// Fallback to instant metrics
-		if err != nil {
+		if err != nil && s.verbose {
 			fmt.Printf("[DEBUG] Historical data unavailable for %s/%s: %v\n",
 				pod.Namespace, workloadName, err)
-		} else {
+		} else if s.verbose {
 			fmt.Printf("[DEBUG] Insufficient historical data for %s/%s (CPU samples: %d, Memory samples: %d)\n",
 				pod.Namespace, workloadName, len(histMetrics.CPUSamples), len(histMetrics.MemorySamples))
 		}
-		return s.recommender.Analyze(pods, workloadName)
+		return analyzeCurrent()
 	}
 
 	// Calculate P95/P99 from historical data
 	cpuPercentiles, err := analyzer.CalculatePercentiles(histMetrics.CPUSamples)
 	if err != nil {
-		fmt.Printf("[DEBUG] Failed to calculate CPU percentiles for %s/%s: %v\n",
-			pod.Namespace, workloadName, err)
-		return s.recommender.Analyze(pods, workloadName)
+		if s.verbose {
+			fmt.Printf("[DEBUG] Failed to calculate CPU percentiles for %s/%s: %v\n",
+				pod.Namespace, workloadName, err)
+		}
+		return analyzeCurrent()
 	}
 
 	memPercentiles, err := analyzer.CalculatePercentiles(histMetrics.MemorySamples)
 	if err != nil {
-		fmt.Printf("[DEBUG] Failed to calculate memory percentiles for %s/%s: %v\n",
-			pod.Namespace, workloadName, err)
-		return s.recommender.Analyze(pods, workloadName)
+		if s.verbose {
+			fmt.Printf("[DEBUG] Failed to calculate memory percentiles for %s/%s: %v\n",
+				pod.Namespace, workloadName, err)
+		}
+		return analyzeCurrent()
 	}
 
 	// Log success with data points
-	fmt.Printf("[INFO] Using %d-day historical analysis for %s/%s (%d CPU samples, %d memory samples)\n",
-		lookbackDays, pod.Namespace, workloadName, len(histMetrics.CPUSamples), len(histMetrics.MemorySamples))
+	if s.verbose {
+		fmt.Printf("[DEBUG] Using %d-day historical analysis for %s/%s (%d pod(s), %d CPU samples, %d memory samples)\n",
+			lookbackDays, pod.Namespace, workloadName, histMetrics.Pods, len(histMetrics.CPUSamples), len(histMetrics.MemorySamples))
+	}
 
 	// Update pod analyses with historical P95 values AND pattern analysis
 	for i := range pods {
@@ -417,6 +621,8 @@ func (s *Scanner) generateHistoricalRecommendation(
 
 		pods[i].ActualCPU = int64(cpuP95)
 		pods[i].ActualMemory = int64(memP95)
+		pods[i].PeakCPU = int64(cpuPercentiles.P99)
+		pods[i].PeakMemory = int64(memPercentiles.P99)
 
 		// Add pattern and growth analysis (Week 9)
 		pods[i].CPUPattern = histMetrics.CPUPattern
@@ -425,15 +631,20 @@ func (s *Scanner) generateHistoricalRecommendation(
 		pods[i].MemoryGrowth = histMetrics.MemoryGrowth
 		pods[i].DataQuality = histMetrics.DataQuality
 		pods[i].HasSufficientData = histMetrics.HasSufficientData
+		pods[i].DataDays = histMetrics.DataDays()
 	}
 
 	// Generate recommendation with historical data
-	rec := s.recommender.Analyze(pods, workloadName)
+	rec := analyze(pods, workloadName)
 
 	if rec != nil {
 		// Update reason to show historical context
-		reasonContext := fmt.Sprintf("Based on %d-day P95: CPU %.0fm, Memory %.0fMi",
-			lookbackDays,
+		period := fmt.Sprintf("%d-day", lookbackDays)
+		if histMetrics.Pods > 1 {
+			period = fmt.Sprintf("%s, %d-pod", period, histMetrics.Pods)
+		}
+		reasonContext := fmt.Sprintf("Based on %s P95: CPU %.0fm, Memory %.0fMi",
+			period,
 			cpuPercentiles.P95,
 			memPercentiles.P95/(1024*1024),
 		)
@@ -469,3 +680,20 @@ func (s *Scanner) generateHistoricalRecommendation(
 	}
 	return nil
 }
+
+// historyWorkload returns the workload whose history the container of
+// pods is sized from: every pod it has owned, including pods replaced by
+// rollouts, or the pod itself when it has no controller
+func historyWorkload(pods []analyzer.PodAnalysis) *models.Workload {
+	pod := pods[0]
+	workload := &models.Workload{
+		Namespace:  pod.Namespace,
+		Kind:       pod.WorkloadType,
+		Deployment: pod.WorkloadName,
+		Container:  pod.ContainerName,
+	}
+	if pod.WorkloadName == "" {
+		workload.Kind, workload.Deployment, workload.Pod = "Pod", pod.Name, pod.Name
+	}
+	return workload
+}
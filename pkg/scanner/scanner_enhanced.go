@@ -3,6 +3,7 @@ package scanner
 import (
 	"context"
 
+	"github.com/opscart/k8s-cost-optimizer/pkg/policy"
 	"github.com/opscart/k8s-cost-optimizer/pkg/pricing"
 	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
 )
@@ -13,6 +14,18 @@ func (s *Scanner) WithPricing(provider pricing.Provider) *Scanner {
 	return s
 }
 
+// WithLimitPolicy sets how recommended limits are derived
+func (s *Scanner) WithLimitPolicy(policy recommender.LimitPolicy) *Scanner {
+	s.recommender.WithLimitPolicy(policy)
+	return s
+}
+
+// WithPolicies sets the optimization policies used for recommendations
+func (s *Scanner) WithPolicies(policies *policy.Set) *Scanner {
+	s.recommender.WithPolicies(policies)
+	return s
+}
+
 // GetPricingProvider returns the current pricing provider
 func (s *Scanner) GetPricingProvider() pricing.Provider {
 	// Try to auto-detect if not already set
@@ -0,0 +1,399 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/analyzer"
+	"github.com/opscart/k8s-cost-optimizer/pkg/kube"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	"github.com/opscart/k8s-cost-optimizer/pkg/policy"
+	"github.com/opscart/k8s-cost-optimizer/pkg/recommender"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// testContainer describes a container's requests and observed usage
+type testContainer struct {
+	name     string
+	cpu      string // request
+	memory   string // request
+	usageCPU string
+	usageMem string
+}
+
+// testCluster builds a fake cluster with one Deployment per entry in
+// workloads, each backed by a single pod
+func testCluster(namespace string, workloads map[string][]testContainer, objects ...runtime.Object) (*fake.Clientset, *metricsfake.Clientset) {
+	var podMetrics []metricsv1beta1.PodMetrics
+	controller := true
+
+	objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+
+	for name, containers := range workloads {
+		rsName := name + "-7d9f8b"
+		podName := rsName + "-abcde"
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      podName,
+				Namespace: namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rsName, Controller: &controller},
+				},
+			},
+		}
+		usage := metricsv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: namespace},
+		}
+
+		for _, c := range containers {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+				Name: c.name,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(c.cpu),
+						corev1.ResourceMemory: resource.MustParse(c.memory),
+					},
+				},
+			})
+			usage.Containers = append(usage.Containers, metricsv1beta1.ContainerMetrics{
+				Name: c.name,
+				Usage: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(c.usageCPU),
+					corev1.ResourceMemory: resource.MustParse(c.usageMem),
+				},
+			})
+		}
+
+		objects = append(objects,
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+			&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+				Name:            rsName,
+				Namespace:       namespace,
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: name, Controller: &controller}},
+			}},
+			pod,
+		)
+		podMetrics = append(podMetrics, usage)
+	}
+
+	metricsClient := &metricsfake.Clientset{}
+	// The fake tracker cannot map PodMetrics to the "pods" resource used by
+	// the metrics API, so serve the list directly
+	metricsClient.AddReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		ns := action.GetNamespace()
+		list := &metricsv1beta1.PodMetricsList{}
+		for _, pm := range podMetrics {
+			if ns == "" || pm.Namespace == ns {
+				list.Items = append(list.Items, pm)
+			}
+		}
+		return true, list, nil
+	})
+
+	return fake.NewSimpleClientset(objects...), metricsClient
+}
+
+func TestScanAndRecommend(t *testing.T) {
+	tests := []struct {
+		name      string
+		workloads map[string][]testContainer
+		objects   []runtime.Object
+		expected  map[string]recommender.RecommendationType // workload/container -> type
+		check     func(t *testing.T, rec *recommender.Recommendation)
+	}{
+		{
+			name: "Over-provisioned deployment is right-sized",
+			workloads: map[string][]testContainer{
+				"web": {{name: "app", cpu: "1", memory: "1Gi", usageCPU: "200m", usageMem: "256Mi"}},
+			},
+			expected: map[string]recommender.RecommendationType{
+				"web/app": recommender.RightSize,
+			},
+		},
+		{
+			name: "Idle deployment is scaled down",
+			workloads: map[string][]testContainer{
+				"batch": {{name: "worker", cpu: "1", memory: "1Gi", usageCPU: "10m", usageMem: "50Mi"}},
+			},
+			expected: map[string]recommender.RecommendationType{
+				"batch/worker": recommender.ScaleDown,
+			},
+		},
+		{
+			name: "Well-sized deployment needs no action",
+			workloads: map[string][]testContainer{
+				"api": {{name: "app", cpu: "100m", memory: "128Mi", usageCPU: "75m", usageMem: "100Mi"}},
+			},
+			expected: map[string]recommender.RecommendationType{
+				"api/app": recommender.NoAction,
+			},
+		},
+		{
+			name: "Sidecar is sized independently",
+			workloads: map[string][]testContainer{
+				"web": {
+					{name: "app", cpu: "1", memory: "1Gi", usageCPU: "200m", usageMem: "256Mi"},
+					{name: "envoy", cpu: "100m", memory: "128Mi", usageCPU: "75m", usageMem: "100Mi"},
+				},
+			},
+			expected: map[string]recommender.RecommendationType{
+				"web/app":   recommender.RightSize,
+				"web/envoy": recommender.NoAction,
+			},
+		},
+		{
+			name: "HPA-managed deployment keeps the CPU request it scales on",
+			workloads: map[string][]testContainer{
+				"web": {{name: "app", cpu: "1", memory: "1Gi", usageCPU: "200m", usageMem: "256Mi"}},
+			},
+			objects: []runtime.Object{
+				&autoscalingv2.HorizontalPodAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{Name: "web-hpa", Namespace: "shop"},
+					Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+						ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					},
+				},
+			},
+			expected: map[string]recommender.RecommendationType{
+				"web/app": recommender.RightSize,
+			},
+			check: func(t *testing.T, rec *recommender.Recommendation) {
+				if rec.RecommendedCPU != rec.CurrentCPU || rec.RecommendedMemory >= rec.CurrentMemory {
+					t.Errorf("Expected only memory to be right-sized, got CPU %dm → %dm, memory %d → %d",
+						rec.CurrentCPU, rec.RecommendedCPU, rec.CurrentMemory, rec.RecommendedMemory)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset, metricsClient := testCluster("shop", tt.workloads, tt.objects...)
+			s := NewWithClients(clientset, metricsClient, false)
+
+			recommendations, err := s.ScanAndRecommend("shop", false)
+			if err != nil {
+				t.Fatalf("ScanAndRecommend failed: %v", err)
+			}
+
+			if len(recommendations) != len(tt.expected) {
+				t.Fatalf("Expected %d recommendations, got %d", len(tt.expected), len(recommendations))
+			}
+
+			for _, rec := range recommendations {
+				key := rec.DeploymentName + "/" + rec.ContainerName
+				expected, ok := tt.expected[key]
+				if !ok {
+					t.Errorf("Unexpected recommendation for %s", key)
+					continue
+				}
+				if rec.Type != expected {
+					t.Errorf("%s: expected %s, got %s (%s)", key, expected, rec.Type, rec.Reason)
+				}
+				if rec.Namespace != "shop" || rec.WorkloadType != "Deployment" {
+					t.Errorf("%s: expected Deployment in shop, got %s in %s", key, rec.WorkloadType, rec.Namespace)
+				}
+				if tt.check != nil {
+					tt.check(t, rec)
+				}
+			}
+		})
+	}
+}
+
+func TestScanAllNamespaces(t *testing.T) {
+	clientset, metricsClient := testCluster("shop", map[string][]testContainer{
+		"web": {{name: "app", cpu: "1", memory: "1Gi", usageCPU: "200m", usageMem: "256Mi"}},
+	}, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "empty"}})
+	s := NewWithClients(clientset, metricsClient, false)
+
+	recommendations, err := s.ScanAndRecommend("", true)
+	if err != nil {
+		t.Fatalf("ScanAndRecommend failed: %v", err)
+	}
+
+	if len(recommendations) != 1 {
+		t.Fatalf("Expected 1 recommendation, got %d", len(recommendations))
+	}
+
+	if recommendations[0].DeploymentName != "web" {
+		t.Errorf("Expected recommendation for web, got %s", recommendations[0].DeploymentName)
+	}
+}
+
+func TestScanVPARecommendations(t *testing.T) {
+	clientset, metricsClient := testCluster("shop", map[string][]testContainer{
+		"web": {{name: "app", cpu: "1", memory: "1Gi", usageCPU: "200m", usageMem: "256Mi"}},
+	})
+
+	vpa := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "autoscaling.k8s.io/v1",
+		"kind":       "VerticalPodAutoscaler",
+		"metadata":   map[string]interface{}{"name": "web-vpa", "namespace": "shop"},
+		"spec": map[string]interface{}{
+			"targetRef":    map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "web"},
+			"updatePolicy": map[string]interface{}{"updateMode": "Off"},
+		},
+		"status": map[string]interface{}{
+			"recommendation": map[string]interface{}{
+				"containerRecommendations": []interface{}{map[string]interface{}{
+					"containerName": "app",
+					"target":        map[string]interface{}{"cpu": "300m", "memory": "300Mi"},
+					"lowerBound":    map[string]interface{}{"cpu": "250m", "memory": "260Mi"},
+					"upperBound":    map[string]interface{}{"cpu": "400m", "memory": "350Mi"},
+				}},
+			},
+		},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{kube.VerticalPodAutoscalerResource: "VerticalPodAutoscalerList"}, vpa)
+
+	s := NewWithClients(clientset, metricsClient, false).WithDynamicClient(dynamicClient, meta.NewDefaultRESTMapper(nil))
+	recommendations, err := s.ScanAndRecommend("shop", false)
+	if err != nil {
+		t.Fatalf("ScanAndRecommend failed: %v", err)
+	}
+	if len(recommendations) != 1 || recommendations[0].Type != recommender.RightSize {
+		t.Fatalf("Expected one RIGHT_SIZE recommendation, got %d", len(recommendations))
+	}
+
+	rec := recommendations[0]
+	if rec.VPAName != "web-vpa" || rec.VPACPU != 300 || rec.VPAMemory != 300<<20 {
+		t.Errorf("Expected the VPA target next to ours, got %q cpu=%dm memory=%d", rec.VPAName, rec.VPACPU, rec.VPAMemory)
+	}
+	if !strings.Contains(rec.Reason, "VPA web-vpa (Off): CPU 300m, Memory 300Mi") {
+		t.Errorf("Expected the VPA recommendation in the reason, got %s", rec.Reason)
+	}
+	// 360m is within the VPA's CPU bounds, 460Mi is above its memory ones
+	if !strings.Contains(rec.Reason, "Disagrees with VPA: Memory 460Mi above upper bound 350Mi") || strings.Contains(rec.Reason, "CPU 360m") {
+		t.Errorf("Expected only the memory disagreement to be flagged, got %s", rec.Reason)
+	}
+}
+
+func TestScanWorkloadHistory(t *testing.T) {
+	clientset, metricsClient := testCluster("shop", map[string][]testContainer{
+		"web": {{name: "app", cpu: "2", memory: "2Gi", usageCPU: "800m", usageMem: "900Mi"}},
+	})
+	// Three pods of web over the lookback, only one of which still runs
+	promClient, queries := fakePrometheus(t, `kube_replicaset_owner{namespace="shop",owner_kind="Deployment",owner_name="web"}`, 3, 1, 200, 256*1024*1024)
+
+	s := NewWithClients(clientset, metricsClient, false)
+	recommendations, err := s.ScanAndRecommendWithHistory(context.Background(), "shop", false, promClient, 7)
+	if err != nil {
+		t.Fatalf("ScanAndRecommendWithHistory failed: %v", err)
+	}
+	if len(recommendations) != 1 || recommendations[0].Type != recommender.RightSize {
+		t.Fatalf("Expected one RIGHT_SIZE recommendation, got %d", len(recommendations))
+	}
+
+	rec := recommendations[0]
+	if rec.RecommendedCPU >= 800 {
+		t.Errorf("Expected CPU sized from the 200m history rather than current usage, got %dm", rec.RecommendedCPU)
+	}
+	if !strings.Contains(rec.Reason, "Based on 7-day, 3-pod P95: CPU 200m, Memory 256Mi") {
+		t.Errorf("Expected the reason to count the pods, got %s", rec.Reason)
+	}
+	for _, query := range *queries {
+		if strings.Contains(query, `pod="web-7d9f8b-abcde"`) {
+			t.Errorf("Expected the workload rather than its current pod to be queried: %s", query)
+		}
+	}
+}
+
+func TestHistoryWorkload(t *testing.T) {
+	tests := []struct {
+		pods     []analyzer.PodAnalysis
+		expected models.Workload
+	}{
+		{
+			pods:     []analyzer.PodAnalysis{{Name: "web-7d9f8b-abcde", Namespace: "shop", ContainerName: "app", WorkloadType: "Deployment", WorkloadName: "web"}},
+			expected: models.Workload{Namespace: "shop", Kind: "Deployment", Deployment: "web", Container: "app"},
+		},
+		{
+			pods: []analyzer.PodAnalysis{
+				{Name: "set-a-1", Namespace: "shop", ContainerName: "app", WorkloadType: "CloneSet", WorkloadName: "set-a"},
+				{Name: "set-a-2", Namespace: "shop", ContainerName: "app", WorkloadType: "CloneSet", WorkloadName: "set-a"},
+			},
+			expected: models.Workload{Namespace: "shop", Kind: "CloneSet", Deployment: "set-a", Container: "app"},
+		},
+		{
+			pods:     []analyzer.PodAnalysis{{Name: "debug", Namespace: "shop", ContainerName: "shell"}},
+			expected: models.Workload{Namespace: "shop", Kind: "Pod", Deployment: "debug", Pod: "debug", Container: "shell"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := historyWorkload(tt.pods); *got != tt.expected {
+			t.Errorf("%s: expected %+v, got %+v", tt.pods[0].Name, tt.expected, *got)
+		}
+	}
+}
+
+func TestScanAnnotations(t *testing.T) {
+	ctx := context.Background()
+	clientset, metricsClient := testCluster("shop", map[string][]testContainer{
+		"web":   {{name: "app", cpu: "1", memory: "1Gi", usageCPU: "200m", usageMem: "256Mi"}},
+		"batch": {{name: "worker", cpu: "1", memory: "1Gi", usageCPU: "10m", usageMem: "50Mi"}},
+	})
+	annotate := func(name string, annotations map[string]string) {
+		deploy, err := clientset.AppsV1().Deployments("shop").Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deploy.Annotations = annotations
+		if _, err := clientset.AppsV1().Deployments("shop").Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	annotate("web", map[string]string{
+		policy.AnnotationSafetyBuffer: "2.0",
+		policy.AnnotationMinCPU:       "500m",
+		policy.AnnotationOwner:        "team-web",
+	})
+	annotate("batch", map[string]string{policy.AnnotationIgnore: "true"})
+
+	s := NewWithClients(clientset, metricsClient, false)
+	recommendations, err := s.ScanAndRecommend("shop", false)
+	if err != nil {
+		t.Fatalf("ScanAndRecommend failed: %v", err)
+	}
+	if len(recommendations) != 1 || recommendations[0].DeploymentName != "web" {
+		t.Fatalf("Expected only web to be recommended, got %d recommendations", len(recommendations))
+	}
+
+	rec := recommendations[0]
+	if rec.RecommendedCPU != 500 || rec.Owner != "team-web" {
+		t.Errorf("Expected 500m owned by team-web, got %dm owned by %q", rec.RecommendedCPU, rec.Owner)
+	}
+	if !strings.Contains(rec.Reason, "safety-buffer=2.0, min-cpu=500m") {
+		t.Errorf("Expected the reason to mention the overrides, got %q", rec.Reason)
+	}
+
+	// Ignoring the namespace skips everything in it
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, "shop", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns.Annotations = map[string]string{policy.AnnotationIgnore: "true"}
+	if _, err := clientset.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if recommendations, err := s.ScanAndRecommend("shop", false); err != nil || len(recommendations) != 0 {
+		t.Errorf("Expected an ignored namespace to be skipped, got %d recommendations (%v)", len(recommendations), err)
+	}
+}
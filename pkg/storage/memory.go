@@ -0,0 +1,522 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+)
+
+// metricsCacheTTL matches the expires_at the SQL stores give cached metrics
+const metricsCacheTTL = 24 * time.Hour
+
+// metricsKey mirrors the unique index on metrics_cache
+type metricsKey struct {
+	clusterID   string
+	namespace   string
+	pod         string
+	collectedAt int64
+}
+
+// usageKey mirrors the unique index on usage_samples
+type usageKey struct {
+	clusterID string
+	namespace string
+	pod       string
+	container string
+	sampledAt int64
+}
+
+type cachedMetrics struct {
+	metrics   models.Metrics
+	expiresAt time.Time
+}
+
+// MemoryStore implements Store interface in memory. Nothing survives the
+// process, which makes it suitable for tests and one-shot scans.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	recommendations []*models.Recommendation // in insertion order
+	byID            map[string]*models.Recommendation
+	audit           []*models.AuditEntry
+	snapshots       []*models.ResourceSnapshot
+	metrics         map[metricsKey]cachedMetrics
+	usage           map[usageKey]models.UsageSample
+
+	// now is replaced in tests to exercise metrics expiry
+	now func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		byID:    make(map[string]*models.Recommendation),
+		metrics: make(map[metricsKey]cachedMetrics),
+		usage:   make(map[usageKey]models.UsageSample),
+		now:     time.Now,
+	}
+}
+
+// copyRecommendation returns a deep copy so callers cannot mutate stored state
+func copyRecommendation(rec *models.Recommendation) *models.Recommendation {
+	c := *rec
+	if rec.Workload != nil {
+		workload := *rec.Workload
+		c.Workload = &workload
+	}
+	if rec.AppliedAt != nil {
+		appliedAt := *rec.AppliedAt
+		c.AppliedAt = &appliedAt
+	}
+	return &c
+}
+
+// SaveRecommendation saves a recommendation
+func (s *MemoryStore) SaveRecommendation(ctx context.Context, rec *models.Recommendation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = s.now()
+	}
+	if _, exists := s.byID[rec.ID]; exists {
+		return fmt.Errorf("recommendation already exists: %s", rec.ID)
+	}
+
+	stored := copyRecommendation(rec)
+	if stored.Workload == nil {
+		stored.Workload = &models.Workload{}
+	}
+	s.recommendations = append(s.recommendations, stored)
+	s.byID[rec.ID] = stored
+
+	return nil
+}
+
+// GetRecommendation retrieves a recommendation by ID
+func (s *MemoryStore) GetRecommendation(ctx context.Context, id string) (*models.Recommendation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return copyRecommendation(rec), nil
+}
+
+// ListRecommendations retrieves recommendations for a namespace
+func (s *MemoryStore) ListRecommendations(ctx context.Context, namespace string, limit int) ([]*models.Recommendation, error) {
+	return s.filterRecommendations(limit, func(rec *models.Recommendation) bool {
+		return rec.Workload.Namespace == namespace
+	}), nil
+}
+
+// filterRecommendations returns copies of the matching recommendations,
+// newest first, like the ORDER BY created_at DESC LIMIT queries
+func (s *MemoryStore) filterRecommendations(limit int, match func(*models.Recommendation) bool) []*models.Recommendation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var recommendations []*models.Recommendation
+	for _, rec := range s.recommendations {
+		if match(rec) {
+			recommendations = append(recommendations, copyRecommendation(rec))
+		}
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].CreatedAt.After(recommendations[j].CreatedAt)
+	})
+
+	if limit >= 0 && len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations
+}
+
+// UpdateRecommendation updates an existing recommendation
+func (s *MemoryStore) UpdateRecommendation(ctx context.Context, rec *models.Recommendation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.byID[rec.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, rec.ID)
+	}
+
+	// Only the apply state is mutable, as in the SQL stores
+	stored.AppliedAt = nil
+	if rec.AppliedAt != nil {
+		appliedAt := *rec.AppliedAt
+		stored.AppliedAt = &appliedAt
+	}
+	stored.AppliedBy = rec.AppliedBy
+
+	return nil
+}
+
+// LogAction logs an action to the audit trail
+func (s *MemoryStore) LogAction(ctx context.Context, entry *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byID[entry.RecommendationID]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, entry.RecommendationID)
+	}
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.ExecutedAt.IsZero() {
+		entry.ExecutedAt = s.now()
+	}
+
+	stored := *entry
+	s.audit = append(s.audit, &stored)
+
+	return nil
+}
+
+// GetAuditLog retrieves audit log entries for a recommendation
+func (s *MemoryStore) GetAuditLog(ctx context.Context, recommendationID string) ([]*models.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []*models.AuditEntry
+	for _, entry := range s.audit {
+		if entry.RecommendationID == recommendationID {
+			c := *entry
+			entries = append(entries, &c)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ExecutedAt.After(entries[j].ExecutedAt)
+	})
+
+	return entries, nil
+}
+
+// SaveSnapshot stores the pre-change state of a workload
+func (s *MemoryStore) SaveSnapshot(ctx context.Context, snapshot *models.ResourceSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byID[snapshot.RecommendationID]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, snapshot.RecommendationID)
+	}
+	if snapshot.ID == "" {
+		snapshot.ID = uuid.New().String()
+	}
+	if snapshot.CreatedAt.IsZero() {
+		snapshot.CreatedAt = s.now()
+	}
+
+	s.snapshots = append(s.snapshots, copySnapshot(snapshot))
+
+	return nil
+}
+
+// GetSnapshot retrieves the most recent snapshot taken for a recommendation
+func (s *MemoryStore) GetSnapshot(ctx context.Context, recommendationID string) (*models.ResourceSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var latest *models.ResourceSnapshot
+	for _, snapshot := range s.snapshots {
+		if snapshot.RecommendationID != recommendationID {
+			continue
+		}
+		if latest == nil || !snapshot.CreatedAt.Before(latest.CreatedAt) {
+			latest = snapshot
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no snapshot found for recommendation: %s", recommendationID)
+	}
+
+	return copySnapshot(latest), nil
+}
+
+func copySnapshot(snapshot *models.ResourceSnapshot) *models.ResourceSnapshot {
+	c := *snapshot
+	if snapshot.Resources != nil {
+		c.Resources = append([]byte(nil), snapshot.Resources...)
+	}
+	if snapshot.Replicas != nil {
+		replicas := *snapshot.Replicas
+		c.Replicas = &replicas
+	}
+	return &c
+}
+
+// CacheMetrics stores metrics in cache
+func (s *MemoryStore) CacheMetrics(ctx context.Context, workload *models.Workload, metrics *models.Metrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := metricsKey{
+		clusterID:   workload.ClusterID,
+		namespace:   workload.Namespace,
+		pod:         workload.Pod,
+		collectedAt: metrics.CollectedAt.UnixNano(),
+	}
+
+	cached := cachedMetrics{
+		metrics:   *metrics,
+		expiresAt: s.now().Add(metricsCacheTTL),
+	}
+	// The SQL stores keep whole hours only
+	cached.metrics.Duration = time.Duration(int(metrics.Duration.Hours())) * time.Hour
+
+	s.metrics[key] = cached
+
+	return nil
+}
+
+// GetCachedMetrics retrieves the most recently collected metrics that
+// have not expired
+func (s *MemoryStore) GetCachedMetrics(ctx context.Context, workload *models.Workload) (*models.Metrics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+
+	var latest *models.Metrics
+	for key, cached := range s.metrics {
+		if key.clusterID != workload.ClusterID || key.namespace != workload.Namespace || key.pod != workload.Pod {
+			continue
+		}
+		if !cached.expiresAt.After(now) {
+			continue
+		}
+		if latest == nil || cached.metrics.CollectedAt.After(latest.CollectedAt) {
+			metrics := cached.metrics
+			latest = &metrics
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no cached metrics found")
+	}
+
+	return latest, nil
+}
+
+// SaveUsageSamples stores collected usage. A sample of a container at a
+// time already stored is ignored.
+func (s *MemoryStore) SaveUsageSamples(ctx context.Context, samples []*models.UsageSample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sample := range samples {
+		key := usageKey{
+			clusterID: sample.ClusterID,
+			namespace: sample.Namespace,
+			pod:       sample.Pod,
+			container: sample.Container,
+			sampledAt: sample.Timestamp.UnixNano(),
+		}
+		if _, ok := s.usage[key]; !ok {
+			s.usage[key] = *sample
+		}
+	}
+
+	return nil
+}
+
+// ListUsageSamples returns the usage collected in a namespace since a
+// time, in time order
+func (s *MemoryStore) ListUsageSamples(ctx context.Context, clusterID, namespace string, since time.Time) ([]*models.UsageSample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var samples []*models.UsageSample
+	for key, sample := range s.usage {
+		if key.clusterID != clusterID || (namespace != "" && key.namespace != namespace) {
+			continue
+		}
+		if sample.Timestamp.Before(since) {
+			continue
+		}
+		sample := sample
+		samples = append(samples, &sample)
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		a, b := samples[i], samples[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Container < b.Container
+	})
+
+	return samples, nil
+}
+
+// PruneUsageSamples deletes the usage collected before a time and returns
+// how many samples were deleted
+func (s *MemoryStore) PruneUsageSamples(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, sample := range s.usage {
+		if sample.Timestamp.Before(before) {
+			delete(s.usage, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// Ping always succeeds
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op; the data lives as long as the store does
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// cutoff returns the start of an analytics window of days
+func (s *MemoryStore) cutoff(days int) time.Time {
+	return s.now().Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+// GetSavingsTrend returns cost savings trend over time
+func (s *MemoryStore) GetSavingsTrend(ctx context.Context, namespace string, days int) (*models.SavingsTrend, error) {
+	cutoff := s.cutoff(days)
+	recs := s.filterRecommendations(-1, func(rec *models.Recommendation) bool {
+		return rec.Workload.Namespace == namespace && !rec.CreatedAt.Before(cutoff)
+	})
+
+	trend := &models.SavingsTrend{
+		Namespace:  namespace,
+		Days:       days,
+		DataPoints: []models.SavingsDataPoint{},
+	}
+
+	// recs are newest first, so days are appended newest first too
+	index := make(map[time.Time]int)
+	for _, rec := range recs {
+		created := rec.CreatedAt.UTC()
+		date := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC)
+
+		i, exists := index[date]
+		if !exists {
+			i = len(trend.DataPoints)
+			index[date] = i
+			trend.DataPoints = append(trend.DataPoints, models.SavingsDataPoint{Date: date})
+		}
+
+		dp := &trend.DataPoints[i]
+		dp.RecommendationCount++
+		dp.PotentialSavings += rec.SavingsMonthly
+		if rec.AppliedAt != nil {
+			dp.AppliedCount++
+			dp.RealizedSavings += rec.SavingsMonthly
+		}
+
+		trend.TotalRecommendations++
+		trend.TotalPotentialSavings += rec.SavingsMonthly
+		if rec.AppliedAt != nil {
+			trend.TotalApplied++
+			trend.TotalRealizedSavings += rec.SavingsMonthly
+		}
+	}
+
+	if trend.TotalRecommendations > 0 {
+		trend.AdoptionRate = float64(trend.TotalApplied) / float64(trend.TotalRecommendations) * 100
+	}
+
+	return trend, nil
+}
+
+// GetWorkloadHistory returns recommendation history for a specific workload
+func (s *MemoryStore) GetWorkloadHistory(ctx context.Context, namespace, deployment string, limit int) ([]*models.Recommendation, error) {
+	return s.filterRecommendations(limit, func(rec *models.Recommendation) bool {
+		return rec.Workload.Namespace == namespace && rec.Workload.Deployment == deployment
+	}), nil
+}
+
+// GetDashboardStats returns aggregate statistics for dashboard
+func (s *MemoryStore) GetDashboardStats(ctx context.Context, namespace string, days int) (*models.DashboardStats, error) {
+	cutoff := s.cutoff(days)
+	recs := s.filterRecommendations(-1, func(rec *models.Recommendation) bool {
+		return rec.Workload.Namespace == namespace && !rec.CreatedAt.Before(cutoff)
+	})
+
+	stats := &models.DashboardStats{
+		Namespace:  namespace,
+		PeriodDays: days,
+	}
+
+	workloads := make(map[string]bool)
+	for _, rec := range recs {
+		stats.TotalRecommendations++
+		stats.PotentialSavings += rec.SavingsMonthly
+		if rec.AppliedAt != nil {
+			stats.AppliedCount++
+			stats.RealizedSavings += rec.SavingsMonthly
+		}
+		// COUNT(DISTINCT deployment) ignores NULLs
+		if rec.Workload.Deployment != "" {
+			workloads[rec.Workload.Deployment] = true
+		}
+	}
+	stats.UniqueWorkloads = len(workloads)
+
+	if stats.TotalRecommendations > 0 {
+		stats.AvgSavingsPerRecommendation = stats.PotentialSavings / float64(stats.TotalRecommendations)
+		stats.AdoptionRate = float64(stats.AppliedCount) / float64(stats.TotalRecommendations) * 100
+	}
+
+	return stats, nil
+}
+
+// ComparePerformance compares current vs previous period
+func (s *MemoryStore) ComparePerformance(ctx context.Context, namespace string, days int) (*models.PerformanceComparison, error) {
+	current := s.cutoff(days)
+	previous := s.cutoff(days * 2)
+	recs := s.filterRecommendations(-1, func(rec *models.Recommendation) bool {
+		return rec.Workload.Namespace == namespace && !rec.CreatedAt.Before(previous)
+	})
+
+	comp := &models.PerformanceComparison{CurrentPeriodDays: days}
+	for _, rec := range recs {
+		if !rec.CreatedAt.Before(current) {
+			comp.CurrentRecommendations++
+			comp.CurrentSavings += rec.SavingsMonthly
+		} else {
+			comp.PreviousRecommendations++
+			comp.PreviousSavings += rec.SavingsMonthly
+		}
+	}
+
+	// Calculate changes
+	if comp.PreviousRecommendations > 0 {
+		comp.RecommendationChange = float64(comp.CurrentRecommendations-comp.PreviousRecommendations) / float64(comp.PreviousRecommendations) * 100
+	}
+	if comp.PreviousSavings > 0 {
+		comp.SavingsChange = (comp.CurrentSavings - comp.PreviousSavings) / comp.PreviousSavings * 100
+	}
+
+	return comp, nil
+}
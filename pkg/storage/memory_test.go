@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+)
+
+func TestMemoryRecommendationRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	rec := testRecommendation("shop", "web", 25.5, time.Time{})
+	if err := store.SaveRecommendation(ctx, rec); err != nil {
+		t.Fatalf("SaveRecommendation failed: %v", err)
+	}
+	if rec.ID == "" || rec.CreatedAt.IsZero() {
+		t.Fatalf("Expected ID and CreatedAt to be filled in, got %q %v", rec.ID, rec.CreatedAt)
+	}
+	if err := store.SaveRecommendation(ctx, rec); err == nil {
+		t.Error("Expected error saving a duplicate ID")
+	}
+
+	// Stored recommendations must not alias the caller's
+	rec.Workload.Deployment = "changed"
+
+	got, err := store.GetRecommendation(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetRecommendation failed: %v", err)
+	}
+	if got.Workload.Deployment != "web" || got.RecommendedCPULimit != 600 {
+		t.Errorf("Recommendation not preserved: %+v %+v", got, got.Workload)
+	}
+
+	appliedAt := time.Now()
+	got.AppliedAt = &appliedAt
+	got.AppliedBy = "alice"
+	got.SavingsMonthly = 0 // not persisted by updates
+	if err := store.UpdateRecommendation(ctx, got); err != nil {
+		t.Fatalf("UpdateRecommendation failed: %v", err)
+	}
+
+	recs, err := store.ListRecommendations(ctx, "shop", 10)
+	if err != nil {
+		t.Fatalf("ListRecommendations failed: %v", err)
+	}
+	if len(recs) != 1 || recs[0].AppliedAt == nil || recs[0].AppliedBy != "alice" || recs[0].SavingsMonthly != 25.5 {
+		t.Fatalf("Expected one applied recommendation, got %+v", recs)
+	}
+
+	if _, err := store.GetRecommendation(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing recommendation, got %v", err)
+	}
+	if err := store.UpdateRecommendation(ctx, &models.Recommendation{ID: "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating missing recommendation, got %v", err)
+	}
+}
+
+func TestMemoryListOrderAndLimit(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		rec := testRecommendation("shop", fmt.Sprintf("web-%d", i), float64(i), now.Add(time.Duration(i)*time.Minute))
+		if err := store.SaveRecommendation(ctx, rec); err != nil {
+			t.Fatalf("SaveRecommendation failed: %v", err)
+		}
+	}
+
+	recs, err := store.ListRecommendations(ctx, "shop", 3)
+	if err != nil {
+		t.Fatalf("ListRecommendations failed: %v", err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("Expected 3 recommendations, got %d", len(recs))
+	}
+	for i, expected := range []string{"web-4", "web-3", "web-2"} {
+		if recs[i].Workload.Deployment != expected {
+			t.Errorf("Position %d: expected %s, got %s", i, expected, recs[i].Workload.Deployment)
+		}
+	}
+}
+
+func TestMemoryAuditAndSnapshots(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	rec := testRecommendation("shop", "web", 10, time.Now())
+	if err := store.SaveRecommendation(ctx, rec); err != nil {
+		t.Fatalf("SaveRecommendation failed: %v", err)
+	}
+
+	start := time.Now()
+	for i, action := range []string{models.AuditActionApplied, models.AuditActionRolledBack} {
+		err := store.LogAction(ctx, &models.AuditEntry{
+			RecommendationID: rec.ID,
+			Action:           action,
+			Status:           models.AuditStatusSuccess,
+			ExecutedAt:       start.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatalf("LogAction failed: %v", err)
+		}
+	}
+
+	entries, err := store.GetAuditLog(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != models.AuditActionRolledBack {
+		t.Fatalf("Expected 2 audit entries newest first, got %+v", entries)
+	}
+
+	// Like the foreign key in the SQL schemas
+	if err := store.LogAction(ctx, &models.AuditEntry{RecommendationID: "missing"}); err == nil {
+		t.Error("Expected error logging an action for a missing recommendation")
+	}
+
+	for _, replicas := range []int32{3, 5} {
+		r := replicas
+		err := store.SaveSnapshot(ctx, &models.ResourceSnapshot{
+			RecommendationID: rec.ID,
+			Namespace:        "shop",
+			WorkloadKind:     "Deployment",
+			WorkloadName:     "web",
+			Replicas:         &r,
+			CreatedAt:        start.Add(time.Duration(r) * time.Second),
+		})
+		if err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+	}
+
+	snapshot, err := store.GetSnapshot(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetSnapshot failed: %v", err)
+	}
+	if snapshot.Replicas == nil || *snapshot.Replicas != 5 {
+		t.Errorf("Expected latest snapshot, got %+v", snapshot)
+	}
+
+	if _, err := store.GetSnapshot(ctx, "missing"); err == nil {
+		t.Error("Expected error for missing snapshot")
+	}
+}
+
+func TestMemoryMetricsCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	workload := &models.Workload{ClusterID: "test", Namespace: "shop", Pod: "web"}
+	older := &models.Metrics{P95CPU: 100, CollectedAt: now.Add(-time.Hour), Duration: 90 * time.Minute}
+	newer := &models.Metrics{P95CPU: 250, CollectedAt: now, Duration: 24 * time.Hour}
+
+	for _, m := range []*models.Metrics{newer, older} {
+		if err := store.CacheMetrics(ctx, workload, m); err != nil {
+			t.Fatalf("CacheMetrics failed: %v", err)
+		}
+	}
+
+	got, err := store.GetCachedMetrics(ctx, workload)
+	if err != nil {
+		t.Fatalf("GetCachedMetrics failed: %v", err)
+	}
+	if got.P95CPU != 250 || got.Duration != 24*time.Hour {
+		t.Errorf("Expected most recently collected metrics, got %+v", got)
+	}
+
+	other := &models.Workload{ClusterID: "test", Namespace: "shop", Pod: "api"}
+	if _, err := store.GetCachedMetrics(ctx, other); err == nil {
+		t.Error("Expected cache miss for another pod")
+	}
+
+	now = now.Add(metricsCacheTTL)
+	if _, err := store.GetCachedMetrics(ctx, workload); err == nil {
+		t.Error("Expected cached metrics to expire after 24h")
+	}
+}
+
+func TestMemoryAnalytics(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	now := time.Now()
+	recs := []*models.Recommendation{
+		testRecommendation("shop", "web", 10, now),
+		testRecommendation("shop", "api", 20, now.Add(-2*24*time.Hour)),
+		testRecommendation("shop", "web", 30, now.Add(-10*24*time.Hour)), // previous period
+		testRecommendation("shop", "web", 40, now.Add(-20*24*time.Hour)), // outside both periods
+		testRecommendation("other", "web", 99, now),
+	}
+	appliedAt := now
+	recs[0].AppliedAt = &appliedAt
+
+	for _, rec := range recs {
+		if err := store.SaveRecommendation(ctx, rec); err != nil {
+			t.Fatalf("SaveRecommendation failed: %v", err)
+		}
+	}
+
+	stats, err := store.GetDashboardStats(ctx, "shop", 7)
+	if err != nil {
+		t.Fatalf("GetDashboardStats failed: %v", err)
+	}
+	if stats.TotalRecommendations != 2 || stats.AppliedCount != 1 || stats.UniqueWorkloads != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.PotentialSavings != 30 || stats.RealizedSavings != 10 || stats.AvgSavingsPerRecommendation != 15 || stats.AdoptionRate != 50 {
+		t.Errorf("Unexpected savings: %+v", stats)
+	}
+
+	trend, err := store.GetSavingsTrend(ctx, "shop", 7)
+	if err != nil {
+		t.Fatalf("GetSavingsTrend failed: %v", err)
+	}
+	if len(trend.DataPoints) != 2 || trend.TotalRecommendations != 2 || trend.TotalApplied != 1 {
+		t.Fatalf("Unexpected trend: %+v", trend)
+	}
+	if !trend.DataPoints[0].Date.After(trend.DataPoints[1].Date) {
+		t.Errorf("Expected newest day first, got %v", trend.DataPoints)
+	}
+	if trend.DataPoints[0].RealizedSavings != 10 || trend.TotalRealizedSavings != 10 || trend.TotalPotentialSavings != 30 {
+		t.Errorf("Unexpected trend savings: %+v", trend)
+	}
+
+	comp, err := store.ComparePerformance(ctx, "shop", 7)
+	if err != nil {
+		t.Fatalf("ComparePerformance failed: %v", err)
+	}
+	if comp.CurrentRecommendations != 2 || comp.PreviousRecommendations != 1 {
+		t.Errorf("Unexpected comparison: %+v", comp)
+	}
+	if comp.CurrentSavings != 30 || comp.PreviousSavings != 30 || comp.RecommendationChange != 100 || comp.SavingsChange != 0 {
+		t.Errorf("Unexpected comparison savings: %+v", comp)
+	}
+
+	history, err := store.GetWorkloadHistory(ctx, "shop", "web", 2)
+	if err != nil {
+		t.Fatalf("GetWorkloadHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].SavingsMonthly != 10 || history[1].SavingsMonthly != 30 {
+		t.Errorf("Expected 2 history entries newest first, got %d", len(history))
+	}
+
+	empty, err := store.GetDashboardStats(ctx, "missing", 7)
+	if err != nil {
+		t.Fatalf("GetDashboardStats failed: %v", err)
+	}
+	if empty.TotalRecommendations != 0 || empty.AdoptionRate != 0 {
+		t.Errorf("Expected empty stats, got %+v", empty)
+	}
+}
+
+func TestMemoryConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := testRecommendation("shop", fmt.Sprintf("web-%d", i), 1, time.Now())
+			if err := store.SaveRecommendation(ctx, rec); err != nil {
+				t.Errorf("SaveRecommendation failed: %v", err)
+				return
+			}
+			if _, err := store.ListRecommendations(ctx, "shop", 100); err != nil {
+				t.Errorf("ListRecommendations failed: %v", err)
+			}
+			if _, err := store.GetDashboardStats(ctx, "shop", 7); err != nil {
+				t.Errorf("GetDashboardStats failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	recs, err := store.ListRecommendations(ctx, "shop", 100)
+	if err != nil {
+		t.Fatalf("ListRecommendations failed: %v", err)
+	}
+	if len(recs) != 20 {
+		t.Errorf("Expected 20 recommendations, got %d", len(recs))
+	}
+}
+
+func TestMemoryUsageSamples(t *testing.T) {
+	checkUsageSamples(t, NewMemoryStore())
+}
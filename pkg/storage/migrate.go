@@ -0,0 +1,421 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSchemaTooNew is returned when the database has migrations applied
+// that this build does not know about
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of cost-scan supports")
+
+// Migration is a numbered schema change loaded from NNN_description.sql,
+// with its optional revert in NNN_description.down.sql
+type Migration struct {
+	Version     int
+	Description string
+	Up          string
+	Down        string
+}
+
+// MigrationStatus reports whether a known migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// dialect holds the SQL that differs between engines
+type dialect struct {
+	// versionTable counts schema_version tables (0 before the first migration)
+	versionTable string
+	// lock serializes concurrent migrators for the rest of a transaction
+	lock string
+	// placeholder returns the n-th (1-based) bind parameter
+	placeholder func(n int) string
+}
+
+var postgresDialect = dialect{
+	versionTable: `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_version'`,
+	lock:         `SELECT pg_advisory_xact_lock(726374)`,
+	placeholder:  func(n int) string { return "$" + strconv.Itoa(n) },
+}
+
+var sqliteDialect = dialect{
+	versionTable: `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`,
+	placeholder:  func(int) string { return "?" },
+}
+
+// Migrator applies and reverts migrations in version order, each inside a
+// transaction, recording applied versions in schema_version
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+
+	// closeDB is set when the Migrator owns its connection
+	closeDB bool
+}
+
+func newMigrator(db *sql.DB, d dialect, fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    d,
+		migrations: migrations,
+	}, nil
+}
+
+// NewMigrator opens the database selected by cfg without migrating it, so
+// that its schema can be inspected or changed
+func NewMigrator(cfg Config) (*Migrator, error) {
+	var m *Migrator
+
+	switch cfg.Type {
+	case "", TypePostgres:
+		store, err := OpenPostgres(cfg.URL)
+		if err != nil {
+			return nil, err
+		}
+		if m, err = store.Migrator(); err != nil {
+			store.Close()
+			return nil, err
+		}
+	case TypeSQLite:
+		store, err := OpenSQLite(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		if m, err = store.Migrator(); err != nil {
+			store.Close()
+			return nil, err
+		}
+	case TypeMemory:
+		return nil, fmt.Errorf("in-memory storage has no schema to migrate")
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s (expected %s or %s)", cfg.Type, TypePostgres, TypeSQLite)
+	}
+
+	m.closeDB = true
+	return m, nil
+}
+
+// loadMigrations reads the migrations in dir, sorted by version
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, ".sql")
+		down := strings.HasSuffix(base, ".down")
+		base = strings.TrimSuffix(base, ".down")
+
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration name %s: expected NNN_description.sql", name)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version}
+			byVersion[version] = m
+		}
+		if len(parts) == 2 && m.Description == "" {
+			m.Description = parts[1]
+		}
+
+		if down {
+			if m.Down != "" {
+				return nil, fmt.Errorf("duplicate down migration for version %d", version)
+			}
+			m.Down = string(content)
+		} else {
+			if m.Up != "" {
+				return nil, fmt.Errorf("duplicate migration for version %d", version)
+			}
+			m.Up = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has a down file but no up file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrations returns the known migrations in version order
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest returns the highest known migration version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// applied returns the applied versions and when they were applied
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+
+	var exists int
+	if err := q.QueryRowContext(ctx, m.dialect.versionTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check schema_version: %w", err)
+	}
+	if exists == 0 {
+		return applied, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_version: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt.Time
+	}
+
+	return applied, rows.Err()
+}
+
+// checkNotNewer fails with ErrSchemaTooNew if any applied version is unknown
+func (m *Migrator) checkNotNewer(applied map[int]time.Time) error {
+	latest := m.Latest()
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, version, latest)
+		}
+	}
+	return nil
+}
+
+// Version returns the highest applied migration version, 0 for an empty
+// database
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+
+	return version, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkNotNewer(applied); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		status = append(status, MigrationStatus{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return status, nil
+}
+
+// Up applies every pending migration in order and returns the ones it ran
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkNotNewer(applied); err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		done, err := m.apply(ctx, migration)
+		if err != nil {
+			return ran, err
+		}
+		if done {
+			ran = append(ran, migration)
+		}
+	}
+
+	return ran, nil
+}
+
+// apply runs one migration and records its version. It returns false when
+// a concurrent migrator applied it first.
+func (m *Migrator) apply(ctx context.Context, migration Migration) (bool, error) {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	applied, err := m.applied(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := applied[migration.Version]; ok {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return false, fmt.Errorf("migration %03d_%s failed: %w", migration.Version, migration.Description, err)
+	}
+
+	record := fmt.Sprintf(`INSERT INTO schema_version (version, applied_at) VALUES (%s, %s)`,
+		m.dialect.placeholder(1), m.dialect.placeholder(2))
+	if _, err := tx.ExecContext(ctx, record, migration.Version, time.Now().UTC()); err != nil {
+		return false, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+
+	return true, nil
+}
+
+// Down reverts the most recently applied steps migrations and returns the
+// ones it reverted, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkNotNewer(applied); err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return reverted, fmt.Errorf("migration %03d_%s cannot be reverted: no down file", migration.Version, migration.Description)
+		}
+
+		if err := m.revert(ctx, migration); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+func (m *Migrator) revert(ctx context.Context, migration Migration) error {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Forget the version first: reverting the base schema drops schema_version
+	forget := `DELETE FROM schema_version WHERE version = ` + m.dialect.placeholder(1)
+	if _, err := tx.ExecContext(ctx, forget, migration.Version); err != nil {
+		return fmt.Errorf("failed to unrecord migration %d: %w", migration.Version, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("reverting %03d_%s failed: %w", migration.Version, migration.Description, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit revert of migration %d: %w", migration.Version, err)
+	}
+
+	return nil
+}
+
+// begin starts a transaction holding the migration lock, if any
+func (m *Migrator) begin(ctx context.Context) (*sql.Tx, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.dialect.lock != "" {
+		if _, err := tx.ExecContext(ctx, m.dialect.lock); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+	}
+
+	return tx, nil
+}
+
+// Close releases the connection opened by NewMigrator. It does nothing for
+// a Migrator borrowed from a store.
+func (m *Migrator) Close() error {
+	if !m.closeDB {
+		return nil
+	}
+	return m.db.Close()
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newTestMigrator(t *testing.T) (*Migrator, *SQLiteStore) {
+	t.Helper()
+
+	store, err := OpenSQLite(filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	migrator, err := store.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator failed: %v", err)
+	}
+
+	return migrator, store
+}
+
+func columnExists(t *testing.T, store *SQLiteStore, table, column string) bool {
+	t.Helper()
+
+	var count int
+	err := store.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil {
+		t.Fatalf("Checking column %s.%s failed: %v", table, column, err)
+	}
+	return count > 0
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/002_second.sql":      {Data: []byte("SELECT 2;")},
+		"m/001_first.sql":       {Data: []byte("SELECT 1;")},
+		"m/001_first.down.sql":  {Data: []byte("SELECT -1;")},
+		"m/README.md":           {Data: []byte("ignored")},
+		"m/010_tenth_thing.sql": {Data: []byte("SELECT 10;")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("loadMigrations failed: %v", err)
+	}
+
+	if len(migrations) != 3 {
+		t.Fatalf("Expected 3 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Description != "first" || migrations[0].Down != "SELECT -1;" {
+		t.Errorf("Unexpected first migration: %+v", migrations[0])
+	}
+	if migrations[1].Down != "" {
+		t.Errorf("Expected no down migration for version 2, got %q", migrations[1].Down)
+	}
+	if migrations[2].Version != 10 || migrations[2].Description != "tenth_thing" {
+		t.Errorf("Unexpected last migration: %+v", migrations[2])
+	}
+
+	invalid := []fstest.MapFS{
+		{"m/first.sql": {Data: []byte("SELECT 1;")}},
+		{"m/001_a.sql": {Data: []byte("SELECT 1;")}, "m/001_b.sql": {Data: []byte("SELECT 1;")}},
+		{"m/001_a.down.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for _, fsys := range invalid {
+		if _, err := loadMigrations(fsys, "m"); err == nil {
+			t.Errorf("Expected error loading %v", fsys)
+		}
+	}
+}
+
+func TestMigratorUpAndStatus(t *testing.T) {
+	ctx := context.Background()
+	migrator, _ := newTestMigrator(t)
+
+	if version, err := migrator.Version(ctx); err != nil || version != 0 {
+		t.Fatalf("Expected empty database at version 0, got %d (%v)", version, err)
+	}
+
+	ran, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(ran) != len(migrator.Migrations()) {
+		t.Errorf("Expected all %d migrations to run, ran %d", len(migrator.Migrations()), len(ran))
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil || version != migrator.Latest() {
+		t.Errorf("Expected version %d, got %d (%v)", migrator.Latest(), version, err)
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range status {
+		if !s.Applied || s.AppliedAt.IsZero() {
+			t.Errorf("Expected migration %d to be applied with a timestamp, got %+v", s.Version, s)
+		}
+	}
+
+	// A second run has nothing to do
+	ran, err = migrator.Up(ctx)
+	if err != nil || len(ran) != 0 {
+		t.Errorf("Expected no pending migrations, ran %d (%v)", len(ran), err)
+	}
+}
+
+func TestMigratorDown(t *testing.T) {
+	ctx := context.Background()
+	migrator, store := newTestMigrator(t)
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	reverted, err := migrator.Down(ctx, 6)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(reverted) != 6 || reverted[0].Version != 9 || reverted[5].Version != 4 {
+		t.Fatalf("Expected versions 9 to 4 reverted, got %+v", reverted)
+	}
+
+	if columnExists(t, store, "usage_samples", "cpu_millicores") {
+		t.Error("Expected usage samples to be dropped")
+	}
+
+	if columnExists(t, store, "recommendations", "hpa_name") {
+		t.Error("Expected HPA columns to be dropped")
+	}
+
+	if columnExists(t, store, "recommendations", "recommended_replicas") {
+		t.Error("Expected replica columns to be dropped")
+	}
+
+	if columnExists(t, store, "recommendations", "owner") {
+		t.Error("Expected owner column to be dropped")
+	}
+
+	if columnExists(t, store, "recommendations", "recommended_cpu_limit_millicores") {
+		t.Error("Expected limit columns to be dropped")
+	}
+	if !columnExists(t, store, "recommendations", "workload_kind") {
+		t.Error("Expected version 3 to remain applied")
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range status {
+		if s.Applied != (s.Version <= 3) {
+			t.Errorf("Migration %d: expected applied=%v", s.Version, s.Version <= 3)
+		}
+	}
+
+	// Reverting everything leaves an empty database that migrates cleanly
+	if _, err := migrator.Down(ctx, 10); err != nil {
+		t.Fatalf("Down to zero failed: %v", err)
+	}
+	if version, err := migrator.Version(ctx); err != nil || version != 0 {
+		t.Fatalf("Expected version 0, got %d (%v)", version, err)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up after full revert failed: %v", err)
+	}
+	if !columnExists(t, store, "recommendations", "recommended_cpu_limit_millicores") {
+		t.Error("Expected limit columns after re-applying migrations")
+	}
+}
+
+func TestMigratorRefusesNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "newer.db")
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	if _, err := store.db.Exec(`INSERT INTO schema_version (version, applied_at) VALUES (999, ?)`, time.Now().UTC()); err != nil {
+		t.Fatalf("Recording future version failed: %v", err)
+	}
+
+	migrator, err := store.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator failed: %v", err)
+	}
+	if _, err := migrator.Up(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Up: expected ErrSchemaTooNew, got %v", err)
+	}
+	if _, err := migrator.Down(ctx, 1); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Down: expected ErrSchemaTooNew, got %v", err)
+	}
+	store.Close()
+
+	if _, err := NewSQLiteStore(path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("NewSQLiteStore: expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestNewMigrator(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cli.db")
+
+	migrator, err := NewMigrator(Config{Type: TypeSQLite, Path: path})
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	defer migrator.Close()
+
+	// Opening for migration must not migrate
+	if version, err := migrator.Version(ctx); err != nil || version != 0 {
+		t.Fatalf("Expected untouched database at version 0, got %d (%v)", version, err)
+	}
+
+	if _, err := NewMigrator(Config{Type: TypeMemory}); err == nil {
+		t.Error("Expected error migrating in-memory storage")
+	}
+}
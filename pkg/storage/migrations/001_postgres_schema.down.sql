@@ -0,0 +1,8 @@
+-- Revert 001: drop the base schema
+-- Every recommendation, audit entry and cached metric is lost
+
+DROP TABLE IF EXISTS clusters;
+DROP TABLE IF EXISTS metrics_cache;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS recommendations;
+DROP TABLE IF EXISTS schema_version;
@@ -120,5 +120,3 @@ CREATE TABLE IF NOT EXISTS schema_version (
     version INTEGER PRIMARY KEY,
     applied_at TIMESTAMPTZ DEFAULT NOW()
 );
-
-INSERT INTO schema_version (version) VALUES (1) ON CONFLICT (version) DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Revert 002: nothing to do
+-- The confidence columns and index are also part of the base schema (001)
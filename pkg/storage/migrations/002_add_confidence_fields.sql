@@ -10,6 +10,3 @@ ADD COLUMN IF NOT EXISTS has_sufficient_data BOOLEAN DEFAULT false;
 
 -- Add index for confidence filtering
 CREATE INDEX IF NOT EXISTS idx_recommendations_confidence ON recommendations(confidence);
-
--- Update schema version
-INSERT INTO schema_version (version) VALUES (2) ON CONFLICT (version) DO NOTHING;
\ No newline at end of file
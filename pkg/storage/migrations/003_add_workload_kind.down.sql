@@ -0,0 +1,4 @@
+-- Revert 003: drop the workload kind column
+
+ALTER TABLE recommendations
+DROP COLUMN IF EXISTS workload_kind;
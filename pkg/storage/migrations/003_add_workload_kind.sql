@@ -0,0 +1,5 @@
+-- Migration 003: Track the workload kind a recommendation targets
+-- Needed to patch the right resource when applying recommendations
+
+ALTER TABLE recommendations
+ADD COLUMN IF NOT EXISTS workload_kind VARCHAR(50);
@@ -0,0 +1,17 @@
+-- Migration 004: Snapshot workload resources before applying recommendations
+-- Used by rollback to restore the original container resources
+
+CREATE TABLE IF NOT EXISTS resource_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    recommendation_id UUID NOT NULL,
+    namespace VARCHAR(255) NOT NULL,
+    workload_kind VARCHAR(50) NOT NULL,
+    workload_name VARCHAR(255) NOT NULL,
+    resources JSONB, -- container name -> original resources block
+    replicas INTEGER, -- original replica count (scale changes only)
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    FOREIGN KEY (recommendation_id) REFERENCES recommendations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_resource_snapshots_recommendation ON resource_snapshots(recommendation_id, created_at DESC);
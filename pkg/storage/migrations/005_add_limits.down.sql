@@ -0,0 +1,7 @@
+-- Revert 005: drop the resource limit columns
+
+ALTER TABLE recommendations
+DROP COLUMN IF EXISTS current_cpu_limit_millicores,
+DROP COLUMN IF EXISTS current_memory_limit_bytes,
+DROP COLUMN IF EXISTS recommended_cpu_limit_millicores,
+DROP COLUMN IF EXISTS recommended_memory_limit_bytes;
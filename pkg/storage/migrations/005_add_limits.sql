@@ -0,0 +1,8 @@
+-- Migration 005: Track current and recommended resource limits
+-- NULL (or 0) means no limit
+
+ALTER TABLE recommendations
+ADD COLUMN IF NOT EXISTS current_cpu_limit_millicores BIGINT,
+ADD COLUMN IF NOT EXISTS current_memory_limit_bytes BIGINT,
+ADD COLUMN IF NOT EXISTS recommended_cpu_limit_millicores BIGINT,
+ADD COLUMN IF NOT EXISTS recommended_memory_limit_bytes BIGINT;
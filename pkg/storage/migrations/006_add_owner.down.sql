@@ -0,0 +1,4 @@
+-- Revert 006: drop the owner column
+
+ALTER TABLE recommendations
+DROP COLUMN IF EXISTS owner;
@@ -0,0 +1,4 @@
+-- Migration 006: Record the owner from the cost-optimizer.io/owner annotation
+
+ALTER TABLE recommendations
+ADD COLUMN IF NOT EXISTS owner VARCHAR(255);
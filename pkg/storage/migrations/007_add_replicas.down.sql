@@ -0,0 +1,5 @@
+-- Revert 007: drop the replica count columns
+
+ALTER TABLE recommendations
+DROP COLUMN IF EXISTS current_replicas,
+DROP COLUMN IF EXISTS recommended_replicas;
@@ -0,0 +1,6 @@
+-- Migration 007: Track current and recommended replica counts for
+-- REPLICA_RIGHT_SIZE recommendations (NULL for other types)
+
+ALTER TABLE recommendations
+ADD COLUMN IF NOT EXISTS current_replicas INTEGER,
+ADD COLUMN IF NOT EXISTS recommended_replicas INTEGER;
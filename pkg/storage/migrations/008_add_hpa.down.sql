@@ -0,0 +1,8 @@
+-- Revert 008: drop the HPA columns
+
+ALTER TABLE recommendations
+DROP COLUMN IF EXISTS hpa_name,
+DROP COLUMN IF EXISTS current_target_cpu_utilization,
+DROP COLUMN IF EXISTS recommended_target_cpu_utilization,
+DROP COLUMN IF EXISTS current_min_replicas,
+DROP COLUMN IF EXISTS recommended_min_replicas;
@@ -0,0 +1,9 @@
+-- Migration 008: Track the HPA changes of HPA_ADJUST recommendations
+-- (NULL for other types)
+
+ALTER TABLE recommendations
+ADD COLUMN IF NOT EXISTS hpa_name VARCHAR(255),
+ADD COLUMN IF NOT EXISTS current_target_cpu_utilization INTEGER,
+ADD COLUMN IF NOT EXISTS recommended_target_cpu_utilization INTEGER,
+ADD COLUMN IF NOT EXISTS current_min_replicas INTEGER,
+ADD COLUMN IF NOT EXISTS recommended_min_replicas INTEGER;
@@ -0,0 +1,3 @@
+-- Revert 009: drop collected usage samples
+
+DROP TABLE IF EXISTS usage_samples;
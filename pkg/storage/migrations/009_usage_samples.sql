@@ -0,0 +1,18 @@
+-- Migration 009: Store the container usage collected from the metrics API
+-- by cost-scan collect, for history without Prometheus
+
+CREATE TABLE IF NOT EXISTS usage_samples (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cluster_id VARCHAR(255) NOT NULL,
+    namespace VARCHAR(255) NOT NULL,
+    workload_kind VARCHAR(50) NOT NULL,
+    workload_name VARCHAR(255) NOT NULL,
+    pod VARCHAR(255) NOT NULL,
+    container VARCHAR(255) NOT NULL,
+    sampled_at TIMESTAMPTZ NOT NULL,
+    cpu_millicores BIGINT NOT NULL,
+    memory_bytes BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_samples_unique ON usage_samples(cluster_id, namespace, pod, container, sampled_at);
+CREATE INDEX IF NOT EXISTS idx_usage_samples_sampled_at ON usage_samples(sampled_at);
@@ -0,0 +1,119 @@
+-- K8s Cost Optimizer Database Schema
+-- Engine: SQLite 3.35+
+-- IDs are generated by the application (UUID strings)
+
+-- Recommendations table
+CREATE TABLE IF NOT EXISTS recommendations (
+    id TEXT PRIMARY KEY,
+    cluster_id TEXT NOT NULL,
+    namespace TEXT NOT NULL,
+    deployment TEXT,
+    pod TEXT NOT NULL,
+    container TEXT,
+
+    -- Recommendation type
+    type TEXT NOT NULL, -- RIGHT_SIZE, SCALE_DOWN, NO_ACTION
+
+    -- Current state
+    current_cpu_millicores INTEGER,
+    current_memory_bytes INTEGER,
+
+    -- Recommended state
+    recommended_cpu_millicores INTEGER,
+    recommended_memory_bytes INTEGER,
+
+    -- Analysis
+    reason TEXT,
+    savings_monthly_usd REAL,
+    impact TEXT, -- HIGH, MEDIUM, LOW
+    risk TEXT, -- NONE, LOW, MEDIUM, HIGH
+
+    -- Week 9: Confidence and pattern analysis
+    confidence TEXT, -- HIGH, MEDIUM, LOW
+    data_quality REAL, -- 0.0-1.0 quality score
+    pattern_info TEXT, -- Human-readable pattern description
+    has_sufficient_data BOOLEAN DEFAULT 0, -- True if >= 3 days of data
+
+    -- Command
+    command TEXT,
+
+    -- Metadata
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    applied_at DATETIME,
+    applied_by TEXT
+);
+
+-- Indexes for recommendations
+CREATE INDEX IF NOT EXISTS idx_recommendations_namespace ON recommendations(namespace, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_recommendations_cluster ON recommendations(cluster_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_recommendations_type ON recommendations(type);
+CREATE INDEX IF NOT EXISTS idx_recommendations_created_at ON recommendations(created_at DESC);
+
+-- Audit log table
+CREATE TABLE IF NOT EXISTS audit_log (
+    id TEXT PRIMARY KEY,
+    recommendation_id TEXT,
+    action TEXT NOT NULL, -- APPLIED, ROLLED_BACK, VIEWED
+    status TEXT NOT NULL, -- SUCCESS, FAILED
+    error_message TEXT,
+    executed_by TEXT,
+    executed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (recommendation_id) REFERENCES recommendations(id) ON DELETE CASCADE
+);
+
+-- Indexes for audit log
+CREATE INDEX IF NOT EXISTS idx_audit_log_recommendation ON audit_log(recommendation_id, executed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_executed_at ON audit_log(executed_at DESC);
+
+-- Metrics cache table
+CREATE TABLE IF NOT EXISTS metrics_cache (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    cluster_id TEXT NOT NULL,
+    namespace TEXT NOT NULL,
+    pod TEXT NOT NULL,
+
+    -- Metrics
+    p95_cpu_millicores INTEGER,
+    p99_cpu_millicores INTEGER,
+    max_cpu_millicores INTEGER,
+    avg_cpu_millicores INTEGER,
+
+    p95_memory_bytes INTEGER,
+    p99_memory_bytes INTEGER,
+    max_memory_bytes INTEGER,
+    avg_memory_bytes INTEGER,
+
+    requested_cpu_millicores INTEGER,
+    requested_memory_bytes INTEGER,
+
+    -- Metadata
+    collected_at DATETIME NOT NULL,
+    duration_hours INTEGER NOT NULL,
+    sample_count INTEGER,
+
+    -- TTL
+    expires_at DATETIME NOT NULL
+);
+
+-- Indexes for metrics cache
+CREATE INDEX IF NOT EXISTS idx_metrics_cache_pod ON metrics_cache(cluster_id, namespace, pod);
+CREATE INDEX IF NOT EXISTS idx_metrics_cache_expires ON metrics_cache(expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_metrics_cache_unique ON metrics_cache(cluster_id, namespace, pod, collected_at);
+
+-- Cluster metadata table
+CREATE TABLE IF NOT EXISTS clusters (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    cloud_provider TEXT, -- azure, aws, gcp, on-prem
+    region TEXT,
+    node_count INTEGER,
+    last_seen DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Version tracking (for migrations)
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER PRIMARY KEY,
+    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- Revert 002: drop the confidence index
+-- The columns belong to the SQLite base schema (001)
+
+DROP INDEX IF EXISTS idx_recommendations_confidence;
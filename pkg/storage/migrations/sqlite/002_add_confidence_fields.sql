@@ -0,0 +1,5 @@
+-- Migration 002: Add confidence and pattern analysis fields
+-- The columns are part of the SQLite base schema, only the index is added here
+
+-- Add index for confidence filtering
+CREATE INDEX IF NOT EXISTS idx_recommendations_confidence ON recommendations(confidence);
@@ -0,0 +1,3 @@
+-- Revert 003: drop the workload kind column (SQLite 3.35+)
+
+ALTER TABLE recommendations DROP COLUMN workload_kind;
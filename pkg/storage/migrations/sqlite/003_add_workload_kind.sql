@@ -0,0 +1,4 @@
+-- Migration 003: Track the workload kind a recommendation targets
+-- Needed to patch the right resource when applying recommendations
+
+ALTER TABLE recommendations ADD COLUMN workload_kind TEXT;
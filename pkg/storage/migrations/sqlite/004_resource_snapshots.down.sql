@@ -0,0 +1,4 @@
+-- Revert 004: drop resource snapshots
+-- Recommendations applied before the revert can no longer be rolled back
+
+DROP TABLE IF EXISTS resource_snapshots;
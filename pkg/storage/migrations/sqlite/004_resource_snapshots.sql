@@ -0,0 +1,17 @@
+-- Migration 004: Snapshot workload resources before applying recommendations
+-- Used by rollback to restore the original container resources
+
+CREATE TABLE IF NOT EXISTS resource_snapshots (
+    id TEXT PRIMARY KEY,
+    recommendation_id TEXT NOT NULL,
+    namespace TEXT NOT NULL,
+    workload_kind TEXT NOT NULL,
+    workload_name TEXT NOT NULL,
+    resources TEXT, -- JSON: container name -> original resources block
+    replicas INTEGER, -- original replica count (scale changes only)
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (recommendation_id) REFERENCES recommendations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_resource_snapshots_recommendation ON resource_snapshots(recommendation_id, created_at DESC);
@@ -0,0 +1,6 @@
+-- Revert 005: drop the resource limit columns (SQLite 3.35+)
+
+ALTER TABLE recommendations DROP COLUMN current_cpu_limit_millicores;
+ALTER TABLE recommendations DROP COLUMN current_memory_limit_bytes;
+ALTER TABLE recommendations DROP COLUMN recommended_cpu_limit_millicores;
+ALTER TABLE recommendations DROP COLUMN recommended_memory_limit_bytes;
@@ -0,0 +1,7 @@
+-- Migration 005: Track current and recommended resource limits
+-- NULL (or 0) means no limit
+
+ALTER TABLE recommendations ADD COLUMN current_cpu_limit_millicores INTEGER;
+ALTER TABLE recommendations ADD COLUMN current_memory_limit_bytes INTEGER;
+ALTER TABLE recommendations ADD COLUMN recommended_cpu_limit_millicores INTEGER;
+ALTER TABLE recommendations ADD COLUMN recommended_memory_limit_bytes INTEGER;
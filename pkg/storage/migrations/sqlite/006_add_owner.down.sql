@@ -0,0 +1,3 @@
+-- Revert 006: drop the owner column (SQLite 3.35+)
+
+ALTER TABLE recommendations DROP COLUMN owner;
@@ -0,0 +1,3 @@
+-- Migration 006: Record the owner from the cost-optimizer.io/owner annotation
+
+ALTER TABLE recommendations ADD COLUMN owner TEXT;
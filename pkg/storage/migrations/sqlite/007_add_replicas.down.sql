@@ -0,0 +1,4 @@
+-- Revert 007: drop the replica count columns (SQLite 3.35+)
+
+ALTER TABLE recommendations DROP COLUMN current_replicas;
+ALTER TABLE recommendations DROP COLUMN recommended_replicas;
@@ -0,0 +1,5 @@
+-- Migration 007: Track current and recommended replica counts for
+-- REPLICA_RIGHT_SIZE recommendations (NULL for other types)
+
+ALTER TABLE recommendations ADD COLUMN current_replicas INTEGER;
+ALTER TABLE recommendations ADD COLUMN recommended_replicas INTEGER;
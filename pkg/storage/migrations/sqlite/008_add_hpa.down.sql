@@ -0,0 +1,7 @@
+-- Revert 008: drop the HPA columns (SQLite 3.35+)
+
+ALTER TABLE recommendations DROP COLUMN hpa_name;
+ALTER TABLE recommendations DROP COLUMN current_target_cpu_utilization;
+ALTER TABLE recommendations DROP COLUMN recommended_target_cpu_utilization;
+ALTER TABLE recommendations DROP COLUMN current_min_replicas;
+ALTER TABLE recommendations DROP COLUMN recommended_min_replicas;
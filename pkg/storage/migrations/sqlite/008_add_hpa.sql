@@ -0,0 +1,8 @@
+-- Migration 008: Track the HPA changes of HPA_ADJUST recommendations
+-- (NULL for other types)
+
+ALTER TABLE recommendations ADD COLUMN hpa_name TEXT;
+ALTER TABLE recommendations ADD COLUMN current_target_cpu_utilization INTEGER;
+ALTER TABLE recommendations ADD COLUMN recommended_target_cpu_utilization INTEGER;
+ALTER TABLE recommendations ADD COLUMN current_min_replicas INTEGER;
+ALTER TABLE recommendations ADD COLUMN recommended_min_replicas INTEGER;
@@ -0,0 +1,18 @@
+-- Migration 009: Store the container usage collected from the metrics API
+-- by cost-scan collect, for history without Prometheus
+
+CREATE TABLE IF NOT EXISTS usage_samples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    cluster_id TEXT NOT NULL,
+    namespace TEXT NOT NULL,
+    workload_kind TEXT NOT NULL,
+    workload_name TEXT NOT NULL,
+    pod TEXT NOT NULL,
+    container TEXT NOT NULL,
+    sampled_at DATETIME NOT NULL,
+    cpu_millicores INTEGER NOT NULL,
+    memory_bytes INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_samples_unique ON usage_samples(cluster_id, namespace, pod, container, sampled_at);
+CREATE INDEX IF NOT EXISTS idx_usage_samples_sampled_at ON usage_samples(sampled_at);
@@ -21,8 +21,28 @@ type PostgresStore struct {
 	dsn string
 }
 
-// NewPostgresStore creates a new PostgreSQL store
+// NewPostgresStore creates a new PostgreSQL store, applying any pending
+// migrations
 func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	store, err := OpenPostgres(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := store.Migrator()
+	if err == nil {
+		_, err = migrator.Up(context.Background())
+	}
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return store, nil
+}
+
+// OpenPostgres connects to PostgreSQL without touching the schema
+func OpenPostgres(dsn string) (*PostgresStore, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -35,36 +55,19 @@ func NewPostgresStore(dsn string) (*PostgresStore, error) {
 
 	// Test connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	store := &PostgresStore{
+	return &PostgresStore{
 		db:  db,
 		dsn: dsn,
-	}
-
-	// Run migrations
-	if err := store.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	return store, nil
+	}, nil
 }
 
-// migrate runs database migrations
-func (s *PostgresStore) migrate() error {
-	// Read schema file
-	schema, err := postgresFS.ReadFile("migrations/001_postgres_schema.sql")
-	if err != nil {
-		return fmt.Errorf("failed to read schema: %w", err)
-	}
-
-	// Execute schema
-	if _, err := s.db.Exec(string(schema)); err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
-	}
-
-	return nil
+// Migrator returns a Migrator for the embedded PostgreSQL migrations
+func (s *PostgresStore) Migrator() (*Migrator, error) {
+	return newMigrator(s.db, postgresDialect, postgresFS, "migrations")
 }
 
 // SaveRecommendation saves a recommendation
@@ -78,13 +81,18 @@ func (s *PostgresStore) SaveRecommendation(ctx context.Context, rec *models.Reco
 
 	query := `
 		INSERT INTO recommendations (
-			id, cluster_id, namespace, deployment, pod, container,
+			id, cluster_id, namespace, deployment, workload_kind, pod, container,
 			type, current_cpu_millicores, current_memory_bytes,
 			recommended_cpu_millicores, recommended_memory_bytes,
 			reason, savings_monthly_usd, impact, risk, command,
 			created_at, applied_at, applied_by,
-			confidence, data_quality, pattern_info, has_sufficient_data
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+			confidence, data_quality, pattern_info, has_sufficient_data,
+			current_cpu_limit_millicores, current_memory_limit_bytes,
+			recommended_cpu_limit_millicores, recommended_memory_limit_bytes,
+			owner, current_replicas, recommended_replicas,
+			hpa_name, current_target_cpu_utilization, recommended_target_cpu_utilization,
+			current_min_replicas, recommended_min_replicas
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36)
 	`
 
 	var appliedAt *time.Time
@@ -94,13 +102,18 @@ func (s *PostgresStore) SaveRecommendation(ctx context.Context, rec *models.Reco
 
 	_, err := s.db.ExecContext(ctx, query,
 		rec.ID, rec.Workload.ClusterID, rec.Workload.Namespace,
-		rec.Workload.Deployment, rec.Workload.Pod, rec.Workload.Container,
+		rec.Workload.Deployment, rec.Workload.Kind, rec.Workload.Pod, rec.Workload.Container,
 		rec.Type, rec.CurrentCPU, rec.CurrentMemory,
 		rec.RecommendedCPU, rec.RecommendedMemory,
 		rec.Reason, rec.SavingsMonthly, rec.Impact, rec.Risk, rec.Command,
 		rec.CreatedAt, appliedAt, rec.AppliedBy,
 		// Week 9 Day 2: Confidence fields
 		rec.Confidence, rec.DataQuality, rec.PatternInfo, rec.HasSufficientData,
+		rec.CurrentCPULimit, rec.CurrentMemoryLimit,
+		rec.RecommendedCPULimit, rec.RecommendedMemoryLimit,
+		rec.Workload.Owner, nullInt32(rec.CurrentReplicas), nullInt32(rec.RecommendedReplicas),
+		rec.HPAName, nullInt32(rec.CurrentTargetCPU), nullInt32(rec.RecommendedTargetCPU),
+		nullInt32(rec.CurrentMinReplicas), nullInt32(rec.RecommendedMinReplicas),
 	)
 
 	return err
@@ -109,58 +122,26 @@ func (s *PostgresStore) SaveRecommendation(ctx context.Context, rec *models.Reco
 // GetRecommendation retrieves a recommendation by ID
 func (s *PostgresStore) GetRecommendation(ctx context.Context, id string) (*models.Recommendation, error) {
 	query := `
-		SELECT id, cluster_id, namespace, deployment, pod, container,
-			type, current_cpu_millicores, current_memory_bytes,
-			recommended_cpu_millicores, recommended_memory_bytes,
-			reason, savings_monthly_usd, impact, risk, command,
-			created_at, applied_at, applied_by
+		SELECT ` + recommendationColumns + `
 		FROM recommendations
 		WHERE id = $1
 	`
 
-	var rec models.Recommendation
-	var workload models.Workload
-	var appliedAt sql.NullTime
-	var deployment, container, appliedBy sql.NullString
-
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&rec.ID, &workload.ClusterID, &workload.Namespace,
-		&deployment, &workload.Pod, &container,
-		&rec.Type, &rec.CurrentCPU, &rec.CurrentMemory,
-		&rec.RecommendedCPU, &rec.RecommendedMemory,
-		&rec.Reason, &rec.SavingsMonthly, &rec.Impact, &rec.Risk, &rec.Command,
-		&rec.CreatedAt, &appliedAt, &appliedBy,
-	)
-
+	rec, err := scanRecommendation(s.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("recommendation not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	workload.Deployment = deployment.String
-	workload.Container = container.String
-	rec.Workload = &workload
-
-	if appliedAt.Valid {
-		rec.AppliedAt = &appliedAt.Time
-	}
-	if appliedBy.Valid {
-		rec.AppliedBy = appliedBy.String
-	}
-
-	return &rec, nil
+	return rec, nil
 }
 
 // ListRecommendations retrieves recommendations for a namespace
 func (s *PostgresStore) ListRecommendations(ctx context.Context, namespace string, limit int) ([]*models.Recommendation, error) {
 	query := `
-		SELECT id, cluster_id, namespace, deployment, pod, container,
-			type, current_cpu_millicores, current_memory_bytes,
-			recommended_cpu_millicores, recommended_memory_bytes,
-			reason, savings_monthly_usd, impact, risk, command,
-			created_at, applied_at, applied_by
+		SELECT ` + recommendationColumns + `
 		FROM recommendations
 		WHERE namespace = $1
 		ORDER BY created_at DESC
@@ -175,35 +156,11 @@ func (s *PostgresStore) ListRecommendations(ctx context.Context, namespace strin
 
 	var recommendations []*models.Recommendation
 	for rows.Next() {
-		var rec models.Recommendation
-		var workload models.Workload
-		var appliedAt sql.NullTime
-		var deployment, container, appliedBy sql.NullString
-
-		err := rows.Scan(
-			&rec.ID, &workload.ClusterID, &workload.Namespace,
-			&deployment, &workload.Pod, &container,
-			&rec.Type, &rec.CurrentCPU, &rec.CurrentMemory,
-			&rec.RecommendedCPU, &rec.RecommendedMemory,
-			&rec.Reason, &rec.SavingsMonthly, &rec.Impact, &rec.Risk, &rec.Command,
-			&rec.CreatedAt, &appliedAt, &appliedBy,
-		)
+		rec, err := scanRecommendation(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		workload.Deployment = deployment.String
-		workload.Container = container.String
-		rec.Workload = &workload
-
-		if appliedAt.Valid {
-			rec.AppliedAt = &appliedAt.Time
-		}
-		if appliedBy.Valid {
-			rec.AppliedBy = appliedBy.String
-		}
-
-		recommendations = append(recommendations, &rec)
+		recommendations = append(recommendations, rec)
 	}
 
 	return recommendations, rows.Err()
@@ -232,7 +189,7 @@ func (s *PostgresStore) UpdateRecommendation(ctx context.Context, rec *models.Re
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("recommendation not found: %s", rec.ID)
+		return fmt.Errorf("%w: %s", ErrNotFound, rec.ID)
 	}
 
 	return nil
@@ -304,6 +261,74 @@ func (s *PostgresStore) GetAuditLog(ctx context.Context, recommendationID string
 	return entries, rows.Err()
 }
 
+// SaveSnapshot stores the pre-change state of a workload
+func (s *PostgresStore) SaveSnapshot(ctx context.Context, snapshot *models.ResourceSnapshot) error {
+	if snapshot.ID == "" {
+		snapshot.ID = uuid.New().String()
+	}
+	if snapshot.CreatedAt.IsZero() {
+		snapshot.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO resource_snapshots (
+			id, recommendation_id, namespace, workload_kind, workload_name,
+			resources, replicas, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	var resources interface{}
+	if len(snapshot.Resources) > 0 {
+		resources = string(snapshot.Resources)
+	}
+
+	_, err := s.db.ExecContext(ctx, query,
+		snapshot.ID, snapshot.RecommendationID, snapshot.Namespace,
+		snapshot.WorkloadKind, snapshot.WorkloadName,
+		resources, snapshot.Replicas, snapshot.CreatedAt,
+	)
+
+	return err
+}
+
+// GetSnapshot retrieves the most recent snapshot taken for a recommendation
+func (s *PostgresStore) GetSnapshot(ctx context.Context, recommendationID string) (*models.ResourceSnapshot, error) {
+	query := `
+		SELECT id, recommendation_id, namespace, workload_kind, workload_name,
+			resources, replicas, created_at
+		FROM resource_snapshots
+		WHERE recommendation_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var snapshot models.ResourceSnapshot
+	var resources sql.NullString
+	var replicas sql.NullInt32
+
+	err := s.db.QueryRowContext(ctx, query, recommendationID).Scan(
+		&snapshot.ID, &snapshot.RecommendationID, &snapshot.Namespace,
+		&snapshot.WorkloadKind, &snapshot.WorkloadName,
+		&resources, &replicas, &snapshot.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no snapshot found for recommendation: %s", recommendationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resources.Valid {
+		snapshot.Resources = []byte(resources.String)
+	}
+	if replicas.Valid {
+		snapshot.Replicas = &replicas.Int32
+	}
+
+	return &snapshot, nil
+}
+
 // CacheMetrics stores metrics in cache
 func (s *PostgresStore) CacheMetrics(ctx context.Context, workload *models.Workload, metrics *models.Metrics) error {
 	query := `
@@ -380,6 +405,43 @@ func (s *PostgresStore) GetCachedMetrics(ctx context.Context, workload *models.W
 	return &metrics, nil
 }
 
+// SaveUsageSamples stores collected usage. A sample of a container at a
+// time already stored is ignored.
+func (s *PostgresStore) SaveUsageSamples(ctx context.Context, samples []*models.UsageSample) error {
+	query := `
+		INSERT INTO usage_samples (
+			` + usageSampleColumns + `
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (cluster_id, namespace, pod, container, sampled_at) DO NOTHING
+	`
+
+	return saveUsageSamples(ctx, s.db, query, samples)
+}
+
+// ListUsageSamples returns the usage collected in a namespace since a
+// time, in time order
+func (s *PostgresStore) ListUsageSamples(ctx context.Context, clusterID, namespace string, since time.Time) ([]*models.UsageSample, error) {
+	query := `
+		SELECT ` + usageSampleColumns + `
+		FROM usage_samples
+		WHERE cluster_id = $1 AND ($2 = '' OR namespace = $2)
+			AND sampled_at >= $3
+		ORDER BY sampled_at, pod, container
+	`
+
+	return queryUsageSamples(ctx, s.db, query, clusterID, namespace, since.UTC())
+}
+
+// PruneUsageSamples deletes the usage collected before a time and returns
+// how many samples were deleted
+func (s *PostgresStore) PruneUsageSamples(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM usage_samples WHERE sampled_at < $1`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // Ping checks database connectivity
 func (s *PostgresStore) Ping(ctx context.Context) error {
 	return s.db.PingContext(ctx)
@@ -457,11 +519,7 @@ func (s *PostgresStore) GetSavingsTrend(ctx context.Context, namespace string, d
 // GetWorkloadHistory returns recommendation history for a specific workload
 func (s *PostgresStore) GetWorkloadHistory(ctx context.Context, namespace, deployment string, limit int) ([]*models.Recommendation, error) {
 	query := `
-		SELECT id, cluster_id, namespace, deployment, pod, container,
-			type, current_cpu_millicores, current_memory_bytes,
-			recommended_cpu_millicores, recommended_memory_bytes,
-			reason, savings_monthly_usd, impact, risk, command,
-			created_at, applied_at, applied_by
+		SELECT ` + recommendationColumns + `
 		FROM recommendations
 		WHERE namespace = $1 AND deployment = $2
 		ORDER BY created_at DESC
@@ -476,35 +534,11 @@ func (s *PostgresStore) GetWorkloadHistory(ctx context.Context, namespace, deplo
 
 	var recommendations []*models.Recommendation
 	for rows.Next() {
-		var rec models.Recommendation
-		var workload models.Workload
-		var appliedAt sql.NullTime
-		var deployment, container, appliedBy sql.NullString
-
-		err := rows.Scan(
-			&rec.ID, &workload.ClusterID, &workload.Namespace,
-			&deployment, &workload.Pod, &container,
-			&rec.Type, &rec.CurrentCPU, &rec.CurrentMemory,
-			&rec.RecommendedCPU, &rec.RecommendedMemory,
-			&rec.Reason, &rec.SavingsMonthly, &rec.Impact, &rec.Risk, &rec.Command,
-			&rec.CreatedAt, &appliedAt, &appliedBy,
-		)
+		rec, err := scanRecommendation(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		workload.Deployment = deployment.String
-		workload.Container = container.String
-		rec.Workload = &workload
-
-		if appliedAt.Valid {
-			rec.AppliedAt = &appliedAt.Time
-		}
-		if appliedBy.Valid {
-			rec.AppliedBy = appliedBy.String
-		}
-
-		recommendations = append(recommendations, &rec)
+		recommendations = append(recommendations, rec)
 	}
 
 	return recommendations, rows.Err()
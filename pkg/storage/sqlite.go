@@ -0,0 +1,636 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opscart/k8s-cost-optimizer/pkg/models"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLiteStore implements Store interface using a SQLite file
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+// NewSQLiteStore opens (or creates) a SQLite database at path and applies
+// any pending migrations. Use ":memory:" for a throwaway in-memory database.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	store, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := store.Migrator()
+	if err == nil {
+		_, err = migrator.Up(context.Background())
+	}
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return store, nil
+}
+
+// OpenSQLite opens (or creates) a SQLite database without touching the schema
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("SQLite database path must be set")
+	}
+
+	// Times are written in a format SQLite's date functions understand
+	dsn := path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer; one connection also keeps an
+	// in-memory database alive for the lifetime of the store
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &SQLiteStore{
+		db:   db,
+		path: path,
+	}, nil
+}
+
+// Migrator returns a Migrator for the embedded SQLite migrations
+func (s *SQLiteStore) Migrator() (*Migrator, error) {
+	return newMigrator(s.db, sqliteDialect, sqliteFS, "migrations/sqlite")
+}
+
+// since returns the cutoff for an analytics window of days, in UTC like
+// every stored timestamp
+func since(days int) time.Time {
+	return time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+// SaveRecommendation saves a recommendation
+func (s *SQLiteStore) SaveRecommendation(ctx context.Context, rec *models.Recommendation) error {
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO recommendations (
+			id, cluster_id, namespace, deployment, workload_kind, pod, container,
+			type, current_cpu_millicores, current_memory_bytes,
+			recommended_cpu_millicores, recommended_memory_bytes,
+			reason, savings_monthly_usd, impact, risk, command,
+			created_at, applied_at, applied_by,
+			confidence, data_quality, pattern_info, has_sufficient_data,
+			current_cpu_limit_millicores, current_memory_limit_bytes,
+			recommended_cpu_limit_millicores, recommended_memory_limit_bytes,
+			owner, current_replicas, recommended_replicas,
+			hpa_name, current_target_cpu_utilization, recommended_target_cpu_utilization,
+			current_min_replicas, recommended_min_replicas
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var appliedAt *time.Time
+	if rec.AppliedAt != nil {
+		utc := rec.AppliedAt.UTC()
+		appliedAt = &utc
+	}
+
+	_, err := s.db.ExecContext(ctx, query,
+		rec.ID, rec.Workload.ClusterID, rec.Workload.Namespace,
+		rec.Workload.Deployment, rec.Workload.Kind, rec.Workload.Pod, rec.Workload.Container,
+		rec.Type, rec.CurrentCPU, rec.CurrentMemory,
+		rec.RecommendedCPU, rec.RecommendedMemory,
+		rec.Reason, rec.SavingsMonthly, rec.Impact, rec.Risk, rec.Command,
+		rec.CreatedAt.UTC(), appliedAt, rec.AppliedBy,
+		rec.Confidence, rec.DataQuality, rec.PatternInfo, rec.HasSufficientData,
+		rec.CurrentCPULimit, rec.CurrentMemoryLimit,
+		rec.RecommendedCPULimit, rec.RecommendedMemoryLimit,
+		rec.Workload.Owner, nullInt32(rec.CurrentReplicas), nullInt32(rec.RecommendedReplicas),
+		rec.HPAName, nullInt32(rec.CurrentTargetCPU), nullInt32(rec.RecommendedTargetCPU),
+		nullInt32(rec.CurrentMinReplicas), nullInt32(rec.RecommendedMinReplicas),
+	)
+
+	return err
+}
+
+// GetRecommendation retrieves a recommendation by ID
+func (s *SQLiteStore) GetRecommendation(ctx context.Context, id string) (*models.Recommendation, error) {
+	query := `
+		SELECT ` + recommendationColumns + `
+		FROM recommendations
+		WHERE id = ?
+	`
+
+	rec, err := scanRecommendation(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// ListRecommendations retrieves recommendations for a namespace
+func (s *SQLiteStore) ListRecommendations(ctx context.Context, namespace string, limit int) ([]*models.Recommendation, error) {
+	query := `
+		SELECT ` + recommendationColumns + `
+		FROM recommendations
+		WHERE namespace = ?
+		ORDER BY created_at DESC
+		LIMIT ?
+	`
+
+	return s.queryRecommendations(ctx, query, namespace, limit)
+}
+
+// queryRecommendations runs a query selecting recommendationColumns
+func (s *SQLiteStore) queryRecommendations(ctx context.Context, query string, args ...interface{}) ([]*models.Recommendation, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recommendations []*models.Recommendation
+	for rows.Next() {
+		rec, err := scanRecommendation(rows)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, rec)
+	}
+
+	return recommendations, rows.Err()
+}
+
+// UpdateRecommendation updates an existing recommendation
+func (s *SQLiteStore) UpdateRecommendation(ctx context.Context, rec *models.Recommendation) error {
+	query := `
+		UPDATE recommendations
+		SET applied_at = ?, applied_by = ?
+		WHERE id = ?
+	`
+
+	var appliedAt *time.Time
+	if rec.AppliedAt != nil {
+		utc := rec.AppliedAt.UTC()
+		appliedAt = &utc
+	}
+
+	result, err := s.db.ExecContext(ctx, query, appliedAt, rec.AppliedBy, rec.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, rec.ID)
+	}
+
+	return nil
+}
+
+// LogAction logs an action to the audit trail
+func (s *SQLiteStore) LogAction(ctx context.Context, entry *models.AuditEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.ExecutedAt.IsZero() {
+		entry.ExecutedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO audit_log (
+			id, recommendation_id, action, status,
+			error_message, executed_by, executed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		entry.ID, entry.RecommendationID, entry.Action, entry.Status,
+		entry.ErrorMessage, entry.ExecutedBy, entry.ExecutedAt.UTC(),
+	)
+
+	return err
+}
+
+// GetAuditLog retrieves audit log entries for a recommendation
+func (s *SQLiteStore) GetAuditLog(ctx context.Context, recommendationID string) ([]*models.AuditEntry, error) {
+	query := `
+		SELECT id, recommendation_id, action, status,
+			error_message, executed_by, executed_at
+		FROM audit_log
+		WHERE recommendation_id = ?
+		ORDER BY executed_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, recommendationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var errorMessage, executedBy sql.NullString
+
+		err := rows.Scan(
+			&entry.ID, &entry.RecommendationID, &entry.Action, &entry.Status,
+			&errorMessage, &executedBy, &entry.ExecutedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.ErrorMessage = errorMessage.String
+		entry.ExecutedBy = executedBy.String
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// SaveSnapshot stores the pre-change state of a workload
+func (s *SQLiteStore) SaveSnapshot(ctx context.Context, snapshot *models.ResourceSnapshot) error {
+	if snapshot.ID == "" {
+		snapshot.ID = uuid.New().String()
+	}
+	if snapshot.CreatedAt.IsZero() {
+		snapshot.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO resource_snapshots (
+			id, recommendation_id, namespace, workload_kind, workload_name,
+			resources, replicas, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var resources interface{}
+	if len(snapshot.Resources) > 0 {
+		resources = string(snapshot.Resources)
+	}
+
+	_, err := s.db.ExecContext(ctx, query,
+		snapshot.ID, snapshot.RecommendationID, snapshot.Namespace,
+		snapshot.WorkloadKind, snapshot.WorkloadName,
+		resources, snapshot.Replicas, snapshot.CreatedAt.UTC(),
+	)
+
+	return err
+}
+
+// GetSnapshot retrieves the most recent snapshot taken for a recommendation
+func (s *SQLiteStore) GetSnapshot(ctx context.Context, recommendationID string) (*models.ResourceSnapshot, error) {
+	query := `
+		SELECT id, recommendation_id, namespace, workload_kind, workload_name,
+			resources, replicas, created_at
+		FROM resource_snapshots
+		WHERE recommendation_id = ?
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var snapshot models.ResourceSnapshot
+	var resources sql.NullString
+	var replicas sql.NullInt32
+
+	err := s.db.QueryRowContext(ctx, query, recommendationID).Scan(
+		&snapshot.ID, &snapshot.RecommendationID, &snapshot.Namespace,
+		&snapshot.WorkloadKind, &snapshot.WorkloadName,
+		&resources, &replicas, &snapshot.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no snapshot found for recommendation: %s", recommendationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resources.Valid {
+		snapshot.Resources = []byte(resources.String)
+	}
+	if replicas.Valid {
+		snapshot.Replicas = &replicas.Int32
+	}
+
+	return &snapshot, nil
+}
+
+// CacheMetrics stores metrics in cache
+func (s *SQLiteStore) CacheMetrics(ctx context.Context, workload *models.Workload, metrics *models.Metrics) error {
+	query := `
+		INSERT INTO metrics_cache (
+			cluster_id, namespace, pod,
+			p95_cpu_millicores, p99_cpu_millicores, max_cpu_millicores, avg_cpu_millicores,
+			p95_memory_bytes, p99_memory_bytes, max_memory_bytes, avg_memory_bytes,
+			requested_cpu_millicores, requested_memory_bytes,
+			collected_at, duration_hours, sample_count, expires_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (cluster_id, namespace, pod, collected_at) DO UPDATE SET
+			p95_cpu_millicores = excluded.p95_cpu_millicores,
+			p99_cpu_millicores = excluded.p99_cpu_millicores,
+			max_cpu_millicores = excluded.max_cpu_millicores,
+			avg_cpu_millicores = excluded.avg_cpu_millicores,
+			p95_memory_bytes = excluded.p95_memory_bytes,
+			p99_memory_bytes = excluded.p99_memory_bytes,
+			max_memory_bytes = excluded.max_memory_bytes,
+			avg_memory_bytes = excluded.avg_memory_bytes,
+			requested_cpu_millicores = excluded.requested_cpu_millicores,
+			requested_memory_bytes = excluded.requested_memory_bytes,
+			expires_at = excluded.expires_at
+	`
+
+	expiresAt := time.Now().UTC().Add(24 * time.Hour)
+	durationHours := int(metrics.Duration.Hours())
+
+	_, err := s.db.ExecContext(ctx, query,
+		workload.ClusterID, workload.Namespace, workload.Pod,
+		metrics.P95CPU, metrics.P99CPU, metrics.MaxCPU, metrics.AvgCPU,
+		metrics.P95Memory, metrics.P99Memory, metrics.MaxMemory, metrics.AvgMemory,
+		metrics.RequestedCPU, metrics.RequestedMemory,
+		metrics.CollectedAt.UTC(), durationHours, metrics.SampleCount, expiresAt,
+	)
+
+	return err
+}
+
+// GetCachedMetrics retrieves cached metrics
+func (s *SQLiteStore) GetCachedMetrics(ctx context.Context, workload *models.Workload) (*models.Metrics, error) {
+	query := `
+		SELECT p95_cpu_millicores, p99_cpu_millicores, max_cpu_millicores, avg_cpu_millicores,
+			p95_memory_bytes, p99_memory_bytes, max_memory_bytes, avg_memory_bytes,
+			requested_cpu_millicores, requested_memory_bytes,
+			collected_at, duration_hours, sample_count
+		FROM metrics_cache
+		WHERE cluster_id = ? AND namespace = ? AND pod = ?
+			AND julianday(expires_at) > julianday(?)
+		ORDER BY collected_at DESC
+		LIMIT 1
+	`
+
+	var metrics models.Metrics
+	var durationHours int
+
+	err := s.db.QueryRowContext(ctx, query,
+		workload.ClusterID, workload.Namespace, workload.Pod, time.Now().UTC(),
+	).Scan(
+		&metrics.P95CPU, &metrics.P99CPU, &metrics.MaxCPU, &metrics.AvgCPU,
+		&metrics.P95Memory, &metrics.P99Memory, &metrics.MaxMemory, &metrics.AvgMemory,
+		&metrics.RequestedCPU, &metrics.RequestedMemory,
+		&metrics.CollectedAt, &durationHours, &metrics.SampleCount,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no cached metrics found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.Duration = time.Duration(durationHours) * time.Hour
+
+	return &metrics, nil
+}
+
+// SaveUsageSamples stores collected usage. A sample of a container at a
+// time already stored is ignored.
+func (s *SQLiteStore) SaveUsageSamples(ctx context.Context, samples []*models.UsageSample) error {
+	query := `
+		INSERT INTO usage_samples (
+			` + usageSampleColumns + `
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (cluster_id, namespace, pod, container, sampled_at) DO NOTHING
+	`
+
+	return saveUsageSamples(ctx, s.db, query, samples)
+}
+
+// ListUsageSamples returns the usage collected in a namespace since a
+// time, in time order
+func (s *SQLiteStore) ListUsageSamples(ctx context.Context, clusterID, namespace string, since time.Time) ([]*models.UsageSample, error) {
+	query := `
+		SELECT ` + usageSampleColumns + `
+		FROM usage_samples
+		WHERE cluster_id = ?1 AND (?2 = '' OR namespace = ?2)
+			AND julianday(sampled_at) >= julianday(?3)
+		ORDER BY sampled_at, pod, container
+	`
+
+	return queryUsageSamples(ctx, s.db, query, clusterID, namespace, since.UTC())
+}
+
+// PruneUsageSamples deletes the usage collected before a time and returns
+// how many samples were deleted
+func (s *SQLiteStore) PruneUsageSamples(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM usage_samples WHERE julianday(sampled_at) < julianday(?)`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Ping checks database connectivity
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the database connection
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// GetSavingsTrend returns cost savings trend over time
+func (s *SQLiteStore) GetSavingsTrend(ctx context.Context, namespace string, days int) (*models.SavingsTrend, error) {
+	query := `
+		SELECT
+			date(created_at) as date,
+			COUNT(*) as recommendation_count,
+			COALESCE(SUM(savings_monthly_usd), 0) as potential_savings,
+			COUNT(CASE WHEN applied_at IS NOT NULL THEN 1 END) as applied_count,
+			COALESCE(SUM(CASE WHEN applied_at IS NOT NULL THEN savings_monthly_usd ELSE 0 END), 0) as realized_savings
+		FROM recommendations
+		WHERE namespace = ?
+			AND julianday(created_at) >= julianday(?)
+		GROUP BY date(created_at)
+		ORDER BY date DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, namespace, since(days))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trend := &models.SavingsTrend{
+		Namespace:  namespace,
+		Days:       days,
+		DataPoints: []models.SavingsDataPoint{},
+	}
+
+	var totalPotential, totalRealized float64
+	var totalRecs, totalApplied int
+
+	for rows.Next() {
+		var dp models.SavingsDataPoint
+		var date string
+		err := rows.Scan(
+			&date,
+			&dp.RecommendationCount,
+			&dp.PotentialSavings,
+			&dp.AppliedCount,
+			&dp.RealizedSavings,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		dp.Date, err = time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trend date %q: %w", date, err)
+		}
+
+		totalPotential += dp.PotentialSavings
+		totalRealized += dp.RealizedSavings
+		totalRecs += dp.RecommendationCount
+		totalApplied += dp.AppliedCount
+
+		trend.DataPoints = append(trend.DataPoints, dp)
+	}
+
+	trend.TotalPotentialSavings = totalPotential
+	trend.TotalRealizedSavings = totalRealized
+	trend.TotalRecommendations = totalRecs
+	trend.TotalApplied = totalApplied
+
+	if totalRecs > 0 {
+		trend.AdoptionRate = float64(totalApplied) / float64(totalRecs) * 100
+	}
+
+	return trend, rows.Err()
+}
+
+// GetWorkloadHistory returns recommendation history for a specific workload
+func (s *SQLiteStore) GetWorkloadHistory(ctx context.Context, namespace, deployment string, limit int) ([]*models.Recommendation, error) {
+	query := `
+		SELECT ` + recommendationColumns + `
+		FROM recommendations
+		WHERE namespace = ? AND deployment = ?
+		ORDER BY created_at DESC
+		LIMIT ?
+	`
+
+	return s.queryRecommendations(ctx, query, namespace, deployment, limit)
+}
+
+// GetDashboardStats returns aggregate statistics for dashboard
+func (s *SQLiteStore) GetDashboardStats(ctx context.Context, namespace string, days int) (*models.DashboardStats, error) {
+	query := `
+		SELECT
+			COUNT(*) as total_recommendations,
+			COUNT(CASE WHEN applied_at IS NOT NULL THEN 1 END) as applied_count,
+			COALESCE(SUM(savings_monthly_usd), 0) as potential_savings,
+			COALESCE(SUM(CASE WHEN applied_at IS NOT NULL THEN savings_monthly_usd ELSE 0 END), 0) as realized_savings,
+			COUNT(DISTINCT deployment) as unique_workloads,
+			COALESCE(AVG(savings_monthly_usd), 0) as avg_savings_per_recommendation
+		FROM recommendations
+		WHERE namespace = ?
+			AND julianday(created_at) >= julianday(?)
+	`
+
+	var stats models.DashboardStats
+	var appliedCount, uniqueWorkloads int
+	var avgSavings float64
+
+	err := s.db.QueryRowContext(ctx, query, namespace, since(days)).Scan(
+		&stats.TotalRecommendations,
+		&appliedCount,
+		&stats.PotentialSavings,
+		&stats.RealizedSavings,
+		&uniqueWorkloads,
+		&avgSavings,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.AppliedCount = appliedCount
+	stats.UniqueWorkloads = uniqueWorkloads
+	stats.Namespace = namespace
+	stats.PeriodDays = days
+	stats.AvgSavingsPerRecommendation = avgSavings
+
+	if stats.TotalRecommendations > 0 {
+		stats.AdoptionRate = float64(stats.AppliedCount) / float64(stats.TotalRecommendations) * 100
+	}
+
+	return &stats, nil
+}
+
+// ComparePerformance compares current vs previous period
+func (s *SQLiteStore) ComparePerformance(ctx context.Context, namespace string, days int) (*models.PerformanceComparison, error) {
+	query := `
+		SELECT
+			COUNT(CASE WHEN julianday(created_at) >= julianday(?2) THEN 1 END) as current_count,
+			COALESCE(SUM(CASE WHEN julianday(created_at) >= julianday(?2) THEN savings_monthly_usd ELSE 0 END), 0) as current_savings,
+			COUNT(CASE WHEN julianday(created_at) < julianday(?2) THEN 1 END) as previous_count,
+			COALESCE(SUM(CASE WHEN julianday(created_at) < julianday(?2) THEN savings_monthly_usd ELSE 0 END), 0) as previous_savings
+		FROM recommendations
+		WHERE namespace = ?1
+			AND julianday(created_at) >= julianday(?3)
+	`
+
+	var comp models.PerformanceComparison
+	var currentCount, previousCount int
+	var currentSavings, previousSavings float64
+
+	err := s.db.QueryRowContext(ctx, query, namespace, since(days), since(days*2)).Scan(
+		&currentCount,
+		&currentSavings,
+		&previousCount,
+		&previousSavings,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	comp.CurrentPeriodDays = days
+	comp.CurrentRecommendations = currentCount
+	comp.PreviousRecommendations = previousCount
+	comp.CurrentSavings = currentSavings
+	comp.PreviousSavings = previousSavings
+
+	// Calculate changes
+	if previousCount > 0 {
+		comp.RecommendationChange = float64(currentCount-previousCount) / float64(previousCount) * 100
+	}
+	if previousSavings > 0 {
+		comp.SavingsChange = (comp.CurrentSavings - previousSavings) / previousSavings * 100
+	}
+
+	return &comp, nil
+}
@@ -2,10 +2,18 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/opscart/k8s-cost-optimizer/pkg/models"
 )
 
+// ErrNotFound is returned (wrapped) when a recommendation does not exist
+var ErrNotFound = errors.New("recommendation not found")
+
 // Store defines the interface for persistent storage
 type Store interface {
 	SaveRecommendation(ctx context.Context, rec *models.Recommendation) error
@@ -16,9 +24,18 @@ type Store interface {
 	LogAction(ctx context.Context, entry *models.AuditEntry) error
 	GetAuditLog(ctx context.Context, recommendationID string) ([]*models.AuditEntry, error)
 
+	SaveSnapshot(ctx context.Context, snapshot *models.ResourceSnapshot) error
+	GetSnapshot(ctx context.Context, recommendationID string) (*models.ResourceSnapshot, error)
+
 	CacheMetrics(ctx context.Context, workload *models.Workload, metrics *models.Metrics) error
 	GetCachedMetrics(ctx context.Context, workload *models.Workload) (*models.Metrics, error)
 
+	// Usage collected from the metrics API by cost-scan collect. An empty
+	// namespace lists every namespace of the cluster.
+	SaveUsageSamples(ctx context.Context, samples []*models.UsageSample) error
+	ListUsageSamples(ctx context.Context, clusterID, namespace string, since time.Time) ([]*models.UsageSample, error)
+	PruneUsageSamples(ctx context.Context, before time.Time) (int64, error)
+
 	// Analytics methods (premium features)
 	GetSavingsTrend(ctx context.Context, namespace string, days int) (*models.SavingsTrend, error)
 	GetWorkloadHistory(ctx context.Context, namespace, deployment string, limit int) ([]*models.Recommendation, error)
@@ -35,3 +52,192 @@ type Config struct {
 	URL     string
 	Timeout int
 }
+
+// Storage types
+const (
+	TypePostgres = "postgres"
+	TypeSQLite   = "sqlite"
+	TypeMemory   = "memory"
+)
+
+// New creates the Store selected by cfg.Type
+func New(cfg Config) (Store, error) {
+	switch cfg.Type {
+	case "", TypePostgres:
+		store, err := NewPostgresStore(cfg.URL)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case TypeSQLite:
+		store, err := NewSQLiteStore(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case TypeMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s (expected %s, %s or %s)", cfg.Type, TypePostgres, TypeSQLite, TypeMemory)
+	}
+}
+
+// ParseDB builds a Config from a --db value: postgres:// and postgresql://
+// URLs select PostgreSQL, anything else is a SQLite file path (an optional
+// sqlite:// prefix is stripped)
+func ParseDB(value string) Config {
+	if strings.HasPrefix(value, "postgres://") || strings.HasPrefix(value, "postgresql://") {
+		return Config{Type: TypePostgres, URL: value}
+	}
+	return Config{Type: TypeSQLite, Path: strings.TrimPrefix(value, "sqlite://")}
+}
+
+// recommendationColumns is the column list shared by all recommendation queries
+const recommendationColumns = `id, cluster_id, namespace, deployment, workload_kind, pod, container,
+			type, current_cpu_millicores, current_memory_bytes,
+			recommended_cpu_millicores, recommended_memory_bytes,
+			reason, savings_monthly_usd, impact, risk, command,
+			created_at, applied_at, applied_by,
+			confidence, data_quality, pattern_info, has_sufficient_data,
+			current_cpu_limit_millicores, current_memory_limit_bytes,
+			recommended_cpu_limit_millicores, recommended_memory_limit_bytes,
+			owner, current_replicas, recommended_replicas,
+			hpa_name, current_target_cpu_utilization, recommended_target_cpu_utilization,
+			current_min_replicas, recommended_min_replicas`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRecommendation reads a row selected with recommendationColumns
+func scanRecommendation(row rowScanner) (*models.Recommendation, error) {
+	var rec models.Recommendation
+	var workload models.Workload
+	var appliedAt sql.NullTime
+	var deployment, kind, container, appliedBy sql.NullString
+	var confidence, patternInfo sql.NullString
+	var dataQuality sql.NullFloat64
+	var hasSufficientData sql.NullBool
+	var currentCPULimit, currentMemoryLimit sql.NullInt64
+	var recommendedCPULimit, recommendedMemoryLimit sql.NullInt64
+	var owner sql.NullString
+	var currentReplicas, recommendedReplicas sql.NullInt32
+	var hpaName sql.NullString
+	var currentTargetCPU, recommendedTargetCPU sql.NullInt32
+	var currentMinReplicas, recommendedMinReplicas sql.NullInt32
+
+	err := row.Scan(
+		&rec.ID, &workload.ClusterID, &workload.Namespace,
+		&deployment, &kind, &workload.Pod, &container,
+		&rec.Type, &rec.CurrentCPU, &rec.CurrentMemory,
+		&rec.RecommendedCPU, &rec.RecommendedMemory,
+		&rec.Reason, &rec.SavingsMonthly, &rec.Impact, &rec.Risk, &rec.Command,
+		&rec.CreatedAt, &appliedAt, &appliedBy,
+		&confidence, &dataQuality, &patternInfo, &hasSufficientData,
+		&currentCPULimit, &currentMemoryLimit,
+		&recommendedCPULimit, &recommendedMemoryLimit,
+		&owner, &currentReplicas, &recommendedReplicas,
+		&hpaName, &currentTargetCPU, &recommendedTargetCPU,
+		&currentMinReplicas, &recommendedMinReplicas,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	workload.Deployment = deployment.String
+	workload.Kind = kind.String
+	workload.Container = container.String
+	workload.Owner = owner.String
+	rec.Workload = &workload
+
+	if appliedAt.Valid {
+		rec.AppliedAt = &appliedAt.Time
+	}
+	if appliedBy.Valid {
+		rec.AppliedBy = appliedBy.String
+	}
+
+	rec.Confidence = confidence.String
+	rec.DataQuality = dataQuality.Float64
+	rec.PatternInfo = patternInfo.String
+	rec.HasSufficientData = hasSufficientData.Bool
+
+	rec.CurrentCPULimit = currentCPULimit.Int64
+	rec.CurrentMemoryLimit = currentMemoryLimit.Int64
+	rec.RecommendedCPULimit = recommendedCPULimit.Int64
+	rec.RecommendedMemoryLimit = recommendedMemoryLimit.Int64
+
+	rec.CurrentReplicas = currentReplicas.Int32
+	rec.RecommendedReplicas = recommendedReplicas.Int32
+
+	rec.HPAName = hpaName.String
+	rec.CurrentTargetCPU = currentTargetCPU.Int32
+	rec.RecommendedTargetCPU = recommendedTargetCPU.Int32
+	rec.CurrentMinReplicas = currentMinReplicas.Int32
+	rec.RecommendedMinReplicas = recommendedMinReplicas.Int32
+
+	return &rec, nil
+}
+
+// nullInt32 stores replica counts and HPA targets as NULL for
+// recommendations that do not change them
+func nullInt32(value int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: value, Valid: value > 0}
+}
+
+// usageSampleColumns is the column list shared by all usage sample queries
+const usageSampleColumns = `cluster_id, namespace, workload_kind, workload_name, pod, container,
+			sampled_at, cpu_millicores, memory_bytes`
+
+// saveUsageSamples runs insert, which binds usageSampleColumns, for every
+// sample in a single transaction
+func saveUsageSamples(ctx context.Context, db *sql.DB, insert string, samples []*models.UsageSample) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, sample := range samples {
+		_, err := stmt.ExecContext(ctx,
+			sample.ClusterID, sample.Namespace, sample.Kind, sample.Workload, sample.Pod, sample.Container,
+			sample.Timestamp.UTC(), sample.CPU, sample.Memory,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queryUsageSamples runs a query selecting usageSampleColumns
+func queryUsageSamples(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]*models.UsageSample, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []*models.UsageSample
+	for rows.Next() {
+		var sample models.UsageSample
+		err := rows.Scan(
+			&sample.ClusterID, &sample.Namespace, &sample.Kind, &sample.Workload, &sample.Pod, &sample.Container,
+			&sample.Timestamp, &sample.CPU, &sample.Memory,
+		)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, &sample)
+	}
+
+	return samples, rows.Err()
+}